@@ -22,6 +22,26 @@ import (
 func main() {{ "Example" | code }}
 ```
 
+## Error handling
+
+Errors returned by lock operations are wrapped in a `*redislock.Error`, which
+records the operation and the key. Match them with `errors.Is` and
+`errors.As`, not `==`:
+
+```go
+lock, err := locker.Obtain(ctx, "my-key", time.Second, 10*time.Second, nil)
+if errors.Is(err, redislock.ErrNotObtained) {
+	var lerr *redislock.Error
+	if errors.As(err, &lerr) {
+		log.Printf("gave up on %s after %d attempts", lerr.Key, lerr.Attempts)
+	}
+	return
+} else if err != nil {
+	log.Fatalln(err)
+}
+defer lock.Release(ctx)
+```
+
 ## Documentation
 
 Full documentation is available on [GoDoc](http://godoc.org/github.com/bsm/redislock)
@@ -0,0 +1,211 @@
+package redislock
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// forceReleaseSignalTTL is the time a release signal pushed by ForceRelease
+// remains available to waiters.
+const forceReleaseSignalTTL = time.Minute
+
+// luaForceReleaseSrc is the source of the force release script, which is also
+// wrapped by unlinkSrc.
+const luaForceReleaseSrc = `
+local v = redis.call("get", KEYS[1])
+if not v or (ARGV[2] ~= "" and string.sub(v, 1, #ARGV[2]) ~= ARGV[2]) then return 0 end
+redis.call("del", KEYS[1])
+redis.call("publish", KEYS[1] .. ":released", "1")
+redis.call("rpush", KEYS[2], "1")
+redis.call("ltrim", KEYS[2], -1, -1)
+redis.call("pexpire", KEYS[2], ARGV[1])
+return v`
+
+var luaForceRelease = redis.NewScript(luaForceReleaseSrc)
+
+// ForceRelease releases the exclusive lock on key regardless of its holder
+// and wakes up waiters using release signals or notifications. It is meant
+// for operational break-glass scenarios, the previous holder is not notified.
+// May return ErrLockNotHeld if the key is not locked.
+func (c *Client) ForceRelease(ctx context.Context, key string) error {
+	return c.forceRelease(ctx, key, "")
+}
+
+// ForceReleaseByToken is like ForceRelease, but only releases the lock if it
+// is held with token.
+// May return ErrLockNotHeld if the key is not locked with token.
+func (c *Client) ForceReleaseByToken(ctx context.Context, key, token string) error {
+	if token == "" {
+		return ErrLockNotHeld
+	}
+	return c.forceRelease(ctx, key, token)
+}
+
+func (c *Client) forceRelease(ctx context.Context, key, token string) error {
+	key = c.defaults.KeyPrefix + key
+	ttlVal := strconv.FormatInt(int64(forceReleaseSignalTTL/time.Millisecond), 10)
+
+	rdb, err := c.route(key)
+	if err != nil {
+		return err
+	}
+
+	script := luaForceRelease
+	if c.defaults.Unlink {
+		script = luaUnlinkForceRelease
+	}
+	res, err := script.Run(ctx, rdb, []string{key, c.signalKey(key)}, ttlVal, token).Result()
+	if err != nil {
+		return err
+	}
+	value, ok := res.(string)
+	if !ok {
+		return ErrLockNotHeld
+	}
+
+	if logger := c.defaults.Logger; logger != nil {
+		logger.Log(LevelWarn, "lock force-released", "key", key, "token", shortToken(token))
+	}
+	if c.defaults.Audit != nil {
+		holder, md := splitValue(value)
+		c.audit(ctx, rdb, AuditForceRelease, key, holder, md, 0, 0, c.defaults.Logger)
+	}
+	return nil
+}
+
+// listScanCount is the COUNT hint passed to SCAN by List.
+const listScanCount = 100
+
+var luaInspect = redis.NewScript(`if redis.call("type", KEYS[1]).ok ~= "string" then return false end return {redis.call("get", KEYS[1]), redis.call("pttl", KEYS[1])}`)
+
+// LockInfo describes the state of an exclusive lock.
+type LockInfo struct {
+	// Key is the key of the lock, without the client's key prefix.
+	Key string
+	// Held reports whether the lock is held.
+	Held bool
+	// Token is the token of the holder. It assumes the holder uses a
+	// random token, as the token cannot be told apart from the metadata
+	// otherwise.
+	Token string
+	// Metadata is the metadata of the holder.
+	Metadata string
+	// Owner identifies the holder, if it set Defaults.Owner.
+	Owner *Owner
+	// TTL is the remaining TTL of the lock, or zero if it does not expire.
+	TTL time.Duration
+	// Sequence is the acquisition sequence number of the holder, if it set
+	// Options.Sequence.
+	Sequence int64
+	// AdvisoryHolders is the number of holders of advisory locks on the
+	// key, and Conflicts the number of advisory locks obtained while others
+	// held them, see Defaults.Advisory. Both are only reported by clients
+	// which set Defaults.Advisory.
+	AdvisoryHolders int
+	Conflicts       int64
+	// Obtained is the time the lock was obtained. Like Expires, the local
+	// estimate of its expiry, it is only reported by Manager.Held.
+	Obtained, Expires time.Time
+}
+
+// Inspect returns the state of the exclusive lock on key in a single round
+// trip, without attempting to obtain it.
+func (c *Client) Inspect(ctx context.Context, key string) (*LockInfo, error) {
+	info := &LockInfo{Key: key}
+
+	rdb, err := c.route(c.defaults.KeyPrefix + key)
+	if err != nil {
+		return nil, err
+	}
+
+	var res interface{}
+	if prefixed := c.defaults.KeyPrefix + key; c.defaults.Advisory {
+		res, err = luaInspectAdvisory.Run(ctx, rdb, []string{prefixed, c.advisoryKey(prefixed), c.conflictsKey(prefixed)}).Result()
+	} else {
+		res, err = luaInspect.Run(ctx, rdb, []string{prefixed}).Result()
+	}
+	if err == redis.Nil {
+		return info, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	vals, _ := res.([]interface{})
+	if len(vals) == 4 {
+		holders, _ := vals[2].(int64)
+		info.AdvisoryHolders = int(holders)
+		info.Conflicts, _ = vals[3].(int64)
+		if vals[0] == nil {
+			return info, nil
+		}
+		vals = vals[:2]
+	}
+	if len(vals) != 2 {
+		return info, nil
+	}
+
+	value, _ := vals[0].(string)
+	pttl, _ := vals[1].(int64)
+
+	info.Held = true
+	info.Token, info.Metadata = splitValue(value)
+	info.Metadata = c.decryptMetadata(info.Metadata)
+	info.Owner, _ = splitOwner(strings.TrimPrefix(value, info.Token))
+	info.Sequence, _ = splitSequence(strings.TrimPrefix(value, info.Token))
+	if pttl > 0 {
+		info.TTL = time.Duration(pttl) * time.Millisecond
+	}
+	return info, nil
+}
+
+// MetadataMap returns the metadata of the holder decoded as set via
+// Options.MetadataMap. Returns nil if the metadata is not a JSON object.
+func (i *LockInfo) MetadataMap() map[string]string {
+	return decodeMetadataMap(i.Metadata)
+}
+
+// UnmarshalMetadata decodes the metadata of the holder, as encoded by
+// MarshalMetadata or set via Options.MetadataMap, into v.
+func (i *LockInfo) UnmarshalMetadata(v interface{}) error {
+	return json.Unmarshal([]byte(i.Metadata), v)
+}
+
+// List returns the held exclusive locks with keys matching the glob-style
+// pattern, which is relative to the client's key prefix. It iterates over
+// keys using SCAN, starting at cursor, and returns the cursor of the next page,
+// which is zero once all keys have been visited. Pages may be empty and, as
+// with SCAN, locks may be returned more than once.
+// Requires the client to implement ScanningClient.
+func (c *Client) List(ctx context.Context, pattern string, cursor uint64) ([]*LockInfo, uint64, error) {
+	scanner, ok := c.client.(ScanningClient)
+	if !ok {
+		return nil, 0, errScanUnsupported
+	}
+
+	prefix := c.defaults.KeyPrefix
+	keys, next, err := scanner.Scan(ctx, cursor, prefix+pattern, listScanCount).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var locks []*LockInfo
+	for _, key := range keys {
+		key = strings.TrimPrefix(key, prefix)
+		if strings.HasSuffix(key, ":fence") || strings.HasSuffix(key, ":seq") || strings.HasSuffix(key, ":advisory") || strings.HasSuffix(key, ":conflicts") {
+			continue
+		}
+
+		info, err := c.Inspect(ctx, key)
+		if err != nil {
+			return nil, 0, err
+		} else if info.Held {
+			locks = append(locks, info)
+		}
+	}
+	return locks, next, nil
+}
@@ -0,0 +1,147 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.ForceRelease", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":signal").Err()).To(Succeed())
+	})
+
+	It("should release regardless of the holder", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(subject.ForceRelease(ctx, lockKey)).To(Succeed())
+		Expect(subject.ForceRelease(ctx, lockKey)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should release by token", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(subject.ForceReleaseByToken(ctx, lockKey, "other")).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(subject.ForceReleaseByToken(ctx, lockKey, "")).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(lock.TTL(ctx)).To(BeNumerically(">", 0))
+
+		Expect(subject.ForceReleaseByToken(ctx, lockKey, lock.Token())).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeZero())
+	})
+
+	It("should wake up waiters", func() {
+		opt := &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(time.Minute),
+			ReleaseNotify: true,
+		}
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+
+		released := make(chan time.Time, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			released <- time.Now()
+			_ = subject.ForceRelease(ctx, lockKey)
+		}()
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(<-released)).To(BeNumerically("<", 500*time.Millisecond))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+})
+
+var _ = Describe("Client.Inspect", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should inspect held locks", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Key).To(Equal(lockKey))
+		Expect(info.Held).To(BeTrue())
+		Expect(info.Token).To(Equal(lock.Token()))
+		Expect(info.Metadata).To(Equal("meta"))
+		Expect(info.TTL).To(BeNumerically("~", time.Hour, time.Second))
+	})
+
+	It("should inspect free locks", func() {
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(&redislock.LockInfo{Key: lockKey}))
+	})
+})
+
+var _ = Describe("Client.List", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+	var keys = []string{lockKey + ".a", lockKey + ".b", lockKey + ".c"}
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		for _, key := range keys {
+			Expect(redisClient.Del(ctx, key, key+":fence").Err()).To(Succeed())
+		}
+	})
+
+	list := func(pattern string) []string {
+		var found []string
+		var cursor uint64
+		for {
+			locks, next, err := subject.List(ctx, pattern, cursor)
+			Expect(err).NotTo(HaveOccurred())
+			for _, info := range locks {
+				Expect(info.Held).To(BeTrue())
+				found = append(found, info.Key+"="+info.Metadata)
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+		return found
+	}
+
+	It("should list held locks", func() {
+		for _, key := range keys[:2] {
+			_, err := subject.Obtain(ctx, key, time.Hour, time.Hour, &redislock.Options{Metadata: "m", Fencing: true})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(list(lockKey + ".*")).To(ConsistOf(keys[0]+"=m", keys[1]+"=m"))
+		Expect(list(lockKey + ".c")).To(BeEmpty())
+	})
+
+	It("should respect the key prefix", func() {
+		subject = redislock.New(redisClient, redislock.Defaults{KeyPrefix: lockKey})
+		_, err := subject.Obtain(ctx, ".c", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list(".*")).To(ConsistOf(".c="))
+	})
+})
@@ -0,0 +1,121 @@
+package redislock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	luaAdvisoryObtain = redis.NewScript(luaNow + `
+redis.call("zremrangebyscore", KEYS[1], "-inf", now)
+local n = redis.call("zcard", KEYS[1])
+redis.call("zadd", KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+local last = redis.call("zrange", KEYS[1], -1, -1, "withscores")
+redis.call("pexpireat", KEYS[1], last[2])
+if n > 0 then
+	redis.call("hincrby", KEYS[2], "count", 1)
+	redis.call("hset", KEYS[2], "last", now)
+end
+return n`)
+	luaInspectAdvisory = redis.NewScript(luaNow + `
+local v, pttl = false, 0
+if redis.call("type", KEYS[1]).ok == "string" then
+	v, pttl = redis.call("get", KEYS[1]), redis.call("pttl", KEYS[1])
+end
+local n = 0
+if redis.call("type", KEYS[2]).ok == "zset" then n = redis.call("zcount", KEYS[2], "(" .. now, "+inf") end
+return {v, pttl, n, tonumber(redis.call("hget", KEYS[3], "count") or "0")}`)
+)
+
+func (c *Client) advisoryKey(key string) string  { return c.companionKey(key, ":advisory") }
+func (c *Client) conflictsKey(key string) string { return c.companionKey(key, ":conflicts") }
+
+// ConflictMetricsCollector is an optional extension of MetricsCollector,
+// which is notified of conflicting advisory locks, see Defaults.Advisory.
+type ConflictMetricsCollector interface {
+	// AdvisoryConflict is called when an advisory lock was obtained while
+	// others held it, with the number of other holders.
+	AdvisoryConflict(key string, holders int)
+}
+
+// obtainAdvisory obtains an advisory lock on key, which always succeeds, and
+// records a conflict if others hold it, see Defaults.Advisory.
+func (c *Client) obtainAdvisory(ctx context.Context, key string, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	opt = c.options(opt)
+	lockTTL = c.lockTTL(lockTTL)
+	key = c.defaults.KeyPrefix + key
+
+	rdb, err := c.clientFor(key, opt.getSelectDB())
+	if err != nil {
+		return nil, err
+	}
+
+	token, value, err := c.newValue(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	once := *opt
+	once.RetryStrategy = NoRetry()
+	opTimeout := opt.getOperationTimeout()
+	keys := []string{c.advisoryKey(key), c.conflictsKey(key)}
+
+	var start time.Time
+	var holders int64
+	stats, err := c.retry(ctx, rdb, key, token, &once, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		n, err := luaAdvisoryObtain.Run(opctx, rdb, keys, value, msArg(lockTTL)).Int64()
+		holders = n
+		return err == nil, wrapOperationErr(ctx, opctx, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if holders > 0 {
+		logger := opt.getLogger()
+		if logger != nil {
+			logger.Log(LevelWarn, "advisory lock conflict", "key", key, "token", shortToken(token), "holders", holders)
+		}
+		if metrics, ok := c.defaults.Metrics.(ConflictMetricsCollector); ok {
+			metrics.AdvisoryConflict(key, int(holders))
+		}
+	}
+
+	effectiveTTL := lockTTL - time.Since(start)
+	if effectiveTTL < 0 {
+		effectiveTTL = 0
+	}
+
+	lock := &Lock{
+		client:       c,
+		rdb:          rdb,
+		key:          key,
+		token:        token,
+		value:        value,
+		ttl:          lockTTL,
+		effectiveTTL: effectiveTTL,
+		obtained:     start,
+		expires:      start.Add(lockTTL),
+		opTimeout:    opTimeout,
+		logger:       opt.getLogger(),
+		scripts:      sharedScripts,
+		scriptArg:    value,
+		stats:        stats,
+		conflicts:    int(holders),
+	}
+	lock.keyBuf[0] = keys[0]
+	lock.scriptKeys = lock.keyBuf[:]
+	return c.track(lock), nil
+}
+
+// Conflicts returns the number of other holders of an advisory lock at the
+// time it was obtained, see Defaults.Advisory. It is zero for other locks.
+func (l *Lock) Conflicts() int {
+	return l.conflicts
+}
@@ -0,0 +1,111 @@
+package redislock_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Advisory", func() {
+	var subject *redislock.Client
+	var metrics *conflictMetrics
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		metrics = new(conflictMetrics)
+		subject = redislock.New(redisClient, redislock.Defaults{Advisory: true, Metrics: metrics})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":advisory", lockKey+":conflicts").Err()).To(Succeed())
+	})
+
+	It("should record conflicts instead of blocking", func() {
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock1.Conflicts()).To(BeZero())
+
+		lock2, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "second"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.Conflicts()).To(Equal(1))
+		Expect(lock2.Metadata()).To(Equal("second"))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Held).To(BeFalse())
+		Expect(info.AdvisoryHolders).To(Equal(2))
+		Expect(info.Conflicts).To(Equal(int64(1)))
+		Expect(metrics.conflicts).To(Equal([]int{1}))
+
+		Expect(lock2.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock2.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock1.Release(ctx)).To(Succeed())
+		Expect(lock1.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(lock2.Release(ctx)).To(Succeed())
+
+		lock3, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock3.Conflicts()).To(BeZero())
+		Expect(lock3.Release(ctx)).To(Succeed())
+
+		info, err = subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.AdvisoryHolders).To(BeZero())
+		Expect(info.Conflicts).To(Equal(int64(1)))
+	})
+
+	It("should not count expired holders", func() {
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, 10*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(20 * time.Millisecond)
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Conflicts()).To(BeZero())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should report exclusive holders", func() {
+		lock, err := redislock.New(redisClient).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Held).To(BeTrue())
+		Expect(info.Token).To(Equal(lock.Token()))
+		Expect(info.TTL).To(BeNumerically("~", time.Minute, time.Second))
+	})
+
+	It("should be enabled per call", func() {
+		lock1, err := redislock.New(redisClient).Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Advisory: true})
+		Expect(err).NotTo(HaveOccurred())
+		lock2, err := redislock.New(redisClient).Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Advisory: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.Conflicts()).To(Equal(1))
+		Expect(lock1.Release(ctx)).To(Succeed())
+		Expect(lock2.Release(ctx)).To(Succeed())
+	})
+})
+
+type conflictMetrics struct {
+	mu        sync.Mutex
+	conflicts []int
+}
+
+func (*conflictMetrics) ObtainAttempt(string, bool)              {}
+func (*conflictMetrics) ObtainDone(string, time.Duration, error) {}
+func (*conflictMetrics) RefreshDone(string, error)               {}
+func (*conflictMetrics) Released(string, time.Duration)          {}
+
+func (m *conflictMetrics) AdvisoryConflict(_ string, holders int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.conflicts = append(m.conflicts, holders)
+}
@@ -0,0 +1,124 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// asyncReleaseTimeout limits each attempt of an asynchronous release.
+	asyncReleaseTimeout = 5 * time.Second
+	// asyncReleaseWindow is the time asynchronous releases of locks without
+	// a local expiry estimate, e.g. resumed locks, are retried for.
+	asyncReleaseWindow = time.Minute
+	// asyncReleaseMinBackoff and asyncReleaseMaxBackoff bound the backoff
+	// between the attempts of an asynchronous release.
+	asyncReleaseMinBackoff = 50 * time.Millisecond
+	asyncReleaseMaxBackoff = 5 * time.Second
+	// asyncReleaseConcurrency is the maximum number of attempts of
+	// asynchronous releases a client has in flight at once.
+	asyncReleaseConcurrency = 16
+)
+
+// asyncReleases are the pending asynchronous releases of a client, see
+// Lock.ReleaseAsync. Each release runs in its own goroutine, slots bound the
+// number of attempts in flight.
+type asyncReleases struct {
+	mu      sync.Mutex
+	pending int
+	slots   chan struct{}
+}
+
+type asyncRelease struct {
+	lock     *Lock
+	done     chan error
+	deadline time.Time
+	backoff  time.Duration
+}
+
+// ReleaseAsync releases the lock like Release, but returns immediately, e.g.
+// so request paths do not block on redis. The release is performed in the
+// background and failed attempts are retried with an exponential backoff
+// until the lock is released, known not to be held, or past its local expiry
+// estimate, see Lock.ValidUntil, after which redis releases it anyway. Locks
+// without an estimate, e.g. resumed locks, are retried for up to a minute.
+// Releases proceed independently, with up to 16 attempts per client in flight
+// at once, so slow attempts or Options.MinHold delays do not hold up others.
+// The returned channel delivers the final result, nil or the error of the
+// last attempt, and is closed; it may be ignored. Failed attempts are logged.
+func (l *Lock) ReleaseAsync() <-chan error {
+	deadline := l.ValidUntil()
+	if deadline.IsZero() {
+		deadline = time.Now().Add(asyncReleaseWindow)
+	}
+
+	p := &asyncRelease{lock: l, done: make(chan error, 1), deadline: deadline, backoff: asyncReleaseMinBackoff}
+	l.client.releases.enqueue(p)
+	return p.done
+}
+
+// PendingReleases returns the number of asynchronous releases which have not
+// finished yet, see Lock.ReleaseAsync.
+func (c *Client) PendingReleases() int {
+	c.releases.mu.Lock()
+	defer c.releases.mu.Unlock()
+
+	return c.releases.pending
+}
+
+func (r *asyncReleases) enqueue(p *asyncRelease) {
+	r.mu.Lock()
+	if r.slots == nil {
+		r.slots = make(chan struct{}, asyncReleaseConcurrency)
+	}
+	r.pending++
+	r.mu.Unlock()
+
+	go r.run(p)
+}
+
+// run attempts p until it is released, known not to be held or past its
+// deadline.
+func (r *asyncReleases) run(p *asyncRelease) {
+	l := p.lock
+
+	// The minimum hold time is waited out before taking a slot, so it does
+	// not hold up other releases.
+	ctx, cancel := context.WithDeadline(context.Background(), p.deadline)
+	_ = l.waitMinHold(ctx)
+	cancel()
+
+	for {
+		r.slots <- struct{}{}
+		ctx, cancel := context.WithTimeout(context.Background(), asyncReleaseTimeout)
+		err := l.Release(ctx)
+		cancel()
+		<-r.slots
+
+		if err != nil && !errors.Is(err, ErrLockNotHeld) && time.Now().Add(p.backoff).Before(p.deadline) {
+			if l.logger != nil {
+				l.logger.Log(LevelWarn, "async release failed, retrying", "key", l.key, "token", shortToken(l.token), "backoff", p.backoff, "error", err)
+			}
+
+			time.Sleep(p.backoff)
+			if p.backoff *= 2; p.backoff > asyncReleaseMaxBackoff {
+				p.backoff = asyncReleaseMaxBackoff
+			}
+			continue
+		}
+
+		if err != nil && l.logger != nil {
+			l.logger.Log(LevelError, "async release failed", "key", l.key, "token", shortToken(l.token), "error", err)
+		}
+
+		r.mu.Lock()
+		r.pending--
+		r.mu.Unlock()
+
+		p.done <- err
+		close(p.done)
+		return
+	}
+}
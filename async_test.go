@@ -0,0 +1,86 @@
+package redislock_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock.ReleaseAsync", func() {
+	var flaky *flakyClient
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		flaky = &flakyClient{RedisClient: redisClient}
+		subject = redislock.New(flaky)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+"b").Err()).To(Succeed())
+	})
+
+	It("should release in the background", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(lock.ReleaseAsync()).Should(Receive(BeNil()))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+		Expect(subject.PendingReleases()).To(BeZero())
+	})
+
+	It("should retry failed releases", func() {
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		lock2, err := subject.Obtain(ctx, lockKey+"b", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		atomic.StoreInt32(&flaky.failures, 3)
+		done1, done2 := lock1.ReleaseAsync(), lock2.ReleaseAsync()
+		Expect(subject.PendingReleases()).To(Equal(2))
+
+		Eventually(done1, time.Second).Should(Receive(BeNil()))
+		Eventually(done2, time.Second).Should(Receive(BeNil()))
+		Expect(redisClient.Exists(ctx, lockKey, lockKey+"b").Val()).To(BeZero())
+		Expect(subject.PendingReleases()).To(BeZero())
+	})
+
+	It("should give up once the lock expired", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 200*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		atomic.StoreInt32(&flaky.failures, 100)
+		start := time.Now()
+		Eventually(lock.ReleaseAsync(), time.Second).Should(Receive(MatchError(errLoading)))
+		Expect(time.Since(start)).To(BeNumerically("<", 300*time.Millisecond))
+		Expect(subject.PendingReleases()).To(BeZero())
+	})
+
+	It("should not hold up releases behind a minimum hold time", func() {
+		held, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{MinHold: 300 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		lock, err := subject.Obtain(ctx, lockKey+"b", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		heldDone := held.ReleaseAsync()
+		Eventually(lock.ReleaseAsync(), 100*time.Millisecond).Should(Receive(BeNil()))
+		Expect(heldDone).NotTo(Receive())
+		Expect(subject.PendingReleases()).To(Equal(1))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+
+		Eventually(heldDone, time.Second).Should(Receive(BeNil()))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should not retry locks which are not held", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		Eventually(lock.ReleaseAsync()).Should(Receive(MatchError(redislock.ErrLockNotHeld)))
+	})
+})
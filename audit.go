@@ -0,0 +1,177 @@
+package redislock
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Audit events, see AuditEvent.Event.
+const (
+	AuditObtain       = "obtain"
+	AuditRefresh      = "refresh"
+	AuditRelease      = "release"
+	AuditForceRelease = "force_release"
+	AuditTransfer     = "transfer"
+)
+
+// defaultAuditMaxLen is the default Audit.MaxLen.
+const defaultAuditMaxLen = 10000
+
+// Audit records an audit trail of locks in a capped redis stream, see
+// Defaults.Audit. Events are appended after each successful operation, in a
+// separate round trip. Failures to append them are logged, but do not fail
+// the operation. The client must implement StreamingClient.
+type Audit struct {
+	// Stream is the key of a stream shared by all locks of the client,
+	// which is not subject to the key prefix.
+	// Default: a stream per lock under its key + ":audit"
+	Stream string
+
+	// MaxLen caps the number of events in each stream, approximately.
+	// Default: 10000
+	MaxLen int64
+}
+
+func (a *Audit) getMaxLen() int64 {
+	if a.MaxLen > 0 {
+		return a.MaxLen
+	}
+	return defaultAuditMaxLen
+}
+
+// StreamingClient is an optional extension of RedisClient which is required
+// to record and read audit events, see Defaults.Audit.
+type StreamingClient interface {
+	XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+	XRangeN(ctx context.Context, stream, start, stop string, count int64) *redis.XMessageSliceCmd
+}
+
+// AuditEvent is an event recorded in an audit stream, see Client.AuditEvents.
+type AuditEvent struct {
+	// ID is the ID of the stream entry.
+	ID string
+	// Time is the time the event was recorded, taken from the ID.
+	Time time.Time
+	// Event is the type of the event, e.g. AuditObtain.
+	Event string
+	// Key is the redis key of the lock.
+	Key string
+	// Token is the token of the holder. Events of ForceRelease report the
+	// token assuming the holder used a random token, see LockInfo.Token.
+	Token string
+	// Metadata is the metadata of the holder.
+	Metadata string
+	// TTL is the TTL set by obtain and refresh events.
+	TTL time.Duration
+}
+
+// auditStream returns the audit stream of key.
+func (c *Client) auditStream(key string) string {
+	if s := c.defaults.Audit.Stream; s != "" {
+		return s
+	}
+	return c.companionKey(key, ":audit")
+}
+
+// audit appends an event for the lock on key. It is a no-op unless
+// Defaults.Audit is set.
+func (c *Client) audit(ctx context.Context, rdb RedisClient, event, key, token, metadata string, ttl time.Duration, opTimeout time.Duration, logger Logger) {
+	a := c.defaults.Audit
+	if a == nil {
+		return
+	}
+	streamer, ok := rdb.(StreamingClient)
+	if !ok {
+		if logger != nil {
+			logger.Log(LevelWarn, "audit failed", "key", key, "event", event, "error", errAuditUnsupported)
+		}
+		return
+	}
+
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	values := []interface{}{"event", event, "key", key, "token", token, "metadata", metadata}
+	if ttl > 0 {
+		values = append(values, "ttl", strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	}
+	err := streamer.XAdd(opctx, &redis.XAddArgs{
+		Stream:       c.auditStream(key),
+		MaxLenApprox: a.getMaxLen(),
+		Values:       values,
+	}).Err()
+	if err != nil && logger != nil {
+		logger.Log(LevelWarn, "audit failed", "key", key, "event", event, "error", wrapOperationErr(ctx, opctx, err))
+	}
+}
+
+// audit appends an event for the lock. The caller must hold argMu.
+func (l *Lock) audit(ctx context.Context, event string, ttl time.Duration) {
+	if l.audited {
+		_, md := splitOwner(l.value[len(l.token):])
+		l.client.audit(ctx, l.rdb, event, l.key, l.token, md, ttl, l.opTimeout, l.logger)
+	}
+}
+
+// AuditEvents returns up to count events recorded for the lock on key since
+// the given time, oldest first. If Audit.Stream is set, key filters the
+// events of the shared stream, of which count are read, or all of them if
+// key is empty.
+func (c *Client) AuditEvents(ctx context.Context, key string, since time.Time, count int64) ([]AuditEvent, error) {
+	if c.defaults.Audit == nil {
+		return nil, nil
+	}
+	streamer, ok := c.client.(StreamingClient)
+	if !ok {
+		return nil, errAuditUnsupported
+	}
+
+	if key != "" {
+		key = c.defaults.KeyPrefix + key
+	}
+	start := "-"
+	if !since.IsZero() {
+		start = strconv.FormatInt(since.UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	msgs, err := streamer.XRangeN(ctx, c.auditStream(key), start, "+", count).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]AuditEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		ev := parseAuditEvent(msg)
+		if key == "" || ev.Key == key {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+func parseAuditEvent(msg redis.XMessage) AuditEvent {
+	str := func(name string) string {
+		s, _ := msg.Values[name].(string)
+		return s
+	}
+
+	ev := AuditEvent{
+		ID:       msg.ID,
+		Event:    str("event"),
+		Key:      str("key"),
+		Token:    str("token"),
+		Metadata: str("metadata"),
+	}
+	if i := strings.IndexByte(msg.ID, '-'); i > 0 {
+		ms, _ := strconv.ParseInt(msg.ID[:i], 10, 64)
+		ev.Time = time.Unix(0, ms*int64(time.Millisecond))
+	}
+	if ms, err := strconv.ParseInt(str("ttl"), 10, 64); err == nil {
+		ev.TTL = time.Duration(ms) * time.Millisecond
+	}
+	return ev
+}
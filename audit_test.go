@@ -0,0 +1,63 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Audit", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":audit", "__redislock_audit__").Err()).To(Succeed())
+	})
+
+	It("should record events per key", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{Audit: &redislock.Audit{}})
+		since := time.Now().Add(-time.Second)
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "job 1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		events, err := subject.AuditEvents(ctx, lockKey, since, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(3))
+		for i, event := range []string{redislock.AuditObtain, redislock.AuditRefresh, redislock.AuditRelease} {
+			Expect(events[i].Event).To(Equal(event))
+			Expect(events[i].Key).To(Equal(lockKey))
+			Expect(events[i].Token).To(Equal(lock.Token()))
+			Expect(events[i].Metadata).To(Equal("job 1"))
+			Expect(events[i].Time).To(BeTemporally("~", time.Now(), time.Second))
+		}
+		Expect(events[0].TTL).To(Equal(time.Minute))
+		Expect(events[1].TTL).To(Equal(time.Hour))
+		Expect(events[2].TTL).To(BeZero())
+	})
+
+	It("should record events in a shared stream", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{Audit: &redislock.Audit{Stream: "__redislock_audit__", MaxLen: 100}})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "job 2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.ForceRelease(ctx, lockKey)).To(Succeed())
+
+		events, err := subject.AuditEvents(ctx, "", time.Time{}, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[1].Event).To(Equal(redislock.AuditForceRelease))
+		Expect(events[1].Token).To(Equal(lock.Token()))
+		Expect(events[1].Metadata).To(Equal("job 2"))
+
+		events, err = subject.AuditEvents(ctx, "other", time.Time{}, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(BeEmpty())
+		Expect(redisClient.Exists(ctx, lockKey+":audit").Val()).To(BeZero())
+	})
+})
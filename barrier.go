@@ -0,0 +1,101 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var luaBarrierArrive = redis.NewScript(`local n = redis.call("incr", KEYS[1]) if n == 1 then redis.call("pexpire", KEYS[1], ARGV[1]) end return n`)
+
+// ErrBarrierExpired is returned by Barrier.Wait when the barrier expired
+// before all participants arrived.
+var ErrBarrierExpired = errors.New("redislock: barrier expired")
+
+// Barrier is a distributed barrier, which blocks participants until the
+// expected number of them has arrived.
+//
+// The barrier is single-use: it counts arrivals under the key, which expires
+// after the TTL, counted from the first arrival. Participants waiting at that
+// point fail with ErrBarrierExpired. The barrier can be reused once expired.
+type Barrier struct {
+	client *Client
+	key    string
+	count  int
+	ttl    time.Duration
+}
+
+// NewBarrier creates a new Barrier for key, which releases participants once
+// count of them have arrived.
+func NewBarrier(client RedisClient, key string, count int, ttl time.Duration) *Barrier {
+	return &Barrier{client: New(client), key: key, count: count, ttl: ttl}
+}
+
+// Wait registers the arrival of a participant and blocks until all
+// participants have arrived. It polls the barrier according to
+// opt.RetryStrategy, or every 100ms if not set. The arrival is counted even if
+// Wait returns an error, so it must not be called again by the same
+// participant.
+// May return ErrNotObtained if ctx is done or the retry strategy gives up
+// before all participants have arrived, or ErrBarrierExpired.
+func (b *Barrier) Wait(ctx context.Context, opt *Options) error {
+	if err := b.client.validate(ctx, b.key, b.ttl); err != nil {
+		return err
+	}
+
+	retry := LinearBackoff(100 * time.Millisecond)
+	if opt != nil && opt.RetryStrategy != nil {
+		retry = opt.getRetryStrategy()
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	ttlVal := strconv.FormatInt(int64(b.ttl/time.Millisecond), 10)
+
+	n, err := b.run(ctx, luaBarrierArrive, opTimeout, ttlVal)
+	var timer *time.Timer
+	for {
+		if err == redis.Nil {
+			return ErrBarrierExpired
+		} else if err != nil && ctx.Err() != nil {
+			return ErrNotObtained
+		} else if err != nil {
+			return err
+		} else if n >= b.count {
+			return nil
+		}
+
+		backoff := retry.NextBackoff()
+		if backoff < 1 {
+			return ErrNotObtained
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(backoff)
+			defer timer.Stop()
+		} else {
+			timer.Reset(backoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrNotObtained
+		case <-timer.C:
+		}
+
+		n, err = b.run(ctx, luaGet, opTimeout)
+	}
+}
+
+func (b *Barrier) run(ctx context.Context, script *redis.Script, opTimeout time.Duration, args ...interface{}) (int, error) {
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	n, err := script.Run(opctx, b.client.client, []string{b.key}, args...).Int()
+	if err != nil && err != redis.Nil {
+		return 0, wrapOperationErr(ctx, opctx, err)
+	}
+	return n, err
+}
@@ -0,0 +1,60 @@
+package redislock_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Barrier", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should validate arguments", func() {
+		Expect(redislock.NewBarrier(redisClient, lockKey, 2, time.Microsecond).Wait(ctx, nil)).To(MatchError(redislock.ErrInvalidTTL))
+		Expect(redislock.NewBarrier(redisClient, "", 2, time.Minute).Wait(ctx, nil)).To(MatchError(redislock.ErrInvalidKey))
+	})
+
+	It("should release all participants at once", func() {
+		barrier := redislock.NewBarrier(redisClient, lockKey, 3, time.Minute)
+		opt := &redislock.Options{RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond)}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				errs <- barrier.Wait(ctx, opt)
+			}()
+		}
+		Consistently(errs, 100*time.Millisecond).ShouldNot(Receive())
+
+		Expect(barrier.Wait(ctx, opt)).To(Succeed())
+		wg.Wait()
+		Expect(<-errs).To(Succeed())
+		Expect(<-errs).To(Succeed())
+	})
+
+	It("should time out", func() {
+		barrier := redislock.NewBarrier(redisClient, lockKey, 2, time.Minute)
+
+		cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		Expect(barrier.Wait(cctx, nil)).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should expire", func() {
+		barrier := redislock.NewBarrier(redisClient, lockKey, 2, 50*time.Millisecond)
+		opt := &redislock.Options{RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond)}
+		Expect(barrier.Wait(ctx, opt)).To(MatchError(redislock.ErrBarrierExpired))
+	})
+})
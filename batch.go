@@ -0,0 +1,169 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RefreshAll refreshes all locks with a new TTL, like Refresh, and returns
+// the error of each lock, or nil if it was refreshed. The refresh scripts of
+// all locks sharing a redis client are run in a single round trip if the
+// client implements PipeliningClient. The operation timeouts of the locks do
+// not apply, ctx limits the whole batch.
+func (c *Client) RefreshAll(ctx context.Context, locks []*Lock, ttl time.Duration) []error {
+	ttlVal := msArg(ttl)
+	start := time.Now()
+
+	errs := make([]error, len(locks))
+	batch(ctx, locks, func(l *Lock) batchCmd {
+		return batchCmd{script: l.activeScripts().refresh, keys: l.scriptKeys, args: []interface{}{l.scriptArg, ttlVal}}
+	}, func(i int, cmd *redis.Cmd) {
+		l := locks[i]
+		err := l.refreshed(start, ttl, cmd.Val(), cmd.Err(), l.logger)
+		if err == nil {
+			l.argMu.RLock()
+			l.audit(ctx, AuditRefresh, ttl)
+			l.argMu.RUnlock()
+		}
+		if metrics := l.client.defaults.Metrics; metrics != nil {
+			metrics.RefreshDone(l.key, err)
+		}
+		wrapErr("refresh", l.key, &err)
+		errs[i] = err
+	})
+	return errs
+}
+
+// ReleaseAll releases all locks, like Release, and returns the error of each
+// lock, or nil if it was released. The release scripts of all locks sharing
+// a redis client are run in a single round trip if the client implements
+// PipeliningClient. The operation timeouts of the locks do not apply, ctx
+// limits the whole batch.
+func (c *Client) ReleaseAll(ctx context.Context, locks []*Lock) []error {
+	for _, l := range locks {
+		l.StopAutoRefresh()
+		l.setReleasing(true)
+	}
+
+	errs := make([]error, len(locks))
+	batch(ctx, locks, (*Lock).releaseBatchCmd, func(i int, cmd *redis.Cmd) {
+		l := locks[i]
+		err := l.released(cmd.Val(), cmd.Err())
+		if err == nil {
+			l.argMu.RLock()
+			l.audit(ctx, AuditRelease, 0)
+			l.argMu.RUnlock()
+		}
+		if err == nil || errors.Is(err, ErrLockNotHeld) {
+			l.forgetIntent()
+		}
+		wrapErr("release", l.key, &err)
+		errs[i] = err
+	})
+
+	for _, l := range locks {
+		l.setReleasing(false)
+		l.releaseLocal()
+	}
+	return errs
+}
+
+func (l *Lock) releaseBatchCmd() batchCmd {
+	script, keys, args := l.releaseCmd()
+	return batchCmd{script: script, keys: keys, args: args}
+}
+
+// batchCmd is a script call within a batch.
+type batchCmd struct {
+	script *redis.Script
+	keys   []string
+	args   []interface{}
+}
+
+// batch runs the script call returned by cmdOf for each lock, grouped by
+// redis client, and passes the results to done.
+func batch(ctx context.Context, locks []*Lock, cmdOf func(*Lock) batchCmd, done func(int, *redis.Cmd)) {
+	for _, l := range locks {
+		l.argMu.RLock()
+		defer l.argMu.RUnlock()
+	}
+
+	var clients []RedisClient
+	groups := make(map[RedisClient][]int)
+	for i, l := range locks {
+		if _, ok := groups[l.rdb]; !ok {
+			clients = append(clients, l.rdb)
+		}
+		groups[l.rdb] = append(groups[l.rdb], i)
+	}
+
+	for _, rdb := range clients {
+		indices := groups[rdb]
+		cmds := make([]batchCmd, len(indices))
+		for j, i := range indices {
+			cmds[j] = cmdOf(locks[i])
+		}
+		for j, res := range runBatch(ctx, rdb, cmds) {
+			done(indices[j], res)
+		}
+	}
+}
+
+// runBatch runs cmds on rdb in a single pipeline if supported, loading
+// scripts missing from the script cache, or one by one otherwise.
+func runBatch(ctx context.Context, rdb RedisClient, cmds []batchCmd) []*redis.Cmd {
+	piper, ok := rdb.(PipeliningClient)
+	if !ok {
+		res := make([]*redis.Cmd, len(cmds))
+		for i, cmd := range cmds {
+			res[i] = cmd.script.Run(ctx, rdb, cmd.keys, cmd.args...)
+		}
+		return res
+	}
+
+	res := execPipeline(ctx, piper, cmds)
+
+	var missing []int
+	loaded := make(map[*redis.Script]error)
+	for i, cmd := range res {
+		if err := cmd.Err(); err == nil || !strings.HasPrefix(err.Error(), "NOSCRIPT ") {
+			continue
+		}
+
+		script := cmds[i].script
+		if _, ok := loaded[script]; !ok {
+			loaded[script] = script.Load(ctx, rdb).Err()
+		}
+		if err := loaded[script]; err != nil {
+			res[i] = redis.NewCmdResult(nil, err)
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return res
+	}
+
+	retry := make([]batchCmd, len(missing))
+	for j, i := range missing {
+		retry[j] = cmds[i]
+	}
+	for j, cmd := range execPipeline(ctx, piper, retry) {
+		res[missing[j]] = cmd
+	}
+	return res
+}
+
+func execPipeline(ctx context.Context, piper PipeliningClient, cmds []batchCmd) []*redis.Cmd {
+	pipe := piper.Pipeline()
+	res := make([]*redis.Cmd, len(cmds))
+	for i, cmd := range cmds {
+		res[i] = pipe.EvalSha(ctx, cmd.script.Hash(), cmd.keys, cmd.args...)
+	}
+	_, _ = pipe.Exec(ctx)
+	return res
+}
@@ -0,0 +1,66 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.RefreshAll and ReleaseAll", func() {
+	var subject *redislock.Client
+	var keys = []string{lockKey + ".1", lockKey + ".2", lockKey + ".3"}
+	var ctx = context.Background()
+
+	obtainAll := func() []*redislock.Lock {
+		var locks []*redislock.Lock
+		for i, key := range keys {
+			lock, err := subject.Obtain(ctx, key, time.Second, time.Minute, &redislock.Options{ReleaseNotify: i == 1})
+			Expect(err).NotTo(HaveOccurred())
+			locks = append(locks, lock)
+		}
+		return locks
+	}
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, keys...).Err()).To(Succeed())
+	})
+
+	It("should refresh and release in a pipeline", func() {
+		locks := obtainAll()
+		Expect(redisClient.Set(ctx, keys[2], "ABCD", 0).Err()).To(Succeed())
+		Expect(redisClient.ScriptFlush(ctx).Err()).To(Succeed())
+
+		errs := subject.RefreshAll(ctx, locks, time.Hour)
+		Expect(errs).To(HaveLen(3))
+		Expect(errs[0]).NotTo(HaveOccurred())
+		Expect(errs[1]).NotTo(HaveOccurred())
+		Expect(errs[2]).To(MatchError(redislock.ErrLockStolen))
+		Expect(locks[0].TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(locks[1].TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(locks[2].Err()).To(MatchError(redislock.ErrLockStolen))
+
+		errs = subject.ReleaseAll(ctx, locks)
+		Expect(errs[0]).NotTo(HaveOccurred())
+		Expect(errs[1]).NotTo(HaveOccurred())
+		Expect(errs[2]).To(MatchError(redislock.ErrLockStolen))
+		Expect(redisClient.Exists(ctx, keys[0], keys[1]).Val()).To(Equal(int64(0)))
+		Expect(locks[0].Err()).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should fall back to individual calls", func() {
+		counting := &scriptCountingClient{RedisClient: redisClient}
+		subject = redislock.New(counting)
+		locks := obtainAll()
+
+		Expect(subject.RefreshAll(ctx, locks, time.Hour)).To(Equal([]error{nil, nil, nil}))
+		Expect(subject.ReleaseAll(ctx, locks)).To(Equal([]error{nil, nil, nil}))
+		Expect(counting.scripts).To(Equal(int32(6)))
+	})
+})
@@ -0,0 +1,296 @@
+// Package bench simulates contended workloads of redislock clients, e.g. to
+// evaluate retry strategies and fast paths objectively. A Scenario runs a
+// number of workers, each of which obtains, holds and releases locks on a
+// set of keys, picking a hot key with a configurable probability. Workers
+// draw keys from seeded random sources, so runs are reproducible up to
+// timing.
+//
+//	res, err := bench.Run(ctx, rdb, bench.Scenario{Workers: 50, Keys: 10, Contention: 0.5})
+//	...
+//	fmt.Println(res)
+//
+// The benchmarks of the package run the scenarios against a
+// redislocktest.Server, or against the redis server at REDISLOCK_BENCH_ADDR
+// if set.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+)
+
+// Scenario describes a simulated workload.
+type Scenario struct {
+	// Workers is the number of concurrent workers.
+	// Default: 10
+	Workers int
+
+	// Keys is the number of keys the workers lock.
+	// Default: 10
+	Keys int
+
+	// Contention is the probability of a worker picking the first, hot key
+	// instead of a uniformly random one, between 0 and 1.
+	// Default: 0
+	Contention float64
+
+	// Operations is the number of locks each worker tries to obtain.
+	// Default: 100
+	Operations int
+
+	// HoldTime is the time each lock is held before it is released.
+	// Default: 1ms
+	HoldTime time.Duration
+
+	// TTL is the lock TTL.
+	// Default: 1s
+	TTL time.Duration
+
+	// WaitTimeout is the wait timeout of each attempt to obtain a lock.
+	// Default: 1s
+	WaitTimeout time.Duration
+
+	// KeyPrefix is prepended to the keys of the scenario.
+	// Default: "__redislock_bench__:"
+	KeyPrefix string
+
+	// Seed seeds the random sources of the workers.
+	Seed int64
+
+	// Defaults configure the client used by the workers.
+	Defaults redislock.Defaults
+
+	// Options are passed to Obtain by all workers. The RetryStrategy must be
+	// safe for concurrent use, or implement
+	// redislock.ReusableRetryStrategy like the built-in ones.
+	// Default: retry every HoldTime
+	Options *redislock.Options
+}
+
+func (s *Scenario) norm() {
+	if s.Workers <= 0 {
+		s.Workers = 10
+	}
+	if s.Keys <= 0 {
+		s.Keys = 10
+	}
+	if s.Operations <= 0 {
+		s.Operations = 100
+	}
+	if s.HoldTime <= 0 {
+		s.HoldTime = time.Millisecond
+	}
+	if s.TTL <= 0 {
+		s.TTL = time.Second
+	}
+	if s.WaitTimeout <= 0 {
+		s.WaitTimeout = time.Second
+	}
+	if s.KeyPrefix == "" {
+		s.KeyPrefix = "__redislock_bench__:"
+	}
+	if s.Options == nil {
+		s.Options = &redislock.Options{RetryStrategy: redislock.LinearBackoff(s.HoldTime)}
+	}
+}
+
+// Result is the outcome of a Scenario.
+type Result struct {
+	// Obtained is the number of locks obtained, Failed the number of
+	// attempts which did not obtain a lock, including errors.
+	Obtained, Failed int
+	// Elapsed is the duration of the run.
+	Elapsed time.Duration
+	// Throughput is the number of locks obtained per second.
+	Throughput float64
+	// P50 and P99 are percentiles of the time spent obtaining a lock, over
+	// the successful attempts.
+	P50, P99 time.Duration
+	// Commands is the number of redis commands sent, including those of
+	// pipelines and transactions.
+	Commands int64
+}
+
+// CommandsPerLock returns the number of redis commands sent per obtained
+// lock.
+func (r *Result) CommandsPerLock() float64 {
+	if r.Obtained == 0 {
+		return 0
+	}
+	return float64(r.Commands) / float64(r.Obtained)
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%d obtained, %d failed in %s (%.0f/s), p50 %s, p99 %s, %.1f commands/lock",
+		r.Obtained, r.Failed, r.Elapsed.Round(time.Millisecond), r.Throughput,
+		r.P50, r.P99, r.CommandsPerLock())
+}
+
+// Run runs s against rdb. Commands are counted via a hook if rdb supports
+// them, like the clients of go-redis, which is added on the first run and
+// kept for later ones, so runs on the same client must not overlap. Other
+// clients are wrapped, which hides optional interfaces, such as
+// redislock.PipeliningClient, from the workers.
+func Run(ctx context.Context, rdb redislock.RedisClient, s Scenario) (*Result, error) {
+	s.norm()
+
+	counter, client := count(rdb)
+	locker := redislock.New(client, s.Defaults)
+	start := atomic.LoadInt64(&counter.n)
+	began := time.Now()
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		failed    int
+		firstErr  error
+		wg        sync.WaitGroup
+	)
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			rnd := rand.New(rand.NewSource(s.Seed + int64(worker)))
+			var own []time.Duration
+			var fails int
+			var werr error
+			for op := 0; op < s.Operations && ctx.Err() == nil; op++ {
+				key := 0
+				if rnd.Float64() >= s.Contention {
+					key = rnd.Intn(s.Keys)
+				}
+
+				t := time.Now()
+				lock, err := locker.Obtain(ctx, s.KeyPrefix+strconv.Itoa(key), s.WaitTimeout, s.TTL, s.Options)
+				if err != nil {
+					fails++
+					if werr == nil && !errors.Is(err, redislock.ErrNotObtained) {
+						werr = err
+					}
+					continue
+				}
+				own = append(own, time.Since(t))
+
+				time.Sleep(s.HoldTime)
+				_ = lock.Release(context.Background())
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			latencies = append(latencies, own...)
+			failed += fails
+			if firstErr == nil {
+				firstErr = werr
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if firstErr != nil {
+		return nil, firstErr
+	}
+
+	res := &Result{
+		Obtained: len(latencies),
+		Failed:   failed,
+		Elapsed:  time.Since(began),
+		Commands: atomic.LoadInt64(&counter.n) - start,
+	}
+	res.Throughput = float64(res.Obtained) / res.Elapsed.Seconds()
+	res.P50, res.P99 = percentile(latencies, 0.5), percentile(latencies, 0.99)
+	return res, nil
+}
+
+// percentile returns the p-th percentile of ds, sorting ds.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	return ds[int(float64(len(ds)-1)*p)]
+}
+
+// --------------------------------------------------------------------
+
+// commandCounter counts redis commands.
+type commandCounter struct{ n int64 }
+
+func (c *commandCounter) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	atomic.AddInt64(&c.n, 1)
+	return ctx, nil
+}
+
+func (c *commandCounter) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (c *commandCounter) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	atomic.AddInt64(&c.n, int64(len(cmds)))
+	return ctx, nil
+}
+
+func (c *commandCounter) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+// hooks are the counters added to hookable clients.
+var hooks sync.Map
+
+type hookable interface {
+	redislock.RedisClient
+	AddHook(redis.Hook)
+}
+
+// count returns the counter of rdb and the client to pass to redislock.
+func count(rdb redislock.RedisClient) (*commandCounter, redislock.RedisClient) {
+	if h, ok := rdb.(hookable); ok {
+		counter, loaded := hooks.LoadOrStore(rdb, new(commandCounter))
+		if !loaded {
+			h.AddHook(counter.(*commandCounter))
+		}
+		return counter.(*commandCounter), rdb
+	}
+
+	counter := new(commandCounter)
+	return counter, &countingClient{RedisClient: rdb, counter: counter}
+}
+
+// countingClient counts the commands of a client without hooks.
+type countingClient struct {
+	redislock.RedisClient
+	counter *commandCounter
+}
+
+func (c *countingClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	atomic.AddInt64(&c.counter.n, 1)
+	return c.RedisClient.SetNX(ctx, key, value, expiration)
+}
+
+func (c *countingClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	atomic.AddInt64(&c.counter.n, 1)
+	return c.RedisClient.Eval(ctx, script, keys, args...)
+}
+
+func (c *countingClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	atomic.AddInt64(&c.counter.n, 1)
+	return c.RedisClient.EvalSha(ctx, sha1, keys, args...)
+}
+
+func (c *countingClient) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	atomic.AddInt64(&c.counter.n, 1)
+	return c.RedisClient.ScriptExists(ctx, hashes...)
+}
+
+func (c *countingClient) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	atomic.AddInt64(&c.counter.n, 1)
+	return c.RedisClient.ScriptLoad(ctx, script)
+}
@@ -0,0 +1,142 @@
+package bench_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/bench"
+	"github.com/muroq/redislock/redislocktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	var server *redislocktest.Server
+	var rdb *redis.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		var err error
+		server, err = redislocktest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+		rdb = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	})
+
+	AfterEach(func() {
+		Expect(rdb.Close()).To(Succeed())
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should simulate contention", func() {
+		res, err := bench.Run(ctx, rdb, bench.Scenario{Workers: 4, Keys: 2, Contention: 0.5, Operations: 5})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Obtained).To(Equal(20))
+		Expect(res.Failed).To(BeZero())
+		Expect(res.P99).To(BeNumerically(">=", res.P50))
+		Expect(res.Throughput).To(BeNumerically(">", 0))
+		Expect(res.CommandsPerLock()).To(BeNumerically(">=", 2))
+		Expect(res.String()).To(ContainSubstring("20 obtained, 0 failed"))
+	})
+
+	It("should count commands of clients without hooks", func() {
+		res, err := bench.Run(ctx, redislocktest.New(), bench.Scenario{Workers: 1, Keys: 1, Operations: 3})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Obtained).To(Equal(3))
+		Expect(res.Commands).To(BeNumerically(">=", 6))
+	})
+
+	It("should report failed attempts", func() {
+		res, err := bench.Run(ctx, rdb, bench.Scenario{
+			Workers:    4,
+			Keys:       1,
+			Operations: 3,
+			HoldTime:   10 * time.Millisecond,
+			Options:    &redislock.Options{RetryStrategy: redislock.NoRetry()},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Obtained + res.Failed).To(Equal(12))
+		Expect(res.Failed).To(BeNumerically(">", 0))
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock/bench")
+}
+
+func BenchmarkContention(b *testing.B) {
+	rdb, closeFn := benchClient(b)
+	defer closeFn()
+
+	for _, contention := range []float64{0, 0.5, 0.9} {
+		b.Run("contention="+strconv.FormatFloat(contention, 'f', -1, 64), func(b *testing.B) {
+			var res *bench.Result
+			for i := 0; i < b.N; i++ {
+				var err error
+				if res, err = bench.Run(context.Background(), rdb, bench.Scenario{Workers: 20, Keys: 10, Contention: contention, Seed: int64(i)}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(res.Throughput, "locks/s")
+			b.ReportMetric(float64(res.P99.Microseconds()), "p99-µs")
+			b.ReportMetric(res.CommandsPerLock(), "cmds/lock")
+		})
+	}
+}
+
+func BenchmarkRetryStrategy(b *testing.B) {
+	rdb, closeFn := benchClient(b)
+	defer closeFn()
+
+	strategies := map[string]func() redislock.RetryStrategy{
+		"linear": func() redislock.RetryStrategy { return redislock.LinearBackoff(time.Millisecond) },
+		"exponential": func() redislock.RetryStrategy {
+			return redislock.ExponentialBackoff(time.Millisecond, 16*time.Millisecond)
+		},
+	}
+	for name, strategy := range strategies {
+		b.Run(name, func(b *testing.B) {
+			var res *bench.Result
+			for i := 0; i < b.N; i++ {
+				var err error
+				if res, err = bench.Run(context.Background(), rdb, bench.Scenario{
+					Workers:    20,
+					Keys:       2,
+					Contention: 0.9,
+					Seed:       int64(i),
+					Options:    &redislock.Options{RetryStrategy: strategy()},
+				}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(res.Throughput, "locks/s")
+			b.ReportMetric(float64(res.P99.Microseconds()), "p99-µs")
+			b.ReportMetric(res.CommandsPerLock(), "cmds/lock")
+		})
+	}
+}
+
+// benchClient connects to REDISLOCK_BENCH_ADDR, or to an embedded server.
+func benchClient(b *testing.B) (*redis.Client, func()) {
+	if addr := os.Getenv("REDISLOCK_BENCH_ADDR"); addr != "" {
+		rdb := redis.NewClient(&redis.Options{Addr: addr})
+		return rdb, func() { _ = rdb.Close() }
+	}
+
+	server, err := redislocktest.NewServer()
+	if err != nil {
+		b.Fatal(err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	return rdb, func() {
+		_ = rdb.Close()
+		_ = server.Close()
+	}
+}
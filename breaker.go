@@ -0,0 +1,112 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned by Obtain and the other operations waiting for
+// locks while the circuit breaker of the client is open, see Breaker.
+var ErrBreakerOpen = errors.New("redislock: circuit breaker open")
+
+// Breaker is a circuit breaker around the attempts to obtain locks, see
+// Defaults.Breaker. Once Threshold consecutive attempts failed due to
+// network errors, timeouts or transient redis errors, see IsTransientError,
+// the breaker opens and further attempts fail with ErrBreakerOpen instead of
+// waiting for redis, which also ends their retries. After OpenDuration, it
+// admits up to HalfOpenProbes attempts at a time: the first to succeed
+// closes the breaker, a failure opens it again. Attempts which fail for
+// other reasons, or are not obtained, count as successes, as redis is
+// reachable. A Breaker may be shared by multiple clients and must not be
+// copied after first use.
+type Breaker struct {
+	// Threshold is the number of consecutive failures which opens the
+	// breaker.
+	// Default: 5
+	Threshold int
+
+	// OpenDuration is the time the breaker remains open before admitting
+	// probes.
+	// Default: 10s
+	OpenDuration time.Duration
+
+	// HalfOpenProbes is the number of probes admitted concurrently once
+	// OpenDuration passed.
+	// Default: 1
+	HalfOpenProbes int
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probes    int
+}
+
+func (b *Breaker) getThreshold() int {
+	if b.Threshold > 0 {
+		return b.Threshold
+	}
+	return 5
+}
+
+func (b *Breaker) getOpenDuration() time.Duration {
+	if b.OpenDuration > 0 {
+		return b.OpenDuration
+	}
+	return 10 * time.Second
+}
+
+func (b *Breaker) getHalfOpenProbes() int {
+	if b.HalfOpenProbes > 0 {
+		return b.HalfOpenProbes
+	}
+	return 1
+}
+
+// Open reports whether the breaker currently rejects attempts, i.e. it is
+// open, or half-open with all probes in flight.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !b.openUntil.IsZero() && (time.Now().Before(b.openUntil) || b.probes >= b.getHalfOpenProbes())
+}
+
+// allow reports whether an attempt may be made at now. Admitted probes must
+// be followed by a call to done.
+func (b *Breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	} else if now.Before(b.openUntil) || b.probes >= b.getHalfOpenProbes() {
+		return false
+	}
+	b.probes++
+	return true
+}
+
+// done records the outcome of an attempt made with ctx at now.
+func (b *Breaker) done(ctx context.Context, now time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	halfOpen := !b.openUntil.IsZero() && !now.Before(b.openUntil)
+	if halfOpen && b.probes > 0 {
+		b.probes--
+	}
+
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// cancelled by the caller, which tells nothing about redis
+	case err != nil && (IsTransientError(err) || errors.Is(err, context.DeadlineExceeded)):
+		b.failures++
+		if halfOpen || b.failures >= b.getThreshold() {
+			b.openUntil, b.probes = now.Add(b.getOpenDuration()), 0
+		}
+	default:
+		b.failures, b.openUntil, b.probes = 0, time.Time{}, 0
+	}
+}
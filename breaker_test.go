@@ -0,0 +1,88 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Breaker", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should fail fast while redis is unavailable", func() {
+		breaker := &redislock.Breaker{Threshold: 3, OpenDuration: 50 * time.Millisecond}
+		flaky := &flakyClient{RedisClient: redisClient, failures: 100}
+		subject := redislock.New(flaky, redislock.Defaults{Breaker: breaker})
+		opt := &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+			RetryOnError:  redislock.IsTransientError,
+		}
+
+		start := time.Now()
+		_, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, opt)
+		Expect(err).To(MatchError(redislock.ErrBreakerOpen))
+		Expect(time.Since(start)).To(BeNumerically("<", 200*time.Millisecond))
+		Expect(breaker.Open()).To(BeTrue())
+
+		var e *redislock.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Attempts).To(Equal(3))
+
+		_, err = redislock.New(redisClient, redislock.Defaults{Breaker: breaker}).Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrBreakerOpen))
+		Expect(atomic.LoadInt32(&flaky.failures)).To(Equal(int32(97)))
+
+		time.Sleep(60 * time.Millisecond)
+		Expect(breaker.Open()).To(BeFalse())
+		_, err = subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(errLoading))
+		Expect(breaker.Open()).To(BeTrue())
+
+		time.Sleep(60 * time.Millisecond)
+		atomic.StoreInt32(&flaky.failures, 0)
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(breaker.Open()).To(BeFalse())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should apply to helpers sharing the breaker", func() {
+		breaker := &redislock.Breaker{Threshold: 1, OpenDuration: time.Minute}
+		flaky := &flakyClient{RedisClient: redisClient, failures: 1}
+		_, err := redislock.New(flaky, redislock.Defaults{Breaker: breaker}).Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(errLoading))
+		Expect(breaker.Open()).To(BeTrue())
+
+		defaults := redislock.Defaults{Breaker: breaker}
+		_, err = redislock.NewSemaphore(redisClient, lockKey, 1, defaults).Acquire(ctx, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrBreakerOpen))
+		_, err = redislock.NewRWLock(redisClient, lockKey, defaults).Lock(ctx, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrBreakerOpen))
+		err = redislock.NewRateLimiter(redisClient, lockKey, 1, time.Second, 1, defaults).Wait(ctx, time.Second, nil)
+		Expect(err).To(MatchError(redislock.ErrBreakerOpen))
+	})
+
+	It("should not count unobtained locks as failures", func() {
+		breaker := &redislock.Breaker{Threshold: 1}
+		subject := redislock.New(redisClient, redislock.Defaults{Breaker: breaker})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		for i := 0; i < 3; i++ {
+			_, err = subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+			Expect(err).To(MatchError(redislock.ErrNotObtained))
+		}
+		Expect(breaker.Open()).To(BeFalse())
+	})
+})
@@ -0,0 +1,191 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var errNoScripts = errors.New("redislock: server permits neither scripts nor functions")
+
+// Capabilities describes the features of the server detected by NewDetect.
+type Capabilities struct {
+	// Server is the name of the server software as reported by HELLO or
+	// INFO, e.g. "redis" or "valkey", or empty if it is unknown.
+	Server string
+	// Version is the version of the server software, e.g. "7.2.4", or empty
+	// if it is unknown.
+	Version string
+	// Scripts reports whether EVAL and EVALSHA are permitted.
+	Scripts bool
+	// Functions reports whether FUNCTION and FCALL are supported and
+	// permitted.
+	Functions bool
+	// SetGet reports whether SET accepts NX and GET at once, which is used
+	// to learn the holder of a lock in the same round trip, see
+	// Options.HolderDetails. It requires Redis 7 or a compatible server.
+	SetGet bool
+	// KeyspaceNotifications reports whether the notifications of deleted
+	// and expired keys are enabled, see Options.ReleaseNotify and Watcher.
+	// It is false if the configuration cannot be read, e.g. on managed
+	// services restricting CONFIG.
+	KeyspaceNotifications bool
+}
+
+// commandClient is implemented by clients which send arbitrary commands,
+// such as *redis.Client and *redis.ClusterClient.
+type commandClient interface {
+	Do(ctx context.Context, args ...interface{}) *redis.Cmd
+}
+
+// NewDetect is like New, but detects the capabilities of the server first,
+// via HELLO, INFO, CONFIG and probes of scripts and functions, and selects
+// the code paths it supports, so the client works against Valkey, KeyDB and
+// managed Redis variants restricting commands: if scripts are denied, but
+// functions are available, lock scripts are called via FCALL, see
+// WithFunctions. SET NX GET is only attempted if supported, and waiters only
+// subscribe to keyspace notifications if they are known to be enabled. The
+// detected Capabilities are reported by Client.Capabilities. Commands which
+// are unknown or denied count as unsupported, other errors, such as network
+// errors, are returned.
+func NewDetect(ctx context.Context, client RedisClient, defaults ...Defaults) (*Client, error) {
+	c := New(client, defaults...)
+	caps, err := detectCapabilities(ctx, c.client)
+	if err != nil {
+		return nil, err
+	}
+
+	if !caps.Scripts {
+		fc, ok := client.(FunctionsCapableClient)
+		if !caps.Functions || !ok {
+			return nil, errNoScripts
+		}
+		c.client = WithFunctions(fc)
+	}
+	if !caps.SetGet {
+		atomic.StoreInt32(&c.noSetGet, 1)
+	}
+	c.caps = caps
+	return c, nil
+}
+
+// Capabilities returns the capabilities of the server detected by NewDetect,
+// or nil if the client was created via New.
+func (c *Client) Capabilities() *Capabilities {
+	if c.caps == nil {
+		return nil
+	}
+	caps := *c.caps
+	return &caps
+}
+
+// keyspaceNotifications reports whether waiters should subscribe to keyspace
+// notifications.
+func (c *Client) keyspaceNotifications() bool {
+	return c.caps == nil || c.caps.KeyspaceNotifications
+}
+
+func detectCapabilities(ctx context.Context, rdb RedisClient) (*Capabilities, error) {
+	caps := new(Capabilities)
+
+	err := luaPing.Run(ctx, rdb, nil).Err()
+	if caps.Scripts = err == nil; !caps.Scripts && !isDenied(err) {
+		return nil, err
+	}
+
+	cmder, ok := rdb.(commandClient)
+	if !ok {
+		return caps, nil
+	}
+
+	if res, err := cmder.Do(ctx, "hello", "2").Result(); err == nil {
+		vals, _ := res.([]interface{})
+		for i := 0; i+1 < len(vals); i += 2 {
+			k, _ := vals[i].(string)
+			v, _ := vals[i+1].(string)
+			switch k {
+			case "server":
+				caps.Server = strings.ToLower(v)
+			case "version":
+				caps.Version = v
+			}
+		}
+	} else if !isDenied(err) {
+		return nil, err
+	}
+
+	if info, err := cmder.Do(ctx, "info", "server").Text(); err == nil {
+		fields := parseInfo(info)
+		if name := fields["server_name"]; name != "" {
+			caps.Server = strings.ToLower(name)
+		} else if caps.Server == "" {
+			caps.Server = "redis"
+		}
+		if v := fields["valkey_version"]; v != "" {
+			caps.Version = v
+		} else if v := fields["redis_version"]; v != "" && caps.Version == "" {
+			caps.Version = v
+		}
+	} else if !isDenied(err) {
+		return nil, err
+	}
+
+	if err := cmder.Do(ctx, "function", "list", "libraryname", "redislock_").Err(); err == nil {
+		caps.Functions = true
+	} else if !isDenied(err) {
+		return nil, err
+	}
+
+	if res, err := cmder.Do(ctx, "config", "get", "notify-keyspace-events").Result(); err == nil {
+		if vals, _ := res.([]interface{}); len(vals) == 2 {
+			flags, _ := vals[1].(string)
+			caps.KeyspaceNotifications = strings.Contains(flags, "K") &&
+				(strings.Contains(flags, "A") || strings.Contains(flags, "g") && strings.Contains(flags, "x"))
+		}
+	} else if !isDenied(err) {
+		return nil, err
+	}
+
+	caps.SetGet = versionAtLeast(caps.Version, 7)
+	return caps, nil
+}
+
+// isDenied reports whether err is the reply of a server which does not know
+// or does not permit a command.
+func isDenied(err error) bool {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) || err == redis.Nil {
+		return false
+	}
+	msg := strings.ToLower(rerr.Error())
+	return isUnknownCommand(err) || strings.HasPrefix(msg, "noperm") || strings.Contains(msg, "disabled") || strings.Contains(msg, "not allowed")
+}
+
+// parseInfo parses the fields of an INFO reply.
+func parseInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			fields[line[:i]] = line[i+1:]
+		}
+	}
+	return fields
+}
+
+// versionAtLeast reports whether the major version of version is at least
+// major.
+func versionAtLeast(version string, major int) bool {
+	if i := strings.IndexByte(version, '.'); i > 0 {
+		version = version[:i]
+	}
+	n, err := strconv.Atoi(version)
+	return err == nil && n >= major
+}
@@ -0,0 +1,105 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewDetect", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should detect valkey", func() {
+		client := &capsClient{Client: redisClient, replies: map[string]interface{}{
+			"hello":    []interface{}{"server", "valkey", "version", "8.0.1", "proto", int64(2)},
+			"info":     "# Server\r\nredis_version:7.2.4\r\nserver_name:valkey\r\nvalkey_version:8.0.1\r\n",
+			"function": []interface{}{},
+			"config":   []interface{}{"notify-keyspace-events", "KEA"},
+		}}
+		subject, err := redislock.NewDetect(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.Capabilities()).To(Equal(&redislock.Capabilities{
+			Server:                "valkey",
+			Version:               "8.0.1",
+			Scripts:               true,
+			Functions:             true,
+			SetGet:                true,
+			KeyspaceNotifications: true,
+		}))
+	})
+
+	It("should detect restricted servers", func() {
+		client := &capsClient{Client: redisClient, replies: map[string]interface{}{
+			"hello":    capsError("ERR unknown command 'hello'"),
+			"info":     "# Server\r\nredis_version:6.3.4\r\n",
+			"function": capsError("ERR unknown command 'function'"),
+			"config":   capsError("NOPERM this user has no permissions to run the 'config' command"),
+		}}
+		subject, err := redislock.NewDetect(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.Capabilities()).To(Equal(&redislock.Capabilities{
+			Server:  "redis",
+			Version: "6.3.4",
+			Scripts: true,
+		}))
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{HolderDetails: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{HolderDetails: true})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(client.setGets).To(BeZero())
+	})
+
+	It("should return other errors", func() {
+		client := &capsClient{Client: redisClient, replies: map[string]interface{}{
+			"info": errors.New("connection reset"),
+		}}
+		_, err := redislock.NewDetect(ctx, client)
+		Expect(err).To(MatchError("connection reset"))
+	})
+
+	It("should not report capabilities of plain clients", func() {
+		Expect(redislock.New(redisClient).Capabilities()).To(BeNil())
+	})
+})
+
+// capsClient replies to commands sent via Do with canned replies.
+type capsClient struct {
+	*redis.Client
+
+	replies map[string]interface{}
+	setGets int
+}
+
+func (c *capsClient) Do(ctx context.Context, args ...interface{}) *redis.Cmd {
+	name, _ := args[0].(string)
+	reply, ok := c.replies[name]
+	if !ok {
+		return redis.NewCmdResult(nil, capsError("ERR unknown command '"+name+"'"))
+	} else if err, ok := reply.(error); ok {
+		return redis.NewCmdResult(nil, err)
+	}
+	return redis.NewCmdResult(reply, nil)
+}
+
+func (c *capsClient) Pipeline() redis.Pipeliner {
+	c.setGets++
+	return c.Client.Pipeline()
+}
+
+type capsError string
+
+func (e capsError) Error() string { return string(e) }
+
+func (capsError) RedisError() {}
@@ -0,0 +1,177 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var errChildUnsupported = errors.New("redislock: child locks require an exclusive parent lock")
+
+// luaChildHeld sets name to the name of the child lock with token ARGV[1] if
+// it is registered in the children hash, KEYS[2], of the parent lock, KEYS[1],
+// and the parent is still held by the token which registered it, otherwise to
+// -1 if the child expired or -2 if the parent was stolen.
+const luaChildHeld = `
+local function held()
+	local parent = redis.call("get", KEYS[1])
+	local owner = redis.call("hget", KEYS[2], "owner")
+	if not parent or not owner then return -1 end
+	if string.sub(parent, 1, #owner) ~= owner then return -2 end
+	return redis.call("hget", KEYS[2], "t:" .. ARGV[1]) or -1
+end
+local name = held()
+`
+
+var (
+	luaChildObtain = redis.NewScript(`
+local parent = redis.call("get", KEYS[1])
+if not parent or string.sub(parent, 1, #ARGV[2]) ~= ARGV[2] then return -1 end
+if redis.call("hget", KEYS[2], "owner") ~= ARGV[2] then
+	redis.call("del", KEYS[2])
+	redis.call("hset", KEYS[2], "owner", ARGV[2])
+end
+if redis.call("hsetnx", KEYS[2], "n:" .. ARGV[1], ARGV[3]) == 0 then return 0 end
+redis.call("hset", KEYS[2], "t:" .. ARGV[3], ARGV[1])
+return math.max(redis.call("pttl", KEYS[1]), 1)`)
+	luaChildPTTL = redis.NewScript(luaChildHeld + `
+if type(name) == "number" then return -3 end
+return redis.call("pttl", KEYS[1])`)
+	luaChildRefresh = redis.NewScript(luaChildHeld + `
+if type(name) == "number" then return name end
+return 1`)
+	luaChildExtend = redis.NewScript(luaChildHeld + `
+if type(name) == "number" then return name end
+return redis.call("pttl", KEYS[1])`)
+	luaChildRelease = redis.NewScript(luaChildHeld + `
+if type(name) == "number" then return name end
+redis.call("hdel", KEYS[2], "n:" .. name, "t:" .. ARGV[1])
+return 1`)
+	luaChildrenRelease = redis.NewScript(`
+if redis.call("hget", KEYS[1], "owner") == ARGV[1] then redis.call("del", KEYS[1]) end
+return 1`)
+
+	childScripts = &lockScripts{pttl: luaChildPTTL, refresh: luaChildRefresh, extend: luaChildExtend, release: luaChildRelease}
+)
+
+func (c *Client) childrenKey(key string) string { return c.companionKey(key, ":children") }
+
+// ObtainChild obtains the child lock name of the lock, which is exclusive
+// among the children of the lock, and reported by Key as the key of the lock
+// followed by a slash and name. Child locks are cheap to obtain, as they do
+// not have a redis key or TTL of their own: they are held as long as the
+// parent is still held by this lock, expire with it without being refreshed
+// and are released with it. Refresh and Extend only check that a child is
+// still held, and Extend reports the TTL of the parent. Only exclusive locks,
+// as returned by Obtain or ObtainFair, may have children, which may not have
+// children of their own. The children of an expired lock remain in redis
+// until the key is locked with children again, see also Purge. Retries
+// according to opt.RetryStrategy until waitTimeout elapsed.
+// May return ErrNotObtained if the child is held, or ErrLockNotHeld if the
+// lock no longer is.
+func (l *Lock) ObtainChild(ctx context.Context, name string, waitTimeout time.Duration, opt *Options) (*Lock, error) {
+	c := l.client
+	key := l.key + "/" + name
+	if ctx == nil {
+		return nil, &Error{Op: "obtain", Key: key, Err: ErrNilContext}
+	} else if name == "" {
+		return nil, &Error{Op: "obtain", Key: key, Err: ErrInvalidKey}
+	} else if l.scripts != exclusiveScripts && l.scripts != signalScripts && l.scripts != notifyScripts {
+		return nil, &Error{Op: "obtain", Key: key, Err: errChildUnsupported}
+	}
+	opt = c.options(opt)
+
+	token, value, err := c.newValue(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{l.key, c.childrenKey(l.key)}
+	opTimeout := opt.getOperationTimeout()
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	var start time.Time
+	var pttl int64
+	stats, err := c.retry(deadlinectx, l.rdb, key, token, opt, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		n, err := luaChildObtain.Run(opctx, l.rdb, keys, name, l.token, token).Int64()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		} else if n < 0 {
+			return false, ErrLockNotHeld
+		}
+		pttl = n
+		return n > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(pttl) * time.Millisecond
+	child := &Lock{
+		client:       c,
+		rdb:          l.rdb,
+		key:          key,
+		token:        token,
+		value:        value,
+		ttl:          ttl,
+		effectiveTTL: ttl,
+		obtained:     start,
+		opTimeout:    opTimeout,
+		logger:       opt.getLogger(),
+		scripts:      childScripts,
+		scriptKeys:   keys,
+		scriptArg:    token,
+		stats:        stats,
+		parent:       l,
+		audited:      c.defaults.Audit != nil,
+	}
+
+	l.mu.Lock()
+	child.expires = l.expires
+	if l.children == nil {
+		l.children = make(map[*Lock]struct{})
+	}
+	if l.doneErr != nil {
+		child.lost(l.doneErr)
+	} else {
+		l.children[child] = struct{}{}
+	}
+	l.mu.Unlock()
+
+	child.audit(ctx, AuditObtain, ttl)
+	return c.track(child).bind(ctx, opt), nil
+}
+
+// releaseChildren deletes the children hash of a released lock, if it had
+// children.
+func (l *Lock) releaseChildren(ctx context.Context) {
+	l.mu.Lock()
+	parent := l.children != nil
+	l.mu.Unlock()
+	if !parent {
+		return
+	}
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	if err := luaChildrenRelease.Run(opctx, l.rdb, []string{l.client.childrenKey(l.key)}, l.token).Err(); err != nil && l.logger != nil {
+		l.logger.Log(LevelWarn, "releasing children failed", "key", l.key, "token", shortToken(l.token), "error", err)
+	}
+}
+
+// removeChild forgets a released child.
+func (l *Lock) removeChild(child *Lock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.children, child)
+}
@@ -0,0 +1,123 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock.ObtainChild", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":children").Err()).To(Succeed())
+	})
+
+	It("should obtain exclusive children", func() {
+		parent, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer parent.Release(ctx)
+
+		child, err := parent.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(child.Key()).To(Equal(lockKey + "/a"))
+		Expect(child.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(child.IsHeld(ctx)).To(BeTrue())
+
+		_, err = parent.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		other, err := parent.ObtainChild(ctx, "b", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Release(ctx)).To(Succeed())
+
+		Expect(child.Release(ctx)).To(Succeed())
+		Expect(child.IsHeld(ctx)).To(BeFalse())
+		Expect(child.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		again, err := parent.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.Release(ctx)).To(Succeed())
+	})
+
+	It("should follow the refreshes of the parent", func() {
+		parent, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer parent.Release(ctx)
+
+		child, err := parent.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(child.ValidUntil()).To(Equal(parent.ValidUntil()))
+
+		Expect(parent.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(child.ValidUntil()).To(Equal(parent.ValidUntil()))
+		Expect(child.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(child.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(child.Extend(ctx, time.Minute, 0)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(child.ValidUntil()).To(Equal(parent.ValidUntil()))
+	})
+
+	It("should be released with the parent", func() {
+		parent, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		child, err := parent.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(parent.Release(ctx)).To(Succeed())
+		Eventually(child.Done()).Should(BeClosed())
+		Expect(child.Err()).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(redisClient.Exists(ctx, lockKey+":children").Val()).To(BeZero())
+
+		_, err = parent.ObtainChild(ctx, "b", time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should expire with the parent", func() {
+		parent, err := subject.Obtain(ctx, lockKey, time.Hour, 50*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		child, err := parent.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(child.Done()).Should(BeClosed())
+		Expect(child.Err()).To(MatchError(redislock.ErrLockExpired))
+		Expect(child.IsHeld(ctx)).To(BeFalse())
+	})
+
+	It("should not be held under a new holder of the parent", func() {
+		parent, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		child, err := parent.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+		holder, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer holder.Release(ctx)
+
+		Expect(child.IsHeld(ctx)).To(BeFalse())
+		Expect(child.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockStolen))
+
+		fresh, err := holder.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fresh.Release(ctx)).To(Succeed())
+	})
+
+	It("should require an exclusive parent", func() {
+		parent, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer parent.Release(ctx)
+
+		child, err := parent.ObtainChild(ctx, "a", time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = child.ObtainChild(ctx, "b", time.Hour, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,102 @@
+package redislock
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// cipherTagPrefix starts encrypted metadata, which is followed by the
+// ciphertext in unpadded base64url encoding and "|".
+const cipherTagPrefix = "enc.v1?"
+
+var errCiphertextTooShort = errors.New("redislock: ciphertext too short")
+
+// Cipher encrypts the metadata of lock values, see Defaults.Cipher. It must be
+// safe for concurrent use.
+type Cipher interface {
+	// Encrypt returns the ciphertext of plaintext.
+	Encrypt(plaintext []byte) ([]byte, error)
+	// Decrypt returns the plaintext of ciphertext, as returned by Encrypt.
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// NewAESGCM returns a Cipher which encrypts metadata via AES-GCM with a random
+// nonce, which is prepended to the ciphertext. The key must be 16, 24 or 32
+// bytes long to select AES-128, AES-192 or AES-256.
+func NewAESGCM(key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aesGCM{aead: aead}, nil
+}
+
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+func (c aesGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (c aesGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, errCiphertextTooShort
+	}
+	return c.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+}
+
+// encryptMetadata encrypts md with the cipher of the client, if any.
+func (c *Client) encryptMetadata(md string) (string, error) {
+	if c.defaults.Cipher == nil || md == "" {
+		return md, nil
+	}
+
+	ciphertext, err := c.defaults.Cipher.Encrypt([]byte(md))
+	if err != nil {
+		return "", err
+	}
+	return cipherTagPrefix + base64.RawURLEncoding.EncodeToString(ciphertext) + "|", nil
+}
+
+// decryptMetadata decrypts md with the cipher of the client. It returns md
+// as-is if it is not encrypted, or cannot be decrypted.
+func (c *Client) decryptMetadata(md string) string {
+	if c.defaults.Cipher == nil || !strings.HasPrefix(md, cipherTagPrefix) || !strings.HasSuffix(md, "|") {
+		return md
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(md[len(cipherTagPrefix) : len(md)-1])
+	if err != nil {
+		return md
+	}
+	plaintext, err := c.defaults.Cipher.Decrypt(ciphertext)
+	if err != nil {
+		return md
+	}
+	return string(plaintext)
+}
+
+// lockValue returns the value of an exclusive lock with token, i.e. the token
+// followed by the owner tag and the encrypted metadata of opt.
+func (c *Client) lockValue(token string, opt *Options) (string, error) {
+	md, err := c.encryptMetadata(opt.getMetadata())
+	if err != nil {
+		return "", err
+	}
+	return token + c.valueTag() + md, nil
+}
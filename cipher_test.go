@@ -0,0 +1,74 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Cipher", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		cipher, err := redislock.NewAESGCM([]byte("0123456789abcdef"))
+		Expect(err).NotTo(HaveOccurred())
+		subject = redislock.New(redisClient, redislock.Defaults{Cipher: cipher})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should encrypt metadata", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "tenant-42"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		value := redisClient.Get(ctx, lockKey).Val()
+		Expect(value).To(HavePrefix(lock.Token() + "enc.v1?"))
+		Expect(value).NotTo(ContainSubstring("tenant-42"))
+		Expect(lock.Metadata()).To(Equal("tenant-42"))
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Metadata).To(Equal("tenant-42"))
+
+		info, err = redislock.New(redisClient).Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Metadata).To(Equal(strings.TrimPrefix(value, lock.Token())))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{HolderDetails: true})
+		var holder *redislock.NotObtainedError
+		Expect(errors.As(err, &holder)).To(BeTrue())
+		Expect(holder.Metadata).To(Equal("tenant-42"))
+	})
+
+	It("should encrypt replaced metadata", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal(lock.Token()))
+
+		Expect(lock.SetMetadata(ctx, "job-7")).To(Succeed())
+		Expect(redisClient.Get(ctx, lockKey).Val()).NotTo(ContainSubstring("job-7"))
+		Expect(lock.Metadata()).To(Equal("job-7"))
+
+		data, err := lock.MarshalBinary()
+		Expect(err).NotTo(HaveOccurred())
+		resumed, err := subject.ResumeBinary(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed.Metadata()).To(Equal("job-7"))
+		Expect(resumed.Refresh(ctx, time.Minute, nil)).To(Succeed())
+	})
+
+	It("should reject invalid keys", func() {
+		_, err := redislock.NewAESGCM([]byte("short"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,41 @@
+package redislock
+
+import "time"
+
+// Clock provides the time to retry backoffs and auto-refresh, see
+// Defaults.Clock. It must be safe for concurrent use.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a timer firing after d, like time.NewTimer.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is created by a Clock and behaves like a time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It reports whether the timer
+	// was active.
+	Stop() bool
+	// Reset changes the timer to fire after d. It reports whether the
+	// timer was active.
+	Reset(d time.Duration) bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+func (c *Client) clock() Clock {
+	if c.defaults.Clock != nil {
+		return c.defaults.Clock
+	}
+	return realClock{}
+}
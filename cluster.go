@@ -0,0 +1,150 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// clusterSlots is the number of hash slots of a redis cluster.
+const clusterSlots = 16384
+
+var errCrossSlot = errors.New("redislock: keys must hash to the same cluster slot")
+
+// clusterRetries bounds the retries of commands failing due to a topology
+// change, see clusterClient. The backoff doubles from clusterRetryBackoff.
+const (
+	clusterRetries      = 4
+	clusterRetryBackoff = 25 * time.Millisecond
+)
+
+// clusterClient wraps a *redis.ClusterClient, which follows a limited number
+// of MOVED and ASK redirects but gives up on TRYAGAIN and, once its retries
+// are exhausted, on CLUSTERDOWN, as seen while slots are resharded or a
+// master fails over. It reloads the cluster state and retries scripts and
+// SET NX with a backoff instead, so operations in flight survive such
+// events.
+type clusterClient struct {
+	*redis.ClusterClient
+}
+
+func (c clusterClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (cmd *redis.BoolCmd) {
+	c.retry(ctx, func() error {
+		cmd = c.ClusterClient.SetNX(ctx, key, value, expiration)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c clusterClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (cmd *redis.Cmd) {
+	c.retry(ctx, func() error {
+		cmd = c.ClusterClient.Eval(ctx, script, keys, args...)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+func (c clusterClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (cmd *redis.Cmd) {
+	c.retry(ctx, func() error {
+		cmd = c.ClusterClient.EvalSha(ctx, sha1, keys, args...)
+		return cmd.Err()
+	})
+	return cmd
+}
+
+// retry runs fn until it succeeds, fails for other reasons than a topology
+// change, or clusterRetries are exhausted.
+func (c clusterClient) retry(ctx context.Context, fn func() error) {
+	backoff := clusterRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if attempt == clusterRetries || !isTopologyError(err) {
+			return
+		}
+
+		if sleep(ctx, backoff) != nil {
+			return
+		}
+		backoff *= 2
+		c.ReloadState(ctx)
+	}
+}
+
+// isTopologyError reports whether err is caused by a change of the cluster
+// topology.
+func isTopologyError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := err.Error()
+	for _, prefix := range []string{"MOVED ", "ASK ", "TRYAGAIN ", "CLUSTERDOWN "} {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// companionKey returns the key of a companion structure of the lock on key,
+// such as the fencing counter. If hash tags are enabled and key has none, the
+// companion key uses key as its hash tag, which places it in the same cluster
+// slot as key.
+func (c *Client) companionKey(key, suffix string) string {
+	if c.hashTags && hashTag(key) == key {
+		return "{" + key + "}" + suffix
+	}
+	return key + suffix
+}
+
+// checkSlots returns an error if hash tags are enabled and keys do not all
+// hash to the same cluster slot.
+func (c *Client) checkSlots(keys []string) error {
+	if !c.hashTags || len(keys) == 0 {
+		return nil
+	}
+
+	slot := hashSlot(keys[0])
+	for _, key := range keys[1:] {
+		if hashSlot(key) != slot {
+			return errCrossSlot
+		}
+	}
+	return nil
+}
+
+// hashTag returns the part of key which is hashed to determine its cluster
+// slot, i.e. the content of the first non-empty {...} section, or the key
+// itself.
+func hashTag(key string) string {
+	if s := strings.IndexByte(key, '{'); s > -1 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			return key[s+1 : s+e+1]
+		}
+	}
+	return key
+}
+
+// hashSlot returns the cluster slot of key.
+func hashSlot(key string) int {
+	return int(crc16(hashTag(key)) % clusterSlots)
+}
+
+// crc16 implements the CRC16-XMODEM checksum used by redis cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,99 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.HashTags", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+	var tagged = []string{"{" + lockKey + "}.a", "{" + lockKey + "}.b"}
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient, redislock.Defaults{HashTags: true})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, "{"+lockKey+"}:fence", tagged[0], tagged[1], tagged[0]+":fence").Err()).To(Succeed())
+	})
+
+	It("should co-locate companion keys", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Fencing: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Get(ctx, "{"+lockKey+"}:fence").Val()).To(Equal("1"))
+
+		lock, err = subject.Obtain(ctx, tagged[0], time.Hour, time.Minute, &redislock.Options{Fencing: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Get(ctx, tagged[0]+":fence").Val()).To(Equal("1"))
+	})
+
+	It("should require multi-key locks to share a slot", func() {
+		_, err := subject.ObtainMulti(ctx, []string{lockKey + ".a", lockKey + ".b"}, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError("redislock: keys must hash to the same cluster slot"))
+
+		lock, err := subject.ObtainMulti(ctx, tagged, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+})
+
+var _ = Describe("ClusterClient", func() {
+	var ctx = context.Background()
+
+	It("should retry during topology changes", func() {
+		cluster := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{redisClient.Options().Addr}})
+		defer cluster.Close()
+		defer cluster.Del(ctx, lockKey)
+
+		hook := &topologyHook{failures: 2}
+		cluster.AddHook(hook)
+		subject := redislock.New(cluster)
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&hook.failures)).To(BeNumerically("<=", 0))
+
+		atomic.StoreInt32(&hook.failures, 2)
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+
+		atomic.StoreInt32(&hook.failures, 100)
+		Expect(lock.Release(ctx)).To(MatchError(ContainSubstring("TRYAGAIN")))
+		atomic.StoreInt32(&hook.failures, 0)
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+})
+
+// topologyHook fails commands with TRYAGAIN errors before they are sent.
+type topologyHook struct {
+	failures int32
+}
+
+func (h *topologyHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if name := cmd.Name(); (name == "set" || name == "evalsha" || name == "eval") && atomic.AddInt32(&h.failures, -1) >= 0 {
+		return ctx, errors.New("TRYAGAIN Multiple keys request during rehashing of slot")
+	}
+	return ctx, nil
+}
+
+func (*topologyHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (*topologyHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (*topologyHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
@@ -0,0 +1,252 @@
+// Command redislock inspects and manages locks held via redislock.
+//
+// Usage:
+//
+//	redislock [flags] inspect KEY
+//	redislock [flags] list [PATTERN]
+//	redislock [flags] force-release [-token TOKEN] KEY
+//	redislock [flags] watch [-interval DURATION] KEY
+//
+// The server is given via -url or the REDISLOCK_URL environment variable, e.g.
+// redis://:password@localhost:6379/0. Keys are relative to -prefix, which
+// must match the Defaults.KeyPrefix of the applications holding the locks.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+)
+
+const usage = `usage: redislock [flags] COMMAND [ARGS]
+
+commands:
+  inspect KEY                           show the holder of a lock
+  list [PATTERN]                        list held locks matching PATTERN (default *)
+  force-release [-token TOKEN] KEY      release a lock regardless of its holder
+  watch [-interval DURATION] KEY        print changes of the holder of a lock
+
+flags:
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr); errors.Is(err, errUsage) {
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "redislock:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("redislock", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	defaultURL := os.Getenv("REDISLOCK_URL")
+	if defaultURL == "" {
+		defaultURL = "redis://127.0.0.1:6379/0"
+	}
+	url := flags.String("url", defaultURL, "redis server URL, defaults to $REDISLOCK_URL")
+	prefix := flags.String("prefix", "", "key prefix of the lock keys")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	} else if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	opt, err := redis.ParseURL(*url)
+	if err != nil {
+		return err
+	}
+	rdb := redis.NewClient(opt)
+	defer rdb.Close()
+
+	cli := &cli{
+		client: redislock.New(rdb, redislock.Defaults{KeyPrefix: *prefix}),
+		stdout: stdout,
+		stderr: stderr,
+	}
+
+	cmd, args := flags.Arg(0), flags.Args()[1:]
+	switch cmd {
+	case "inspect":
+		return cli.inspect(ctx, args)
+	case "list":
+		return cli.list(ctx, args)
+	case "force-release":
+		return cli.forceRelease(ctx, args)
+	case "watch":
+		return cli.watch(ctx, args)
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n", cmd)
+	flags.Usage()
+	return errUsage
+}
+
+type cli struct {
+	client *redislock.Client
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (c *cli) inspect(ctx context.Context, args []string) error {
+	flags := c.flags("inspect", "KEY")
+	if err := c.parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+
+	info, err := c.client.Inspect(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return c.print(info)
+}
+
+func (c *cli) list(ctx context.Context, args []string) error {
+	flags := c.flags("list", "[PATTERN]")
+	if err := c.parse(flags, args, 0, 1); err != nil {
+		return err
+	}
+
+	pattern := "*"
+	if flags.NArg() != 0 {
+		pattern = flags.Arg(0)
+	}
+
+	var all []*redislock.LockInfo
+	for cursor := uint64(0); ; {
+		locks, next, err := c.client.List(ctx, pattern, cursor)
+		if err != nil {
+			return err
+		}
+		all = append(all, locks...)
+
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	return c.print(all...)
+}
+
+func (c *cli) forceRelease(ctx context.Context, args []string) error {
+	flags := c.flags("force-release", "[-token TOKEN] KEY")
+	token := flags.String("token", "", "only release the lock if it is held with TOKEN")
+	if err := c.parse(flags, args, 1, 1); err != nil {
+		return err
+	}
+
+	key := flags.Arg(0)
+	info, err := c.client.Inspect(ctx, key)
+	if err != nil {
+		return err
+	} else if !info.Held {
+		return fmt.Errorf("%q is not locked", key)
+	}
+
+	if *token != "" {
+		err = c.client.ForceReleaseByToken(ctx, key, *token)
+	} else {
+		err = c.client.ForceRelease(ctx, key)
+	}
+	if errors.Is(err, redislock.ErrLockNotHeld) {
+		return fmt.Errorf("%q is not locked with token %q", key, *token)
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "released %q held by %q\n", key, info.Token)
+	return nil
+}
+
+func (c *cli) watch(ctx context.Context, args []string) error {
+	flags := c.flags("watch", "[-interval DURATION] KEY")
+	interval := flags.Duration("interval", time.Second, "polling interval")
+	if err := c.parse(flags, args, 1, 1); err != nil {
+		return err
+	} else if *interval <= 0 {
+		return fmt.Errorf("invalid interval %v", *interval)
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	var last *redislock.LockInfo
+	for {
+		info, err := c.client.Inspect(ctx, flags.Arg(0))
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if last == nil || info.Held != last.Held || info.Token != last.Token || info.Metadata != last.Metadata {
+			state := "released"
+			if info.Held {
+				state = fmt.Sprintf("held by %q, metadata %q, ttl %v", info.Token, info.Metadata, info.TTL.Round(time.Millisecond))
+			}
+			fmt.Fprintf(c.stdout, "%s %s\n", time.Now().Format(time.RFC3339), state)
+		}
+		last = info
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *cli) flags(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: redislock %s %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses args and checks the number of positional arguments.
+func (c *cli) parse(flags *flag.FlagSet, args []string, min, max int) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	} else if n := flags.NArg(); n < min || n > max {
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// print prints locks as a table.
+func (c *cli) print(locks ...*redislock.LockInfo) error {
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tHELD\tTOKEN\tMETADATA\tTTL")
+	for _, info := range locks {
+		ttl := "-"
+		if info.Held && info.TTL > 0 {
+			ttl = info.TTL.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n", info.Key, info.Held, info.Token, info.Metadata, ttl)
+	}
+	return w.Flush()
+}
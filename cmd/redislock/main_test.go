@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redislocktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const lockKey = "__bsm_redislock_cli_test__"
+
+var _ = Describe("redislock", func() {
+	var rdb *redis.Client
+	var lock *redislock.Lock
+	var stdout, stderr *bytes.Buffer
+	var ctx = context.Background()
+
+	cmd := func(args ...string) error {
+		stdout.Reset()
+		stderr.Reset()
+		return run(ctx, append([]string{"-url", "redis://" + redisAddr + "/9"}, args...), stdout, stderr)
+	}
+
+	BeforeEach(func() {
+		stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+		rdb = redis.NewClient(&redis.Options{Network: "tcp", Addr: redisAddr, DB: 9})
+
+		var err error
+		lock, err = redislock.Obtain(ctx, rdb, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "worker-1"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(rdb.Del(ctx, lockKey, lockKey+":signal").Err()).To(Succeed())
+		Expect(rdb.Close()).To(Succeed())
+	})
+
+	It("should inspect locks", func() {
+		Expect(cmd("inspect", lockKey)).To(Succeed())
+		Expect(stdout.String()).To(MatchRegexp(`^KEY +HELD +TOKEN +METADATA +TTL\n` + lockKey + ` +true +` + lock.Token() + ` +worker-1 +(59\.\d+s|1m0s)\n$`))
+
+		Expect(cmd("inspect", lockKey+"-missing")).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring(lockKey + "-missing  false"))
+	})
+
+	It("should list locks", func() {
+		Expect(cmd("list", "__bsm_redislock_cli_*")).To(Succeed())
+		Expect(strings.Split(strings.TrimSpace(stdout.String()), "\n")).To(HaveLen(2))
+		Expect(stdout.String()).To(ContainSubstring(lock.Token()))
+	})
+
+	It("should force-release locks", func() {
+		Expect(cmd("force-release", "-token", "wrong", lockKey)).To(MatchError(ContainSubstring("is not locked with token")))
+		Expect(rdb.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+
+		Expect(cmd("force-release", "-token", lock.Token(), lockKey)).To(Succeed())
+		Expect(stdout.String()).To(Equal(`released "` + lockKey + `" held by "` + lock.Token() + `"` + "\n"))
+		Expect(rdb.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+
+		Expect(cmd("force-release", lockKey)).To(MatchError(ContainSubstring("is not locked")))
+	})
+
+	It("should watch locks", func() {
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- run(ctx, []string{"-url", "redis://" + redisAddr + "/9", "watch", "-interval", "10ms", lockKey}, stdout, stderr)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		Expect(lock.Release(context.Background())).To(Succeed())
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		Expect(<-done).To(Succeed())
+
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(ContainSubstring(`held by "` + lock.Token() + `", metadata "worker-1"`))
+		Expect(lines[1]).To(HaveSuffix(" released"))
+	})
+
+	It("should reject invalid usage", func() {
+		Expect(cmd()).To(MatchError(errUsage))
+		Expect(cmd("unknown")).To(MatchError(errUsage))
+		Expect(cmd("inspect")).To(MatchError(errUsage))
+		Expect(stderr.String()).To(HavePrefix("usage: redislock inspect KEY"))
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock/cmd/redislock")
+}
+
+var redisAddr = "127.0.0.1:6379"
+
+var embedded *redislocktest.Server
+
+var _ = BeforeSuite(func() {
+	if os.Getenv("REDISLOCK_TEST_EMBEDDED") != "" {
+		var err error
+		embedded, err = redislocktest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+		redisAddr = embedded.Addr()
+	}
+})
+
+var _ = AfterSuite(func() {
+	if embedded != nil {
+		Expect(embedded.Close()).To(Succeed())
+	}
+})
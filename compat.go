@@ -0,0 +1,78 @@
+package redislock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Compat selects a format of lock values which is interoperable with other
+// lock libraries, see Defaults.Compat.
+type Compat int
+
+const (
+	// CompatNone stores the token, followed by the owner tag and the
+	// metadata, as a string.
+	CompatNone Compat = iota
+
+	// CompatRedlock stores a token of 32 lowercase hex characters (128
+	// random bits) as a string, set via SET NX PX, as do node-redlock,
+	// redis-py and the Redlock implementations of most other languages.
+	// Like this package, they only release or extend a lock if the key
+	// still holds their value, so locks taken by either side are respected
+	// by the other and can be handed over by passing on the token.
+	CompatRedlock
+
+	// CompatRedisson stores the lock as a hash, like the RLock of Redisson:
+	// the field is the token, formatted as "<uuid>:<thread id>", which is
+	// generated as "<random uuid>:1", and its value is the hold count, which
+	// is always 1. Release publishes Redisson's unlock message to the
+	// redisson_lock__channel:{key} channel, so Redisson waiters retry
+	// immediately. Redisson locks are not reentered by this package.
+	CompatRedisson
+)
+
+const luaRedissonHeld = `local t = redis.call("type", KEYS[1]).ok local held = t == "hash" and redis.call("hexists", KEYS[1], ARGV[1]) == 1 `
+
+var (
+	luaRedissonObtain  = redis.NewScript(`if redis.call("exists", KEYS[1]) == 1 then return 0 end redis.call("hset", KEYS[1], ARGV[1], 1) redis.call("pexpire", KEYS[1], ARGV[2]) return 1`)
+	luaRedissonPTTL    = redis.NewScript(luaRedissonHeld + `if held then return redis.call("pttl", KEYS[1]) else return -3 end`)
+	luaRedissonRefresh = redis.NewScript(luaRedissonHeld + `if held then return redis.call("pexpire", KEYS[1], ARGV[2]) elseif t ~= "none" then return -2 else return -1 end`)
+	luaRedissonExtend  = redis.NewScript(luaRedissonHeld + `if not held then if t ~= "none" then return -2 else return -1 end end local n = math.max(redis.call("pttl", KEYS[1]), 0) + tonumber(ARGV[2]) if tonumber(ARGV[3]) > 0 then n = math.min(n, tonumber(ARGV[3])) end redis.call("pexpire", KEYS[1], n) return n`)
+	luaRedissonRelease = redis.NewScript(luaRedissonHeld + `if not held then if t ~= "none" then return -2 else return -1 end end
+redis.call("del", KEYS[1])
+local channel = "redisson_lock__channel:{" .. KEYS[1] .. "}"
+if string.find(KEYS[1], "{", 1, true) then channel = "redisson_lock__channel:" .. KEYS[1] end
+redis.call("publish", channel, 0)
+return 1`)
+
+	redissonScripts = &lockScripts{pttl: luaRedissonPTTL, refresh: luaRedissonRefresh, extend: luaRedissonExtend, release: luaRedissonRelease}
+)
+
+// compatToken generates a token in the format of the compatibility mode.
+func compatToken(mode Compat) (string, error) {
+	buf := randomBufs.Get().(*[16]byte)
+	defer randomBufs.Put(buf)
+
+	if _, err := io.ReadFull(rand.Reader, buf[:]); err != nil {
+		return "", err
+	}
+	if mode != CompatRedisson {
+		return hex.EncodeToString(buf[:]), nil
+	}
+
+	buf[6] = buf[6]&0x0f | 0x40
+	buf[8] = buf[8]&0x3f | 0x80
+
+	var uuid [38]byte
+	hex.Encode(uuid[0:8], buf[0:4])
+	hex.Encode(uuid[9:13], buf[4:6])
+	hex.Encode(uuid[14:18], buf[6:8])
+	hex.Encode(uuid[19:23], buf[8:10])
+	hex.Encode(uuid[24:36], buf[10:])
+	uuid[8], uuid[13], uuid[18], uuid[23] = '-', '-', '-', '-'
+	uuid[36], uuid[37] = ':', '1'
+	return string(uuid[:]), nil
+}
@@ -0,0 +1,75 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Compat", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should store redlock values", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{Compat: redislock.CompatRedlock, Owner: &redislock.Owner{}})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "ignored"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Token()).To(MatchRegexp(`^[0-9a-f]{32}$`))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal(lock.Token()))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should store redisson hashes", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{Compat: redislock.CompatRedisson})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Token()).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}:1$`))
+		Expect(redisClient.HGetAll(ctx, lockKey).Val()).To(Equal(map[string]string{lock.Token(): "1"}))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Extend(ctx, time.Hour, 0)).To(BeNumerically("~", 2*time.Hour, time.Second))
+
+		sub := redisClient.Subscribe(ctx, "redisson_lock__channel:{"+lockKey+"}")
+		defer sub.Close()
+		_, err = sub.Receive(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+		var msg *redis.Message
+		Eventually(sub.Channel()).Should(Receive(&msg))
+		Expect(msg.Payload).To(Equal("0"))
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should respect other redisson holders", func() {
+		Expect(redisClient.HSet(ctx, lockKey, "c0ffee:42", 1).Err()).To(Succeed())
+		subject := redislock.New(redisClient, redislock.Defaults{Compat: redislock.CompatRedisson})
+
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		lock := subject.Resume(lockKey, "c0ffee:42", "")
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(subject.Resume(lockKey, "c0ffee:43", "").Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockStolen))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+})
@@ -0,0 +1,31 @@
+package redislock
+
+import "context"
+
+// lockContextKey is the context key of the lock stored by NewContext.
+type lockContextKey struct{}
+
+// NewContext returns a copy of ctx which carries lock, so code deep down the
+// call stack can check or refresh it via FromContext without the lock being
+// passed along explicitly.
+func NewContext(ctx context.Context, lock *Lock) context.Context {
+	return context.WithValue(ctx, lockContextKey{}, lock)
+}
+
+// FromContext returns the lock stored in ctx by NewContext, if any.
+func FromContext(ctx context.Context) (*Lock, bool) {
+	lock, ok := ctx.Value(lockContextKey{}).(*Lock)
+	return lock, ok && lock != nil
+}
+
+// CheckContext returns ErrLockNotHeld if ctx carries no lock, or Lock.Err of
+// the lock stored in ctx, i.e. nil while it is not known to be lost. It does
+// not contact redis, use Lock.IsHeld on the lock returned by FromContext to
+// verify it authoritatively.
+func CheckContext(ctx context.Context) error {
+	lock, ok := FromContext(ctx)
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return lock.Err()
+}
@@ -0,0 +1,42 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewContext", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should propagate locks", func() {
+		_, ok := redislock.FromContext(ctx)
+		Expect(ok).To(BeFalse())
+		Expect(redislock.CheckContext(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(redislock.CheckContext(redislock.NewContext(ctx, nil))).To(MatchError(redislock.ErrLockNotHeld))
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		lctx := redislock.NewContext(ctx, lock)
+		found, ok := redislock.FromContext(lctx)
+		Expect(ok).To(BeTrue())
+		Expect(found).To(BeIdenticalTo(lock))
+		Expect(redislock.CheckContext(lctx)).To(Succeed())
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redislock.CheckContext(lctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+})
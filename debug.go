@@ -0,0 +1,145 @@
+package redislock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// debugListLimit is the maximum number of locks listed by DebugHandler per
+// request.
+const debugListLimit = 1000
+
+// luaQueueLength counts the waiters of a fair lock which have not timed out.
+var luaQueueLength = redis.NewScript(luaNow + `return redis.call("zcount", KEYS[1], string.format("(%.0f", now), "+inf")`)
+
+// DebugHandler returns an http.Handler which renders the state of the locks
+// of client as JSON, e.g. to be mounted under /debug/locks:
+//
+//	http.Handle("/debug/locks", redislock.DebugHandler(client))
+//
+// The response always includes the locks held by the process, as tracked by
+// Defaults.Manager or, without one, the process-wide registry, see Held.
+// The query parameter pattern additionally lists up to 1000 held locks in
+// redis matching the pattern, see Client.List. Each key query parameter
+// adds the state of that key, including the number of waiters of its fair
+// queue and the statistics recorded with Defaults.RecordStats. Only GET and
+// HEAD requests are accepted.
+func DebugHandler(client *Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		res, err := client.debugReport(r.Context(), r.URL.Query().Get("pattern"), r.URL.Query()["key"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+	})
+}
+
+// debugReport is the response of DebugHandler.
+type debugReport struct {
+	Held  []debugLock `json:"held"`
+	Locks []debugLock `json:"locks,omitempty"`
+	Keys  []debugKey  `json:"keys,omitempty"`
+}
+
+type debugLock struct {
+	Key      string     `json:"key"`
+	Held     bool       `json:"held"`
+	Token    string     `json:"token,omitempty"`
+	Metadata string     `json:"metadata,omitempty"`
+	Owner    *Owner     `json:"owner,omitempty"`
+	TTL      string     `json:"ttl,omitempty"`
+	Obtained *time.Time `json:"obtained,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+}
+
+type debugKey struct {
+	debugLock
+	Waiters int64     `json:"waiters"`
+	Stats   *KeyStats `json:"stats,omitempty"`
+}
+
+func newDebugLock(info *LockInfo) debugLock {
+	lock := debugLock{
+		Key:      info.Key,
+		Held:     info.Held,
+		Token:    info.Token,
+		Metadata: info.Metadata,
+		Owner:    info.Owner,
+	}
+	if info.TTL > 0 {
+		lock.TTL = info.TTL.String()
+	}
+	if !info.Obtained.IsZero() {
+		lock.Obtained = &info.Obtained
+	}
+	if !info.Expires.IsZero() {
+		lock.Expires = &info.Expires
+	}
+	return lock
+}
+
+func (c *Client) debugReport(ctx context.Context, pattern string, keys []string) (*debugReport, error) {
+	held := Held()
+	if c.defaults.Manager != nil {
+		held = c.defaults.Manager.Held()
+	}
+
+	res := &debugReport{Held: make([]debugLock, 0, len(held))}
+	for i := range held {
+		res.Held = append(res.Held, newDebugLock(&held[i]))
+	}
+
+	if pattern != "" {
+		var cursor uint64
+		for {
+			infos, next, err := c.List(ctx, pattern, cursor)
+			if err != nil {
+				return nil, err
+			}
+			for _, info := range infos {
+				res.Locks = append(res.Locks, newDebugLock(info))
+			}
+			if cursor = next; cursor == 0 || len(res.Locks) >= debugListLimit {
+				break
+			}
+		}
+		if len(res.Locks) > debugListLimit {
+			res.Locks = res.Locks[:debugListLimit]
+		}
+	}
+
+	for _, key := range keys {
+		info, err := c.Inspect(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		waiters, err := luaQueueLength.Run(ctx, c.client, []string{c.queueTimeoutKey(c.defaults.KeyPrefix + key)}).Int64()
+		if err != nil {
+			return nil, err
+		}
+
+		dk := debugKey{debugLock: newDebugLock(info), Waiters: waiters}
+		if c.defaults.RecordStats {
+			if dk.Stats, err = c.Stats(ctx, key); err != nil {
+				return nil, err
+			}
+		}
+		res.Keys = append(res.Keys, dk)
+	}
+	return res, nil
+}
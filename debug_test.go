@@ -0,0 +1,90 @@
+package redislock_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DebugHandler", func() {
+	var subject *redislock.Client
+	var manager *redislock.Manager
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		manager = redislock.NewManager()
+		subject = redislock.New(redisClient, redislock.Defaults{Manager: manager, RecordStats: true})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":stats", lockKey+":queue", lockKey+":queue-timeouts").Err()).To(Succeed())
+	})
+
+	get := func(target string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		redislock.DebugHandler(subject).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		var res map[string]interface{}
+		if rec.Code == http.StatusOK {
+			Expect(json.Unmarshal(rec.Body.Bytes(), &res)).To(Succeed())
+		}
+		return rec, res
+	}
+
+	It("should render held locks", func() {
+		_, res := get("/")
+		Expect(res).To(HaveKeyWithValue("held", BeEmpty()))
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "job-1"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		rec, res := get("/?pattern=" + lockKey + "*&key=" + lockKey)
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(res["held"]).To(ConsistOf(And(
+			HaveKeyWithValue("key", lockKey),
+			HaveKeyWithValue("token", lock.Token()),
+			HaveKeyWithValue("metadata", "job-1"),
+			HaveKey("expires"),
+		)))
+		Expect(res["locks"]).To(ConsistOf(HaveKeyWithValue("key", lockKey)))
+		Expect(res["keys"]).To(ConsistOf(And(
+			HaveKeyWithValue("held", true),
+			HaveKeyWithValue("waiters", BeNumerically("==", 0)),
+			HaveKeyWithValue("stats", HaveKeyWithValue("Acquired", BeNumerically("==", 1))),
+		)))
+	})
+
+	It("should count waiters", func() {
+		lock, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		done := make(chan error, 1)
+		go func() {
+			waiter, err := subject.ObtainFair(ctx, lockKey, 5*time.Second, time.Minute, &redislock.Options{RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond)})
+			if err == nil {
+				err = waiter.Release(ctx)
+			}
+			done <- err
+		}()
+
+		Eventually(func() interface{} {
+			_, res := get("/?key=" + lockKey)
+			return res["keys"]
+		}).Should(ConsistOf(HaveKeyWithValue("waiters", BeNumerically("==", 1))))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Eventually(done, time.Second).Should(Receive(BeNil()))
+	})
+
+	It("should reject other methods", func() {
+		rec := httptest.NewRecorder()
+		redislock.DebugHandler(subject).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,101 @@
+package redislock
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError is returned by Do and RunWithRefresh if fn panics and
+// Defaults.RecoverPanics is set.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("redislock: panic: %v", e.Value)
+}
+
+// Do obtains the lock, runs fn and releases the lock again, even if fn
+// panics, in which case the panic continues once the lock is released, or is
+// returned as a *PanicError if Defaults.RecoverPanics is set. It returns the
+// error returned by fn, or the error from Obtain (e.g. ErrNotObtained) if the
+// lock could not be obtained.
+func (c *Client) Do(ctx context.Context, key string, waitTimeout, lockTTL time.Duration, opt *Options, fn func(context.Context) error) (err error) {
+	lock, err := c.Obtain(ctx, key, waitTimeout, lockTTL, opt)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := lock.Release(context.Background()); e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	return c.call(ctx, fn)
+}
+
+// RunWithRefresh obtains the lock on key with ttl, or Defaults.TTL if zero,
+// retrying according to the default RetryStrategy until ctx is done, and runs fn while the lock is
+// refreshed with ttl every refreshEvery, or every third of ttl if zero. The
+// context passed to fn is cancelled once the lock is lost. The lock is
+// released when fn returns or panics, see Do. It returns the error returned by fn, the error
+// which revealed the loss of the lock, or the error from obtaining it.
+func (c *Client) RunWithRefresh(ctx context.Context, key string, ttl, refreshEvery time.Duration, fn func(context.Context) error) (err error) {
+	if err := c.validate(ctx, key, ttl); err != nil {
+		return err
+	}
+	ttl = c.lockTTL(ttl)
+	if refreshEvery <= 0 {
+		refreshEvery = ttl / 3
+	}
+
+	lock, err := c.obtainLock(ctx, key, ttl, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if lock.Err() != nil {
+			return
+		}
+		if e := lock.Release(context.Background()); e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	runctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-lock.Done():
+			cancel()
+		case <-runctx.Done():
+		}
+	}()
+
+	lock.StartAutoRefresh(runctx, refreshEvery, ttl, nil)
+
+	err = c.call(runctx, fn)
+	if lerr := lock.Err(); lerr != nil {
+		err = lerr
+	}
+	return err
+}
+
+// call calls fn, recovering panics as a *PanicError if Defaults.RecoverPanics
+// is set.
+func (c *Client) call(ctx context.Context, fn func(context.Context) error) (err error) {
+	if c.defaults.RecoverPanics {
+		defer func() {
+			if v := recover(); v != nil {
+				err = &PanicError{Value: v, Stack: debug.Stack()}
+			}
+		}()
+	}
+	return fn(ctx)
+}
@@ -0,0 +1,121 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.Do", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should run fn under the lock", func() {
+		err := subject.Do(ctx, lockKey, time.Hour, time.Hour, nil, func(context.Context) error {
+			Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+
+	It("should return fn errors", func() {
+		errTest := errors.New("test")
+		err := subject.Do(ctx, lockKey, time.Hour, time.Hour, nil, func(context.Context) error {
+			return errTest
+		})
+		Expect(err).To(Equal(errTest))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+
+	It("should release on panic", func() {
+		Expect(func() {
+			_ = subject.Do(ctx, lockKey, time.Hour, time.Hour, nil, func(context.Context) error {
+				panic("boom")
+			})
+		}).To(PanicWith("boom"))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+
+	It("should recover panics", func() {
+		subject = redislock.New(redisClient, redislock.Defaults{RecoverPanics: true})
+		err := subject.Do(ctx, lockKey, time.Hour, time.Hour, nil, func(context.Context) error {
+			panic("boom")
+		})
+		var perr *redislock.PanicError
+		Expect(errors.As(err, &perr)).To(BeTrue())
+		Expect(perr.Value).To(Equal("boom"))
+		Expect(string(perr.Stack)).To(ContainSubstring("do_test.go"))
+		Expect(err).To(MatchError("redislock: panic: boom"))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+
+	It("should not run fn if not obtained", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		called := false
+		err := subject.Do(ctx, lockKey, time.Hour, time.Hour, nil, func(context.Context) error {
+			called = true
+			return nil
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(called).To(BeFalse())
+	})
+})
+
+var _ = Describe("Client.RunWithRefresh", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should refresh while fn runs", func() {
+		err := subject.RunWithRefresh(ctx, lockKey, 60*time.Millisecond, 10*time.Millisecond, func(context.Context) error {
+			time.Sleep(150 * time.Millisecond)
+			Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically(">", 30*time.Millisecond))
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should cancel fn once the lock is lost", func() {
+		err := subject.RunWithRefresh(ctx, lockKey, time.Minute, 10*time.Millisecond, func(ctx context.Context) error {
+			Expect(redisClient.Set(context.Background(), lockKey, "ABCD", 0).Err()).To(Succeed())
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		Expect(err).To(MatchError(redislock.ErrLockStolen))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal("ABCD"))
+	})
+
+	It("should not run fn if not obtained", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		called := false
+		err := subject.RunWithRefresh(ctx, lockKey, time.Minute, 0, func(context.Context) error {
+			called = true
+			return nil
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(called).To(BeFalse())
+	})
+})
@@ -0,0 +1,121 @@
+package redislock
+
+import (
+	"context"
+	"time"
+)
+
+// Done returns a channel which is closed once the lock is known to be no
+// longer held: after it was released, after a refresh found it expired or
+// stolen, or once its TTL has run out without a successful refresh. Err
+// reports the reason.
+func (l *Lock) Done() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.done == nil {
+		l.done = make(chan struct{})
+		if l.doneErr != nil {
+			close(l.done)
+		} else if !l.expires.IsZero() {
+			l.expiry = time.AfterFunc(time.Until(l.expires), l.expire)
+		}
+	}
+	return l.done
+}
+
+// Err returns nil while the lock is held. Once Done is closed, it returns
+// ErrLockNotHeld after a release, or the error which revealed the loss of the
+// lock, such as ErrLockExpired or ErrLockStolen, or the context error if the
+// lock was released via Options.ReleaseOnCancel.
+func (l *Lock) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.doneErr
+}
+
+// expire marks the lock as expired, unless it was refreshed in the meantime.
+func (l *Lock) expire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if d := time.Until(l.expires); d > 0 {
+		l.expiry.Reset(d)
+		return
+	}
+	l.setLost(ErrLockExpired)
+}
+
+// extend records a successful refresh which started at start.
+func (l *Lock) extend(start time.Time, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.doneErr != nil || l.parent != nil {
+		return
+	}
+	l.expires = start.Add(ttl)
+	if l.expiry != nil {
+		l.expiry.Reset(time.Until(l.expires))
+	}
+	for child := range l.children {
+		child.mu.Lock()
+		child.expires = l.expires
+		child.mu.Unlock()
+	}
+}
+
+// lost marks the lock as no longer held.
+func (l *Lock) lost(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.setLost(err)
+}
+
+// setReleasing records whether the lock is being released by this process,
+// so a Watcher does not mistake the release for a loss.
+func (l *Lock) setReleasing(releasing bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.releasing = releasing
+}
+
+func (l *Lock) setLost(err error) {
+	if l.doneErr != nil {
+		return
+	}
+
+	l.doneErr = err
+	l.setReleasedLocal()
+	if l.expiry != nil {
+		l.expiry.Stop()
+	}
+	if l.done != nil {
+		close(l.done)
+	}
+	for child := range l.children {
+		child.lost(err)
+	}
+}
+
+// bind releases the lock once ctx is done, if enabled by
+// opt.ReleaseOnCancel, and returns it.
+func (l *Lock) bind(ctx context.Context, opt *Options) *Lock {
+	if !opt.getReleaseOnCancel() || ctx.Done() == nil {
+		return l
+	}
+
+	done := l.Done()
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.lost(ctx.Err())
+			_ = l.Release(context.Background())
+		case <-done:
+		}
+	}()
+	return l
+}
@@ -0,0 +1,87 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock.Done", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should close on release", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Done()).NotTo(BeClosed())
+		Expect(lock.Err()).NotTo(HaveOccurred())
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Done()).To(BeClosed())
+		Expect(lock.Err()).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should close on expiry", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 50*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Done()).NotTo(BeClosed())
+
+		Eventually(lock.Done()).Should(BeClosed())
+		Expect(lock.Err()).To(MatchError(redislock.ErrLockExpired))
+	})
+
+	It("should stay open while refreshed", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 100*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		done := lock.Done()
+
+		time.Sleep(50 * time.Millisecond)
+		Expect(lock.Refresh(ctx, time.Second, nil)).To(Succeed())
+		Consistently(done, 150*time.Millisecond).ShouldNot(BeClosed())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(done).To(BeClosed())
+	})
+
+	It("should close when a refresh finds the lock stolen", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", time.Hour).Err()).To(Succeed())
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(MatchError(redislock.ErrLockStolen))
+		Expect(lock.Done()).To(BeClosed())
+		Expect(lock.Err()).To(MatchError(redislock.ErrLockStolen))
+	})
+
+	It("should release on cancellation", func() {
+		cctx, cancel := context.WithCancel(ctx)
+		lock, err := subject.Obtain(cctx, lockKey, time.Hour, time.Hour, &redislock.Options{ReleaseOnCancel: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Done()).NotTo(BeClosed())
+
+		cancel()
+		Eventually(lock.Done()).Should(BeClosed())
+		Expect(lock.Err()).To(Equal(context.Canceled))
+		Eventually(func() int64 { return redisClient.Exists(ctx, lockKey).Val() }).Should(BeZero())
+	})
+
+	It("should not release on cancellation by default", func() {
+		cctx, cancel := context.WithCancel(ctx)
+		lock, err := subject.Obtain(cctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		cancel()
+		Consistently(lock.Done(), 50*time.Millisecond).ShouldNot(BeClosed())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+})
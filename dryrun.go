@@ -0,0 +1,104 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// obtainDryRun makes a single attempt to obtain the shadow lock of key and
+// returns a lock either way, see Defaults.DryRun.
+func (c *Client) obtainDryRun(ctx context.Context, key string, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	opt = c.options(opt)
+	lockTTL = c.lockTTL(lockTTL)
+	key = c.defaults.KeyPrefix + key
+	shadow := c.companionKey(key, ":dryrun")
+
+	rdb, err := c.clientFor(key, opt.getSelectDB())
+	if err != nil {
+		return nil, err
+	}
+
+	token, value, err := c.newValue(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	once := *opt
+	once.RetryStrategy = NoRetry()
+	opTimeout := opt.getOperationTimeout()
+
+	var start time.Time
+	stats, err := c.retry(ctx, rdb, key, token, &once, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		ok, err := rdb.SetNX(opctx, shadow, value, lockTTL).Result()
+		return ok, wrapOperationErr(ctx, opctx, err)
+	})
+	contended := errors.Is(err, ErrNotObtained)
+	if err != nil && !contended {
+		return nil, err
+	}
+	if contended {
+		start = time.Now()
+		stats = LockStats{Attempts: 1}
+		rdb = dryRunClient{}
+	}
+
+	effectiveTTL := lockTTL - time.Since(start)
+	if effectiveTTL < 0 {
+		effectiveTTL = 0
+	}
+
+	lock := &Lock{
+		client:       c,
+		rdb:          rdb,
+		key:          key,
+		token:        token,
+		value:        value,
+		ttl:          lockTTL,
+		effectiveTTL: effectiveTTL,
+		obtained:     start,
+		expires:      start.Add(lockTTL),
+		opTimeout:    opTimeout,
+		logger:       opt.getLogger(),
+		scripts:      exclusiveScripts,
+		scriptArg:    value,
+		stats:        stats,
+	}
+	lock.keyBuf[0] = shadow
+	lock.scriptKeys = lock.keyBuf[:]
+	return c.track(lock), nil
+}
+
+// dryRunClient backs the locks returned for contended shadow locks. It
+// answers all commands as if they succeeded, without a round trip.
+type dryRunClient struct{}
+
+func (dryRunClient) SetNX(context.Context, string, interface{}, time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, nil)
+}
+
+func (dryRunClient) Eval(context.Context, string, []string, ...interface{}) *redis.Cmd {
+	return redis.NewCmdResult(int64(1), nil)
+}
+
+func (dryRunClient) EvalSha(context.Context, string, []string, ...interface{}) *redis.Cmd {
+	return redis.NewCmdResult(int64(1), nil)
+}
+
+func (dryRunClient) ScriptExists(_ context.Context, hashes ...string) *redis.BoolSliceCmd {
+	exists := make([]bool, len(hashes))
+	for i := range exists {
+		exists[i] = true
+	}
+	return redis.NewBoolSliceResult(exists, nil)
+}
+
+func (dryRunClient) ScriptLoad(context.Context, string) *redis.StringCmd {
+	return redis.NewStringResult("", nil)
+}
@@ -0,0 +1,56 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.DryRun", func() {
+	var ctx = context.Background()
+	var shadowKey = lockKey + ":dryrun"
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, shadowKey).Err()).To(Succeed())
+	})
+
+	It("should never write the lock key", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		logger := new(capturingLogger)
+		subject := redislock.New(redisClient, redislock.Defaults{DryRun: true, Logger: logger})
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Key()).To(Equal(lockKey))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal("ABCD"))
+		Expect(redisClient.Get(ctx, shadowKey).Val()).To(Equal(lock.Token()))
+
+		contended, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contended.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(contended.Release(ctx)).To(Succeed())
+		Expect(redisClient.Get(ctx, shadowKey).Val()).To(Equal(lock.Token()))
+		Expect(logger.messages()).To(ContainElement("info: lock not obtained"))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(redisClient.PTTL(ctx, shadowKey).Val()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, shadowKey).Val()).To(Equal(int64(0)))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal("ABCD"))
+	})
+
+	It("should be enabled per call", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		subject := redislock.New(redisClient)
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{DryRun: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+	})
+})
@@ -0,0 +1,126 @@
+package redislock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LeaderInfo describes the leader of an Election.
+type LeaderInfo struct {
+	// Elected reports whether there is a leader.
+	Elected bool
+	// Token is the lock token of the leader.
+	Token string
+	// Metadata is the metadata the leader campaigned with, e.g. its identity.
+	Metadata string
+}
+
+// Election elects a single leader among the candidates campaigning for a key.
+// The leader holds an exclusive lock on the key, which is refreshed in the
+// background until the leader resigns.
+type Election struct {
+	client *Client
+	key    string
+	ttl    time.Duration
+
+	lock *Lock
+	mu   sync.Mutex
+}
+
+// NewElection creates a new Election for key. The leadership lapses after
+// ttl if the leader fails to refresh it, e.g. because it crashed.
+func NewElection(client RedisClient, key string, ttl time.Duration) *Election {
+	return &Election{client: New(client), key: key, ttl: ttl}
+}
+
+// Campaign blocks until the caller is elected leader or ctx is done, retrying
+// according to opt.RetryStrategy, or every third of the TTL if not set. Use
+// opt.Metadata to identify the candidate to observers.
+//
+// Once elected, the lock is refreshed every third of the TTL until Resign is
+// called. Use Lock.Done on the returned lock to detect a loss of leadership.
+// Calling Campaign while leader returns the current lock.
+// May return ErrNotObtained if not successful.
+func (e *Election) Campaign(ctx context.Context, opt *Options) (*Lock, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.lock != nil && e.lock.Err() == nil {
+		return e.lock, nil
+	}
+
+	var o Options
+	if opt != nil {
+		o = *opt
+	}
+	if o.RetryStrategy == nil {
+		o.RetryStrategy = LinearBackoff(e.ttl / 3)
+	}
+
+	lock, err := e.client.obtainLock(ctx, e.key, e.ttl, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	lock.StartAutoRefresh(context.Background(), e.ttl/3, e.ttl, nil)
+	e.lock = lock
+	return lock, nil
+}
+
+// Resign gives up the leadership, if held, so another candidate can be
+// elected.
+// May return ErrLockNotHeld if the leadership has been lost already.
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	lock := e.lock
+	e.lock = nil
+	e.mu.Unlock()
+
+	if lock == nil {
+		return nil
+	}
+	return lock.Release(ctx)
+}
+
+// Leader returns the current leader.
+func (e *Election) Leader(ctx context.Context) (LeaderInfo, error) {
+	info, err := e.client.Inspect(ctx, e.key)
+	if err != nil {
+		return LeaderInfo{}, err
+	}
+	return LeaderInfo{Elected: info.Held, Token: info.Token, Metadata: info.Metadata}, nil
+}
+
+// Observe returns a channel which receives the current leader and each
+// subsequent change of leadership, polled every third of the TTL. The channel
+// is closed once ctx is done. Errors are retried on the next poll.
+func (e *Election) Observe(ctx context.Context) <-chan LeaderInfo {
+	ch := make(chan LeaderInfo)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+
+		var last LeaderInfo
+		var sent bool
+		for {
+			if info, err := e.Leader(ctx); err == nil && (!sent || info != last) {
+				select {
+				case ch <- info:
+					last, sent = info, true
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ch
+}
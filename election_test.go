@@ -0,0 +1,57 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Election", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should elect a single leader", func() {
+		e1 := redislock.NewElection(redisClient, lockKey, 300*time.Millisecond)
+		e2 := redislock.NewElection(redisClient, lockKey, 300*time.Millisecond)
+
+		octx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		leaders := e2.Observe(octx)
+		Eventually(leaders).Should(Receive(Equal(redislock.LeaderInfo{})))
+
+		lock, err := e1.Campaign(ctx, &redislock.Options{Metadata: "e1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e1.Campaign(ctx, nil)).To(BeIdenticalTo(lock))
+
+		var info redislock.LeaderInfo
+		Eventually(leaders).Should(Receive(&info))
+		Expect(info).To(Equal(redislock.LeaderInfo{Elected: true, Token: lock.Token(), Metadata: "e1"}))
+
+		cctx, ccancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		defer ccancel()
+		_, err = e2.Campaign(cctx, &redislock.Options{Metadata: "e2"})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.Done()).NotTo(BeClosed())
+
+		Expect(e1.Resign(ctx)).To(Succeed())
+		Expect(lock.Done()).To(BeClosed())
+
+		lock2, err := e2.Campaign(ctx, &redislock.Options{Metadata: "e2"})
+		Expect(err).NotTo(HaveOccurred())
+		defer e2.Resign(ctx)
+
+		Eventually(func() string {
+			select {
+			case info = <-leaders:
+			default:
+			}
+			return info.Token
+		}).Should(Equal(lock2.Token()))
+	})
+})
@@ -0,0 +1,83 @@
+package redislock
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// epochTagPrefix starts the epoch tag, which is stored after the token and
+// the sequence tag of a lock value, see Options.Epoch.
+const epochTagPrefix = "epoch.v1?"
+
+// ErrLeaseEpochMismatch is returned when refreshing a lock obtained with
+// Options.Epoch, whose key holds the token of the lock with an earlier epoch,
+// e.g. after redis was restored from a stale snapshot or failed over to a
+// lagging replica. It matches both ErrLockNotHeld and ErrNotObtained.
+var ErrLeaseEpochMismatch error = &lockLostError{msg: "redislock: lease epoch mismatch"}
+
+// luaEpochRefresh refreshes the lock if the key holds ARGV[1] and replaces
+// it with ARGV[3], the value of the next epoch. The key may hold ARGV[3]
+// already, if the reply to the previous refresh was lost. It returns -4 if
+// the key holds the token with another epoch, ARGV[4] being the value up to
+// the epoch.
+var luaEpochRefresh = redis.NewScript(`
+local v = redis.call("get", KEYS[1])
+if v == ARGV[1] or v == ARGV[3] then redis.call("set", KEYS[1], ARGV[3], "px", ARGV[2]) return 1 end
+if not v then return -1 end
+if string.sub(v, 1, #ARGV[4]) == ARGV[4] then return -4 end
+return -2`)
+
+// splitEpoch splits the leading sequence and epoch tags off the metadata of
+// a lock value. It returns the tags before the epoch, the epoch, or 0 if
+// there is none, and the remaining metadata.
+func splitEpoch(metadata string) (seqTag string, epoch int64, rest string) {
+	_, rest = splitSequence(metadata)
+	seqTag = metadata[:len(metadata)-len(rest)]
+	if !strings.HasPrefix(rest, epochTagPrefix) {
+		return seqTag, 0, rest
+	}
+	end := strings.IndexByte(rest, '|')
+	if end < 0 {
+		return seqTag, 0, rest
+	}
+
+	epoch, err := strconv.ParseInt(rest[len(epochTagPrefix):end], 10, 64)
+	if err != nil {
+		return seqTag, 0, rest
+	}
+	return seqTag, epoch, rest[end+1:]
+}
+
+// withEpoch returns value, the value of a lock with token, with its epoch
+// tag set to epoch.
+func withEpoch(token, value string, epoch int64) string {
+	seqTag, _, rest := splitEpoch(value[len(token):])
+	return token + seqTag + epochTagPrefix + strconv.FormatInt(epoch, 10) + "|" + rest
+}
+
+// Epoch returns the lease epoch of the lock, which is rotated by each
+// successful Refresh, see Options.Epoch. Returns 0 if it was obtained
+// without.
+func (l *Lock) Epoch() int64 {
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	_, epoch, _ := splitEpoch(l.value[len(l.token):])
+	return epoch
+}
+
+// epochRefreshArgs returns the arguments of luaEpochRefresh for l, besides
+// the TTL, and the value of the next epoch.
+func (l *Lock) epochRefreshArgs() (next, prefix string) {
+	seqTag, epoch, _ := splitEpoch(l.value[len(l.token):])
+	return withEpoch(l.token, l.value, epoch+1), l.token + seqTag + epochTagPrefix
+}
+
+// hasEpoch reports whether the metadata of a lock value starts with an epoch
+// tag.
+func hasEpoch(metadata string) bool {
+	_, epoch, _ := splitEpoch(metadata)
+	return epoch > 0
+}
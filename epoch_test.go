@@ -0,0 +1,93 @@
+package redislock_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Options.Epoch", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient, redislock.Defaults{Owner: &redislock.Owner{Host: "web-1", PID: 42}})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":seq").Err()).To(Succeed())
+	})
+
+	It("should rotate the epoch on refresh", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Epoch: true, Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Epoch()).To(Equal(int64(1)))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.Epoch()).To(Equal(int64(3)))
+		Expect(lock.Metadata()).To(Equal("meta"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Token).To(Equal(lock.Token()))
+		Expect(info.Metadata).To(Equal("meta"))
+		Expect(info.Owner).To(Equal(&redislock.Owner{Host: "web-1", PID: 42}))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should accept the next epoch if a reply was lost", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Epoch: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		next := strings.Replace(redisClient.Get(ctx, lockKey).Val(), "epoch.v1?1|", "epoch.v1?2|", 1)
+		Expect(redisClient.Set(ctx, lockKey, next, time.Minute).Err()).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(lock.Epoch()).To(Equal(int64(2)))
+	})
+
+	It("should detect stale restores", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Epoch: true, Sequence: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Sequence()).To(Equal(int64(1)))
+
+		snapshot := redisClient.Get(ctx, lockKey).Val()
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(lock.Epoch()).To(Equal(int64(2)))
+		Expect(lock.Sequence()).To(Equal(int64(1)))
+
+		Expect(redisClient.Set(ctx, lockKey, snapshot, time.Minute).Err()).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLeaseEpochMismatch))
+		Expect(lock.Err()).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should report other holders as stolen", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Epoch: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", time.Minute).Err()).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockStolen))
+	})
+
+	It("should rotate resumed locks", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Epoch: true})
+		Expect(err).NotTo(HaveOccurred())
+		data, err := lock.MarshalBinary()
+		Expect(err).NotTo(HaveOccurred())
+
+		resumed, err := subject.ResumeBinary(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(resumed.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(resumed.Epoch()).To(Equal(int64(3)))
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLeaseEpochMismatch))
+		Expect(resumed.Release(ctx)).To(Succeed())
+	})
+})
@@ -0,0 +1,122 @@
+package redislock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LockEventType is the type of a LockEvent.
+type LockEventType int
+
+const (
+	// EventAcquired is emitted when the lock key is set, i.e. the lock was
+	// obtained, transferred or its metadata changed.
+	EventAcquired LockEventType = iota + 1
+	// EventRefreshed is emitted when the TTL of the lock key is changed.
+	EventRefreshed
+	// EventReleased is emitted when the lock key is deleted.
+	EventReleased
+	// EventExpired is emitted when the lock key expires.
+	EventExpired
+)
+
+func (t LockEventType) String() string {
+	switch t {
+	case EventAcquired:
+		return "acquired"
+	case EventRefreshed:
+		return "refreshed"
+	case EventReleased:
+		return "released"
+	case EventExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// LockEvent is a state transition of a lock, see Client.Watch.
+type LockEvent struct {
+	// Type is the type of the transition.
+	Type LockEventType
+	// Key is the redis key of the lock.
+	Key string
+	// Time is the time the event was received.
+	Time time.Time
+}
+
+// Watch streams the state transitions of the lock on key until ctx is done,
+// e.g. for dashboards or workflows which depend on a lock. Releases are
+// reported via the release notifications of holders using
+// Options.ReleaseNotify. All other events, and releases by other holders, are
+// derived from keyspace notifications, which must be enabled on the server,
+// e.g. via "CONFIG SET notify-keyspace-events Kg$x", and are only received if
+// the client is a *redis.Client. Consecutive releases are reported once. The
+// returned channel is closed once ctx is done or the subscription fails. The
+// client must implement SubscribingClient.
+func (c *Client) Watch(ctx context.Context, key string) (_ <-chan LockEvent, err error) {
+	key = c.defaults.KeyPrefix + key
+	defer wrapErr("watch", key, &err)
+
+	subscriber, ok := c.client.(SubscribingClient)
+	if !ok {
+		return nil, errEventsUnsupported
+	}
+	sub, err := c.subscribe(ctx, subscriber, c.client, key)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan LockEvent)
+	go func() {
+		defer close(events)
+		defer sub.Close()
+
+		var last LockEventType
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				typ := lockEventType(key, msg)
+				if typ == 0 || (typ == EventReleased && (last == EventReleased || last == EventExpired)) {
+					continue
+				}
+				last = typ
+
+				select {
+				case events <- LockEvent{Type: typ, Key: key, Time: time.Now()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// lockEventType maps a release notification or keyspace notification to the
+// type of event, or zero if it is not a lock state transition.
+func lockEventType(key string, msg *redis.Message) LockEventType {
+	if msg.Channel == releasedChannel(key) {
+		return EventReleased
+	}
+
+	switch msg.Payload {
+	case "set":
+		return EventAcquired
+	case "expire":
+		return EventRefreshed
+	case "del":
+		return EventReleased
+	case "expired":
+		return EventExpired
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.Watch", func() {
+	var subject *redislock.Client
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Expect(redisClient.Del(context.Background(), lockKey).Err()).To(Succeed())
+	})
+
+	// publish simulates the keyspace notifications sent by redis, if enabled.
+	publish := func(event string) {
+		Expect(redisClient.Publish(ctx, "__keyspace@9__:"+lockKey, event).Err()).To(Succeed())
+	}
+
+	next := func(events <-chan redislock.LockEvent) redislock.LockEventType {
+		var event redislock.LockEvent
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Key).To(Equal(lockKey))
+		Expect(event.Time).To(BeTemporally("~", time.Now(), time.Second))
+		return event.Type
+	}
+
+	It("should stream lock state transitions", func() {
+		events, err := subject.Watch(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, event := range []string{"set", "expire", "expired", "set", "del"} {
+			publish(event)
+		}
+		Expect(next(events)).To(Equal(redislock.EventAcquired))
+		Expect(next(events)).To(Equal(redislock.EventRefreshed))
+		Expect(next(events)).To(Equal(redislock.EventExpired))
+		Expect(next(events)).To(Equal(redislock.EventAcquired))
+		Expect(next(events)).To(Equal(redislock.EventReleased))
+		Expect(redislock.EventReleased.String()).To(Equal("released"))
+	})
+
+	It("should report release notifications once", func() {
+		events, err := subject.Watch(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{ReleaseNotify: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+		publish("del")
+		publish("set")
+
+		Expect(next(events)).To(Equal(redislock.EventReleased))
+		Expect(next(events)).To(Equal(redislock.EventAcquired))
+	})
+
+	It("should close the channel once the context is done", func() {
+		events, err := subject.Watch(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		cancel()
+		Eventually(events).Should(BeClosed())
+	})
+})
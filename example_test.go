@@ -2,6 +2,7 @@ package redislock_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -14,7 +15,7 @@ func Example() {
 	// Connect to redis.
 	client := redis.NewClient(&redis.Options{
 		Network: "tcp",
-		Addr:    "127.0.0.1:6379",
+		Addr:    redisAddr,
 	})
 	defer client.Close()
 
@@ -25,7 +26,7 @@ func Example() {
 
 	// Try to obtain lock.
 	lock, err := locker.Obtain(ctx, "my-key", 100*time.Millisecond, 100*time.Millisecond, nil)
-	if err == redislock.ErrNotObtained {
+	if errors.Is(err, redislock.ErrNotObtained) {
 		fmt.Println("Could not obtain lock!")
 	} else if err != nil {
 		log.Fatalln(err)
@@ -63,7 +64,7 @@ func Example() {
 }
 
 func ExampleClient_Obtain_retry() {
-	client := redis.NewClient(&redis.Options{Network: "tcp", Addr: "127.0.0.1:6379"})
+	client := redis.NewClient(&redis.Options{Network: "tcp", Addr: redisAddr})
 	defer client.Close()
 
 	locker := redislock.New(client)
@@ -77,7 +78,7 @@ func ExampleClient_Obtain_retry() {
 	lock, err := locker.Obtain(ctx, "my-key", time.Second, time.Second, &redislock.Options{
 		RetryStrategy: backoff,
 	})
-	if err == redislock.ErrNotObtained {
+	if errors.Is(err, redislock.ErrNotObtained) {
 		fmt.Println("Could not obtain lock!")
 	} else if err != nil {
 		log.Fatalln(err)
@@ -88,7 +89,7 @@ func ExampleClient_Obtain_retry() {
 }
 
 func ExampleClient_Obtain_customDeadline() {
-	client := redis.NewClient(&redis.Options{Network: "tcp", Addr: "127.0.0.1:6379"})
+	client := redis.NewClient(&redis.Options{Network: "tcp", Addr: redisAddr})
 	defer client.Close()
 
 	locker := redislock.New(client)
@@ -102,7 +103,7 @@ func ExampleClient_Obtain_customDeadline() {
 	lock, err := locker.Obtain(ctx, "my-key", time.Second, time.Second, &redislock.Options{
 		RetryStrategy: backoff,
 	})
-	if err == redislock.ErrNotObtained {
+	if errors.Is(err, redislock.ErrNotObtained) {
 		fmt.Println("Could not obtain lock!")
 	} else if err != nil {
 		log.Fatalln(err)
@@ -0,0 +1,54 @@
+package redislock
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExpiredError is returned instead of ErrLockExpired if Defaults.StrictExpiry
+// is set. It matches ErrLockExpired, ErrLockNotHeld and ErrNotObtained.
+type ExpiredError struct {
+	// Ago is the time since the lock expired, according to its local
+	// expiry estimate, see Lock.ValidUntil. It is negative if the lock was
+	// gone early, e.g. because its key was deleted or evicted.
+	Ago time.Duration
+}
+
+func (e *ExpiredError) Error() string {
+	if e.Ago < 0 {
+		return fmt.Sprintf("redislock: lock expired %s early", (-e.Ago).Round(time.Millisecond))
+	}
+	return fmt.Sprintf("redislock: lock expired %s ago", e.Ago.Round(time.Millisecond))
+}
+
+// Is reports whether target is ErrLockExpired, ErrLockNotHeld or
+// ErrNotObtained.
+func (e *ExpiredError) Is(target error) bool {
+	return target == ErrLockExpired || target == ErrLockNotHeld || target == ErrNotObtained
+}
+
+// ExpiredMetricsCollector is an optional extension of MetricsCollector, which
+// is notified of locks found expired, see Defaults.StrictExpiry.
+type ExpiredMetricsCollector interface {
+	// LockExpired is called when a refresh or release finds a lock expired,
+	// with the time since its local expiry estimate.
+	LockExpired(key string, ago time.Duration)
+}
+
+// strictExpiry turns err into an ExpiredError if the client is strict about
+// expiry and err reports an expired lock, or a lock which is not held past
+// its local expiry estimate.
+func (l *Lock) strictExpiry(err, notHeld error) error {
+	if !l.client.defaults.StrictExpiry || (err != ErrLockExpired && err != notHeld) {
+		return err
+	}
+
+	ago := time.Since(l.ValidUntil())
+	if err == notHeld && ago < 0 {
+		return err
+	}
+	if metrics, ok := l.client.defaults.Metrics.(ExpiredMetricsCollector); ok {
+		metrics.LockExpired(l.key, ago)
+	}
+	return &ExpiredError{Ago: ago}
+}
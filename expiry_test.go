@@ -0,0 +1,86 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.StrictExpiry", func() {
+	var subject *redislock.Client
+	var metrics *expiredMetrics
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		metrics = new(expiredMetrics)
+		subject = redislock.New(redisClient, redislock.Defaults{StrictExpiry: true, Metrics: metrics})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should report how long ago locks expired", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(50 * time.Millisecond)
+
+		err = lock.Refresh(ctx, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrLockExpired))
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		var expired *redislock.ExpiredError
+		Expect(errors.As(err, &expired)).To(BeTrue())
+		Expect(expired.Ago).To(BeNumerically("~", 30*time.Millisecond, 20*time.Millisecond))
+		Expect(err.Error()).To(MatchRegexp(`^redislock: refresh "` + lockKey + `": lock expired \d+ms ago$`))
+
+		err = lock.Release(ctx)
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(errors.As(err, &expired)).To(BeTrue())
+		Expect(metrics.expired).To(HaveLen(2))
+		Expect(metrics.expired[0]).To(BeNumerically(">", 0))
+	})
+
+	It("should report locks gone early", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+
+		err = lock.Release(ctx)
+		var expired *redislock.ExpiredError
+		Expect(errors.As(err, &expired)).To(BeTrue())
+		Expect(expired.Ago).To(BeNumerically("<", 0))
+		Expect(err).To(MatchError(ContainSubstring("early")))
+	})
+
+	It("should not affect stolen locks", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockStolen))
+		Expect(metrics.expired).To(BeEmpty())
+	})
+})
+
+type expiredMetrics struct {
+	mu      sync.Mutex
+	expired []time.Duration
+}
+
+func (*expiredMetrics) ObtainAttempt(string, bool)              {}
+func (*expiredMetrics) ObtainDone(string, time.Duration, error) {}
+func (*expiredMetrics) RefreshDone(string, error)               {}
+func (*expiredMetrics) Released(string, time.Duration)          {}
+
+func (m *expiredMetrics) LockExpired(_ string, ago time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expired = append(m.expired, ago)
+}
@@ -0,0 +1,191 @@
+package redislock
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultQueueTimeout is the default time after which an inactive waiter is
+// removed from a fair lock queue.
+const defaultQueueTimeout = 5 * time.Second
+
+// maxPriority is the maximum absolute priority of a fair lock waiter. Queue
+// scores are offset by 1e13 per priority level, which must remain exactly
+// representable as a float.
+const maxPriority = 100
+
+// defaultPriorityAging is the default time after which the priority of a
+// waiter for a fair lock rises by one level.
+const defaultPriorityAging = time.Second
+
+// maxHoldSamples is the number of recent hold durations kept per fair lock
+// to estimate the wait time of its waiters, see Options.OnQueue.
+const maxHoldSamples = 10
+
+// holdSampleTTL is the time after which the hold durations of a fair lock
+// expire unless it is granted again.
+const holdSampleTTL = time.Hour
+
+var (
+	luaFairObtain = redis.NewScript(luaNow + `
+local stale = redis.call("zrangebyscore", KEYS[3], "-inf", now)
+for i = 1, #stale do redis.call("zrem", KEYS[2], stale[i]) end
+redis.call("zremrangebyscore", KEYS[3], "-inf", now)
+
+local waited = redis.call("zscore", KEYS[2], ARGV[3])
+if not waited then
+	local band = -tonumber(ARGV[5]) * 1e13
+	local score = band + now
+	local tail = redis.call("zrangebyscore", KEYS[2], string.format("%.0f", score), string.format("(%.0f", band + 1e13), "withscores")
+	if #tail > 0 then score = tonumber(tail[#tail]) + 1 end
+	redis.call("zadd", KEYS[2], string.format("%.0f", score), ARGV[3])
+end
+redis.call("zadd", KEYS[3], now + tonumber(ARGV[4]), ARGV[3])
+
+local aging = tonumber(ARGV[8])
+local function effective(score)
+	if aging <= 0 then return score end
+	local band = math.floor(score / 1e13) * 1e13
+	return score - math.floor(math.max(now - (score - band), 0) / aging) * 1e13
+end
+local mine = effective(tonumber(redis.call("zscore", KEYS[2], ARGV[3])))
+local queue = redis.call("zrange", KEYS[2], 0, -1, "withscores")
+local pos = 1
+for i = 1, #queue, 2 do
+	if queue[i] ~= ARGV[3] then
+		local s = effective(tonumber(queue[i + 1]))
+		if s < mine or (s == mine and queue[i] < ARGV[3]) then pos = pos + 1 end
+	end
+end
+
+if pos == 1 and redis.call("exists", KEYS[1]) == 0 then
+	redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])
+	redis.call("zrem", KEYS[2], ARGV[3])
+	redis.call("zrem", KEYS[3], ARGV[3])
+	local granted = redis.call("get", KEYS[5])
+	if waited and granted then
+		redis.call("lpush", KEYS[4], now - tonumber(granted))
+		redis.call("ltrim", KEYS[4], 0, tonumber(ARGV[6]) - 1)
+		redis.call("pexpire", KEYS[4], ARGV[7])
+	end
+	redis.call("set", KEYS[5], now, "px", ARGV[7])
+	return {1, 0, 0}
+end
+
+redis.call("pexpire", KEYS[2], ARGV[4])
+redis.call("pexpire", KEYS[3], ARGV[4])
+
+local holds = redis.call("lrange", KEYS[4], 0, -1)
+if #holds == 0 then return {0, pos, -1} end
+local sum = 0
+for i = 1, #holds do sum = sum + tonumber(holds[i]) end
+local avg = sum / #holds
+local left = avg
+local granted = redis.call("get", KEYS[5])
+if granted then left = math.max(avg - (now - tonumber(granted)), 0) end
+local pttl = redis.call("pttl", KEYS[1])
+if pttl == -2 then left = 0 elseif pttl >= 0 and pttl < left then left = pttl end
+return {0, pos, math.floor((pos - 1) * avg + left)}`)
+	luaFairLeave = redis.NewScript(`
+redis.call("zrem", KEYS[1], ARGV[1])
+redis.call("zrem", KEYS[2], ARGV[1])
+return 1`)
+)
+
+func (c *Client) queueKey(key string) string        { return c.companionKey(key, ":queue") }
+func (c *Client) queueTimeoutKey(key string) string { return c.companionKey(key, ":queue-timeouts") }
+func (c *Client) holdsKey(key string) string        { return c.companionKey(key, ":holds") }
+func (c *Client) grantedKey(key string) string      { return c.companionKey(key, ":granted") }
+
+// ObtainFair obtains a lock like Obtain, but grants it to waiters in the order
+// of their Options.Priority, highest first, and of their first attempt among
+// waiters of the same priority. The priority of a waiter rises with the time
+// it waited, see Options.PriorityAging, so low-priority waiters are not
+// starved by a steady stream of higher-priority ones. Waiters which stop retrying for longer than
+// Options.QueueTimeout are removed from the queue. Fairness only applies
+// among callers of ObtainFair, plain Obtain calls may still take the lock
+// while it is free. Waiters may follow their progress via Options.OnQueue.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainFair(ctx context.Context, key string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	if err := c.validate(ctx, key, lockTTL); err != nil {
+		return nil, err
+	}
+	opt = c.options(opt)
+	lockTTL = c.lockTTL(lockTTL)
+	key = c.defaults.KeyPrefix + key
+
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := c.lockValue(token, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	keys := []string{key, c.queueKey(key), c.queueTimeoutKey(key), c.holdsKey(key), c.grantedKey(key)}
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+	queueTimeoutVal := strconv.FormatInt(int64(opt.getQueueTimeout()/time.Millisecond), 10)
+	priorityVal := strconv.Itoa(opt.getPriority())
+	agingVal := msArg(opt.getPriorityAging())
+	onQueue := opt.getOnQueue()
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	var start time.Time
+	stats, err := c.retry(deadlinectx, c.client, key, token, opt, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		res, err := luaFairObtain.Run(opctx, c.client, keys, value, ttlVal, token, queueTimeoutVal, priorityVal, maxHoldSamples, msArg(holdSampleTTL), agingVal).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+
+		vals, _ := res.([]interface{})
+		if len(vals) != 3 {
+			return false, nil
+		}
+		status, _ := vals[0].(int64)
+		pos, _ := vals[1].(int64)
+		eta, _ := vals[2].(int64)
+		if status != 1 && onQueue != nil {
+			wait := time.Duration(-1)
+			if eta >= 0 {
+				wait = time.Duration(eta) * time.Millisecond
+			}
+			onQueue(int(pos), wait)
+		}
+		return status == 1, nil
+	})
+	if err != nil {
+		leavectx, cancel := cleanupContext(opTimeout)
+		defer cancel()
+		_ = luaFairLeave.Run(leavectx, c.client, keys[1:3], token).Err()
+		return nil, err
+	}
+
+	return c.track(&Lock{
+		client:     c,
+		rdb:        c.client,
+		key:        key,
+		token:      token,
+		value:      value,
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opTimeout,
+		logger:     opt.getLogger(),
+		scripts:    exclusiveScripts,
+		scriptKeys: []string{key},
+		scriptArg:  value,
+		stats:      stats,
+	}).bind(ctx, opt), nil
+}
@@ -0,0 +1,256 @@
+package redislock_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.ObtainFair", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	retry := &redislock.Options{}
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+		retry.RetryStrategy = redislock.LinearBackoff(5 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":queue", lockKey+":queue-timeouts", lockKey+":holds", lockKey+":granted").Err()).To(Succeed())
+	})
+
+	It("should obtain when free", func() {
+		lock, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(redisClient.ZCard(ctx, lockKey+":queue").Val()).To(BeZero())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should grant the lock in arrival order", func() {
+		holder, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		order := make(chan string, 3)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		wg.Add(3)
+		obtain := func(name string) {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			lock, err := subject.ObtainFair(ctx, lockKey, 5*time.Second, time.Minute, retry)
+			Expect(err).NotTo(HaveOccurred())
+			order <- name
+			time.Sleep(20 * time.Millisecond)
+			Expect(lock.Release(ctx)).To(Succeed())
+		}
+
+		go obtain("first")
+		Eventually(func() int64 { return redisClient.ZCard(ctx, lockKey+":queue").Val() }).Should(Equal(int64(1)))
+		go obtain("second")
+		Eventually(func() int64 { return redisClient.ZCard(ctx, lockKey+":queue").Val() }).Should(Equal(int64(2)))
+		go obtain("third")
+		Eventually(func() int64 { return redisClient.ZCard(ctx, lockKey+":queue").Val() }).Should(Equal(int64(3)))
+
+		Expect(holder.Release(ctx)).To(Succeed())
+		Expect(<-order).To(Equal("first"))
+		Expect(<-order).To(Equal("second"))
+		Expect(<-order).To(Equal("third"))
+	})
+
+	It("should grant the lock by priority", func() {
+		holder, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		order := make(chan string, 3)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		wg.Add(3)
+		obtain := func(name string, priority int) {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			opt := &redislock.Options{RetryStrategy: retry.RetryStrategy, Priority: priority}
+			lock, err := subject.ObtainFair(ctx, lockKey, 5*time.Second, time.Minute, opt)
+			Expect(err).NotTo(HaveOccurred())
+			order <- name
+			time.Sleep(20 * time.Millisecond)
+			Expect(lock.Release(ctx)).To(Succeed())
+		}
+
+		go obtain("low", -1)
+		Eventually(func() int64 { return redisClient.ZCard(ctx, lockKey+":queue").Val() }).Should(Equal(int64(1)))
+		go obtain("normal", 0)
+		Eventually(func() int64 { return redisClient.ZCard(ctx, lockKey+":queue").Val() }).Should(Equal(int64(2)))
+		go obtain("urgent", 1000)
+		Eventually(func() int64 { return redisClient.ZCard(ctx, lockKey+":queue").Val() }).Should(Equal(int64(3)))
+
+		Expect(holder.Release(ctx)).To(Succeed())
+		Expect(<-order).To(Equal("urgent"))
+		Expect(<-order).To(Equal("normal"))
+		Expect(<-order).To(Equal("low"))
+	})
+
+	It("should age waiters under steady high-priority load", func() {
+		holder, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		done := make(chan struct{})
+		var grants int32
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer close(done)
+
+		urgent := func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			opt := &redislock.Options{RetryStrategy: retry.RetryStrategy, Priority: 5, PriorityAging: 50 * time.Millisecond}
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				lock, err := subject.ObtainFair(ctx, lockKey, time.Second, time.Minute, opt)
+				if err == redislock.ErrNotObtained {
+					continue
+				}
+				Expect(err).NotTo(HaveOccurred())
+				atomic.AddInt32(&grants, 1)
+				time.Sleep(10 * time.Millisecond)
+				Expect(lock.Release(ctx)).To(Succeed())
+			}
+		}
+		wg.Add(2)
+		go urgent()
+		go urgent()
+		Eventually(func() int64 { return redisClient.ZCard(ctx, lockKey+":queue").Val() }).Should(Equal(int64(2)))
+
+		obtained := make(chan *redislock.Lock, 1)
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			opt := &redislock.Options{RetryStrategy: retry.RetryStrategy, Priority: -5, PriorityAging: 50 * time.Millisecond}
+			lock, err := subject.ObtainFair(ctx, lockKey, 5*time.Second, time.Minute, opt)
+			Expect(err).NotTo(HaveOccurred())
+			obtained <- lock
+		}()
+		Eventually(func() int64 { return redisClient.ZCard(ctx, lockKey+":queue").Val() }).Should(Equal(int64(3)))
+
+		Expect(holder.Release(ctx)).To(Succeed())
+		var lock *redislock.Lock
+		Eventually(obtained, 3*time.Second).Should(Receive(&lock))
+		Expect(atomic.LoadInt32(&grants)).To(BeNumerically(">", 2))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should report the queue position and estimated wait", func() {
+		holder, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var mu sync.Mutex
+		positions := map[string]int{}
+		etas := map[string]time.Duration{}
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer close(release)
+
+		obtain := func(name string) {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			lock, err := subject.ObtainFair(ctx, lockKey, 5*time.Second, time.Minute, &redislock.Options{
+				RetryStrategy: redislock.LinearBackoff(5 * time.Millisecond),
+				OnQueue: func(position int, eta time.Duration) {
+					mu.Lock()
+					defer mu.Unlock()
+					positions[name], etas[name] = position, eta
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			<-release
+			Expect(lock.Release(ctx)).To(Succeed())
+		}
+		report := func(name string) (int, time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			return positions[name], etas[name]
+		}
+
+		wg.Add(1)
+		go obtain("first")
+		Eventually(func() int { pos, _ := report("first"); return pos }).Should(Equal(1))
+		_, eta := report("first")
+		Expect(eta).To(BeNumerically("<", 0))
+
+		// The first waiter records the hold duration of the holder.
+		time.Sleep(20 * time.Millisecond)
+		Expect(holder.Release(ctx)).To(Succeed())
+		Eventually(func() int64 { return redisClient.LLen(ctx, lockKey+":holds").Val() }).Should(Equal(int64(1)))
+		holds, err := redisClient.LRange(ctx, lockKey+":holds", 0, 0).Result()
+		Expect(err).NotTo(HaveOccurred())
+		ms, err := strconv.ParseFloat(holds[0], 64)
+		Expect(err).NotTo(HaveOccurred())
+		hold := time.Duration(ms) * time.Millisecond
+		Expect(hold).To(BeNumerically(">=", 20*time.Millisecond))
+
+		// Estimates are based on the recorded hold duration: the head of the
+		// queue waits for the rest of the current hold, each waiter behind it
+		// for one more hold.
+		wg.Add(1)
+		go obtain("second")
+		Eventually(func() int { pos, _ := report("second"); return pos }).Should(Equal(1))
+		wg.Add(1)
+		go obtain("third")
+		Eventually(func() int { pos, _ := report("third"); return pos }).Should(Equal(2))
+
+		_, second := report("second")
+		Expect(second).To(BeNumerically(">=", 0))
+		Expect(second).To(BeNumerically("<=", hold))
+		_, third := report("third")
+		Expect(third).To(BeNumerically(">=", hold))
+		Expect(third).To(BeNumerically("<=", 2*hold))
+	})
+
+	It("should leave the queue when giving up", func() {
+		holder, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer holder.Release(ctx)
+
+		_, err = subject.ObtainFair(ctx, lockKey, 30*time.Millisecond, time.Minute, retry)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(redisClient.ZCard(ctx, lockKey+":queue").Val()).To(BeZero())
+	})
+
+	It("should skip inactive waiters", func() {
+		holder, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		// A crashed waiter still at the head of the queue.
+		Expect(redisClient.ZAdd(ctx, lockKey+":queue", &redis.Z{Score: 0, Member: "crashed"}).Err()).To(Succeed())
+		Expect(redisClient.ZAdd(ctx, lockKey+":queue-timeouts", &redis.Z{Score: 1, Member: "crashed"}).Err()).To(Succeed())
+		Expect(holder.Release(ctx)).To(Succeed())
+
+		lock, err := subject.ObtainFair(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+})
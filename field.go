@@ -0,0 +1,157 @@
+package redislock
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// maxFieldPurge is the maximum number of expired fields removed from a field
+// lock hash per obtain or release.
+const maxFieldPurge = 100
+
+// luaFieldHeld splits ARGV[1], the length of the field, a colon, the field and
+// the lock value, and sets left to the remaining TTL of the field lock in the
+// hash KEYS[1], whose deadlines are kept in the sorted set KEYS[2], or to -1
+// if it expired or -2 if it is held with another value.
+const luaFieldHeld = luaNow + `
+local sep = string.find(ARGV[1], ":", 1, true)
+local field = string.sub(ARGV[1], sep + 1, sep + tonumber(string.sub(ARGV[1], 1, sep - 1)))
+local value = string.sub(ARGV[1], sep + #field + 1)
+local function held()
+	local v = redis.call("hget", KEYS[1], field)
+	local s = redis.call("zscore", KEYS[2], field)
+	if not v or not s or tonumber(s) <= now then return -1 end
+	if v ~= value then return -2 end
+	return tonumber(s) - now
+end
+local left = held()
+`
+
+// luaFieldPurge removes up to ARGV[3] expired fields.
+const luaFieldPurge = `
+local stale = redis.call("zrangebyscore", KEYS[2], "-inf", now, "limit", 0, tonumber(ARGV[3]))
+for i = 1, #stale do
+	redis.call("hdel", KEYS[1], stale[i])
+	redis.call("zrem", KEYS[2], stale[i])
+end
+`
+
+// luaFieldExpire expires the hash and its deadlines with the last field.
+const luaFieldExpire = `
+local last = redis.call("zrange", KEYS[2], -1, -1, "withscores")
+if last[2] then
+	redis.call("pexpireat", KEYS[1], last[2])
+	redis.call("pexpireat", KEYS[2], last[2])
+else
+	redis.call("del", KEYS[1], KEYS[2])
+end
+`
+
+var (
+	luaFieldObtain = redis.NewScript(luaFieldHeld + luaFieldPurge + `
+if left == -2 then return 0 end
+redis.call("hset", KEYS[1], field, value)
+redis.call("zadd", KEYS[2], now + tonumber(ARGV[2]), field)` + luaFieldExpire + `
+return 1`)
+	luaFieldPTTL = redis.NewScript(luaFieldHeld + `
+if left < 0 then return -3 end
+return left`)
+	luaFieldRefresh = redis.NewScript(luaFieldHeld + `
+if left < 0 then return left end
+redis.call("zadd", KEYS[2], now + tonumber(ARGV[2]), field)` + luaFieldExpire + `
+return 1`)
+	luaFieldExtend = redis.NewScript(luaFieldHeld + `
+if left < 0 then return left end
+local t = left + tonumber(ARGV[2])
+if tonumber(ARGV[3]) > 0 then t = math.min(t, tonumber(ARGV[3])) end
+redis.call("zadd", KEYS[2], now + t, field)` + luaFieldExpire + `
+return t`)
+	luaFieldRelease = redis.NewScript(luaFieldHeld + `
+if left < 0 then return left end
+redis.call("hdel", KEYS[1], field)
+redis.call("zrem", KEYS[2], field)` + luaFieldExpire + `
+return 1`)
+
+	fieldScripts = &lockScripts{pttl: luaFieldPTTL, refresh: luaFieldRefresh, extend: luaFieldExtend, release: luaFieldRelease}
+)
+
+func (c *Client) deadlinesKey(key string) string { return c.companionKey(key, ":deadlines") }
+
+// ObtainField obtains a lock on field of the redis hash at key, whose TTL is
+// emulated via a sorted set of deadlines under key + ":deadlines". Storing
+// the locks of many small entities as fields of a few hashes, e.g. one per
+// entity type, avoids a key per lock when locking millions of entities.
+// Expired fields are removed as other fields of the hash are locked, and
+// the hash expires with its last field. The lock is reported by Key as key
+// followed by "#" and field. Field locks can be refreshed, extended and
+// released like other locks, but do not support release signals or
+// notifications, fencing and Lock.SetMetadata. Retries according to
+// opt.RetryStrategy until waitTimeout elapsed.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainField(ctx context.Context, key, field string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	if err := c.validate(ctx, key, lockTTL); err != nil {
+		return nil, err
+	} else if field == "" {
+		return nil, &Error{Op: "obtain", Key: c.defaults.KeyPrefix + key, Err: ErrInvalidKey}
+	}
+	opt = c.options(opt)
+	lockTTL = c.lockTTL(lockTTL)
+	key = c.defaults.KeyPrefix + key
+
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := c.lockValue(token, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := strconv.Itoa(len(field)) + ":" + field + value
+	opTimeout := opt.getOperationTimeout()
+	keys := []string{key, c.deadlinesKey(key)}
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	var start time.Time
+	stats, err := c.retry(deadlinectx, c.client, key, token, opt, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		status, err := luaFieldObtain.Run(opctx, c.client, keys, arg, ttlVal, maxFieldPurge).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		return status == int64(1), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{
+		client:     c,
+		rdb:        c.client,
+		key:        key + "#" + field,
+		token:      token,
+		value:      value,
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opTimeout,
+		logger:     opt.getLogger(),
+		scripts:    fieldScripts,
+		scriptKeys: keys,
+		scriptArg:  arg,
+		stats:      stats,
+		audited:    c.defaults.Audit != nil,
+	}
+	lock.audit(ctx, AuditObtain, lockTTL)
+	return c.track(lock).bind(ctx, opt), nil
+}
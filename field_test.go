@@ -0,0 +1,78 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.ObtainField", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":deadlines").Err()).To(Succeed())
+	})
+
+	It("should lock fields of a hash", func() {
+		lock, err := subject.ObtainField(ctx, lockKey, "a", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Key()).To(Equal(lockKey + "#a"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(redisClient.HGet(ctx, lockKey, "a").Val()).To(Equal(lock.Token()))
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = subject.ObtainField(ctx, lockKey, "a", 0, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		other, err := subject.ObtainField(ctx, lockKey, "b", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", time.Hour, time.Second))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(redisClient.HExists(ctx, lockKey, "a").Val()).To(BeFalse())
+
+		Expect(other.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey, lockKey+":deadlines").Val()).To(BeZero())
+	})
+
+	It("should refresh and extend", func() {
+		lock, err := subject.ObtainField(ctx, lockKey, "a", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Extend(ctx, time.Hour, 90*time.Minute)).To(BeNumerically("~", 90*time.Minute, time.Second))
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", 90*time.Minute, time.Second))
+	})
+
+	It("should expire fields", func() {
+		lock, err := subject.ObtainField(ctx, lockKey, "a", time.Hour, 50*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = subject.ObtainField(ctx, lockKey, "b", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		time.Sleep(100 * time.Millisecond)
+		Expect(lock.IsHeld(ctx)).To(BeFalse())
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockExpired))
+
+		again, err := subject.ObtainField(ctx, lockKey, "a", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockStolen))
+		Expect(again.Release(ctx)).To(Succeed())
+	})
+
+	It("should reject empty fields", func() {
+		_, err := subject.ObtainField(ctx, lockKey, "", time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidKey))
+	})
+})
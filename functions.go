@@ -0,0 +1,177 @@
+package redislock
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// FunctionsCapableClient is the interface of clients which can be wrapped by
+// WithFunctions, such as *redis.Client. Cluster clients are not supported, as
+// FUNCTION LOAD would only reach a single master.
+type FunctionsCapableClient interface {
+	RedisClient
+	BlockingClient
+	SubscribingClient
+	PatternSubscribingClient
+	ScanningClient
+
+	Do(ctx context.Context, args ...interface{}) *redis.Cmd
+}
+
+var _ FunctionsCapableClient = (*redis.Client)(nil)
+
+// WithFunctions wraps client, so it registers the lock scripts as Redis
+// Functions via FUNCTION LOAD and calls them via FCALL instead of EVALSHA.
+// Unlike cached scripts, functions are persisted and replicated by the server
+// and can be listed by operators via FUNCTION LIST. Each script is registered
+// as a library named redislock_<sha1 of the script> on first use. Servers
+// which do not support functions, i.e. before Redis 7, are detected on the
+// first attempt, the wrapper then falls back to EVAL and EVALSHA. The wrapped
+// client does not support pipelining, so Client.RefreshAll and
+// Client.ReleaseAll process locks one by one.
+func WithFunctions(client FunctionsCapableClient) FunctionsCapableClient {
+	return &functionsClient{FunctionsCapableClient: client, loaded: make(map[string]struct{})}
+}
+
+type functionsClient struct {
+	FunctionsCapableClient
+
+	mu          sync.Mutex
+	loaded      map[string]struct{}
+	unsupported bool
+}
+
+// Eval registers script as a function, unless functions are unsupported or it
+// is registered already, and calls it.
+func (c *functionsClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	sha := scriptSHA(script)
+	if c.isLoaded(sha) {
+		if cmd := c.fcall(ctx, sha, keys, args); cmd.Err() != errNoFunction {
+			return cmd
+		}
+	}
+
+	if ok, err := c.load(ctx, sha, script); err != nil {
+		return redis.NewCmdResult(nil, err)
+	} else if !ok {
+		return c.FunctionsCapableClient.Eval(ctx, script, keys, args...)
+	}
+	return c.fcall(ctx, sha, keys, args)
+}
+
+// EvalSha calls the function registered for sha1. It returns a NOSCRIPT error
+// if the function has not been registered yet, so the caller falls back to
+// Eval, as redis.Script does.
+func (c *functionsClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	if c.isUnsupported() {
+		return c.FunctionsCapableClient.EvalSha(ctx, sha1, keys, args...)
+	} else if !c.isLoaded(sha1) {
+		return redis.NewCmdResult(nil, errNoFunction)
+	}
+	return c.fcall(ctx, sha1, keys, args)
+}
+
+// ScriptLoad loads script into the script cache and registers it as a
+// function, unless functions are unsupported.
+func (c *functionsClient) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	cmd := c.FunctionsCapableClient.ScriptLoad(ctx, script)
+	if cmd.Err() != nil {
+		return cmd
+	}
+	if _, err := c.load(ctx, cmd.Val(), script); err != nil {
+		return redis.NewStringResult("", err)
+	}
+	return cmd
+}
+
+func (c *functionsClient) fcall(ctx context.Context, sha string, keys []string, args []interface{}) *redis.Cmd {
+	cmdArgs := make([]interface{}, 0, 3+len(keys)+len(args))
+	cmdArgs = append(cmdArgs, "fcall", functionName(sha), len(keys))
+	for _, key := range keys {
+		cmdArgs = append(cmdArgs, key)
+	}
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := c.Do(ctx, cmdArgs...)
+	if err := cmd.Err(); err != nil && strings.Contains(err.Error(), "Function not found") {
+		// The function is gone, e.g. after FUNCTION FLUSH or a failover to
+		// a replica without it, have the caller register it again.
+		c.mu.Lock()
+		delete(c.loaded, sha)
+		c.mu.Unlock()
+		return redis.NewCmdResult(nil, errNoFunction)
+	}
+	return cmd
+}
+
+// load registers script as a function. It returns false if functions are
+// not supported by the server.
+func (c *functionsClient) load(ctx context.Context, sha, script string) (bool, error) {
+	if c.isUnsupported() {
+		return false, nil
+	}
+
+	name := functionName(sha)
+	lib := "#!lua name=" + name + "\n" +
+		"redis.register_function('" + name + "', function(KEYS, ARGV)\n" + script + "\nend)"
+	if err := c.Do(ctx, "function", "load", "replace", lib).Err(); err != nil {
+		if !isUnknownCommand(err) {
+			return false, err
+		}
+
+		c.mu.Lock()
+		c.unsupported = true
+		c.mu.Unlock()
+		return false, nil
+	}
+
+	c.mu.Lock()
+	c.loaded[sha] = struct{}{}
+	c.mu.Unlock()
+	return true, nil
+}
+
+func (c *functionsClient) isLoaded(sha string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.loaded[sha]
+	return ok
+}
+
+func (c *functionsClient) isUnsupported() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.unsupported
+}
+
+// errNoFunction makes redis.Script fall back from EvalSha to Eval.
+var errNoFunction error = functionsError("NOSCRIPT redislock: function not registered")
+
+type functionsError string
+
+func (e functionsError) Error() string { return string(e) }
+
+func (functionsError) RedisError() {}
+
+func functionName(sha string) string {
+	return "redislock_" + sha
+}
+
+func scriptSHA(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// isUnknownCommand reports whether err is the reply of a server which does
+// not know a command or subcommand.
+func isUnknownCommand(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.HasPrefix(msg, "err unknown command") || strings.HasPrefix(msg, "err unknown subcommand")
+}
@@ -0,0 +1,96 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithFunctions", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should fall back to scripts", func() {
+		subject := redislock.New(redislock.WithFunctions(redisClient))
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should call functions", func() {
+		functions := &functionsClient{Client: redisClient, libs: make(map[string]string)}
+		subject := redislock.New(redislock.WithFunctions(functions))
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(functions.loads).To(Equal(1))
+		Expect(functions.calls).To(Equal(2))
+
+		functions.flush()
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(functions.loads).To(Equal(3))
+		Expect(functions.calls).To(Equal(4))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+})
+
+// functionsClient emulates FUNCTION LOAD and FCALL via EVAL.
+type functionsClient struct {
+	*redis.Client
+
+	mu           sync.Mutex
+	libs         map[string]string
+	loads, calls int
+}
+
+func (c *functionsClient) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.libs = make(map[string]string)
+}
+
+func (c *functionsClient) Do(ctx context.Context, args ...interface{}) *redis.Cmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch args[0] {
+	case "function":
+		lib := args[3].(string)
+		name := strings.TrimPrefix(lib[:strings.IndexByte(lib, '\n')], "#!lua name=")
+		body := lib[strings.Index(lib, "function(KEYS, ARGV)\n")+21 : len(lib)-5]
+		c.libs[name] = body
+		c.loads++
+		return redis.NewCmdResult(name, nil)
+	case "fcall":
+		body, ok := c.libs[args[1].(string)]
+		if !ok {
+			return redis.NewCmdResult(nil, errors.New("ERR Function not found"))
+		}
+		c.calls++
+
+		n := args[2].(int)
+		keys := make([]string, n)
+		for i := range keys {
+			keys[i] = args[3+i].(string)
+		}
+		return c.Client.Eval(ctx, body, keys, args[3+n:]...)
+	}
+	return c.Client.Do(ctx, args...)
+}
@@ -0,0 +1,124 @@
+package redislock
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// luaGroupMember checks that ARGV[1] is a live member of the group holding
+// the lock, see Options.Group.
+const luaGroupMember = luaNow + `
+local s = redis.call("zscore", KEYS[2], ARGV[1])
+if not s or tonumber(s) <= now or redis.call("exists", KEYS[1]) == 0 then
+	redis.call("zrem", KEYS[2], ARGV[1])
+	return -1
+end
+`
+
+// luaGroupExpire expires the lock and its members with the last member.
+const luaGroupExpire = `
+local last = redis.call("zrange", KEYS[2], -1, -1, "withscores")
+redis.call("pexpireat", KEYS[1], last[2])
+redis.call("pexpireat", KEYS[2], last[2])
+`
+
+var (
+	luaGroupObtain = redis.NewScript(luaNow + `
+local v = redis.call("get", KEYS[1])
+if v and v ~= ARGV[3] then return 0 end
+if not v then
+	redis.call("del", KEYS[2])
+	redis.call("set", KEYS[1], ARGV[3])
+end
+redis.call("zremrangebyscore", KEYS[2], "-inf", now)
+redis.call("zadd", KEYS[2], now + tonumber(ARGV[2]), ARGV[1])` + luaGroupExpire + `
+return 1`)
+	luaGroupRefresh = redis.NewScript(luaGroupMember + `
+redis.call("zadd", KEYS[2], now + tonumber(ARGV[2]), ARGV[1])` + luaGroupExpire + `
+return 1`)
+	luaGroupExtend = redis.NewScript(luaGroupMember + `
+local t = tonumber(s) - now + tonumber(ARGV[2])
+if tonumber(ARGV[3]) > 0 then t = math.min(t, tonumber(ARGV[3])) end
+redis.call("zadd", KEYS[2], now + t, ARGV[1])` + luaGroupExpire + `
+return t`)
+	luaGroupRelease = redis.NewScript(luaGroupMember + `
+redis.call("zrem", KEYS[2], ARGV[1])
+redis.call("zremrangebyscore", KEYS[2], "-inf", now)
+if redis.call("zcard", KEYS[2]) == 0 then
+	redis.call("del", KEYS[1], KEYS[2])
+	return 1
+end` + luaGroupExpire + `
+return 1`)
+	luaGroupPTTL = redis.NewScript(luaNow + `
+local s = redis.call("zscore", KEYS[2], ARGV[1])
+if not s or redis.call("exists", KEYS[1]) == 0 then return -3 end
+local d = tonumber(s) - now
+if d <= 0 then return -3 end
+return d`)
+
+	groupScripts = &lockScripts{pttl: luaGroupPTTL, refresh: luaGroupRefresh, extend: luaGroupExtend, release: luaGroupRelease}
+)
+
+// obtainGroup retries to obtain a share of the lock on key for the group of
+// opt, see Options.Group.
+func (c *Client) obtainGroup(ctx context.Context, rdb RedisClient, key string, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	group := opt.getGroup()
+	member, err := RandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := c.lockValue(group, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{key, c.companionKey(key, ":group")}
+	opTimeout := opt.getOperationTimeout()
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+
+	var start time.Time
+	stats, err := c.retry(ctx, rdb, key, member, opt, true, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		status, err := luaGroupObtain.Run(opctx, rdb, keys, member, ttlVal, value).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		return status == int64(1), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	effectiveTTL := lockTTL - time.Since(start)
+	if effectiveTTL < 0 {
+		effectiveTTL = 0
+	}
+
+	lock := &Lock{
+		client:       c,
+		rdb:          rdb,
+		key:          key,
+		token:        group,
+		value:        value,
+		ttl:          lockTTL,
+		effectiveTTL: effectiveTTL,
+		obtained:     start,
+		expires:      start.Add(lockTTL),
+		opTimeout:    opTimeout,
+		logger:       opt.getLogger(),
+		scripts:      groupScripts,
+		scriptKeys:   keys,
+		scriptArg:    member,
+		stats:        stats,
+		audited:      c.defaults.Audit != nil,
+	}
+	lock.audit(ctx, AuditObtain, lockTTL)
+	return c.track(lock), nil
+}
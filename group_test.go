@@ -0,0 +1,66 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Options.Group", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":group").Err()).To(Succeed())
+	})
+
+	It("should share locks within groups", func() {
+		blue, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Group: "billing", Metadata: "v1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blue.Token()).To(Equal("billing"))
+		Expect(blue.Metadata()).To(Equal("v1"))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal("billingv1"))
+
+		green, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Group: "billing", Metadata: "v1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.ZCard(ctx, lockKey+":group").Val()).To(Equal(int64(2)))
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", time.Hour, time.Second))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Group: "search"})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(blue.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(blue.Refresh(ctx, 2*time.Hour, nil)).To(Succeed())
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", 2*time.Hour, time.Second))
+		Expect(blue.Extend(ctx, time.Hour, 0)).To(BeNumerically("~", 3*time.Hour, time.Second))
+
+		Expect(blue.Release(ctx)).To(Succeed())
+		Expect(blue.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", time.Hour, time.Second))
+
+		Expect(green.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey, lockKey+":group").Val()).To(BeZero())
+	})
+
+	It("should expire with the last holder", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 20*time.Millisecond, &redislock.Options{Group: "billing"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.SetMetadata(ctx, "v2")).NotTo(Succeed())
+
+		time.Sleep(30 * time.Millisecond)
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockExpired))
+
+		other, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Group: "search"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Release(ctx)).To(Succeed())
+	})
+})
@@ -0,0 +1,56 @@
+package redislock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// selfTestKey is the key locked by SelfTest.
+const selfTestKey = "__redislock_selftest__"
+
+var luaPing = redis.NewScript(`return 1`)
+
+// Ping checks that the server is reachable and executes scripts.
+func (c *Client) Ping(ctx context.Context) error {
+	return luaPing.Run(ctx, c.client, nil).Err()
+}
+
+// SelfTestResult reports the timings of a successful SelfTest.
+type SelfTestResult struct {
+	// Latency is the round-trip time of a Ping.
+	Latency time.Duration
+
+	// Elapsed is the time taken to obtain, refresh and release the test
+	// lock.
+	Elapsed time.Duration
+}
+
+// SelfTest verifies that locking works against the server, e.g. as a preflight
+// check during deployments. It pings the server, then obtains, refreshes and
+// releases a throwaway lock with the client defaults, which fails if scripts
+// are unavailable or writes are denied, e.g. by ACLs or on a read-only
+// replica. Concurrent self-tests wait for each other until ctx is done.
+func (c *Client) SelfTest(ctx context.Context) (*SelfTestResult, error) {
+	start := time.Now()
+	if err := c.Ping(ctx); err != nil {
+		return nil, err
+	}
+	res := &SelfTestResult{Latency: time.Since(start)}
+
+	start = time.Now()
+	lock, err := c.obtainLock(ctx, selfTestKey, 10*time.Second, &Options{RetryStrategy: LinearBackoff(10 * time.Millisecond)})
+	if err != nil {
+		return nil, err
+	}
+	if err := lock.Refresh(ctx, 10*time.Second, nil); err != nil {
+		_ = lock.Release(context.Background())
+		return nil, err
+	}
+	if err := lock.Release(ctx); err != nil {
+		return nil, err
+	}
+	res.Elapsed = time.Since(start)
+	return res, nil
+}
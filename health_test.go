@@ -0,0 +1,29 @@
+package redislock_test
+
+import (
+	"context"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.SelfTest", func() {
+	var ctx = context.Background()
+
+	It("should ping", func() {
+		Expect(redislock.New(redisClient).Ping(ctx)).To(Succeed())
+		Expect(redislock.New(&flakyClient{RedisClient: redisClient, failures: 1}).Ping(ctx)).To(MatchError(errLoading))
+	})
+
+	It("should obtain and release a lock", func() {
+		res, err := redislock.New(redisClient).SelfTest(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Latency).To(BeNumerically(">", 0))
+		Expect(res.Elapsed).To(BeNumerically(">", 0))
+		Expect(redisClient.Exists(ctx, "__redislock_selftest__").Val()).To(BeZero())
+
+		_, err = redislock.New(&flakyClient{RedisClient: redisClient, failures: 1}).SelfTest(ctx)
+		Expect(err).To(MatchError(errLoading))
+	})
+})
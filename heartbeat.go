@@ -0,0 +1,130 @@
+package redislock
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	luaHeartbeatObtain = redis.NewScript(luaNow + `
+if not redis.call("set", KEYS[1], ARGV[1], "nx", "px", ARGV[2]) then return 0 end
+redis.call("set", KEYS[2], now, "px", ARGV[2])
+return 1`)
+	luaHeartbeatRefresh = redis.NewScript(luaNow + `
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] then if v then return -2 else return -1 end end
+redis.call("set", KEYS[2], now, "px", ARGV[2])
+return redis.call("pexpire", KEYS[1], ARGV[2])`)
+	luaHeartbeatExtend = redis.NewScript(luaNow + `
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] then if v then return -2 else return -1 end end
+local t = math.max(redis.call("pttl", KEYS[1]), 0) + tonumber(ARGV[2])
+if tonumber(ARGV[3]) > 0 then t = math.min(t, tonumber(ARGV[3])) end
+redis.call("pexpire", KEYS[1], t)
+redis.call("set", KEYS[2], now, "px", t)
+return t`)
+	luaHeartbeatRelease = redis.NewScript(`
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] then if v then return -2 else return -1 end end
+return redis.call("del", KEYS[1], KEYS[2]) > 0 and 1 or 0`)
+	luaSteal = redis.NewScript(luaNow + `
+local v = redis.call("get", KEYS[1])
+if v then
+	local beat = redis.call("get", KEYS[2])
+	if not beat or now - tonumber(beat) < tonumber(ARGV[3]) then return 0 end
+end
+redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])
+redis.call("set", KEYS[2], now, "px", ARGV[2])
+if v then return 2 end
+return 1`)
+
+	heartbeatScripts = &lockScripts{pttl: luaPTTL, refresh: luaHeartbeatRefresh, extend: luaHeartbeatExtend, release: luaHeartbeatRelease}
+)
+
+func (c *Client) heartbeatKey(key string) string { return c.companionKey(key, ":heartbeat") }
+
+// obtainHeartbeat is like SETNX, but also records the heartbeat of the lock.
+func (c *Client) obtainHeartbeat(ctx context.Context, rdb RedisClient, key, value string, ttl time.Duration) (bool, error) {
+	status, err := luaHeartbeatObtain.Run(ctx, rdb, []string{key, c.heartbeatKey(key)}, value, msArg(ttl)).Result()
+	return status == int64(1), err
+}
+
+// StealAfter obtains the lock on key, taking it over from the current holder
+// if the holder has not refreshed it for at least grace, according to the
+// heartbeat recorded with Options.Heartbeat, e.g. to replace a wedged holder
+// before its long TTL runs out. Holders without a heartbeat are never taken
+// over. The previous holder finds its lock stolen on its next refresh or
+// release. The lock is obtained with Options.Heartbeat, so it can be taken
+// over in turn.
+//
+// The lock is obtained with the TTL, metadata and other settings of the
+// client Defaults, and requires Defaults.TTL to be set. StealAfter makes a
+// single attempt, unless Defaults.RetryStrategy is set, in which case it
+// retries until the strategy gives up or ctx is done.
+// May return ErrNotObtained if not successful.
+func (c *Client) StealAfter(ctx context.Context, key string, grace time.Duration) (*Lock, error) {
+	if err := c.validate(ctx, key, 0); err != nil {
+		return nil, err
+	}
+	opt := c.options(nil)
+	lockTTL := c.defaults.TTL
+	key = c.defaults.KeyPrefix + key
+
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := c.lockValue(token, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	keys := []string{key, c.heartbeatKey(key)}
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+
+	var start time.Time
+	var stolen bool
+	stats, err := c.retry(ctx, c.client, key, token, opt, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		status, err := luaSteal.Run(opctx, c.client, keys, value, ttlVal, msArg(grace)).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		stolen = status == int64(2)
+		return status == int64(1) || stolen, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stolen && opt.getLogger() != nil {
+		opt.getLogger().Log(LevelWarn, "lock stolen from stale holder", "key", key, "token", shortToken(token), "grace", grace)
+	}
+
+	lock := &Lock{
+		client:     c,
+		rdb:        c.client,
+		key:        key,
+		token:      token,
+		value:      value,
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opTimeout,
+		logger:     opt.getLogger(),
+		scripts:    heartbeatScripts,
+		scriptKeys: keys,
+		scriptArg:  value,
+		stats:      stats,
+		audited:    c.defaults.Audit != nil,
+	}
+	lock.audit(ctx, AuditObtain, lockTTL)
+	return c.track(lock).bind(ctx, opt), nil
+}
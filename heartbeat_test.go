@@ -0,0 +1,86 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.StealAfter", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+	var heartbeatKey = lockKey + ":heartbeat"
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient, redislock.Defaults{TTL: time.Minute})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, heartbeatKey).Err()).To(Succeed())
+	})
+
+	It("should take over stale holders", func() {
+		holder, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Heartbeat: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Exists(ctx, heartbeatKey).Val()).To(Equal(int64(1)))
+
+		_, err = subject.StealAfter(ctx, lockKey, 50*time.Millisecond)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		time.Sleep(30 * time.Millisecond)
+		Expect(holder.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		time.Sleep(30 * time.Millisecond)
+		_, err = subject.StealAfter(ctx, lockKey, 50*time.Millisecond)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		time.Sleep(30 * time.Millisecond)
+		successor := redislock.New(redisClient, redislock.Defaults{TTL: time.Minute, Metadata: "successor"})
+		lock, err := successor.StealAfter(ctx, lockKey, 50*time.Millisecond)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("successor"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(holder.Refresh(ctx, time.Hour, nil)).To(MatchError(redislock.ErrLockStolen))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey, heartbeatKey).Val()).To(BeZero())
+	})
+
+	It("should obtain free locks", func() {
+		lock, err := subject.StealAfter(ctx, lockKey, time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Extend(ctx, time.Minute, 0)).To(BeNumerically("~", 2*time.Minute, time.Second))
+		Expect(redisClient.PTTL(ctx, heartbeatKey).Val()).To(BeNumerically("~", 2*time.Minute, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should never take over holders without heartbeat", func() {
+		holder, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer holder.Release(ctx)
+
+		_, err = subject.StealAfter(ctx, lockKey, 0)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should retry according to the default retry strategy", func() {
+		holder, err := subject.Obtain(ctx, lockKey, time.Hour, 50*time.Millisecond, &redislock.Options{Heartbeat: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer holder.Release(ctx)
+
+		subject = redislock.New(redisClient, redislock.Defaults{
+			TTL:           time.Minute,
+			RetryStrategy: func() redislock.RetryStrategy { return redislock.LinearBackoff(10 * time.Millisecond) },
+		})
+		lock, err := subject.StealAfter(ctx, lockKey, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should require a default TTL", func() {
+		_, err := redislock.New(redisClient).StealAfter(ctx, lockKey, time.Second)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+	})
+})
@@ -0,0 +1,76 @@
+package redislock
+
+import (
+	"context"
+	"time"
+)
+
+// Hook intercepts obtaining and releasing locks, e.g. to enforce naming
+// conventions or maximum TTLs, or to prefix keys with a tenant, centrally for
+// all users of a client, see Defaults.Hooks. Nil funcs are skipped.
+type Hook struct {
+	// BeforeObtain is called before each call to obtain a lock. It may
+	// modify req or reject it by returning an error, which is returned
+	// wrapped in an Error.
+	BeforeObtain func(ctx context.Context, req *ObtainRequest) error
+
+	// AfterObtain is called once a call to obtain a lock has finished, with
+	// the request as modified by BeforeObtain. lock is nil if err is set,
+	// which includes rejections by BeforeObtain.
+	AfterObtain func(ctx context.Context, req *ObtainRequest, lock *Lock, err error)
+
+	// BeforeRelease is called before each release of a lock. Returning an
+	// error aborts the release, which leaves the lock held and refreshed.
+	BeforeRelease func(ctx context.Context, lock *Lock) error
+}
+
+// ObtainRequest is a call to obtain a lock, as seen by Hook.BeforeObtain.
+type ObtainRequest struct {
+	// Key is the key of the lock, without Defaults.KeyPrefix.
+	Key string
+	// TTL is the lock TTL, zero for the default TTL.
+	TTL time.Duration
+	// Options are the options of the call, which may be nil. Hooks must
+	// not modify the Options passed by the caller, but may replace them.
+	Options *Options
+}
+
+// obtainHooked obtains a lock like obtainRemote, after passing the request
+// through the BeforeObtain hooks, and reports the outcome to the AfterObtain
+// hooks.
+func (c *Client) obtainHooked(ctx context.Context, key string, lockTTL time.Duration, opt *Options) (lock *Lock, err error) {
+	req := &ObtainRequest{Key: key, TTL: lockTTL, Options: opt}
+	defer func() {
+		for _, h := range c.defaults.Hooks {
+			if h.AfterObtain != nil {
+				h.AfterObtain(ctx, req, lock, err)
+			}
+		}
+	}()
+
+	for _, h := range c.defaults.Hooks {
+		if h.BeforeObtain == nil {
+			continue
+		}
+		if err := h.BeforeObtain(ctx, req); err != nil {
+			return nil, &Error{Op: "obtain", Key: c.defaults.KeyPrefix + req.Key, Err: err}
+		}
+	}
+	if err := c.validate(ctx, req.Key, req.TTL); err != nil {
+		return nil, err
+	}
+	return c.obtainUnhooked(ctx, req.Key, req.TTL, req.Options)
+}
+
+// beforeRelease passes l through the BeforeRelease hooks of its client.
+func (l *Lock) beforeRelease(ctx context.Context) error {
+	for _, h := range l.client.defaults.Hooks {
+		if h.BeforeRelease == nil {
+			continue
+		}
+		if err := h.BeforeRelease(ctx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Hooks", func() {
+	var ctx = context.Background()
+	var errPolicy = errors.New("policy violation")
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, "tenant:"+lockKey).Err()).To(Succeed())
+	})
+
+	It("should enforce policies before obtaining", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{Hooks: []redislock.Hook{
+			{BeforeObtain: func(_ context.Context, req *redislock.ObtainRequest) error {
+				if !strings.HasPrefix(req.Key, "__") {
+					return errPolicy
+				}
+				return nil
+			}},
+			{BeforeObtain: func(_ context.Context, req *redislock.ObtainRequest) error {
+				if req.TTL > time.Minute {
+					req.TTL = time.Minute
+				}
+				req.Key = "tenant:" + req.Key
+				return nil
+			}},
+		}})
+
+		_, err := subject.Obtain(ctx, "bad-key", time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(errPolicy))
+		Expect(err).To(MatchError(ContainSubstring(`"bad-key"`)))
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Key()).To(Equal("tenant:" + lockKey))
+		Expect(redisClient.PTTL(ctx, "tenant:"+lockKey).Val()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should validate modified requests", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{Hooks: []redislock.Hook{
+			{BeforeObtain: func(_ context.Context, req *redislock.ObtainRequest) error {
+				req.Key = ""
+				return nil
+			}},
+		}})
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidKey))
+	})
+
+	It("should report outcomes after obtaining", func() {
+		var outcomes []string
+		subject := redislock.New(redisClient, redislock.Defaults{Hooks: []redislock.Hook{
+			{AfterObtain: func(_ context.Context, req *redislock.ObtainRequest, lock *redislock.Lock, err error) {
+				if err != nil {
+					outcomes = append(outcomes, req.Key+": "+err.Error())
+				} else {
+					outcomes = append(outcomes, req.Key+": "+lock.Token())
+				}
+			}},
+		}})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		_, err = subject.Obtain(ctx, lockKey, 0, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(outcomes).To(Equal([]string{
+			lockKey + ": " + lock.Token(),
+			lockKey + ": " + err.Error(),
+		}))
+	})
+
+	It("should abort releases", func() {
+		allow := false
+		subject := redislock.New(redisClient, redislock.Defaults{Hooks: []redislock.Hook{
+			{BeforeRelease: func(_ context.Context, lock *redislock.Lock) error {
+				if !allow {
+					return errPolicy
+				}
+				return nil
+			}},
+		}})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(MatchError(errPolicy))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal(lock.Token()))
+
+		allow = true
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+})
@@ -0,0 +1,259 @@
+package redislock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var errIntentsDisabled = errors.New("redislock: Defaults.Intents is not set")
+
+// Intents records a write-ahead intent for each lock obtained by the client,
+// so that a process restarting after a crash can clean up its own locks via
+// Client.Recover instead of waiting for their TTLs, see Defaults.Intents.
+// Intents are recorded once a lock is obtained, in a separate round trip, and
+// removed once it is released or transferred. Failures to record or remove
+// them are logged, but do not fail the operation. Only exclusive locks, which
+// support Lock.MarshalBinary, are recorded.
+type Intents struct {
+	// Store persists the intents. It must not be shared by processes which
+	// may run at the same time, e.g. use a store per host or replica.
+	Store IntentStore
+
+	// Release makes Recover release the recovered locks instead of
+	// re-attaching them.
+	Release bool
+}
+
+// IntentStore persists lock intents, see Intents. It must be safe for
+// concurrent use.
+type IntentStore interface {
+	// Put records data for the lock with token, replacing earlier data.
+	Put(ctx context.Context, token string, data []byte) error
+	// Delete removes the data of the lock with token, if any.
+	Delete(ctx context.Context, token string) error
+	// List returns the data of all recorded locks by token.
+	List(ctx context.Context) (map[string][]byte, error)
+}
+
+// recordIntent records the intent of the lock, if enabled and supported.
+func (c *Client) recordIntent(lock *Lock) {
+	in := c.defaults.Intents
+	if in == nil {
+		return
+	}
+	data, err := lock.MarshalBinary()
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := withOperationTimeout(context.Background(), lock.opTimeout)
+	defer cancel()
+
+	if err := in.Store.Put(ctx, lock.token, data); err != nil && lock.logger != nil {
+		lock.logger.Log(LevelWarn, "recording intent failed", "key", lock.key, "token", shortToken(lock.token), "error", err)
+		return
+	}
+	lock.intent = true
+}
+
+// forgetIntent removes the intent of the lock, if recorded.
+func (l *Lock) forgetIntent() {
+	if !l.intent {
+		return
+	}
+
+	ctx, cancel := withOperationTimeout(context.Background(), l.opTimeout)
+	defer cancel()
+
+	if err := l.client.defaults.Intents.Store.Delete(ctx, l.token); err != nil && l.logger != nil {
+		l.logger.Log(LevelWarn, "removing intent failed", "key", l.key, "token", shortToken(l.token), "error", err)
+	}
+}
+
+// Recover processes the intents left behind by an earlier run of the process,
+// see Defaults.Intents. Locks which are still held are re-attached and
+// returned, sorted by key, so the process can refresh or release them, or
+// released if Intents.Release is set. Intents of locks which are no longer
+// held are removed. Recover should be called once at startup, before the
+// client obtains locks.
+func (c *Client) Recover(ctx context.Context) ([]*Lock, error) {
+	in := c.defaults.Intents
+	if in == nil {
+		return nil, errIntentsDisabled
+	}
+
+	intents, err := in.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locks []*Lock
+	var firstErr error
+	for token, data := range intents {
+		lock, err := c.resumeBinary(data)
+		if err != nil {
+			if c.defaults.Logger != nil {
+				c.defaults.Logger.Log(LevelWarn, "discarding invalid intent", "token", shortToken(token), "error", err)
+			}
+			err = in.Store.Delete(ctx, token)
+		} else if in.Release {
+			lock.intent = true
+			if err = lock.Release(ctx); errors.Is(err, ErrLockNotHeld) {
+				err = nil
+			}
+		} else {
+			var held bool
+			if held, err = lock.IsHeld(ctx); err == nil && held {
+				locks = append(locks, c.track(lock))
+			} else if err == nil {
+				err = in.Store.Delete(ctx, token)
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	sort.Slice(locks, func(i, j int) bool { return locks[i].key < locks[j].key })
+	return locks, firstErr
+}
+
+// --------------------------------------------------------------------
+
+// FileIntentStore returns an IntentStore which keeps intents in a JSON file
+// at path. The file is replaced atomically on each change and created on the
+// first one.
+func FileIntentStore(path string) IntentStore {
+	return &fileIntentStore{path: path}
+}
+
+type fileIntentStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (s *fileIntentStore) Put(_ context.Context, token string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	intents, err := s.read()
+	if err != nil {
+		return err
+	}
+	intents[token] = data
+	return s.write(intents)
+}
+
+func (s *fileIntentStore) Delete(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	intents, err := s.read()
+	if err != nil {
+		return err
+	} else if _, ok := intents[token]; !ok {
+		return nil
+	}
+	delete(intents, token)
+	return s.write(intents)
+}
+
+func (s *fileIntentStore) List(context.Context) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+func (s *fileIntentStore) read() (map[string][]byte, error) {
+	intents := make(map[string][]byte)
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return intents, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &intents); err != nil {
+		return nil, err
+	}
+	return intents, nil
+}
+
+func (s *fileIntentStore) write(intents map[string][]byte) error {
+	b, err := json.Marshal(intents)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// --------------------------------------------------------------------
+
+var (
+	luaIntentPut    = redis.NewScript(`return redis.call("hset", KEYS[1], ARGV[1], ARGV[2])`)
+	luaIntentDelete = redis.NewScript(`return redis.call("hdel", KEYS[1], ARGV[1])`)
+	luaIntentList   = redis.NewScript(`return redis.call("hgetall", KEYS[1])`)
+)
+
+// RedisIntentStore returns an IntentStore which keeps intents in a redis hash
+// at key, which is not subject to the key prefix. The hash should live on a
+// different server than the locks, or at least be persisted, so it survives
+// the same failures as the locks.
+func RedisIntentStore(client RedisClient, key string) IntentStore {
+	return &redisIntentStore{client: client, key: key}
+}
+
+type redisIntentStore struct {
+	client RedisClient
+	key    string
+}
+
+func (s *redisIntentStore) Put(ctx context.Context, token string, data []byte) error {
+	return luaIntentPut.Run(ctx, s.client, []string{s.key}, token, data).Err()
+}
+
+func (s *redisIntentStore) Delete(ctx context.Context, token string) error {
+	return luaIntentDelete.Run(ctx, s.client, []string{s.key}, token).Err()
+}
+
+func (s *redisIntentStore) List(ctx context.Context) (map[string][]byte, error) {
+	res, err := luaIntentList.Run(ctx, s.client, []string{s.key}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	fields, _ := res.([]interface{})
+	intents := make(map[string][]byte, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		token, _ := fields[i].(string)
+		data, _ := fields[i+1].(string)
+		intents[token] = []byte(data)
+	}
+	return intents, nil
+}
@@ -0,0 +1,103 @@
+package redislock_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Intents", func() {
+	var dir string
+	var store redislock.IntentStore
+	var ctx = context.Background()
+
+	newClient := func(release bool) *redislock.Client {
+		return redislock.New(redisClient, redislock.Defaults{Intents: &redislock.Intents{Store: store, Release: release}})
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "redislock")
+		Expect(err).NotTo(HaveOccurred())
+		store = redislock.FileIntentStore(filepath.Join(dir, "intents.json"))
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+		Expect(redisClient.Del(ctx, lockKey, lockKey+".2").Err()).To(Succeed())
+	})
+
+	It("should re-attach held locks", func() {
+		lock, err := newClient(false).Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.List(ctx)).To(HaveLen(1))
+
+		locks, err := newClient(false).Recover(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(HaveLen(1))
+		Expect(locks[0].Key()).To(Equal(lockKey))
+		Expect(locks[0].Token()).To(Equal(lock.Token()))
+		Expect(locks[0].Metadata()).To(Equal("meta"))
+
+		Expect(locks[0].Release(ctx)).To(Succeed())
+		Expect(store.List(ctx)).To(BeEmpty())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should release recovered locks", func() {
+		_, err := newClient(true).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		locks, err := newClient(true).Recover(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(BeEmpty())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+		Expect(store.List(ctx)).To(BeEmpty())
+	})
+
+	It("should drop intents of lost locks", func() {
+		_, err := newClient(false).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = newClient(false).Obtain(ctx, lockKey+".2", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+
+		locks, err := newClient(false).Recover(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(HaveLen(1))
+		Expect(locks[0].Key()).To(Equal(lockKey + ".2"))
+		Expect(store.List(ctx)).To(HaveLen(1))
+	})
+
+	It("should remove intents of released locks", func() {
+		lock, err := newClient(false).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(store.List(ctx)).To(BeEmpty())
+	})
+
+	It("should store intents in redis", func() {
+		store = redislock.RedisIntentStore(redisClient, lockKey+".2")
+		lock, err := newClient(false).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		locks, err := newClient(false).Recover(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(HaveLen(1))
+		Expect(locks[0].Token()).To(Equal(lock.Token()))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(store.List(ctx)).To(BeEmpty())
+	})
+
+	It("should require intents", func() {
+		_, err := redislock.New(redisClient).Recover(ctx)
+		Expect(err).To(MatchError("redislock: Defaults.Intents is not set"))
+	})
+})
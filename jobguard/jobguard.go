@@ -0,0 +1,174 @@
+// Package jobguard makes sure a scheduled job runs on a single instance at a
+// time, e.g. when every replica of a service schedules the same cron jobs.
+//
+// Jobs returned by Guard implement the Job interface of
+// github.com/robfig/cron/v3, so they can be scheduled directly:
+//
+//	guard := jobguard.New(redislock.New(rdb), nil)
+//	c := cron.New()
+//	c.AddJob("@hourly", guard.GuardedJob("report", time.Minute, sendReport))
+//	c.AddJob("@daily", guard.Wrap("cleanup", time.Minute, cleanupJob))
+package jobguard
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/muroq/redislock"
+)
+
+// Job is a scheduled job, compatible with cron.Job.
+type Job interface {
+	Run()
+}
+
+// JobFunc adapts a function to a Job.
+type JobFunc func()
+
+// Run calls fn.
+func (fn JobFunc) Run() { fn() }
+
+// Options configure a Guard.
+type Options struct {
+	// KeyPrefix is prepended to job names to form the lock keys.
+	// Default: jobguard:
+	KeyPrefix string
+
+	// MinHold keeps the lock after a run has finished until MinHold has
+	// passed since the run started, so instances whose clocks or schedulers
+	// lag behind skip the same scheduled run instead of repeating it.
+	// Default: release the lock once the run has finished
+	MinHold time.Duration
+
+	// Timeout limits the duration of each run by cancelling its context.
+	// Default: no limit
+	Timeout time.Duration
+
+	// LockOptions are used to obtain the locks.
+	// Default: a single attempt
+	LockOptions *redislock.Options
+
+	// OnSkip is called when a run is skipped, as another instance holds
+	// the lock.
+	OnSkip func(name string)
+
+	// OnDone is called after each run with its duration and the error it
+	// returned, or the error revealing the loss of the lock.
+	OnDone func(name string, took time.Duration, err error)
+
+	// OnError is called when the lock cannot be obtained or released due to
+	// an error, and by jobs for errors returned by Run.
+	OnError func(name string, err error)
+}
+
+func (o *Options) getKeyPrefix() string {
+	if o.KeyPrefix != "" {
+		return o.KeyPrefix
+	}
+	return "jobguard:"
+}
+
+// Guard runs jobs while holding a lock named after the job.
+type Guard struct {
+	client *redislock.Client
+	opt    Options
+}
+
+// New creates a new Guard.
+func New(client *redislock.Client, opt *Options) *Guard {
+	g := &Guard{client: client}
+	if opt != nil {
+		g.opt = *opt
+	}
+	return g
+}
+
+// GuardedJob returns a job which calls fn via Run, with a background context.
+func (g *Guard) GuardedJob(name string, ttl time.Duration, fn func(context.Context) error) Job {
+	return JobFunc(func() {
+		if err := g.Run(context.Background(), name, ttl, fn); err != nil && !errors.Is(err, redislock.ErrNotObtained) && g.opt.OnError != nil {
+			g.opt.OnError(name, err)
+		}
+	})
+}
+
+// Wrap returns a job which runs job via Run. Wrapped jobs cannot observe the
+// loss of the lock, as job.Run takes no context.
+func (g *Guard) Wrap(name string, ttl time.Duration, job Job) Job {
+	return g.GuardedJob(name, ttl, func(context.Context) error {
+		job.Run()
+		return nil
+	})
+}
+
+// Run obtains the lock for name with ttl and calls fn, unless another
+// instance holds the lock, in which case it returns ErrNotObtained. The
+// lock is refreshed every third of ttl while fn runs. The context passed to
+// fn is cancelled once the lock is lost or the Timeout has passed.
+// Run returns the error returned by fn, or the error revealing the loss of
+// the lock.
+func (g *Guard) Run(ctx context.Context, name string, ttl time.Duration, fn func(context.Context) error) (err error) {
+	lock, err := g.client.ObtainWith(ctx, g.opt.getKeyPrefix()+name,
+		redislock.WithOptions(g.opt.LockOptions),
+		redislock.WithTTL(ttl),
+	)
+	if errors.Is(err, redislock.ErrNotObtained) {
+		if g.opt.OnSkip != nil {
+			g.opt.OnSkip(name)
+		}
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	defer g.done(name, lock, start)
+
+	var runctx context.Context
+	var cancel context.CancelFunc
+	if g.opt.Timeout > 0 {
+		runctx, cancel = context.WithTimeout(ctx, g.opt.Timeout)
+	} else {
+		runctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	go func() {
+		select {
+		case <-lock.Done():
+			cancel()
+		case <-runctx.Done():
+		}
+	}()
+
+	ttl = lock.RequestedTTL()
+	lock.StartAutoRefresh(runctx, ttl/3, ttl, nil)
+	defer lock.StopAutoRefresh()
+
+	err = fn(runctx)
+	if lerr := lock.Err(); lerr != nil {
+		err = lerr
+	}
+	if g.opt.OnDone != nil {
+		g.opt.OnDone(name, time.Since(start), err)
+	}
+	return err
+}
+
+// done releases the lock, or keeps it until MinHold.
+func (g *Guard) done(name string, lock *redislock.Lock, start time.Time) {
+	if lock.Err() != nil {
+		return
+	}
+
+	var err error
+	if hold := g.opt.MinHold - time.Since(start); hold >= time.Millisecond {
+		err = lock.Refresh(context.Background(), hold, nil)
+	} else {
+		err = lock.Release(context.Background())
+	}
+	if err != nil && g.opt.OnError != nil {
+		g.opt.OnError(name, err)
+	}
+}
@@ -0,0 +1,118 @@
+package jobguard_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/jobguard"
+	"github.com/muroq/redislock/redislocktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Guard", func() {
+	var backend *redislocktest.Client
+	var client *redislock.Client
+	var subject *jobguard.Guard
+	var skipped []string
+	var mu sync.Mutex
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		backend = redislocktest.New()
+		client = redislock.New(backend)
+		skipped = nil
+		subject = jobguard.New(client, &jobguard.Options{
+			OnSkip: func(name string) {
+				mu.Lock()
+				skipped = append(skipped, name)
+				mu.Unlock()
+			},
+		})
+	})
+
+	It("should run jobs on a single instance", func() {
+		started, finish := make(chan struct{}), make(chan struct{})
+		go subject.GuardedJob("report", time.Minute, func(context.Context) error {
+			close(started)
+			<-finish
+			return nil
+		}).Run()
+		<-started
+
+		var runs int
+		job := subject.Wrap("report", time.Minute, jobguard.JobFunc(func() { runs++ }))
+		job.Run()
+		Expect(runs).To(Equal(0))
+		Expect(skipped).To(Equal([]string{"report"}))
+
+		close(finish)
+		Eventually(func() (bool, error) {
+			info, err := client.Inspect(ctx, "jobguard:report")
+			return info.Held, err
+		}).Should(BeFalse())
+
+		job.Run()
+		Expect(runs).To(Equal(1))
+	})
+
+	It("should return errors", func() {
+		errFailed := errors.New("failed")
+		var took time.Duration
+		var doneErr error
+		subject = jobguard.New(client, &jobguard.Options{
+			KeyPrefix: "jobs.",
+			OnDone: func(name string, d time.Duration, err error) {
+				took, doneErr = d, err
+			},
+		})
+
+		err := subject.Run(ctx, "report", time.Minute, func(context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			return errFailed
+		})
+		Expect(err).To(MatchError(errFailed))
+		Expect(doneErr).To(MatchError(errFailed))
+		Expect(took).To(BeNumerically(">=", 10*time.Millisecond))
+
+		lock, err := client.Obtain(ctx, "jobs.report", time.Second, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(subject.Run(ctx, "report", time.Minute, nil)).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should hold the lock for MinHold", func() {
+		subject = jobguard.New(client, &jobguard.Options{MinHold: time.Hour})
+		Expect(subject.Run(ctx, "report", time.Minute, func(context.Context) error { return nil })).To(Succeed())
+
+		info, err := client.Inspect(ctx, "jobguard:report")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Held).To(BeTrue())
+		Expect(info.TTL).To(BeNumerically("~", time.Hour, time.Second))
+	})
+
+	It("should cancel runs once the lock is lost", func() {
+		err := subject.Run(ctx, "report", 30*time.Millisecond, func(ctx context.Context) error {
+			backend.FlushAll()
+			lock, err := client.Obtain(ctx, "jobguard:report", time.Second, time.Minute, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer lock.Release(ctx)
+
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		Expect(err).To(MatchError(redislock.ErrLockStolen))
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock/jobguard")
+}
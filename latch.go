@@ -0,0 +1,123 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var luaLatchCountDown = redis.NewScript(`
+redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2], "nx")
+local n = tonumber(redis.call("get", KEYS[1]))
+local d = math.min(n, tonumber(ARGV[3]))
+if d > 0 then n = redis.call("decrby", KEYS[1], d) end
+return n`)
+
+// ErrLatchExpired is returned by Latch.Wait when the latch expired before
+// its count reached zero.
+var ErrLatchExpired = errors.New("redislock: latch expired")
+
+// Latch is a distributed countdown latch, which blocks waiters until
+// participants have counted it down to zero, e.g. to wait for all shards of a
+// job to finish.
+//
+// The latch is created with its initial count by the first call to CountDown
+// or Wait and expires after the TTL, counted from then. Waiters still waiting
+// at that point fail with ErrLatchExpired. The latch can be reused once
+// expired.
+type Latch struct {
+	client *Client
+	key    string
+	count  int
+	ttl    time.Duration
+}
+
+// NewLatch creates a new Latch for key, which releases waiters once it has
+// been counted down count times.
+func NewLatch(client RedisClient, key string, count int, ttl time.Duration) *Latch {
+	return &Latch{client: New(client), key: key, count: count, ttl: ttl}
+}
+
+// CountDown decrements the count of the latch, unless it has reached zero, and
+// returns the remaining count.
+func (l *Latch) CountDown(ctx context.Context) (int, error) {
+	return l.run(ctx, 1, 0)
+}
+
+// Count returns the remaining count of the latch.
+func (l *Latch) Count(ctx context.Context) (int, error) {
+	return l.run(ctx, 0, 0)
+}
+
+// Wait blocks until the count of the latch has reached zero. It polls the
+// latch according to opt.RetryStrategy, or every 100ms if not set.
+// May return ErrNotObtained if ctx is done or the retry strategy gives up
+// before the count has reached zero, or ErrLatchExpired.
+func (l *Latch) Wait(ctx context.Context, opt *Options) error {
+	retry := LinearBackoff(100 * time.Millisecond)
+	if opt != nil && opt.RetryStrategy != nil {
+		retry = opt.getRetryStrategy()
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	n, err := l.run(ctx, 0, opTimeout)
+
+	var timer *time.Timer
+	for {
+		if err == redis.Nil {
+			return ErrLatchExpired
+		} else if err != nil && ctx.Err() != nil {
+			return ErrNotObtained
+		} else if err != nil {
+			return err
+		} else if n <= 0 {
+			return nil
+		}
+
+		backoff := retry.NextBackoff()
+		if backoff < 1 {
+			return ErrNotObtained
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(backoff)
+			defer timer.Stop()
+		} else {
+			timer.Reset(backoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrNotObtained
+		case <-timer.C:
+		}
+
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		n, err = luaGet.Run(opctx, l.client.client, []string{l.key}).Int()
+		if err != nil && err != redis.Nil {
+			err = wrapOperationErr(ctx, opctx, err)
+		}
+		cancel()
+	}
+}
+
+// run creates the latch if it does not exist and decrements its count by
+// up to d.
+func (l *Latch) run(ctx context.Context, d int, opTimeout time.Duration) (int, error) {
+	if err := l.client.validate(ctx, l.key, l.ttl); err != nil {
+		return 0, err
+	}
+
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	ttlVal := strconv.FormatInt(int64(l.ttl/time.Millisecond), 10)
+	n, err := luaLatchCountDown.Run(opctx, l.client.client, []string{l.key}, l.count, ttlVal, d).Int()
+	if err != nil {
+		return 0, wrapOperationErr(ctx, opctx, err)
+	}
+	return n, nil
+}
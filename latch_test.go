@@ -0,0 +1,62 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Latch", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should validate arguments", func() {
+		_, err := redislock.NewLatch(redisClient, lockKey, 2, 0).CountDown(ctx)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		_, err = redislock.NewLatch(redisClient, "", 2, time.Minute).Count(ctx)
+		Expect(err).To(MatchError(redislock.ErrInvalidKey))
+	})
+
+	It("should release waiters at zero", func() {
+		latch := redislock.NewLatch(redisClient, lockKey, 2, time.Minute)
+		opt := &redislock.Options{RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond)}
+
+		errs := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			errs <- latch.Wait(ctx, opt)
+		}()
+		Consistently(errs, 50*time.Millisecond).ShouldNot(Receive())
+		Expect(latch.Count(ctx)).To(Equal(2))
+
+		Expect(latch.CountDown(ctx)).To(Equal(1))
+		Consistently(errs, 50*time.Millisecond).ShouldNot(Receive())
+
+		Expect(latch.CountDown(ctx)).To(Equal(0))
+		Eventually(errs).Should(Receive(BeNil()))
+
+		Expect(latch.CountDown(ctx)).To(Equal(0))
+		Expect(latch.Wait(ctx, opt)).To(Succeed())
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", time.Minute, time.Second))
+	})
+
+	It("should time out", func() {
+		latch := redislock.NewLatch(redisClient, lockKey, 1, time.Minute)
+
+		cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		Expect(latch.Wait(cctx, nil)).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should expire", func() {
+		latch := redislock.NewLatch(redisClient, lockKey, 1, 50*time.Millisecond)
+		opt := &redislock.Options{RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond)}
+		Expect(latch.Wait(ctx, opt)).To(MatchError(redislock.ErrLatchExpired))
+	})
+})
@@ -0,0 +1,58 @@
+package redislock
+
+import (
+	"context"
+	"time"
+)
+
+// Lease is a lock which is refreshed in the background until it is closed or
+// lost. It bundles a Lock with the management of its lifecycle.
+type Lease struct {
+	lock *Lock
+}
+
+// Acquire obtains a lock on key with ttl, retrying according to the
+// RetryStrategy until ctx is done, and returns it as a Lease. The lease is
+// refreshed with ttl every third of it, until it is closed or found to be
+// expired or stolen, or its TTL has run out without a successful refresh.
+// A ttl of zero defaults to Defaults.TTL or 1m.
+// May return ErrNotObtained if not successful.
+func (c *Client) Acquire(ctx context.Context, key string, ttl time.Duration, opt *Options) (*Lease, error) {
+	if ttl = c.lockTTL(ttl); ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if err := c.validate(ctx, key, ttl); err != nil {
+		return nil, err
+	}
+
+	lock, err := c.obtainLock(ctx, key, ttl, opt)
+	if err != nil {
+		return nil, err
+	}
+	lock = lock.bind(ctx, opt)
+	lock.StartAutoRefresh(context.Background(), ttl/3, ttl, nil)
+	return &Lease{lock: lock}, nil
+}
+
+// Lock returns the underlying lock.
+func (l *Lease) Lock() *Lock {
+	return l.lock
+}
+
+// Done returns a channel which is closed once the lease is closed or lost.
+func (l *Lease) Done() <-chan struct{} {
+	return l.lock.Done()
+}
+
+// Err returns nil while the lease is held. Once Done is closed, it returns
+// ErrLockNotHeld after Close, or the error which revealed the loss of the
+// lease, such as ErrLockExpired or ErrLockStolen.
+func (l *Lease) Err() error {
+	return l.lock.Err()
+}
+
+// Close stops refreshing the lease and releases it.
+// May return ErrLockExpired or ErrLockStolen if the lease was lost.
+func (l *Lease) Close(ctx context.Context) error {
+	return l.lock.Release(ctx)
+}
@@ -0,0 +1,51 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lease", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should refresh until closed", func() {
+		lease, err := subject.Acquire(ctx, lockKey, 60*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lease.Lock().Key()).To(Equal(lockKey))
+
+		_, err = subject.Acquire(ctx, lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Consistently(lease.Done(), 150*time.Millisecond).ShouldNot(BeClosed())
+		Expect(lease.Err()).NotTo(HaveOccurred())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+
+		Expect(lease.Close(ctx)).To(Succeed())
+		Expect(lease.Done()).To(BeClosed())
+		Expect(lease.Err()).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+
+	It("should report the loss of the lease", func() {
+		lease, err := subject.Acquire(ctx, lockKey, 60*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		Eventually(lease.Done()).Should(BeClosed())
+		Expect(lease.Err()).To(MatchError(redislock.ErrLockStolen))
+		Expect(lease.Close(ctx)).To(MatchError(redislock.ErrLockStolen))
+	})
+})
@@ -0,0 +1,113 @@
+package redislock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// localLocks serialises attempts to obtain the same key within the process,
+// see Defaults.CoalesceLocal.
+type localLocks struct {
+	slots map[string]*localSlot
+	mu    sync.Mutex
+}
+
+type localSlot struct {
+	taken chan struct{}
+	refs  int
+}
+
+// acquire takes the slot for key. If wait is set, it waits until the slot is
+// free or ctx is done. It returns a function giving the slot back, or nil if
+// the slot was not taken.
+func (ll *localLocks) acquire(ctx context.Context, key string, wait bool) func() {
+	ll.mu.Lock()
+	slot, ok := ll.slots[key]
+	if !ok {
+		slot = &localSlot{taken: make(chan struct{}, 1)}
+		ll.slots[key] = slot
+	}
+	slot.refs++
+	ll.mu.Unlock()
+
+	select {
+	case slot.taken <- struct{}{}:
+		return func() {
+			<-slot.taken
+			ll.unref(key, slot)
+		}
+	default:
+	}
+
+	if wait {
+		select {
+		case slot.taken <- struct{}{}:
+			return func() {
+				<-slot.taken
+				ll.unref(key, slot)
+			}
+		case <-ctx.Done():
+		}
+	}
+	ll.unref(key, slot)
+	return nil
+}
+
+func (ll *localLocks) unref(key string, slot *localSlot) {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+
+	if slot.refs--; slot.refs == 0 {
+		delete(ll.slots, key)
+	}
+}
+
+// obtainLocal is obtainLock, but waits for other attempts and holders of key
+// within the process first, see Defaults.CoalesceLocal.
+func (c *Client) obtainLocal(ctx context.Context, key string, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	opt = c.options(opt)
+
+	// Without retries, a single attempt must not wait for others either.
+	wait := true
+	if r, ok := opt.getRetryStrategy().(linearBackoff); ok && r < 1 {
+		wait = false
+	}
+
+	unlock := c.local.acquire(ctx, key, wait)
+	if unlock == nil {
+		return nil, &Error{Op: "obtain", Key: c.defaults.KeyPrefix + key, Err: ErrNotObtained}
+	}
+
+	lock, err := c.obtainRemote(ctx, key, lockTTL, opt)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	if lock.doneErr != nil {
+		unlock()
+	} else {
+		lock.unlockLocal = unlock
+	}
+	return lock, nil
+}
+
+// releaseLocal lets other attempts to obtain the lock within the process
+// proceed.
+func (l *Lock) releaseLocal() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.setReleasedLocal()
+}
+
+func (l *Lock) setReleasedLocal() {
+	if l.unlockLocal != nil {
+		l.unlockLocal()
+		l.unlockLocal = nil
+	}
+}
@@ -0,0 +1,75 @@
+package redislock_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.CoalesceLocal", func() {
+	var subject *redislock.Client
+	var counting *scriptCountingClient
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		counting = &scriptCountingClient{RedisClient: redisClient}
+		subject = redislock.New(counting, redislock.Defaults{CoalesceLocal: true})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should wait within the process", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var obtained int32
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, &redislock.Options{
+					RetryStrategy: redislock.LinearBackoff(5 * time.Millisecond),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				atomic.AddInt32(&obtained, 1)
+				Expect(lock.Release(ctx)).To(Succeed())
+			}()
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		Expect(atomic.LoadInt32(&counting.setNXs)).To(Equal(int32(1)))
+		Expect(atomic.LoadInt32(&obtained)).To(Equal(int32(0)))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		wg.Wait()
+		Expect(obtained).To(Equal(int32(10)))
+		Expect(counting.setNXs).To(Equal(int32(11)))
+	})
+
+	It("should not wait without retries", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(counting.setNXs).To(Equal(int32(1)))
+
+		// Waiters proceed once the lock is known to be lost.
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockExpired))
+
+		lock, err = subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+})
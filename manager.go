@@ -0,0 +1,134 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Manager tracks the locks held by the process, e.g. to release all of them
+// on shutdown, so other processes can take over without waiting for the locks
+// to expire. Locks are tracked until they are known to be lost, see Lock.Done.
+// It is safe for concurrent use.
+type Manager struct {
+	locks map[*Lock]struct{}
+	waits map[*localWait]struct{}
+	mu    sync.Mutex
+}
+
+// NewManager creates a new Manager. Pass it via Defaults.Manager to track
+// all locks obtained by a client.
+func NewManager() *Manager {
+	return &Manager{locks: make(map[*Lock]struct{})}
+}
+
+// registry is the process-wide registry of locks, see Defaults.Register.
+var registry = NewManager()
+
+// Held returns the locks held by the process, as tracked in the process-wide
+// registry by clients with Defaults.Register, e.g. to report the resources
+// owned by the instance on a health endpoint.
+func Held() []LockInfo {
+	return registry.Held()
+}
+
+// Track tracks lock until it is known to be lost and returns it.
+func (m *Manager) Track(lock *Lock) *Lock {
+	m.track(lock, nil)
+	return lock
+}
+
+// track tracks lock and calls lost, if set, once it is no longer tracked.
+func (m *Manager) track(lock *Lock, lost func()) {
+	m.mu.Lock()
+	m.locks[lock] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-lock.Done()
+
+		m.mu.Lock()
+		delete(m.locks, lock)
+		m.mu.Unlock()
+
+		if lost != nil {
+			lost()
+		}
+	}()
+}
+
+// Held returns the tracked locks. The TTL and expiry of each lock are
+// determined locally, see Lock.ValidUntil.
+func (m *Manager) Held() []LockInfo {
+	locks := m.tracked()
+	infos := make([]LockInfo, 0, len(locks))
+	for _, lock := range locks {
+		infos = append(infos, LockInfo{
+			Key:      strings.TrimPrefix(lock.key, lock.client.defaults.KeyPrefix),
+			Held:     true,
+			Token:    lock.token,
+			Metadata: lock.Metadata(),
+			TTL:      lock.RemainingLocal(),
+			Obtained: lock.obtained,
+			Expires:  lock.ValidUntil(),
+		})
+	}
+	return infos
+}
+
+// ReleaseAll releases all tracked locks concurrently. It returns the first
+// error other than ErrLockNotHeld.
+func (m *Manager) ReleaseAll(ctx context.Context) error {
+	locks := m.tracked()
+	errs := make([]error, len(locks))
+
+	var wg sync.WaitGroup
+	for i, lock := range locks {
+		wg.Add(1)
+		go func(i int, lock *Lock) {
+			defer wg.Done()
+			errs[i] = lock.Release(ctx)
+		}(i, lock)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrLockNotHeld) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) tracked() []*Lock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	locks := make([]*Lock, 0, len(m.locks))
+	for lock := range m.locks {
+		locks = append(locks, lock)
+	}
+	return locks
+}
+
+// track tracks lock in the client's manager, the process-wide registry and
+// watcher, if any, records its intent, and returns it.
+func (c *Client) track(lock *Lock) *Lock {
+	if m := c.defaults.Manager; m != nil {
+		m.Track(lock)
+	}
+	if c.defaults.Register {
+		var lost func()
+		if metrics, ok := c.defaults.Metrics.(HeldMetricsCollector); ok {
+			metrics.HeldChanged(lock.key, 1)
+			lost = func() { metrics.HeldChanged(lock.key, -1) }
+		}
+		registry.track(lock, lost)
+	}
+	if w := c.defaults.Watcher; w != nil {
+		w.Watch(lock)
+	}
+	c.recordIntent(lock)
+	return lock
+}
@@ -0,0 +1,78 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manager", func() {
+	var manager *redislock.Manager
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		manager = redislock.NewManager()
+		subject = redislock.New(redisClient, redislock.Defaults{Manager: manager})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+".2", lockKey+".2:granted").Err()).To(Succeed())
+	})
+
+	It("should track held locks", func() {
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		lock2, err := subject.ObtainFair(ctx, lockKey+".2", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		held := manager.Held()
+		Expect(held).To(HaveLen(2))
+		for _, info := range held {
+			if info.Key == lockKey {
+				Expect(info.Token).To(Equal(lock1.Token()))
+				Expect(info.Metadata).To(Equal("meta"))
+				Expect(info.TTL).To(BeNumerically("~", time.Hour, time.Second))
+			} else {
+				Expect(info.Key).To(Equal(lockKey + ".2"))
+				Expect(info.Token).To(Equal(lock2.Token()))
+			}
+		}
+
+		Expect(lock2.Release(ctx)).To(Succeed())
+		Eventually(manager.Held).Should(HaveLen(1))
+	})
+
+	It("should register held locks process-wide", func() {
+		subject = redislock.New(redisClient, redislock.Defaults{Register: true})
+		Expect(redislock.Held()).To(BeEmpty())
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		held := redislock.Held()
+		Expect(held).To(HaveLen(1))
+		Expect(held[0].Key).To(Equal(lockKey))
+		Expect(held[0].Token).To(Equal(lock.Token()))
+		Expect(held[0].Obtained).To(BeTemporally("~", time.Now(), time.Second))
+		Expect(held[0].Expires).To(Equal(lock.ValidUntil()))
+		Expect(manager.Held()).To(BeEmpty())
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Eventually(redislock.Held).Should(BeEmpty())
+	})
+
+	It("should release all locks", func() {
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = subject.Obtain(ctx, lockKey+".2", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(manager.ReleaseAll(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey, lockKey+".2").Val()).To(BeZero())
+		Eventually(manager.Held).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,82 @@
+package redislock
+
+import (
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// luaMigrateCanon defines canon, which strips the version tags off a lock
+// value.
+const luaMigrateCanon = `
+local function canon(v)
+	for _, tag in ipairs({"seq", "epoch", "owner"}) do v = string.gsub(v, tag .. "%.v%d+%?[^|]*|", "") end
+	return v
+end
+`
+
+// luaMigrateCheck returns StatusExpired or StatusStolen unless KEYS[1] holds
+// ARGV[1], or a value which only differs from it by its version tags.
+const luaMigrateCheck = luaMigrateCanon + `
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] and (not v or canon(v) ~= canon(ARGV[1])) then if v then return -2 else return -1 end end
+`
+
+var (
+	luaMigratePTTL = redis.NewScript(luaMigrateCanon + `
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] and (not v or canon(v) ~= canon(ARGV[1])) then return -3 end
+return redis.call("pttl", KEYS[1])`)
+	luaMigrateRefresh = redis.NewScript(luaMigrateCheck + `return redis.call("pexpire", KEYS[1], ARGV[2])`)
+	luaMigrateExtend  = redis.NewScript(luaMigrateCheck + `
+local t = math.max(redis.call("pttl", KEYS[1]), 0) + tonumber(ARGV[2])
+if tonumber(ARGV[3]) > 0 then t = math.min(t, tonumber(ARGV[3])) end
+redis.call("pexpire", KEYS[1], t)
+return t`)
+	luaMigrateRelease = redis.NewScript(luaMigrateCheck + `return redis.call("del", KEYS[1])`)
+
+	migrationScripts = &lockScripts{pttl: luaMigratePTTL, refresh: luaMigrateRefresh, extend: luaMigrateExtend, release: luaMigrateRelease}
+)
+
+// Migration keeps fleets running different versions of the package
+// compatible while the format of lock values changes, e.g. as tags such as
+// the owner tag are added, see Defaults.Migration. During the migration
+// window, the TTL, Refresh, Extend and Release of exclusive locks accept the
+// lock value in both formats, i.e. with and without the version tags of the
+// owner, sequence and epoch, so a lock obtained by one version and resumed
+// by another, see Resume, is not reported as stolen. Releases do not record
+// statistics during the window, see Defaults.RecordStats.
+type Migration struct {
+	// Until ends the migration window. Afterwards, the value of each lock
+	// must match exactly again.
+	// Default: no end
+	Until time.Time
+
+	// Legacy omits the owner tag, see Defaults.Owner, from the values of
+	// locks obtained during the window, so previous versions, which compare
+	// values exactly, accept locks handed over from this one.
+	Legacy bool
+}
+
+// active reports whether the migration window is open at now.
+func (m *Migration) active(now time.Time) bool {
+	return m != nil && (m.Until.IsZero() || now.Before(m.Until))
+}
+
+// valueTag returns the tag written between the token and the metadata of
+// lock values obtained by the client.
+func (c *Client) valueTag() string {
+	if m := c.defaults.Migration; m != nil && m.Legacy && m.active(time.Now()) {
+		return ""
+	}
+	return c.ownerTag
+}
+
+// activeScripts returns the scripts managing the lock, which accept both
+// value formats while the migration window of the client is open.
+func (l *Lock) activeScripts() *lockScripts {
+	if l.scripts == exclusiveScripts && l.client.defaults.Migration.active(time.Now()) {
+		return migrationScripts
+	}
+	return l.scripts
+}
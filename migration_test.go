@@ -0,0 +1,79 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Migration", func() {
+	var owned *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		owned = redislock.New(redisClient, redislock.Defaults{Owner: &redislock.Owner{Host: "web-1", PID: 1}})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should accept values without version tags", func() {
+		lock, err := owned.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Get(ctx, lockKey).Val()).NotTo(Equal(lock.Token() + "meta"))
+
+		subject := redislock.New(redisClient, redislock.Defaults{Migration: &redislock.Migration{}})
+		resumed := subject.Resume(lockKey, lock.Token(), "meta")
+		Expect(resumed.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(resumed.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(resumed.Extend(ctx, time.Minute, 0)).To(BeNumerically("~", time.Hour+time.Minute, time.Second))
+		Expect(resumed.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should accept values with version tags", func() {
+		lock, err := redislock.New(redisClient).Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+
+		subject := redislock.New(redisClient, redislock.Defaults{
+			Owner:     &redislock.Owner{Host: "web-1", PID: 1},
+			Migration: &redislock.Migration{Until: time.Now().Add(time.Hour)},
+		})
+		data, err := lock.MarshalBinary()
+		Expect(err).NotTo(HaveOccurred())
+		resumed, err := subject.ResumeBinary(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed.Refresh(ctx, time.Hour, nil)).To(Succeed())
+
+		stolen := subject.Resume(lockKey, lock.Token(), "other")
+		Expect(stolen.Refresh(ctx, time.Hour, nil)).To(MatchError(redislock.ErrLockStolen))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should match exactly once the window ended", func() {
+		lock, err := owned.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		subject := redislock.New(redisClient, redislock.Defaults{Migration: &redislock.Migration{Until: time.Now().Add(-time.Second)}})
+		resumed := subject.Resume(lockKey, lock.Token(), "meta")
+		Expect(resumed.Refresh(ctx, time.Hour, nil)).To(MatchError(redislock.ErrLockStolen))
+	})
+
+	It("should write legacy values", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{
+			Owner:     &redislock.Owner{Host: "web-1", PID: 1},
+			Migration: &redislock.Migration{Legacy: true},
+		})
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal(lock.Token() + "meta"))
+
+		resumed := redislock.New(redisClient).Resume(lockKey, lock.Token(), "meta")
+		Expect(resumed.Release(ctx)).To(Succeed())
+	})
+})
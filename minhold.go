@@ -0,0 +1,39 @@
+package redislock
+
+import (
+	"context"
+	"time"
+)
+
+// waitMinHold blocks until the lock was held for Options.MinHold, or until it
+// expires, whichever comes first. It returns the context error if ctx is done
+// before.
+func (l *Lock) waitMinHold(ctx context.Context) error {
+	if l.minHold <= 0 || l.Err() != nil {
+		return nil
+	}
+
+	d := l.minHold - time.Since(l.obtained)
+	if rem := l.RemainingLocal(); rem < d {
+		d = rem
+	}
+	if d <= 0 {
+		return nil
+	}
+
+	if l.logger != nil {
+		l.logger.Log(LevelDebug, "delaying release", "key", l.key, "token", shortToken(l.token), "delay", d)
+	}
+
+	timer := l.client.clock().NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	case <-l.Done():
+		return nil
+	}
+}
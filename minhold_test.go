@@ -0,0 +1,62 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Options.MinHold", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should delay early releases", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{MinHold: 50 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+
+		start := time.Now()
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("~", 50*time.Millisecond, 20*time.Millisecond))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should not delay late releases", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{MinHold: 20 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(30 * time.Millisecond)
+
+		start := time.Now()
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("<", 10*time.Millisecond))
+	})
+
+	It("should not wait past expiry", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 30*time.Millisecond, &redislock.Options{MinHold: time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+
+		start := time.Now()
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(time.Since(start)).To(BeNumerically("<", 100*time.Millisecond))
+	})
+
+	It("should keep the lock if the context is done first", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{MinHold: time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+
+		cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		Expect(lock.Release(cctx)).To(MatchError(context.DeadlineExceeded))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+	})
+})
@@ -0,0 +1,319 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// luaMultiReleaseSrc is the source of the release script, which is also
+// wrapped by unlinkSrc.
+const luaMultiReleaseSrc = `
+local res = 1
+for i = 1, #KEYS do
+	local v = redis.call("get", KEYS[i])
+	if v == ARGV[1] then
+		redis.call("del", KEYS[i])
+	elseif v then
+		res = -2
+	elseif res == 1 then
+		res = -1
+	end
+end
+return res`
+
+var (
+	luaMultiObtain = redis.NewScript(`
+for i = 1, #KEYS do
+	if redis.call("exists", KEYS[i]) == 1 then return 0 end
+end
+for i = 1, #KEYS do
+	redis.call("set", KEYS[i], ARGV[1], "px", ARGV[2])
+end
+return 1`)
+	luaMultiObtainAny = redis.NewScript(`
+local obtained = {}
+for i = 1, #KEYS do
+	if redis.call("set", KEYS[i], ARGV[1], "nx", "px", ARGV[2]) then
+		table.insert(obtained, i)
+	end
+end
+return obtained`)
+	luaMultiRefresh = redis.NewScript(`
+for i = 1, #KEYS do
+	local v = redis.call("get", KEYS[i])
+	if v ~= ARGV[1] then
+		if v then return -2 else return -1 end
+	end
+end
+for i = 1, #KEYS do
+	redis.call("pexpire", KEYS[i], ARGV[2])
+end
+return 1`)
+	luaMultiExtend = redis.NewScript(`
+local t = -1
+for i = 1, #KEYS do
+	local v = redis.call("get", KEYS[i])
+	if v ~= ARGV[1] then
+		if v then return -2 else return -1 end
+	end
+	local pttl = math.max(redis.call("pttl", KEYS[i]), 0)
+	if t == -1 or pttl < t then t = pttl end
+end
+t = t + tonumber(ARGV[2])
+if tonumber(ARGV[3]) > 0 then t = math.min(t, tonumber(ARGV[3])) end
+for i = 1, #KEYS do
+	redis.call("pexpire", KEYS[i], t)
+end
+return t`)
+	luaMultiRelease = redis.NewScript(luaMultiReleaseSrc)
+	luaMultiPTTL    = redis.NewScript(`
+local min = -3
+for i = 1, #KEYS do
+	if redis.call("get", KEYS[i]) ~= ARGV[1] then return -3 end
+	local t = redis.call("pttl", KEYS[i])
+	if min == -3 or t < min then min = t end
+end
+return min`)
+)
+
+var multiScripts = &lockScripts{pttl: luaMultiPTTL, refresh: luaMultiRefresh, extend: luaMultiExtend, release: luaMultiRelease, unlinkRelease: luaUnlinkMultiRelease}
+
+var errNoKeys = errors.New("redislock: no keys given")
+
+// ObtainMulti atomically obtains a lock on all keys using the given TTL. Either
+// all or none of the keys are locked. The returned lock refreshes and releases
+// all keys together. On a redis cluster, all keys must hash to the same slot,
+// e.g. by sharing a {hash-tag}, which is validated if Defaults.HashTags is
+// enabled.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainMulti(ctx context.Context, keys []string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	if len(keys) == 0 {
+		return nil, errNoKeys
+	}
+	for _, key := range keys {
+		if err := c.validate(ctx, key, lockTTL); err != nil {
+			return nil, err
+		}
+	}
+	opt = c.options(opt)
+	lockTTL = c.lockTTL(lockTTL)
+
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.defaults.KeyPrefix + key
+	}
+	keys = prefixed
+	if err := c.checkSlots(keys); err != nil {
+		return nil, err
+	}
+
+	value, err := c.lockValue(token, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	var start time.Time
+	stats, err := c.retry(deadlinectx, c.client, keys[0], token, opt, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		status, err := luaMultiObtain.Run(opctx, c.client, keys, value, ttlVal).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		return status == int64(1), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.track(&Lock{
+		client:     c,
+		rdb:        c.client,
+		key:        keys[0],
+		token:      token,
+		value:      value,
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opTimeout,
+		logger:     opt.getLogger(),
+		scripts:    multiScripts,
+		scriptKeys: keys,
+		scriptArg:  value,
+		stats:      stats,
+	}).bind(ctx, opt), nil
+}
+
+// ObtainMulti is a short-cut for New(...).ObtainMulti(...).
+func ObtainMulti(ctx context.Context, client RedisClient, keys []string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	return New(client).ObtainMulti(ctx, keys, waitTimeout, lockTTL, opt)
+}
+
+// ObtainPartial obtains a lock on as many of keys as are available, in a
+// single round trip. The returned lock refreshes and releases the obtained
+// keys together, see Lock.Keys, but is lost as soon as any of them is lost.
+// The keys which could not be obtained are returned in the given order. It
+// retries according to opt.RetryStrategy only while none of the keys is
+// available. The same cluster constraints apply as to ObtainMulti.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainPartial(ctx context.Context, keys []string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, []string, error) {
+	if len(keys) == 0 {
+		return nil, nil, errNoKeys
+	}
+	for _, key := range keys {
+		if err := c.validate(ctx, key, lockTTL); err != nil {
+			return nil, nil, err
+		}
+	}
+	opt = c.options(opt)
+	lockTTL = c.lockTTL(lockTTL)
+
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	unique := make([]string, 0, len(keys))
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, key)
+		prefixed = append(prefixed, c.defaults.KeyPrefix+key)
+	}
+	if err := c.checkSlots(prefixed); err != nil {
+		return nil, nil, err
+	}
+
+	value, err := c.lockValue(token, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	var start time.Time
+	var obtained []interface{}
+	stats, err := c.retry(deadlinectx, c.client, prefixed[0], token, opt, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		res, err := luaMultiObtainAny.Run(opctx, c.client, prefixed, value, ttlVal).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		obtained, _ = res.([]interface{})
+		return len(obtained) != 0, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locked := make([]string, 0, len(obtained))
+	missed := make([]bool, len(unique))
+	for i := range missed {
+		missed[i] = true
+	}
+	for _, n := range obtained {
+		i := int(n.(int64)) - 1
+		locked = append(locked, prefixed[i])
+		missed[i] = false
+	}
+
+	var failed []string
+	for i, key := range unique {
+		if missed[i] {
+			failed = append(failed, key)
+		}
+	}
+
+	lock := c.track(&Lock{
+		client:     c,
+		rdb:        c.client,
+		key:        locked[0],
+		token:      token,
+		value:      value,
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opTimeout,
+		logger:     opt.getLogger(),
+		scripts:    multiScripts,
+		scriptKeys: locked,
+		scriptArg:  value,
+		stats:      stats,
+	}).bind(ctx, opt)
+	return lock, failed, nil
+}
+
+// ObtainPartial is a short-cut for New(...).ObtainPartial(...).
+func ObtainPartial(ctx context.Context, client RedisClient, keys []string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, []string, error) {
+	return New(client).ObtainPartial(ctx, keys, waitTimeout, lockTTL, opt)
+}
+
+// ObtainOrdered obtains an individual lock on each of keys, one at a time and
+// in sorted order, so concurrent callers with overlapping keys cannot
+// deadlock each other. Unlike ObtainMulti, the keys need not reside on the
+// same redis node. The wait timeout applies to all keys together. If any
+// lock cannot be obtained, all locks obtained so far are released. The locks
+// are returned in sorted key order, duplicate keys are locked once.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainOrdered(ctx context.Context, keys []string, waitTimeout, lockTTL time.Duration, opt *Options) ([]*Lock, error) {
+	if len(keys) == 0 {
+		return nil, errNoKeys
+	}
+	for _, key := range keys {
+		if err := c.validate(ctx, key, lockTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	locks := make([]*Lock, 0, len(sorted))
+	for i, key := range sorted {
+		if i > 0 && key == sorted[i-1] {
+			continue
+		}
+
+		lock, err := c.obtainLock(deadlinectx, key, lockTTL, opt)
+		if err != nil {
+			for j := len(locks) - 1; j >= 0; j-- {
+				_ = locks[j].Release(context.Background())
+			}
+			return nil, err
+		}
+		locks = append(locks, lock.bind(ctx, opt))
+	}
+	return locks, nil
+}
@@ -0,0 +1,138 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.ObtainMulti", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+	var keys = []string{lockKey + ":a", lockKey + ":b", lockKey + ":c"}
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, keys...).Err()).To(Succeed())
+	})
+
+	It("should obtain all keys", func() {
+		lock, err := subject.ObtainMulti(ctx, keys, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Key()).To(Equal(keys[0]))
+		Expect(lock.Keys()).To(Equal(keys))
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(Equal(int64(3)))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = subject.ObtainMulti(ctx, keys[1:], time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(ctx, keys[2], time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(Equal(int64(0)))
+	})
+
+	It("should update metadata on all keys", func() {
+		lock, err := subject.ObtainMulti(ctx, keys, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.SetMetadata(ctx, "meta")).To(Succeed())
+		for _, key := range keys {
+			Expect(redisClient.Get(ctx, key).Val()).To(Equal(lock.Token() + "meta"))
+		}
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should not obtain partially", func() {
+		Expect(redisClient.Set(ctx, keys[1], "ABCD", 0).Err()).To(Succeed())
+
+		_, err := subject.ObtainMulti(ctx, keys, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(Equal(int64(1)))
+	})
+
+	It("should report stolen keys", func() {
+		lock, err := subject.ObtainMulti(ctx, keys, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redisClient.Set(ctx, keys[1], "ABCD", 0).Err()).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeZero())
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(MatchError(redislock.ErrLockStolen))
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockStolen))
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(Equal(int64(1)))
+	})
+})
+
+var _ = Describe("Client.ObtainPartial", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+	var keys = []string{lockKey + ":a", lockKey + ":b", lockKey + ":c"}
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, keys...).Err()).To(Succeed())
+	})
+
+	It("should obtain available keys", func() {
+		Expect(redisClient.Set(ctx, keys[1], "ABCD", 0).Err()).To(Succeed())
+
+		lock, failed, err := subject.ObtainPartial(ctx, append(keys, keys[0]), time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Keys()).To(Equal([]string{keys[0], keys[2]}))
+		Expect(failed).To(Equal([]string{keys[1]}))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(Equal(int64(1)))
+	})
+
+	It("should fail if no key is available", func() {
+		Expect(redisClient.Set(ctx, keys[0], "ABCD", 0).Err()).To(Succeed())
+
+		_, _, err := subject.ObtainPartial(ctx, keys[:1], time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+	})
+})
+
+var _ = Describe("Client.ObtainOrdered", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+	var keys = []string{lockKey + ":a", lockKey + ":b", lockKey + ":c"}
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, keys...).Err()).To(Succeed())
+	})
+
+	It("should obtain keys in order", func() {
+		locks, err := subject.ObtainOrdered(ctx, []string{keys[2], keys[0], keys[1], keys[0]}, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(HaveLen(3))
+		for i, lock := range locks {
+			Expect(lock.Key()).To(Equal(keys[i]))
+			Expect(lock.Release(ctx)).To(Succeed())
+		}
+	})
+
+	It("should roll back on failure", func() {
+		Expect(redisClient.Set(ctx, keys[2], "ABCD", 0).Err()).To(Succeed())
+
+		_, err := subject.ObtainOrdered(ctx, keys, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(Equal(int64(1)))
+	})
+})
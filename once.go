@@ -0,0 +1,110 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var luaOnceComplete = redis.NewScript(`redis.call("set", KEYS[2], "1") local v = redis.call("get", KEYS[1]) if v == ARGV[1] then return redis.call("del", KEYS[1]) elseif v then return -2 else return -1 end`)
+
+// Once runs functions at most once per key across all processes, recording
+// successful completion under key + ":done", which never expires.
+type Once struct {
+	client *Client
+}
+
+// NewOnce creates a new Once.
+func NewOnce(client RedisClient) *Once {
+	return &Once{client: New(client)}
+}
+
+// Do calls fn, unless it has completed successfully for key before. While fn
+// runs, it holds an exclusive lock on key, which is obtained with ttl and
+// refreshed every third of it, so other callers wait for fn to finish and
+// take over if it fails or the process crashes. Waiting callers poll every
+// 100ms.
+//
+// Do returns nil if fn has completed, or the error returned by fn, after which
+// other callers may retry. If the lock was lost while fn was running, its
+// completion is recorded, but Do returns ErrLockExpired or ErrLockStolen, as
+// fn may have run concurrently.
+// May return ErrNotObtained if ctx is done while waiting.
+func (o *Once) Do(ctx context.Context, key string, ttl time.Duration, fn func(context.Context) error) error {
+	doneKey := o.client.companionKey(key, ":done")
+	retry := LinearBackoff(100 * time.Millisecond)
+
+	var timer *time.Timer
+	for {
+		if done, err := o.done(ctx, doneKey); err != nil || done {
+			return err
+		}
+
+		lock, err := o.client.obtainLock(ctx, key, ttl, nil)
+		if err == nil {
+			return o.run(ctx, lock, doneKey, fn)
+		} else if !errors.Is(err, ErrNotObtained) {
+			return err
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(retry.NextBackoff())
+			defer timer.Stop()
+		} else {
+			timer.Reset(retry.NextBackoff())
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrNotObtained
+		case <-timer.C:
+		}
+	}
+}
+
+func (o *Once) done(ctx context.Context, doneKey string) (bool, error) {
+	err := luaGet.Run(ctx, o.client.client, []string{doneKey}).Err()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil && ctx.Err() != nil {
+		return false, ErrNotObtained
+	}
+	return err == nil, err
+}
+
+func (o *Once) run(ctx context.Context, lock *Lock, doneKey string, fn func(context.Context) error) error {
+	// Another caller may have completed between the check and the obtain.
+	if done, err := o.done(ctx, doneKey); err != nil || done {
+		_ = lock.Release(context.Background())
+		return err
+	}
+
+	// Release the lock if fn panics, rather than refreshing it forever.
+	returned := false
+	defer func() {
+		if !returned {
+			_ = lock.Release(context.Background())
+		}
+	}()
+
+	lock.StartAutoRefresh(ctx, lock.ttl/3, lock.ttl, nil)
+	err := fn(ctx)
+	returned = true
+	lock.StopAutoRefresh()
+
+	if err != nil {
+		_ = lock.Release(context.Background())
+		return err
+	}
+
+	res, err := luaOnceComplete.Run(context.Background(), lock.rdb, []string{lock.key, doneKey}, lock.value).Result()
+	if err != nil {
+		return err
+	} else if res != int64(1) {
+		return lock.lostBy(res, lock.logger, ErrLockNotHeld)
+	}
+	lock.lost(ErrLockNotHeld)
+	return nil
+}
@@ -0,0 +1,74 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Once", func() {
+	var subject *redislock.Once
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.NewOnce(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":done").Err()).To(Succeed())
+	})
+
+	It("should run once across callers", func() {
+		var calls int32
+		fn := func(context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(subject.Do(ctx, lockKey, time.Minute, fn)).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		Expect(subject.Do(ctx, lockKey, time.Minute, fn)).To(Succeed())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should allow retries after failures", func() {
+		errTest := errors.New("test")
+		Expect(subject.Do(ctx, lockKey, time.Minute, func(context.Context) error { return errTest })).To(Equal(errTest))
+
+		var called bool
+		Expect(subject.Do(ctx, lockKey, time.Minute, func(context.Context) error { called = true; return nil })).To(Succeed())
+		Expect(called).To(BeTrue())
+	})
+
+	It("should release on panic", func() {
+		Expect(func() {
+			_ = subject.Do(ctx, lockKey, time.Minute, func(context.Context) error { panic("boom") })
+		}).To(PanicWith("boom"))
+		Expect(redisClient.Exists(ctx, lockKey, lockKey+":done").Val()).To(BeZero())
+	})
+
+	It("should report lost locks", func() {
+		err := subject.Do(ctx, lockKey, time.Minute, func(context.Context) error {
+			return redisClient.Set(ctx, lockKey, "ABCD", time.Minute).Err()
+		})
+		Expect(err).To(MatchError(redislock.ErrLockStolen))
+		Expect(subject.Do(ctx, lockKey, time.Minute, func(context.Context) error { return errors.New("ran twice") })).To(Succeed())
+	})
+})
@@ -0,0 +1,122 @@
+package redislock
+
+import (
+	"context"
+	"time"
+)
+
+// defaultTTL is the lock TTL used by ObtainWith unless WithTTL is given.
+const defaultTTL = time.Minute
+
+// Option configures ObtainWith and RefreshWith.
+type Option func(*config)
+
+type config struct {
+	Options
+	ttl         time.Duration
+	waitTimeout time.Duration
+}
+
+func newConfig(opts []Option) *config {
+	cfg := new(config)
+	for _, o := range opts {
+		o(cfg)
+	}
+	return cfg
+}
+
+// WithOptions applies all settings of opt. Options given after it override
+// individual settings.
+func WithOptions(opt *Options) Option {
+	return func(c *config) {
+		if opt != nil {
+			c.Options = *opt
+		}
+	}
+}
+
+// WithProfile sets Options.Profile.
+func WithProfile(name string) Option {
+	return func(c *config) { c.Profile = name }
+}
+
+// WithTTL sets the lock TTL. Default: Defaults.TTL or 1m for ObtainWith, the
+// requested TTL of the lock for RefreshWith.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) { c.ttl = ttl }
+}
+
+// WithWaitTimeout limits the time spent retrying to obtain a lock.
+// Default: only limited by the context and the retry strategy
+func WithWaitTimeout(timeout time.Duration) Option {
+	return func(c *config) { c.waitTimeout = timeout }
+}
+
+// WithTTLFromContext sets Options.TTLFromContext, bounded by Options.MinTTL
+// and Options.MaxTTL.
+func WithTTLFromContext(min, max time.Duration) Option {
+	return func(c *config) { c.TTLFromContext, c.MinTTL, c.MaxTTL = true, min, max }
+}
+
+// WithRetryStrategy sets Options.RetryStrategy.
+func WithRetryStrategy(s RetryStrategy) Option {
+	return func(c *config) { c.RetryStrategy = s }
+}
+
+// WithMetadata sets Options.Metadata.
+func WithMetadata(md string) Option {
+	return func(c *config) { c.Metadata = md }
+}
+
+// WithMetadataMap sets Options.MetadataMap.
+func WithMetadataMap(md map[string]string) Option {
+	return func(c *config) { c.MetadataMap = md }
+}
+
+// WithToken sets Options.IdempotencyToken.
+func WithToken(token string) Option {
+	return func(c *config) { c.IdempotencyToken = token }
+}
+
+// ObtainWith is a variant of Obtain configured by functional options.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainWith(ctx context.Context, key string, opts ...Option) (*Lock, error) {
+	cfg := newConfig(opts)
+	opt, ttl, err := c.withProfile(key, &cfg.Options, cfg.ttl)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Options = *opt
+
+	if ttl = c.lockTTL(ttl); ttl <= 0 {
+		ttl = defaultTTL
+	}
+	ttl = cfg.ttlFromContext(ctx, ttl)
+	if err := c.validate(ctx, key, ttl); err != nil {
+		return nil, err
+	}
+
+	waitctx := ctx
+	if cfg.waitTimeout > 0 {
+		var cancel context.CancelFunc
+		waitctx, cancel = context.WithTimeout(ctx, cfg.waitTimeout)
+		defer cancel()
+	}
+
+	lock, err := c.obtainLock(waitctx, key, ttl, &cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	return lock.bind(ctx, &cfg.Options), nil
+}
+
+// RefreshWith is a variant of Refresh configured by functional options.
+func (l *Lock) RefreshWith(ctx context.Context, opts ...Option) error {
+	cfg := newConfig(opts)
+
+	ttl := cfg.ttl
+	if ttl <= 0 {
+		ttl = l.ttl
+	}
+	return l.Refresh(ctx, ttl, &cfg.Options)
+}
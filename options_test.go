@@ -0,0 +1,89 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.ObtainWith", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should obtain with defaults", func() {
+		lock, err := subject.ObtainWith(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.RequestedTTL()).To(Equal(time.Minute))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should apply options", func() {
+		lock, err := subject.ObtainWith(ctx, lockKey,
+			redislock.WithOptions(&redislock.Options{Metadata: "ignored"}),
+			redislock.WithTTL(time.Hour),
+			redislock.WithMetadata("meta"),
+			redislock.WithToken("0123456789abcdefghijkl"),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Token()).To(Equal("0123456789abcdefghijkl"))
+		Expect(lock.Metadata()).To(Equal("meta"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+
+		Expect(lock.RefreshWith(ctx, redislock.WithTTL(time.Minute))).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock.RefreshWith(ctx)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should derive the TTL from the context", func() {
+		reqctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		lock, err := subject.ObtainWith(reqctx, lockKey, redislock.WithTTLFromContext(0, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.RequestedTTL()).To(BeNumerically("~", 30*time.Second, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		lock, err = subject.Obtain(reqctx, lockKey, time.Second, time.Hour, &redislock.Options{TTLFromContext: true, MaxTTL: 10 * time.Second})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.RequestedTTL()).To(Equal(10 * time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		lock, err = subject.ObtainWith(reqctx, lockKey, redislock.WithTTLFromContext(time.Minute, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.RequestedTTL()).To(Equal(time.Minute))
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		lock, err = subject.ObtainWith(ctx, lockKey, redislock.WithTTLFromContext(0, 0), redislock.WithTTL(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.RequestedTTL()).To(Equal(time.Hour))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should retry until the wait timeout", func() {
+		lock, err := subject.ObtainWith(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		start := time.Now()
+		_, err = subject.ObtainWith(ctx, lockKey,
+			redislock.WithRetryStrategy(redislock.LinearBackoff(10*time.Millisecond)),
+			redislock.WithWaitTimeout(50*time.Millisecond),
+		)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(time.Since(start)).To(BeNumerically("~", 50*time.Millisecond, 30*time.Millisecond))
+	})
+})
@@ -0,0 +1,74 @@
+package redislock
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ownerTagPrefix starts the owner tag, which is stored between the token and
+// the metadata of a lock value. The version allows the format to evolve.
+const ownerTagPrefix = "owner.v1?"
+
+// Owner identifies the process holding a lock, see Defaults.Owner.
+type Owner struct {
+	// Host is the hostname of the holder.
+	// Default: os.Hostname
+	Host string
+	// PID is the process ID of the holder.
+	// Default: os.Getpid
+	PID int
+	// Label is an application-defined label, e.g. the name of a worker.
+	Label string
+}
+
+// encodeOwnerTag resolves the defaults of o and encodes it as a tag, e.g.
+// owner.v1?host=web-1&label=billing&pid=42|.
+func encodeOwnerTag(o *Owner) string {
+	if o == nil {
+		return ""
+	}
+
+	host := o.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	pid := o.PID
+	if pid == 0 {
+		pid = os.Getpid()
+	}
+
+	vals := url.Values{"host": {host}, "pid": {strconv.Itoa(pid)}}
+	if o.Label != "" {
+		vals.Set("label", o.Label)
+	}
+	return ownerTagPrefix + vals.Encode() + "|"
+}
+
+// splitOwner splits the sequence, epoch and owner tags off the metadata of a
+// lock value, if there are any. It returns the metadata as set by the holder.
+func splitOwner(metadata string) (*Owner, string) {
+	_, _, metadata = splitEpoch(metadata)
+	if !strings.HasPrefix(metadata, ownerTagPrefix) {
+		return nil, metadata
+	}
+	end := strings.IndexByte(metadata, '|')
+	if end < 0 {
+		return nil, metadata
+	}
+
+	vals, err := url.ParseQuery(metadata[len(ownerTagPrefix):end])
+	if err != nil {
+		return nil, metadata
+	}
+	pid, _ := strconv.Atoi(vals.Get("pid"))
+	return &Owner{Host: vals.Get("host"), PID: pid, Label: vals.Get("label")}, metadata[end+1:]
+}
+
+// ownerTag returns the sequence, epoch and owner tags of the metadata of a
+// lock value, or an empty string.
+func ownerTag(metadata string) string {
+	_, md := splitOwner(metadata)
+	return metadata[:len(metadata)-len(md)]
+}
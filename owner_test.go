@@ -0,0 +1,71 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Owner", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient, redislock.Defaults{Owner: &redislock.Owner{Label: "billing"}})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should embed the owner", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "step 1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("step 1"))
+
+		host, _ := os.Hostname()
+		pid := strconv.Itoa(os.Getpid())
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal(lock.Token() + "owner.v1?host=" + host + "&label=billing&pid=" + pid + "|step 1"))
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Token).To(Equal(lock.Token()))
+		Expect(info.Metadata).To(Equal("step 1"))
+		Expect(info.Owner).To(Equal(&redislock.Owner{Host: host, PID: os.Getpid(), Label: "billing"}))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{HolderDetails: true})
+		var details *redislock.NotObtainedError
+		Expect(errors.As(err, &details)).To(BeTrue())
+		Expect(details.Metadata).To(Equal("step 1"))
+
+		Expect(lock.SetMetadata(ctx, "step 2")).To(Succeed())
+		Expect(lock.Metadata()).To(Equal("step 2"))
+		info, err = subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Metadata).To(Equal("step 2"))
+		Expect(info.Owner.Label).To(Equal("billing"))
+
+		data, err := lock.MarshalBinary()
+		Expect(err).NotTo(HaveOccurred())
+		resumed, err := redislock.New(redisClient).ResumeBinary(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed.Metadata()).To(Equal("step 2"))
+		Expect(resumed.Release(ctx)).To(Succeed())
+	})
+
+	It("should not report owners of plain locks", func() {
+		lock, err := redislock.New(redisClient).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Owner).To(BeNil())
+	})
+})
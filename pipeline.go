@@ -0,0 +1,147 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PendingLock is an attempt to obtain a lock which was queued on a pipeline,
+// see Client.ObtainPipelined.
+type PendingLock struct {
+	lock   *Lock
+	result func() (bool, error)
+}
+
+// ObtainPipelined queues a single attempt to obtain the lock on pipe, e.g. a
+// transaction created via TxPipeline, so the lock is taken in the same round
+// trip as the application's other commands, or atomically with them. The
+// outcome is reported by PendingLock.Lock once pipe was executed. The
+// lock is not retried and the wait-related options, i.e. RetryStrategy,
+// AcquireIf, HolderDetails, CapBackoffAtTTL, Fencing and ReleaseOnCancel,
+// are ignored, as are SelectDB and, as the obtain bypasses them, statistics.
+// pipe must belong to the redis client of c.
+func (c *Client) ObtainPipelined(ctx context.Context, pipe redis.Pipeliner, key string, lockTTL time.Duration, opt *Options) (*PendingLock, error) {
+	if err := c.validate(ctx, key, lockTTL); err != nil {
+		return nil, err
+	}
+
+	opt = c.options(opt)
+	lockTTL = c.lockTTL(lockTTL)
+	key = c.defaults.KeyPrefix + key
+
+	token, value, err := c.newValue(opt)
+	if err != nil {
+		return nil, &Error{Op: "obtain", Key: key, Err: err}
+	}
+
+	p := new(PendingLock)
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+	if c.defaults.Compat == CompatRedisson {
+		cmd := luaRedissonObtain.Eval(ctx, pipe, []string{key}, value, ttlVal)
+		p.result = func() (bool, error) { n, err := cmd.Int(); return n == 1, err }
+	} else if scripts := opt.getScripts(); scripts != nil && scripts.Obtain != nil {
+		cmd := scripts.Obtain.Eval(ctx, pipe, append([]string{key}, scripts.Keys...), value, ttlVal)
+		p.result = func() (bool, error) { n, err := cmd.Int(); return n == 1, err }
+	} else {
+		cmd := pipe.SetNX(ctx, key, value, lockTTL)
+		p.result = cmd.Result
+	}
+
+	start := time.Now()
+	p.lock = &Lock{
+		client:     c,
+		rdb:        c.client,
+		key:        key,
+		token:      token,
+		value:      value,
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opt.getOperationTimeout(),
+		logger:     opt.getLogger(),
+		scripts:    exclusiveScripts,
+		scriptKeys: []string{key},
+		scriptArg:  value,
+		stats:      LockStats{Attempts: 1},
+		audited:    c.defaults.Audit != nil,
+	}
+	c.setScripts(p.lock, opt)
+	p.lock.statsKey = ""
+	return p, nil
+}
+
+// Lock returns the lock once the pipeline was executed. Its expiry is
+// counted from the call to ObtainPipelined.
+// May return ErrNotObtained if the lock is held by another client.
+func (p *PendingLock) Lock(ctx context.Context) (_ *Lock, err error) {
+	l := p.lock
+	defer wrapErr("obtain", l.key, &err)
+
+	ok, err := p.result()
+	if err == redis.Nil {
+		err = nil
+	}
+	if err != nil {
+		if l.logger != nil {
+			l.logger.Log(LevelError, "obtain failed", "key", l.key, "token", shortToken(l.token), "error", err)
+		}
+		return nil, err
+	} else if !ok {
+		if l.logger != nil {
+			l.logger.Log(LevelInfo, "lock not obtained", "key", l.key, "token", shortToken(l.token), "attempt", 1)
+		}
+		return nil, ErrNotObtained
+	}
+
+	if l.logger != nil {
+		l.logger.Log(LevelInfo, "lock obtained", "key", l.key, "token", shortToken(l.token), "attempt", 1)
+	}
+	l.audit(ctx, AuditObtain, l.ttl)
+	return l.client.track(l), nil
+}
+
+// PendingRelease is a release of a lock which was queued on a pipeline, see
+// Lock.ReleasePipelined.
+type PendingRelease struct {
+	lock *Lock
+	cmd  *redis.Cmd
+}
+
+// ReleasePipelined queues the release of the lock on pipe, e.g. a
+// transaction created via TxPipeline, and stops its auto-refresh watchdog.
+// The outcome is reported by PendingRelease.Err once pipe was executed. pipe
+// must belong to the redis client the lock was obtained from.
+func (l *Lock) ReleasePipelined(ctx context.Context, pipe redis.Pipeliner) *PendingRelease {
+	l.StopAutoRefresh()
+
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	script, keys, args := l.releaseCmd()
+	return &PendingRelease{lock: l, cmd: script.Eval(ctx, pipe, keys, args...)}
+}
+
+// Err returns the error of the release once the pipeline was executed.
+// May return ErrLockExpired or ErrLockStolen, both of which match ErrLockNotHeld.
+func (p *PendingRelease) Err(ctx context.Context) (err error) {
+	l := p.lock
+	defer wrapErr("release", l.key, &err)
+	defer l.releaseLocal()
+
+	if err = l.released(p.cmd.Val(), p.cmd.Err()); err != nil {
+		if errors.Is(err, ErrLockNotHeld) {
+			l.forgetIntent()
+		}
+		return err
+	}
+	l.forgetIntent()
+
+	l.argMu.RLock()
+	l.audit(ctx, AuditRelease, 0)
+	l.argMu.RUnlock()
+	return nil
+}
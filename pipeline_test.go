@@ -0,0 +1,67 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.ObtainPipelined", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":data").Err()).To(Succeed())
+	})
+
+	It("should obtain and release within transactions", func() {
+		var pending *redislock.PendingLock
+		_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			var err error
+			pending, err = subject.ObtainPipelined(ctx, pipe, lockKey, time.Minute, &redislock.Options{Metadata: "tx"})
+			Expect(err).NotTo(HaveOccurred())
+			pipe.Set(ctx, lockKey+":data", "1", 0)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		lock, err := pending.Lock(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("tx"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(redisClient.Get(ctx, lockKey+":data").Val()).To(Equal("1"))
+
+		var again *redislock.PendingLock
+		_, err = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			again, err = subject.ObtainPipelined(ctx, pipe, lockKey, time.Minute, nil)
+			return err
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = again.Lock(ctx)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		var release *redislock.PendingRelease
+		_, err = redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			release = lock.ReleasePipelined(ctx, pipe)
+			pipe.Del(ctx, lockKey+":data")
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(release.Err(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey, lockKey+":data").Val()).To(BeZero())
+
+		_, _ = redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			release = lock.ReleasePipelined(ctx, pipe)
+			return nil
+		})
+		Expect(release.Err(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+})
@@ -0,0 +1,112 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	errPrepareUnsupported = errors.New("redislock: PrepareRelease is only supported by exclusive locks")
+	errNotPrepared        = errors.New("redislock: release not prepared")
+)
+
+var (
+	luaPrepareRelease = redis.NewScript(`
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] then if v then return -2 else return -1 end end
+redis.call("set", KEYS[2], ARGV[2], "px", math.max(redis.call("pttl", KEYS[1]), 1))
+return 1`)
+	luaReleasing = redis.NewScript(`
+local v = redis.call("get", KEYS[1])
+if not v then return 1 end
+local m = redis.call("get", KEYS[2])
+if m and string.sub(v, 1, #m) == m then return 1 end
+return 0`)
+	luaDelIfEqual = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) end return 0`)
+)
+
+func (c *Client) releasingKey(key string) string { return c.companionKey(key, ":releasing") }
+
+// PrepareRelease is the first step of a two-phase release: it marks the lock
+// as releasing under the lock key + ":releasing", while it remains held, so
+// the holder can flush its side effects knowing no one else can obtain the
+// lock in the meantime. Waiters which set Options.ReleasingBackoff see the
+// mark and retry sooner. The mark expires with the TTL the lock had left, it
+// is not extended by refreshes. ConfirmRelease completes the release. Only
+// exclusive locks, as returned by Obtain, ObtainFair or Resume, can be
+// prepared.
+// May return ErrLockExpired or ErrLockStolen, both of which match
+// ErrLockNotHeld, if the lock is no longer held.
+func (l *Lock) PrepareRelease(ctx context.Context) (err error) {
+	defer wrapErr("prepare release", l.key, &err)
+	if ctx == nil {
+		return ErrNilContext
+	} else if l.scripts != exclusiveScripts && l.scripts != signalScripts && l.scripts != notifyScripts {
+		return errPrepareUnsupported
+	}
+
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	keys := []string{l.key, l.client.releasingKey(l.key)}
+	status, err := luaPrepareRelease.Run(opctx, l.rdb, keys, l.value, l.token).Result()
+	if err != nil {
+		return wrapOperationErr(ctx, opctx, err)
+	} else if status != int64(1) {
+		return l.lostBy(status, l.logger, ErrLockNotHeld)
+	}
+
+	l.mu.Lock()
+	l.prepared = true
+	l.mu.Unlock()
+
+	if l.logger != nil {
+		l.logger.Log(LevelDebug, "release prepared", "key", l.key, "token", shortToken(l.token))
+	}
+	return nil
+}
+
+// ConfirmRelease completes a release prepared via PrepareRelease, like
+// Release, and removes the releasing mark. If the release fails with an
+// error other than ErrLockNotHeld, the lock remains prepared, so the call can
+// be retried. Returns an error if the release was not prepared.
+// May return ErrLockExpired or ErrLockStolen, both of which match
+// ErrLockNotHeld.
+func (l *Lock) ConfirmRelease(ctx context.Context) error {
+	l.mu.Lock()
+	prepared := l.prepared
+	l.mu.Unlock()
+
+	if !prepared {
+		return &Error{Op: "release", Key: l.key, Err: errNotPrepared}
+	}
+
+	err := l.Release(ctx)
+	if err != nil && !errors.Is(err, ErrLockNotHeld) {
+		return err
+	}
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	if e := luaDelIfEqual.Run(opctx, l.rdb, []string{l.client.releasingKey(l.key)}, l.token).Err(); e != nil && l.logger != nil {
+		l.logger.Log(LevelWarn, "releasing mark not removed", "key", l.key, "token", shortToken(l.token), "error", e)
+	}
+	return err
+}
+
+// releasing reports whether the holder of key prepared its release, or the
+// lock was released already.
+func (c *Client) releasing(ctx context.Context, rdb RedisClient, key string, opTimeout time.Duration) bool {
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	status, err := luaReleasing.Run(opctx, rdb, []string{key, c.releasingKey(key)}).Result()
+	return err == nil && status == int64(1)
+}
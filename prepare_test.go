@@ -0,0 +1,94 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock.PrepareRelease", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":releasing").Err()).To(Succeed())
+	})
+
+	It("should keep the lock until confirmed", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(lock.PrepareRelease(ctx)).To(Succeed())
+		Expect(redisClient.Get(ctx, lockKey+":releasing").Val()).To(Equal(lock.Token()))
+		Expect(redisClient.PTTL(ctx, lockKey+":releasing").Val()).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock.ConfirmRelease(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey, lockKey+":releasing").Val()).To(BeZero())
+	})
+
+	It("should require a prepared release", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.ConfirmRelease(ctx)).To(MatchError(`redislock: release "` + lockKey + `": release not prepared`))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+	})
+
+	It("should fail on lost locks", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		Expect(lock.PrepareRelease(ctx)).To(MatchError(redislock.ErrLockStolen))
+		Expect(redisClient.Exists(ctx, lockKey+":releasing").Val()).To(BeZero())
+	})
+
+	It("should shorten the backoff of waiters", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.PrepareRelease(ctx)).To(Succeed())
+
+		done := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+
+			waiter, err := subject.Obtain(ctx, lockKey, 5*time.Second, time.Minute, &redislock.Options{
+				RetryStrategy:    redislock.LinearBackoff(time.Hour),
+				ReleasingBackoff: 10 * time.Millisecond,
+			})
+			if err == nil {
+				err = waiter.Release(ctx)
+			}
+			done <- err
+		}()
+
+		time.Sleep(30 * time.Millisecond)
+		Expect(lock.ConfirmRelease(ctx)).To(Succeed())
+		Eventually(done, time.Second).Should(Receive(BeNil()))
+	})
+
+	It("should not shorten the backoff for unprepared locks", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		start := time.Now()
+		_, err = subject.Obtain(ctx, lockKey, 100*time.Millisecond, time.Minute, &redislock.Options{
+			RetryStrategy:    redislock.LinearBackoff(time.Hour),
+			ReleasingBackoff: 10 * time.Millisecond,
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(time.Since(start)).To(BeNumerically("<", 50*time.Millisecond))
+	})
+})
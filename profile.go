@@ -0,0 +1,68 @@
+package redislock
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+var errUnknownProfile = errors.New("redislock: unknown profile")
+
+// RegisterProfile registers opt under name, so calls to obtain locks can
+// reference it via Options.Profile or WithProfile, e.g. to tune the TTLs and
+// retries of a class of locks in one place. Registering a name again replaces
+// the profile for subsequent calls. The Options are shared by all calls using
+// the profile, so their RetryStrategy must implement ReusableRetryStrategy,
+// like the built-in ones. The Profile of opt is ignored.
+func (c *Client) RegisterProfile(name string, opt Options) {
+	opt.Profile = ""
+
+	c.profilesMu.Lock()
+	defer c.profilesMu.Unlock()
+
+	if c.profiles == nil {
+		c.profiles = make(map[string]*Options)
+	}
+	c.profiles[name] = &opt
+}
+
+// lookupProfile returns the profile registered under name.
+func (c *Client) lookupProfile(name string) (*Options, bool) {
+	c.profilesMu.RLock()
+	defer c.profilesMu.RUnlock()
+
+	opt, ok := c.profiles[name]
+	return opt, ok
+}
+
+// withProfile returns opt merged with the profile it references, if any, and
+// ttl, or the TTL of the merged options if ttl is zero.
+func (c *Client) withProfile(key string, opt *Options, ttl time.Duration) (*Options, time.Duration, error) {
+	if opt == nil {
+		return nil, ttl, nil
+	}
+	if opt.Profile != "" {
+		profile, ok := c.lookupProfile(opt.Profile)
+		if !ok {
+			return nil, 0, &Error{Op: "obtain", Key: c.defaults.KeyPrefix + key, Err: errUnknownProfile}
+		}
+
+		merged := *opt
+		mergeOptions(&merged, profile)
+		opt = &merged
+	}
+	if ttl <= 0 && opt.TTL > 0 {
+		ttl = opt.TTL
+	}
+	return opt, ttl, nil
+}
+
+// mergeOptions sets the zero fields of dst to those of src.
+func mergeOptions(dst, src *Options) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < d.NumField(); i++ {
+		if f := d.Field(i); f.IsZero() {
+			f.Set(s.Field(i))
+		}
+	}
+}
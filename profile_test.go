@@ -0,0 +1,80 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.RegisterProfile", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+		subject.RegisterProfile("reports", redislock.Options{
+			TTL:           time.Minute,
+			Metadata:      "reports",
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+		})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should apply profiles", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 0, &redislock.Options{Profile: "reports"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.Metadata()).To(Equal("reports"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+
+		start := time.Now()
+		_, err = subject.Obtain(ctx, lockKey, 50*time.Millisecond, 0, &redislock.Options{Profile: "reports"})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		var e *redislock.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Attempts).To(BeNumerically(">", 1))
+		Expect(time.Since(start)).To(BeNumerically(">=", 40*time.Millisecond))
+	})
+
+	It("should let options override profiles", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 20*time.Second, &redislock.Options{Profile: "reports", Metadata: "custom"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.Metadata()).To(Equal("custom"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", 20*time.Second, time.Second))
+	})
+
+	It("should apply profiles to ObtainWith", func() {
+		lock, err := subject.ObtainWith(ctx, lockKey, redislock.WithProfile("reports"))
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.Metadata()).To(Equal("reports"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+	})
+
+	It("should replace profiles", func() {
+		subject.RegisterProfile("reports", redislock.Options{TTL: 10 * time.Second})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 0, &redislock.Options{Profile: "reports"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.Metadata()).To(BeEmpty())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", 10*time.Second, time.Second))
+	})
+
+	It("should reject unknown profiles", func() {
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, 0, &redislock.Options{Profile: "unknown"})
+		Expect(err).To(MatchError(`redislock: obtain "` + lockKey + `": unknown profile`))
+	})
+})
@@ -0,0 +1,120 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrQuotaExceeded is returned by Obtain and the helpers built on it if the
+// quota of the key prefix of a lock is exhausted, see Quota.
+var ErrQuotaExceeded = errors.New("redislock: quota exceeded")
+
+// luaQuotaPurge removes the expired locks from the sorted set KEYS[2], which
+// holds the locked keys scored by their deadline.
+const luaQuotaPurge = luaNow + `
+redis.call("zremrangebyscore", KEYS[2], "-inf", now)
+`
+
+// luaQuotaExpire keeps the sorted set until its last lock expires.
+const luaQuotaExpire = `
+local last = redis.call("zrange", KEYS[2], -1, -1, "withscores")
+if last[2] then redis.call("pexpireat", KEYS[2], last[2]) else redis.call("del", KEYS[2]) end
+`
+
+var (
+	luaQuotaObtain = redis.NewScript(luaQuotaPurge + `
+if redis.call("exists", KEYS[1]) == 1 then return 0 end
+if redis.call("zcard", KEYS[2]) >= tonumber(ARGV[3]) then return -1 end
+redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])
+redis.call("zadd", KEYS[2], now + tonumber(ARGV[2]), KEYS[1])` + luaQuotaExpire + `
+return 1`)
+	luaQuotaRefresh = redis.NewScript(luaQuotaPurge + `
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] then if v then return -2 else return -1 end end
+redis.call("pexpire", KEYS[1], ARGV[2])
+redis.call("zadd", KEYS[2], now + tonumber(ARGV[2]), KEYS[1])` + luaQuotaExpire + `
+return 1`)
+	luaQuotaExtend = redis.NewScript(luaQuotaPurge + `
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] then if v then return -2 else return -1 end end
+local t = math.max(redis.call("pttl", KEYS[1]), 0) + tonumber(ARGV[2])
+if tonumber(ARGV[3]) > 0 then t = math.min(t, tonumber(ARGV[3])) end
+redis.call("pexpire", KEYS[1], t)
+redis.call("zadd", KEYS[2], now + t, KEYS[1])` + luaQuotaExpire + `
+return t`)
+	luaQuotaRelease = redis.NewScript(luaQuotaPurge + `
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[1] then if v then return -2 else return -1 end end
+redis.call("del", KEYS[1])
+redis.call("zrem", KEYS[2], KEYS[1])` + luaQuotaExpire + `
+return 1`)
+	luaQuotaUsage = redis.NewScript(luaQuotaPurge + `return redis.call("zcard", KEYS[2])`)
+
+	quotaScripts = &lockScripts{pttl: luaPTTL, refresh: luaQuotaRefresh, extend: luaQuotaExtend, release: luaQuotaRelease}
+)
+
+// Quota caps the number of locks held at a time under key prefixes, e.g. one
+// prefix per tenant, so a misbehaving tenant cannot exhaust the coordination
+// capacity of a service, see Defaults.Quota. The locked keys of each prefix
+// are counted in a sorted set under the prefix + ":quota", which is updated
+// atomically with the locks. On clusters, the keys of each prefix must share
+// a hash tag, e.g. "{tenant-a}:".
+type Quota struct {
+	// Limits maps key prefixes, including Defaults.KeyPrefix, to the
+	// maximum number of locks held under them. The longest matching prefix
+	// applies, keys matching none are not limited.
+	Limits map[string]int
+}
+
+// limit returns the longest prefix of key with a limit and its limit.
+func (q *Quota) limit(key string) (string, int) {
+	var prefix string
+	limit := -1
+	for p, n := range q.Limits {
+		if strings.HasPrefix(key, p) && (limit < 0 || len(p) > len(prefix)) {
+			prefix, limit = p, n
+		}
+	}
+	return prefix, limit
+}
+
+// quotaKey returns the key counting the locks of key and the limit, or an
+// empty key if key is not limited.
+func (c *Client) quotaKey(key string) (string, int) {
+	if c.defaults.Quota == nil {
+		return "", 0
+	}
+	prefix, limit := c.defaults.Quota.limit(key)
+	if limit < 0 {
+		return "", 0
+	}
+	return prefix + ":quota", limit
+}
+
+// obtainQuota is like SETNX, but fails with ErrQuotaExceeded if the locks
+// counted under quotaKey reached limit.
+func (c *Client) obtainQuota(ctx context.Context, rdb RedisClient, key, quotaKey, value string, ttl time.Duration, limit int) (bool, error) {
+	status, err := luaQuotaObtain.Run(ctx, rdb, []string{key, quotaKey}, value, msArg(ttl), limit).Int64()
+	if err != nil {
+		return false, err
+	} else if status == -1 {
+		return false, ErrQuotaExceeded
+	}
+	return status == 1, nil
+}
+
+// QuotaUsage returns the number of locks held under prefix, which must be
+// one of the prefixes of Defaults.Quota.
+func (c *Client) QuotaUsage(ctx context.Context, prefix string) (n int, err error) {
+	defer wrapErr("quota", prefix, &err)
+
+	opctx, cancel := withOperationTimeout(ctx, c.defaults.OperationTimeout)
+	defer cancel()
+
+	count, err := luaQuotaUsage.Run(opctx, c.client, []string{prefix, prefix + ":quota"}).Int64()
+	return int(count), wrapOperationErr(ctx, opctx, err)
+}
@@ -0,0 +1,70 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Quota", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+	var tenant = lockKey + ":tenant:"
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient, redislock.Defaults{Quota: &redislock.Quota{Limits: map[string]int{
+			lockKey: 10,
+			tenant:  2,
+		}}})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, tenant+"a", tenant+"b", tenant+"c", tenant+":quota", lockKey+":quota").Err()).To(Succeed())
+	})
+
+	It("should cap the locks held under a prefix", func() {
+		lock1, err := subject.Obtain(ctx, tenant+"a", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		lock2, err := subject.Obtain(ctx, tenant+"b", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.QuotaUsage(ctx, tenant)).To(Equal(2))
+
+		_, err = subject.Obtain(ctx, tenant+"c", time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrQuotaExceeded))
+		Expect(redisClient.Exists(ctx, tenant+"c").Val()).To(BeZero())
+
+		_, err = subject.Obtain(ctx, tenant+"a", 0, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		lock3, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.QuotaUsage(ctx, lockKey)).To(Equal(1))
+		Expect(lock3.Release(ctx)).To(Succeed())
+
+		Expect(lock1.Release(ctx)).To(Succeed())
+		Expect(subject.QuotaUsage(ctx, tenant)).To(Equal(1))
+		lock4, err := subject.Obtain(ctx, tenant+"c", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock4.Release(ctx)).To(Succeed())
+		Expect(lock2.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, tenant+":quota").Val()).To(BeZero())
+	})
+
+	It("should not count expired locks", func() {
+		lock, err := subject.Obtain(ctx, tenant+"a", time.Hour, 50*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = subject.Obtain(ctx, tenant+"b", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(lock.Refresh(ctx, 100*time.Millisecond, nil)).To(Succeed())
+		Expect(redisClient.ZScore(ctx, tenant+":quota", tenant+"a").Val()).To(BeNumerically(">", 0))
+
+		Eventually(func() (int, error) { return subject.QuotaUsage(ctx, tenant) }).Should(Equal(1))
+		_, err = subject.Obtain(ctx, tenant+"c", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+})
@@ -0,0 +1,150 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// luaRateLimit implements the generic cell rate algorithm: the key holds the
+// theoretical arrival time (TAT) of the next request in milliseconds, which
+// may run ahead of now by up to the burst allowance.
+var luaRateLimit = redis.NewScript(luaNow + `
+local interval = tonumber(ARGV[1])
+local burst = interval * tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local tat = tonumber(redis.call("get", KEYS[1]) or now)
+if tat < now then tat = now end
+local tat2 = tat + interval * n
+local diff = now - (tat2 - burst)
+if diff < 0 then return {0, math.floor((now - (tat - burst)) / interval), math.ceil(-diff)} end
+redis.call("set", KEYS[1], string.format("%.3f", tat2), "px", math.ceil(tat2 - now))
+return {1, math.floor(diff / interval), 0}`)
+
+// ErrInvalidLimit is returned by the operations of a RateLimiter created with
+// a non-positive limit or period.
+var ErrInvalidLimit = errors.New("redislock: invalid rate limit")
+
+// RateLimiter is a distributed rate limiter, which allows events at a
+// steady rate with bursts of up to a number of events, e.g. to throttle
+// calls to an external API across processes. Its state is a single key,
+// which expires once the limiter is idle.
+type RateLimiter struct {
+	client   *Client
+	key      string
+	interval float64
+	burst    int
+}
+
+// RateLimitResult is the outcome of an attempt to perform events, see
+// RateLimiter.AllowN.
+type RateLimitResult struct {
+	// Allowed reports whether the events were allowed.
+	Allowed bool
+	// Remaining is the number of events which would be allowed right now.
+	Remaining int
+	// RetryAfter is the time after which the events would be allowed, if
+	// they were not.
+	RetryAfter time.Duration
+}
+
+// NewRateLimiter creates a new RateLimiter for key, which allows limit
+// events per period, with bursts of up to burst events. A burst of less
+// than one allows a single event at a time. Optional defaults configure the
+// underlying client like New, except that the key is used as is. If limit or
+// per are not positive, its operations return ErrInvalidLimit.
+func NewRateLimiter(client RedisClient, key string, limit int, per time.Duration, burst int, defaults ...Defaults) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	var interval float64
+	if limit > 0 && per > 0 {
+		interval = float64(per) / float64(time.Millisecond) / float64(limit)
+	}
+	return &RateLimiter{client: New(client, defaults...), key: key, interval: interval, burst: burst}
+}
+
+// Allow is a shorthand for AllowN(ctx, 1).
+func (r *RateLimiter) Allow(ctx context.Context) (*RateLimitResult, error) {
+	return r.AllowN(ctx, 1)
+}
+
+// AllowN reports whether n events may happen now and records them if so.
+func (r *RateLimiter) AllowN(ctx context.Context, n int) (*RateLimitResult, error) {
+	if r.interval <= 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	res, err := luaRateLimit.Run(ctx, r.client.client, []string{r.key},
+		strconv.FormatFloat(r.interval, 'f', -1, 64), r.burst, n,
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	vals, _ := res.([]interface{})
+	if len(vals) != 3 {
+		return &RateLimitResult{}, nil
+	}
+	allowed, _ := vals[0].(int64)
+	remaining, _ := vals[1].(int64)
+	retryAfter, _ := vals[2].(int64)
+	return &RateLimitResult{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(retryAfter) * time.Millisecond,
+	}, nil
+}
+
+// Wait is a shorthand for WaitN(ctx, 1, waitTimeout, opt).
+func (r *RateLimiter) Wait(ctx context.Context, waitTimeout time.Duration, opt *Options) error {
+	return r.WaitN(ctx, 1, waitTimeout, opt)
+}
+
+// WaitN waits until n events are allowed and records them. Unless
+// opt.RetryStrategy is set, it retries after the time reported by each
+// attempt, see RateLimitResult.RetryAfter.
+// May return ErrNotObtained if not allowed within waitTimeout.
+func (r *RateLimiter) WaitN(ctx context.Context, n int, waitTimeout time.Duration, opt *Options) error {
+	if r.interval <= 0 {
+		return &Error{Op: "obtain", Key: r.key, Err: ErrInvalidLimit}
+	}
+
+	o := *r.client.options(opt)
+	var after retryAfter
+	if o.RetryStrategy == nil {
+		o.RetryStrategy = &after
+	}
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	c := r.client
+	opTimeout := o.getOperationTimeout()
+	_, err := c.retry(deadlinectx, c.client, r.key, "", &o, false, func(ctx context.Context) (bool, error) {
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		res, err := r.AllowN(opctx, n)
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		after = retryAfter(res.RetryAfter)
+		return res.Allowed, nil
+	})
+	return err
+}
+
+// retryAfter retries after the time reported by the last attempt to perform
+// events.
+type retryAfter time.Duration
+
+func (r *retryAfter) NextBackoff() time.Duration {
+	if *r < retryAfter(time.Millisecond) {
+		return time.Millisecond
+	}
+	return time.Duration(*r)
+}
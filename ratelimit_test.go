@@ -0,0 +1,69 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var subject *redislock.RateLimiter
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.NewRateLimiter(redisClient, lockKey, 10, time.Second, 3)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should reject invalid limits", func() {
+		for _, limiter := range []*redislock.RateLimiter{
+			redislock.NewRateLimiter(redisClient, lockKey, 0, time.Second, 1),
+			redislock.NewRateLimiter(redisClient, lockKey, -1, time.Second, 1),
+			redislock.NewRateLimiter(redisClient, lockKey, 10, 0, 1),
+			redislock.NewRateLimiter(redisClient, lockKey, 10, -time.Second, 1),
+		} {
+			_, err := limiter.Allow(ctx)
+			Expect(err).To(MatchError(redislock.ErrInvalidLimit))
+			Expect(limiter.Wait(ctx, time.Second, nil)).To(MatchError(redislock.ErrInvalidLimit))
+		}
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should allow bursts", func() {
+		for i := 2; i >= 0; i-- {
+			res, err := subject.Allow(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Allowed).To(BeTrue())
+			Expect(res.Remaining).To(Equal(i))
+		}
+
+		res, err := subject.Allow(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Remaining).To(BeZero())
+		Expect(res.RetryAfter).To(BeNumerically("~", 100*time.Millisecond, 20*time.Millisecond))
+
+		res, err = subject.AllowN(ctx, 4)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Allowed).To(BeFalse())
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", 300*time.Millisecond, 20*time.Millisecond))
+	})
+
+	It("should wait", func() {
+		res, err := subject.AllowN(ctx, 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Allowed).To(BeTrue())
+
+		start := time.Now()
+		Expect(subject.Wait(ctx, time.Second, nil)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("~", 100*time.Millisecond, 30*time.Millisecond))
+
+		Expect(subject.WaitN(ctx, 3, 50*time.Millisecond, nil)).To(MatchError(redislock.ErrNotObtained))
+	})
+})
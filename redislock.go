@@ -4,30 +4,211 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	mathrand "math/rand"
+	"net"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock/scripts"
+)
+
+// Sources of the release scripts, which are also wrapped by withReleaseStats
+// and unlinkSrc.
+const (
+	luaReleaseSrc       = scripts.ReleaseSrc
+	luaReleaseSignalSrc = `local v = redis.call("get", KEYS[1]) if v == ARGV[1] then redis.call("del", KEYS[1]) redis.call("rpush", KEYS[2], "1") redis.call("ltrim", KEYS[2], -1, -1) redis.call("pexpire", KEYS[2], ARGV[2]) return 1 elseif v then return -2 else return -1 end`
+	luaReleaseNotifySrc = `local v = redis.call("get", KEYS[1]) if v == ARGV[1] then redis.call("del", KEYS[1]) redis.call("publish", KEYS[1] .. ":released", "1") return 1 elseif v then return -2 else return -1 end`
 )
 
 var (
-	luaRefresh = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`)
-	luaRelease = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`)
-	luaPTTL    = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pttl", KEYS[1]) else return -3 end`)
+	luaRefresh          = scripts.Refresh
+	luaRelease          = redis.NewScript(luaReleaseSrc)
+	luaPTTL             = scripts.PTTL
+	luaReleaseSignal    = redis.NewScript(luaReleaseSignalSrc)
+	luaReleaseNotify    = redis.NewScript(luaReleaseNotifySrc)
+	luaFence            = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("incr", KEYS[2]) else return false end`)
+	luaRefreshFence     = redis.NewScript(`local v = redis.call("get", KEYS[1]) if v ~= ARGV[1] then if v then return -2 else return -1 end end redis.call("pexpire", KEYS[1], ARGV[2]) return {1, redis.call("incr", KEYS[2])}`)
+	luaObtainInspect    = redis.NewScript(`if redis.call("set", KEYS[1], ARGV[1], "nx", "px", ARGV[2]) then return 1 end return {redis.call("get", KEYS[1]) or "", redis.call("pttl", KEYS[1])}`)
+	luaExtend           = redis.NewScript(`local v = redis.call("get", KEYS[1]) if v ~= ARGV[1] then if v then return -2 else return -1 end end local t = math.max(redis.call("pttl", KEYS[1]), 0) + tonumber(ARGV[2]) if tonumber(ARGV[3]) > 0 then t = math.min(t, tonumber(ARGV[3])) end redis.call("pexpire", KEYS[1], t) return t`)
+	luaGet              = redis.NewScript(`return redis.call("get", KEYS[1])`)
+	luaReplace          = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("set", KEYS[1], ARGV[2], "px", ARGV[3]) else return false end`)
+	luaObtainOrReobtain = redis.NewScript(`if redis.call("set", KEYS[1], ARGV[1], "nx", "px", ARGV[2]) or redis.call("get", KEYS[1]) == ARGV[1] and redis.call("pexpire", KEYS[1], ARGV[2]) == 1 then return 1 end return 0`)
+	luaSetMetadata      = redis.NewScript(`for _, k in ipairs(KEYS) do local v = redis.call("get", k) if v ~= ARGV[1] then if v then return -2 else return -1 end end end for _, k in ipairs(KEYS) do local t = redis.call("pttl", k) if t > 0 then redis.call("set", k, ARGV[2], "px", t) else redis.call("set", k, ARGV[2]) end end return 1`)
 )
 
 var (
-	// ErrNotObtained is returned when a lock cannot be obtained.
+	// ErrNotObtained is returned when a lock cannot be obtained. Like all
+	// errors of lock operations, it is wrapped in an Error, use errors.Is to
+	// check for it.
 	ErrNotObtained = errors.New("redislock: not obtained")
 
 	// ErrLockNotHeld is returned when trying to release an inactive lock.
 	ErrLockNotHeld = errors.New("redislock: lock not held")
+
+	// ErrLockExpired is returned when trying to release or refresh a lock
+	// that has expired. It matches both ErrLockNotHeld and ErrNotObtained.
+	ErrLockExpired error = &lockLostError{msg: "redislock: lock expired"}
+
+	// ErrLockStolen is returned when trying to release or refresh a lock
+	// that has been obtained by someone else. It matches both ErrLockNotHeld
+	// and ErrNotObtained.
+	ErrLockStolen error = &lockLostError{msg: "redislock: lock stolen"}
+
+	// ErrInvalidTTL is returned when a TTL, or the duration of an Extend, is
+	// below the millisecond resolution of redis.
+	ErrInvalidTTL = errors.New("redislock: invalid TTL")
+
+	// ErrInvalidKey is returned when trying to obtain a lock on an empty key.
+	ErrInvalidKey = errors.New("redislock: invalid key")
+
+	// ErrNilContext is returned when a lock operation is passed a nil context.
+	ErrNilContext = errors.New("redislock: nil context")
+
+	errSelectDBUnsupported  = errors.New("redislock: SelectDB and Route.DB require a *redis.Client")
+	errScanUnsupported      = errors.New("redislock: List requires a ScanningClient")
+	errWatchUnsupported     = errors.New("redislock: Watcher requires a PatternSubscribingClient")
+	errEventsUnsupported    = errors.New("redislock: Watch requires a SubscribingClient")
+	errMetadataUnsupported  = errors.New("redislock: SetMetadata is not supported by shared locks")
+	errAuditUnsupported     = errors.New("redislock: Audit requires a StreamingClient")
+	errBumpFenceUnsupported = errors.New("redislock: BumpFence is only supported by exclusive locks")
+)
+
+// NotObtainedError is returned by Obtain instead of ErrNotObtained if
+// Options.HolderDetails is set. It describes the holder of the lock as seen on
+// the last attempt and matches ErrNotObtained.
+type NotObtainedError struct {
+	// Key is the key of the lock.
+	Key string
+	// Metadata is the metadata of the current holder.
+	Metadata string
+	// TTL is the remaining TTL of the current holder.
+	TTL time.Duration
+
+	// value is the lock value of the current holder, see ObtainOrInspect.
+	value string
+}
+
+func (e *NotObtainedError) Error() string {
+	return fmt.Sprintf("redislock: not obtained, held with metadata %q for another %s", e.Metadata, e.TTL)
+}
+
+// Is matches ErrNotObtained.
+func (e *NotObtainedError) Is(target error) bool {
+	return target == ErrNotObtained
+}
+
+// Error is returned by lock operations. It records the operation and the key
+// and wraps the underlying error, such as ErrNotObtained, ErrLockExpired or
+// an error returned by redis, which can be inspected via errors.Is and
+// errors.As.
+type Error struct {
+	// Op is the failed operation, e.g. "obtain" or "release".
+	Op string
+	// Key is the redis key of the lock.
+	Key string
+	// Err is the underlying error.
+	Err error
+	// Attempts is the number of attempts made to obtain the lock, if Op is
+	// "obtain".
+	Attempts int
+	// Stopped is the reason retrying to obtain the lock stopped, if Op is
+	// "obtain".
+	Stopped StopReason
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("redislock: %s %q: %s", e.Op, e.Key, strings.TrimPrefix(e.Err.Error(), "redislock: "))
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StopReason is the reason retrying to obtain a lock stopped, see
+// Error.Stopped.
+type StopReason int
+
+const (
+	// StopNone is reported if retrying did not stop, e.g. because the
+	// circuit breaker was open.
+	StopNone StopReason = iota
+	// StopStrategy is reported if the retry strategy gave up.
+	StopStrategy
+	// StopAttempts is reported if the attempts limit of LimitRetry or Limit
+	// was reached.
+	StopAttempts
+	// StopElapsed is reported if the time limit of Limit, RetryUntil or
+	// Options.MaxElapsed was reached.
+	StopElapsed
+	// StopContext is reported if the context was done, or its deadline too
+	// close for the next backoff.
+	StopContext
+	// StopAborted is reported if Options.OnRetry aborted.
+	StopAborted
+	// StopError is reported if the last attempt failed with an error not
+	// accepted by Options.RetryOnError.
+	StopError
 )
 
-// RedisClient is a minimal client interface.
+func (r StopReason) String() string {
+	switch r {
+	case StopStrategy:
+		return "strategy"
+	case StopAttempts:
+		return "attempts"
+	case StopElapsed:
+		return "elapsed"
+	case StopContext:
+		return "context"
+	case StopAborted:
+		return "aborted"
+	case StopError:
+		return "error"
+	}
+	return "none"
+}
+
+// stopReason returns the reason s gave up, StopStrategy unless s knows
+// better.
+func stopReason(s RetryStrategy) StopReason {
+	if r, ok := s.(interface{ stopReason() StopReason }); ok {
+		if reason := r.stopReason(); reason != StopNone {
+			return reason
+		}
+	}
+	return StopStrategy
+}
+
+// wrapErr wraps *err, if set, in an Error for op on key.
+func wrapErr(op, key string, err *error) {
+	if *err != nil {
+		*err = &Error{Op: op, Key: key, Err: *err}
+	}
+}
+
+type lockLostError struct{ msg string }
+
+func (e *lockLostError) Error() string { return e.msg }
+
+func (e *lockLostError) Is(target error) bool {
+	return target == ErrLockNotHeld || target == ErrNotObtained
+}
+
+// RedisClient is a minimal client interface. It is implemented natively by
+// go-redis/v8 clients, including redis.UniversalClient, i.e. failover
+// (Sentinel), cluster and ring clients, other clients can be plugged in via
+// adapters such as the redisv9 package.
 type RedisClient interface {
 	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
 	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
@@ -36,177 +217,2488 @@ type RedisClient interface {
 	ScriptLoad(ctx context.Context, script string) *redis.StringCmd
 }
 
+// BlockingClient is an optional extension of RedisClient which is required to
+// wait for release signals, see Options.ReleaseSignal.
+type BlockingClient interface {
+	BLPop(ctx context.Context, timeout time.Duration, keys ...string) *redis.StringSliceCmd
+}
+
+// SubscribingClient is an optional extension of RedisClient which is required
+// to wait for release notifications, see Options.ReleaseNotify.
+type SubscribingClient interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// ScanningClient is an optional extension of RedisClient which is required
+// to list locks, see Client.List.
+type ScanningClient interface {
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+}
+
+// PatternSubscribingClient is an optional extension of RedisClient which is
+// required to watch keyspace events, see Watcher.
+type PatternSubscribingClient interface {
+	PSubscribe(ctx context.Context, patterns ...string) *redis.PubSub
+}
+
+// PipeliningClient is an optional extension of RedisClient which allows to
+// batch operations on many locks into a single round trip, see
+// Client.RefreshAll.
+type PipeliningClient interface {
+	Pipeline() redis.Pipeliner
+}
+
+var (
+	_ RedisClient              = (*redis.Client)(nil)
+	_ RedisClient              = (*redis.ClusterClient)(nil)
+	_ RedisClient              = (*redis.Ring)(nil)
+	_ RedisClient              = (redis.UniversalClient)(nil)
+	_ BlockingClient           = (*redis.Client)(nil)
+	_ SubscribingClient        = (*redis.Client)(nil)
+	_ ScanningClient           = (*redis.Client)(nil)
+	_ PatternSubscribingClient = (*redis.Client)(nil)
+	_ PipeliningClient         = (*redis.Client)(nil)
+	_ PipeliningClient         = (*redis.ClusterClient)(nil)
+	_ BlockingClient           = (redis.UniversalClient)(nil)
+	_ SubscribingClient        = (redis.UniversalClient)(nil)
+	_ PatternSubscribingClient = (redis.UniversalClient)(nil)
+	_ PipeliningClient         = (redis.UniversalClient)(nil)
+)
+
 // Client wraps a redis client.
 type Client struct {
 	client RedisClient
-	tmp    []byte
-	tmpMu  sync.Mutex
+
+	dbs   map[int]*redis.Client
+	dbsMu sync.Mutex
+
+	defaults Defaults
+	hashTags bool
+	local    *localLocks
+	noSetGet int32
+	ownerTag string
+	caps     *Capabilities
+	routes   []Route
+	releases asyncReleases
+
+	profiles   map[string]*Options
+	profilesMu sync.RWMutex
+}
+
+// Defaults are client-wide defaults, which are overridden by per-call
+// options.
+type Defaults struct {
+	// TTL is used when a lock TTL of zero is passed, and by StealAfter.
+	TTL time.Duration
+
+	// RetryStrategy creates the retry strategy for each call which does not
+	// set Options.RetryStrategy.
+	RetryStrategy func() RetryStrategy
+
+	// Metadata is used unless Options.Metadata is set.
+	Metadata string
+
+	// KeyPrefix is prepended to all lock keys, e.g. "myapp:locks:". The
+	// prefixed key is reported by Lock.Key.
+	KeyPrefix string
+
+	// Owner embeds the identity of the process in the value of exclusive
+	// locks, between the token and the metadata, so redis-cli shows who
+	// holds each lock. It is reported as LockInfo.Owner by Inspect and List
+	// and is not part of Lock.Metadata. Resume expects the metadata to
+	// include the owner tag, use MarshalBinary and ResumeBinary to hand over
+	// such locks.
+	Owner *Owner
+
+	// Cipher encrypts the metadata of exclusive locks, so it is not exposed
+	// to anyone with read access to redis, see NewAESGCM. Lock.Metadata,
+	// Inspect, List and HolderDetails report the decrypted metadata, while
+	// the audit trail keeps it encrypted. Metadata which cannot be decrypted,
+	// e.g. of clients with another key, is reported as stored. As the
+	// ciphertext changes with each encryption, use MarshalBinary and
+	// ResumeBinary to hand over such locks, or pass the stored metadata to
+	// Resume.
+	Cipher Cipher
+
+	// Metrics receives metrics of all locks obtained by the client.
+	Metrics MetricsCollector
+
+	// Tracer traces Obtain, ObtainWith and the helpers built on them, as
+	// well as Refresh, Extend and Release of all locks obtained by the
+	// client, see Tracer and the redislockotel package.
+	// Default: no tracing
+	Tracer Tracer
+
+	// Logger is used unless Options.Logger is set.
+	Logger Logger
+
+	// OperationTimeout is used unless Options.OperationTimeout is set. It
+	// also limits the commands of WaitForRelease.
+	OperationTimeout time.Duration
+
+	// HashTags places the companion keys of a lock, such as its fencing
+	// counter, release signal list and fair queue, in the cluster slot of the
+	// lock key, by using the lock key as their hash tag unless it contains
+	// one already. ObtainMulti then also requires all keys to hash to the
+	// same slot. It is enabled automatically for *redis.ClusterClient, which
+	// also follows MOVED and ASK redirects during script execution, and for
+	// *redis.Ring, which shards keys the same way. Scripts and SET NX on
+	// cluster clients failing due to resharding or failovers are retried a
+	// few times with a backoff after reloading the cluster state.
+	HashTags bool
+
+	// Manager tracks all locks obtained by the client, except for RWLock and
+	// Semaphore locks.
+	Manager *Manager
+
+	// Register tracks the same locks as Manager in the process-wide
+	// registry reported by Held. If Metrics implements
+	// HeldMetricsCollector, it is notified as registered locks come and go.
+	Register bool
+
+	// Watcher watches all locks obtained by the client, except for RWLock
+	// and Semaphore locks, see Watcher.
+	Watcher *Watcher
+
+	// Scripts is used unless Options.Scripts is set.
+	Scripts *Scripts
+
+	// RecordStats records the number of acquisitions, failed attempts and
+	// the total hold time of each key in a companion hash, see Client.Stats.
+	// It applies to locks obtained via Obtain and ObtainWith, unless their
+	// Scripts replace the obtain or release script. The statistics do not
+	// expire.
+	RecordStats bool
+
+	// Clock is used for retry backoffs and auto-refresh, e.g. to advance
+	// time in tests instead of sleeping, see redislocktest.Client. Context
+	// deadlines, such as the wait timeout of Obtain, are unaffected.
+	// Default: the system clock
+	Clock Clock
+
+	// Routes direct the locks on keys with certain prefixes to other redis
+	// DBs or endpoints, e.g. to isolate noisy high-churn locks from critical
+	// low-latency ones, see Route. They apply to Obtain, ObtainWith and the
+	// helpers built on them, unless Options.SelectDB is set, to the locks
+	// they return, and to Resume, Inspect, ForceRelease, Purge and
+	// WaitForRelease. Hash tags are enabled according to the client passed to
+	// New or HashTags.
+	Routes []Route
+
+	// CoalesceLocal makes concurrent attempts to obtain the same key via
+	// the client wait for each other and for the holder within the process,
+	// so only one of them at a time talks to redis. An attempt without
+	// retries fails immediately if the key is taken within the process.
+	// Waiters proceed once the lock is released or known to be lost, see
+	// Lock.Done. It applies to Obtain, ObtainWith, ObtainOrdered, Once and
+	// Election.
+	CoalesceLocal bool
+
+	// Unlink deletes the keys of released locks via UNLINK instead of DEL,
+	// which frees their memory in a background thread, so releasing locks
+	// with large metadata does not block the server. Purge then also unlinks
+	// companion keys. It applies to exclusive locks, including ObtainMulti,
+	// and ForceRelease, and requires redis 4.
+	Unlink bool
+
+	// RecoverPanics makes Do and RunWithRefresh return panics in fn as a
+	// *PanicError instead of re-panicking once the lock is released.
+	RecoverPanics bool
+
+	// Compat makes exclusive locks obtained via Obtain and ObtainWith, and
+	// those resumed via Resume, interoperable with other lock libraries
+	// sharing the same keys, see Compat. Lock values then consist of the
+	// token only, which is generated in the format of the library unless
+	// Options.TokenGenerator or Options.IdempotencyToken is set. Metadata
+	// and Owner are not stored. CompatRedisson locks do not support
+	// Options.Scripts, Fencing, AcquireIf, IdempotencyToken, HolderDetails,
+	// release signals or notifications, statistics and Lock.SetMetadata.
+	// Default: CompatNone
+	Compat Compat
+
+	// Audit records obtain, refresh, release and transfer events of locks
+	// obtained via Obtain and ObtainWith, or resumed via Resume, as well as
+	// ForceRelease events, in a capped redis stream, see Audit and
+	// Client.AuditEvents.
+	Audit *Audit
+
+	// Breaker makes attempts to obtain locks fail fast with ErrBreakerOpen
+	// while redis is unavailable, see Breaker. It applies to all operations
+	// of the client which retry to obtain locks, as well as to Semaphore,
+	// RWLock and RateLimiter.Wait if passed to their constructors. A Breaker
+	// may be shared by several clients.
+	Breaker *Breaker
+
+	// DryRun shadows locking in an existing system before it is enforced:
+	// Obtain, ObtainWith and the helpers built on them validate their
+	// arguments and make a single attempt to obtain a shadow lock under the
+	// lock key + ":dryrun", but return a lock whether or not it is
+	// contended, without ever writing the lock key itself. Metrics, logs
+	// and traces report the outcome of the attempt, so contended attempts
+	// are recorded as ErrNotObtained. Locks obtained on a contended shadow
+	// key do not talk to redis at all, their operations always succeed.
+	// The attempt is not retried. Shadow locks ignore CoalesceLocal,
+	// RecordStats and Audit, as well as Options.Group, AcquireIf,
+	// HolderDetails, Fencing, Scripts, release signals and notifications.
+	// Options.DryRun enables it per call.
+	DryRun bool
+
+	// Advisory rolls out locking gradually: Obtain, ObtainWith and the
+	// helpers built on them always obtain advisory locks, which do not
+	// exclude each other, instead of waiting for other holders. Holders are
+	// tracked in a sorted set under the lock key + ":advisory". Obtaining an
+	// advisory lock while others hold it counts as a conflict, which is
+	// logged, recorded under the lock key + ":conflicts", reported by
+	// Lock.Conflicts and Inspect, and reported to Metrics if it implements
+	// ConflictMetricsCollector. Advisory locks are neither seen by nor
+	// exclude exclusive locks, they can be refreshed and released like
+	// shared locks, but ignore CoalesceLocal, RecordStats and Audit, as well
+	// as Options.Group, AcquireIf, HolderDetails, Fencing, Scripts, release
+	// signals and notifications. Options.Advisory enables it per call.
+	Advisory bool
+
+	// Hooks intercept Obtain, ObtainWith and the helpers built on them, as
+	// well as Release and ReleaseWithInfo of all locks obtained by the
+	// client, in order, see Hook.
+	Hooks []Hook
+
+	// Quota caps the number of locks held under key prefixes, see Quota. It
+	// applies to Obtain, ObtainWith and the helpers built on them, unless
+	// Options.Scripts replace the obtain script. Limited locks do not
+	// support RecordStats, release signals or notifications, IdempotencyToken
+	// and AcquireIf.
+	Quota *Quota
+
+	// StrictExpiry makes refreshes and releases of expired locks return an
+	// *ExpiredError, which reports how long ago the lock expired according
+	// to its local expiry estimate, instead of ErrLockExpired. Locks which
+	// are not held past their estimated expiry are reported as expired, too,
+	// rather than as ErrNotObtained or ErrLockNotHeld. If Metrics implements
+	// ExpiredMetricsCollector, it is notified of each expired lock.
+	StrictExpiry bool
+
+	// Starvation detects keys which callers keep failing to obtain, and
+	// reports them to its callback and to Metrics, if it implements
+	// StarvationMetricsCollector, see Starvation. It applies to all
+	// operations of the client which retry to obtain locks, as well as to
+	// Semaphore, RWLock and RateLimiter.Wait if passed to their
+	// constructors. A Starvation may be shared by several clients.
+	Starvation *Starvation
+
+	// Migration keeps exclusive locks compatible with other versions of the
+	// package during rolling deploys which change the format of lock
+	// values, see Migration.
+	Migration *Migration
+
+	// Intents records the keys and tokens of exclusive locks obtained or
+	// resumed by the client, so the process can re-attach or release them
+	// via Client.Recover after a crash, see Intents.
+	Intents *Intents
+}
+
+// MetricsCollector receives lock metrics, see Defaults.Metrics. It must be
+// safe for concurrent use.
+type MetricsCollector interface {
+	// ObtainAttempt is called before each attempt to obtain a lock. The
+	// first attempt of each call is not a retry.
+	ObtainAttempt(key string, retry bool)
+
+	// ObtainDone is called once a call to obtain a lock has finished, with
+	// the time spent waiting. err is nil if the lock was obtained.
+	ObtainDone(key string, wait time.Duration, err error)
+
+	// RefreshDone is called after each refresh. err is nil on success.
+	RefreshDone(key string, err error)
+
+	// Released is called after a lock was released, with the time it was
+	// held for.
+	Released(key string, held time.Duration)
+}
+
+// HeldMetricsCollector is an optional extension of MetricsCollector, e.g. to
+// maintain a gauge of held locks, see Defaults.Register.
+type HeldMetricsCollector interface {
+	// HeldChanged is called with a delta of 1 when a registered lock was
+	// obtained, and with -1 once it is known to be lost, see Lock.Done.
+	HeldChanged(key string, delta int)
+}
+
+// New creates a new Client instance with a custom namespace. If defaults are
+// given, the last one applies to all locks obtained by the client.
+func New(client RedisClient, defaults ...Defaults) *Client {
+	c := &Client{client: client}
+	if n := len(defaults); n > 0 {
+		c.defaults = defaults[n-1]
+	}
+	cc, cluster := client.(*redis.ClusterClient)
+	if cluster {
+		c.client = clusterClient{cc}
+	}
+	_, ring := client.(*redis.Ring)
+	c.hashTags = c.defaults.HashTags || cluster || ring
+	if c.defaults.CoalesceLocal {
+		c.local = &localLocks{slots: make(map[string]*localSlot)}
+	}
+	if c.defaults.Compat == CompatNone {
+		c.ownerTag = encodeOwnerTag(c.defaults.Owner)
+	}
+	c.routes = newRoutes(c.defaults.Routes)
+	return c
+}
+
+// Close closes the internal clients created for Options.SelectDB and
+// Route.DB. It does not close the client passed to New, nor those of
+// Defaults.Routes.
+func (c *Client) Close() error {
+	c.dbsMu.Lock()
+	defer c.dbsMu.Unlock()
+
+	var err error
+	for db, client := range c.dbs {
+		if e := client.Close(); e != nil && err == nil {
+			err = e
+		}
+		delete(c.dbs, db)
+	}
+	return err
+}
+
+// Obtain tries to obtain a new lock using a key with the given TTL.
+// May return ErrNotObtained if not successful.
+func (c *Client) Obtain(ctx context.Context, key string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	opt, lockTTL, err := c.withProfile(key, opt, lockTTL)
+	if err != nil {
+		return nil, err
+	}
+	lockTTL = opt.ttlFromContext(ctx, lockTTL)
+	if err := c.validate(ctx, key, lockTTL); err != nil {
+		return nil, err
+	}
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	lock, err := c.obtainLock(deadlinectx, key, lockTTL, opt)
+	if err != nil {
+		return nil, err
+	}
+	return lock.bind(ctx, opt), nil
+}
+
+// obtainLock retries to obtain the lock until ctx is done.
+func (c *Client) obtainLock(ctx context.Context, key string, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	if len(c.defaults.Hooks) != 0 {
+		return c.obtainHooked(ctx, key, lockTTL, opt)
+	}
+	return c.obtainUnhooked(ctx, key, lockTTL, opt)
+}
+
+func (c *Client) obtainUnhooked(ctx context.Context, key string, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	if c.defaults.DryRun || opt.getDryRun() {
+		return c.obtainDryRun(ctx, key, lockTTL, opt)
+	}
+	if c.defaults.Advisory || opt.getAdvisory() {
+		return c.obtainAdvisory(ctx, key, lockTTL, opt)
+	}
+	if c.local != nil {
+		return c.obtainLocal(ctx, key, lockTTL, opt)
+	}
+	return c.obtainRemote(ctx, key, lockTTL, opt)
+}
+
+func (c *Client) obtainRemote(ctx context.Context, key string, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	opt = c.options(opt)
+	lockTTL = c.lockTTL(lockTTL)
+	key = c.defaults.KeyPrefix + key
+
+	rdb, err := c.clientFor(key, opt.getSelectDB())
+	if err != nil {
+		return nil, err
+	}
+	if opt.getGroup() != "" {
+		return c.obtainGroup(ctx, rdb, key, lockTTL, opt)
+	}
+
+	token, value, err := c.newValue(opt)
+	if err != nil {
+		return nil, err
+	}
+	epochs := opt.getEpoch() && c.defaults.Compat == CompatNone
+	if epochs {
+		value = withEpoch(token, value, 1)
+	}
+
+	var holder, expiry *NotObtainedError
+	if opt.getHolderDetails() || opt.getCapBackoffAtTTL() {
+		holder = &NotObtainedError{Key: key}
+	}
+	if opt.getCapBackoffAtTTL() {
+		expiry = holder
+	}
+
+	var start time.Time
+	var fence int64
+	unstamped := value
+	stats, err := c.retryHolder(ctx, rdb, key, token, opt, true, expiry, func(ctx context.Context) (bool, error) {
+		if holder != nil {
+			holder.TTL, holder.value = 0, ""
+		}
+		start = time.Now()
+		var ok bool
+		var err error
+		if opt.getSequence() {
+			var seq int64
+			if seq, err = c.obtainSequence(ctx, rdb, key, token, unstamped, lockTTL, opt.getOperationTimeout()); seq > 0 {
+				ok, value = true, withSequence(token, unstamped, seq)
+			}
+		} else {
+			ok, err = c.obtain(ctx, rdb, key, value, lockTTL, opt, holder)
+		}
+		if err != nil || !ok || !opt.getFencing() {
+			return ok, err
+		}
+
+		fence, err = c.fence(ctx, rdb, key, value, opt.getOperationTimeout())
+		return fence > 0, err
+	})
+	if err != nil {
+		var e *Error
+		if errors.Is(err, ErrNotObtained) && opt.getHolderDetails() && errors.As(err, &e) {
+			e.Err = holder
+			return nil, e
+		}
+		return nil, err
+	}
+
+	effectiveTTL := lockTTL - time.Since(start)
+	if effectiveTTL < 0 {
+		effectiveTTL = 0
+	}
+
+	lock := &Lock{
+		client:       c,
+		rdb:          rdb,
+		key:          key,
+		token:        token,
+		value:        value,
+		ttl:          lockTTL,
+		effectiveTTL: effectiveTTL,
+		fence:        fence,
+		obtained:     start,
+		expires:      start.Add(lockTTL),
+		opTimeout:    opt.getOperationTimeout(),
+		logger:       opt.getLogger(),
+		scripts:      exclusiveScripts,
+		scriptArg:    value,
+		stats:        stats,
+		audited:      c.defaults.Audit != nil,
+		minHold:      opt.getMinHold(),
+	}
+	lock.keyBuf[0] = key
+	lock.scriptKeys = lock.keyBuf[:]
+	c.setScripts(lock, opt)
+	lock.epochs = epochs && (lock.scripts == exclusiveScripts || lock.scripts == signalScripts || lock.scripts == notifyScripts)
+	lock.audit(ctx, AuditObtain, lockTTL)
+	return c.track(lock), nil
+}
+
+// setScripts selects the scripts of an exclusive lock obtained with opt.
+func (c *Client) setScripts(lock *Lock, opt *Options) {
+	if c.defaults.Compat == CompatRedisson {
+		lock.scripts = redissonScripts
+	} else if scripts := opt.getScripts(); scripts != nil {
+		lock.scripts = scripts.lockScripts()
+		lock.scriptKeys = append([]string{lock.key}, scripts.Keys...)
+	} else if opt.getHeartbeat() {
+		lock.scripts = heartbeatScripts
+		lock.scriptKeys = []string{lock.key, c.heartbeatKey(lock.key)}
+	} else if quotaKey, _ := c.quotaKey(lock.key); quotaKey != "" {
+		lock.scripts = quotaScripts
+		lock.scriptKeys = []string{lock.key, quotaKey}
+	} else if opt.getReleaseNotify() {
+		lock.scripts = notifyScripts
+	} else if opt.getReleaseSignal() {
+		lock.scripts = signalScripts
+		lock.scriptKeys = []string{lock.key, c.signalKey(lock.key)}
+	}
+	if c.defaults.RecordStats && lock.scripts.releaseStats != nil {
+		lock.statsKey = c.statsKey(lock.key)
+	}
+}
+
+// options resolves per-call options against the client defaults.
+func (c *Client) options(opt *Options) *Options {
+	if c.defaults.RetryStrategy == nil && c.defaults.Metadata == "" && c.defaults.Logger == nil && c.defaults.Scripts == nil && c.defaults.OperationTimeout == 0 {
+		if opt == nil {
+			return &defaultOptions
+		}
+		return opt
+	}
+
+	var o Options
+	if opt != nil {
+		o = *opt
+	}
+	if o.RetryStrategy == nil && c.defaults.RetryStrategy != nil {
+		o.RetryStrategy = c.defaults.RetryStrategy()
+	}
+	if o.Metadata == "" {
+		o.Metadata = c.defaults.Metadata
+	}
+	if o.Logger == nil {
+		o.Logger = c.defaults.Logger
+	}
+	if o.Scripts == nil {
+		o.Scripts = c.defaults.Scripts
+	}
+	if o.OperationTimeout <= 0 {
+		o.OperationTimeout = c.defaults.OperationTimeout
+	}
+	return &o
+}
+
+// lockTTL returns ttl or the default TTL if ttl is zero.
+func (c *Client) lockTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 && c.defaults.TTL > 0 {
+		return c.defaults.TTL
+	}
+	return ttl
+}
+
+// validate checks the arguments of an obtain call, wrapping any violation in
+// an Error.
+func (c *Client) validate(ctx context.Context, key string, ttl time.Duration) error {
+	var err error
+	if ctx == nil {
+		err = ErrNilContext
+	} else if key == "" {
+		err = ErrInvalidKey
+	} else if c.lockTTL(ttl) < time.Millisecond {
+		err = ErrInvalidTTL
+	}
+	if err != nil {
+		return &Error{Op: "obtain", Key: c.defaults.KeyPrefix + key, Err: err}
+	}
+	return nil
+}
+
+// retry calls try until it succeeds, the retry strategy gives up or ctx is
+// done, in which case it returns ErrNotObtained. On success, it returns the
+// number of attempts and the time spent. If notify is set and rdb
+// supports it, it is woken by the release notifications or signals configured
+// in opt instead of sleeping through the whole backoff. Errors returned by
+// try are retried if accepted by opt.RetryOnError. Before each wait,
+// opt.OnRetry may abort.
+func (c *Client) retry(ctx context.Context, rdb RedisClient, key, token string, opt *Options, notify bool, try func(context.Context) (bool, error)) (LockStats, error) {
+	return c.retryHolder(ctx, rdb, key, token, opt, notify, nil, try)
+}
+
+// retryHolder is like retry, but caps each backoff just past holder.TTL, as
+// updated by the failed attempt, if holder is set.
+func (c *Client) retryHolder(ctx context.Context, rdb RedisClient, key, token string, opt *Options, notify bool, holder *NotObtainedError, try func(context.Context) (bool, error)) (stats LockStats, err error) {
+	var attempts int
+	var stopped StopReason
+	defer func() {
+		if err != nil {
+			err = &Error{Op: "obtain", Key: key, Err: err, Attempts: attempts, Stopped: stopped}
+		}
+	}()
+
+	retry := opt.getRetryStrategy()
+	retryOnError := opt.getRetryOnError()
+	onRetry := opt.getOnRetry()
+	maxElapsed := opt.getMaxElapsed()
+	releasingBackoff := opt.getReleasingBackoff()
+	logger := opt.getLogger()
+	short := shortToken(token)
+
+	clock := c.clock()
+	began := clock.Now()
+	metrics := c.defaults.Metrics
+	if metrics != nil {
+		defer func() { metrics.ObtainDone(key, clock.Now().Sub(began), err) }()
+	}
+	if token != "" && (c.defaults.Manager != nil || c.defaults.Register) {
+		defer c.trackWait(key, token)()
+	}
+	if c.defaults.Starvation != nil {
+		defer func() {
+			now := clock.Now()
+			c.starvationDone(key, now, now.Sub(began), err)
+		}()
+	}
+
+	if sctx, span := c.startSpan(ctx, "redislock.obtain", key); span != nil {
+		ctx = sctx
+		defer func() {
+			span.SetAttempts(attempts)
+			endSpan(span, err, "not_obtained")
+		}()
+	}
+
+	var blocker BlockingClient
+	var subscriber SubscribingClient
+	if notify && opt.getReleaseNotify() {
+		subscriber, _ = rdb.(SubscribingClient)
+	} else if notify && opt.getReleaseSignal() {
+		blocker, _ = rdb.(BlockingClient)
+	}
+
+	var timer Timer
+	var sub *redis.PubSub
+	var released <-chan *redis.Message
+	breaker := c.defaults.Breaker
+	for attempt := 1; ; attempt++ {
+		if breaker != nil && !breaker.allow(clock.Now()) {
+			if logger != nil {
+				logger.Log(LevelError, "obtain failed", "key", key, "token", short, "attempt", attempt, "error", ErrBreakerOpen)
+			}
+			return stats, ErrBreakerOpen
+		}
+
+		attempts = attempt
+		if logger != nil {
+			logger.Log(LevelDebug, "obtain attempt", "key", key, "token", short, "attempt", attempt)
+		}
+		if metrics != nil {
+			metrics.ObtainAttempt(key, attempt > 1)
+		}
+
+		ok, err := try(ctx)
+		if breaker != nil {
+			breaker.done(ctx, clock.Now(), err)
+		}
+		if err != nil && ctx.Err() != nil {
+			if logger != nil {
+				logger.Log(LevelInfo, "lock not obtained", "key", key, "token", short, "attempt", attempt)
+			}
+			stopped = StopContext
+			return stats, ErrNotObtained
+		} else if err != nil && (retryOnError == nil || !retryOnError(err)) {
+			if logger != nil {
+				logger.Log(LevelError, "obtain failed", "key", key, "token", short, "attempt", attempt, "error", err)
+			}
+			stopped = StopError
+			return stats, err
+		} else if ok {
+			if logger != nil {
+				logger.Log(LevelInfo, "lock obtained", "key", key, "token", short, "attempt", attempt)
+			}
+			return LockStats{Attempts: attempt, Wait: clock.Now().Sub(began)}, nil
+		} else if err != nil && logger != nil {
+			logger.Log(LevelWarn, "obtain failed, retrying", "key", key, "token", short, "attempt", attempt, "error", err)
+		}
+
+		backoff := retry.NextBackoff()
+		if holder != nil && err == nil && holder.TTL > 0 && holder.TTL < backoff {
+			backoff = holder.TTL + time.Millisecond
+		}
+		if backoff < 1 {
+			stopped = stopReason(retry)
+		}
+		if backoff < 1 && err != nil {
+			if logger != nil {
+				logger.Log(LevelError, "obtain failed", "key", key, "token", short, "attempt", attempt, "error", err)
+			}
+			return stats, err
+		} else if backoff < 1 {
+			if logger != nil {
+				logger.Log(LevelInfo, "lock not obtained", "key", key, "token", short, "attempt", attempt)
+			}
+			return stats, ErrNotObtained
+		}
+		if releasingBackoff > 0 && err == nil && releasingBackoff < backoff && c.releasing(ctx, rdb, key, opt.getOperationTimeout()) {
+			backoff = releasingBackoff
+		}
+		if deadline, ok := ctx.Deadline(); ok && blocker == nil && subscriber == nil && time.Until(deadline) < backoff {
+			if logger != nil {
+				logger.Log(LevelInfo, "lock not obtained", "key", key, "token", short, "attempt", attempt)
+			}
+			stopped = StopContext
+			return stats, ErrNotObtained
+		}
+		if maxElapsed > 0 && clock.Now().Sub(began)+backoff > maxElapsed {
+			if logger != nil {
+				logger.Log(LevelInfo, "lock not obtained", "key", key, "token", short, "attempt", attempt)
+			}
+			stopped = StopElapsed
+			if err != nil {
+				return stats, err
+			}
+			return stats, ErrNotObtained
+		}
+		if onRetry != nil && !onRetry(attempt, backoff) {
+			if logger != nil {
+				logger.Log(LevelInfo, "lock not obtained, retry aborted", "key", key, "token", short, "attempt", attempt)
+			}
+			stopped = StopAborted
+			if err != nil {
+				return stats, err
+			}
+			return stats, ErrNotObtained
+		}
+		if logger != nil {
+			logger.Log(LevelDebug, "obtain backoff", "key", key, "token", short, "attempt", attempt, "backoff", backoff)
+		}
+
+		if blocker != nil {
+			if err := c.waitSignal(ctx, blocker, key, backoff); err != nil {
+				if logger != nil {
+					logger.Log(LevelInfo, "lock not obtained", "key", key, "token", short, "attempt", attempt)
+				}
+				if ctx.Err() != nil {
+					stopped = StopContext
+				}
+				return stats, err
+			}
+			continue
+		}
+
+		if subscriber != nil && sub == nil {
+			if sub, err = c.subscribe(ctx, subscriber, rdb, key); err != nil {
+				if logger != nil {
+					logger.Log(LevelWarn, "release notifications unavailable", "key", key, "token", short, "error", err)
+				}
+				subscriber = nil
+			} else {
+				defer sub.Close()
+				released = sub.Channel()
+			}
+		}
+
+		if timer == nil {
+			timer = clock.NewTimer(backoff)
+			defer timer.Stop()
+		} else {
+			if !timer.Stop() {
+				select {
+				case <-timer.C():
+				default:
+				}
+			}
+			timer.Reset(backoff)
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				if logger != nil {
+					logger.Log(LevelInfo, "lock not obtained", "key", key, "token", short, "attempt", attempt)
+				}
+				stopped = StopContext
+				return stats, ErrNotObtained
+			case <-timer.C():
+				break wait
+			case msg := <-released:
+				if isReleaseMessage(msg) {
+					break wait
+				}
+			}
+		}
+	}
+}
+
+// subscribe subscribes to the release channel of key and, if the DB of rdb is
+// known, to the keyspace notifications of key. The latter are only delivered
+// if enabled on the server via notify-keyspace-events, and are skipped if
+// NewDetect found them disabled.
+func (c *Client) subscribe(ctx context.Context, subscriber SubscribingClient, rdb RedisClient, key string) (*redis.PubSub, error) {
+	channels := []string{releasedChannel(key)}
+	if client, ok := rdb.(*redis.Client); ok && c.keyspaceNotifications() {
+		channels = append(channels, "__keyspace@"+strconv.Itoa(client.Options().DB)+"__:"+key)
+	}
+
+	sub := subscriber.Subscribe(ctx, channels...)
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+func isReleaseMessage(msg *redis.Message) bool {
+	return strings.HasSuffix(msg.Channel, ":released") || msg.Payload == "del" || msg.Payload == "expired"
+}
+
+func releasedChannel(key string) string {
+	return key + ":released"
+}
+
+// waitSignal blocks until the lock is released by its holder or the timeout is
+// reached. The timeout is rounded up to whole seconds.
+func (c *Client) waitSignal(ctx context.Context, blocker BlockingClient, key string, timeout time.Duration) error {
+	if rem := timeout % time.Second; rem != 0 {
+		timeout += time.Second - rem
+	}
+
+	err := blocker.BLPop(ctx, timeout, c.signalKey(key)).Err()
+	if ctx.Err() != nil {
+		return ErrNotObtained
+	} else if err != nil && err != redis.Nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) signalKey(key string) string {
+	return c.companionKey(key, ":signal")
+}
+
+// ObtainResult is the outcome of an asynchronous lock acquisition.
+type ObtainResult struct {
+	Lock *Lock
+	Err  error
+}
+
+// ObtainAsync is a non-blocking variant of Obtain. It attempts to obtain the
+// lock in the background and delivers exactly one result before closing the
+// returned channel. If ctx is cancelled before the lock is obtained, the
+// context error is delivered.
+func (c *Client) ObtainAsync(ctx context.Context, key string, waitTimeout, lockTTL time.Duration, opt *Options) <-chan ObtainResult {
+	ch := make(chan ObtainResult, 1)
+	go func() {
+		defer close(ch)
+
+		lock, err := c.Obtain(ctx, key, waitTimeout, lockTTL, opt)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if lock != nil {
+				_ = lock.Release(context.Background())
+			}
+			lock, err = nil, ctxErr
+		}
+		ch <- ObtainResult{Lock: lock, Err: err}
+	}()
+	return ch
+}
+
+// obtain makes a single attempt to obtain the lock. If holder is set, it is
+// updated with the current holder if the key is already locked.
+func (c *Client) obtain(ctx context.Context, rdb RedisClient, key, value string, ttl time.Duration, opt *Options, holder *NotObtainedError) (bool, error) {
+	opTimeout := opt.getOperationTimeout()
+
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	var ok bool
+	var err error
+	reentrant := opt.getIdempotencyToken() != ""
+	if c.defaults.Compat == CompatRedisson {
+		status, err := luaRedissonObtain.Run(opctx, rdb, []string{key}, value, msArg(ttl)).Result()
+		return status == int64(1), wrapOperationErr(ctx, opctx, err)
+	} else if scripts := opt.getScripts(); scripts != nil && scripts.Obtain != nil {
+		ok, err = scripts.obtainScript(opctx, rdb, key, value, ttl)
+	} else if opt.getHeartbeat() {
+		ok, err = c.obtainHeartbeat(opctx, rdb, key, value, ttl)
+	} else if quotaKey, limit := c.quotaKey(key); quotaKey != "" {
+		ok, err = c.obtainQuota(opctx, rdb, key, quotaKey, value, ttl, limit)
+	} else if c.defaults.RecordStats {
+		ok, err = c.obtainStats(opctx, rdb, key, value, ttl)
+		if err == nil && !ok && holder != nil {
+			ok, err = c.obtainInspect(opctx, rdb, key, value, ttl, holder)
+		}
+	} else if reentrant && holder == nil {
+		ok, err = c.obtainOrReobtain(opctx, rdb, key, value, ttl)
+		reentrant = false
+	} else if piper, isPiper := rdb.(PipeliningClient); isPiper && holder != nil && atomic.LoadInt32(&c.noSetGet) == 0 {
+		ok, err = c.obtainGet(opctx, piper, rdb, key, value, ttl, holder)
+	} else {
+		ok, err = c.obtainSetNX(opctx, rdb, key, value, ttl, holder)
+	}
+	if err != nil || ok {
+		return ok, wrapOperationErr(ctx, opctx, err)
+	}
+
+	if reentrant {
+		if ok, err := c.reobtain(ctx, rdb, key, value, ttl, opTimeout); err != nil || ok {
+			return ok, err
+		}
+	}
+
+	if acquireIf := opt.getAcquireIf(); acquireIf != nil {
+		return c.obtainIf(ctx, rdb, key, value, ttl, opTimeout, acquireIf)
+	}
+	return false, nil
+}
+
+// obtainSetNX tries a plain SET NX first, so the uncontended case needs no
+// script. If holder is set, it is updated via obtainInspect on failure.
+func (c *Client) obtainSetNX(ctx context.Context, rdb RedisClient, key, value string, ttl time.Duration, holder *NotObtainedError) (bool, error) {
+	ok, err := rdb.SetNX(ctx, key, value, ttl).Result()
+	if err == nil && !ok && holder != nil {
+		return c.obtainInspect(ctx, rdb, key, value, ttl, holder)
+	}
+	return ok, err
+}
+
+// obtainGet is like obtainSetNX, but learns the holder in the same round trip
+// by pipelining SET NX GET, supported since redis 7, with PTTL. On older
+// servers, it falls back to obtainSetNX for this and all future attempts.
+func (c *Client) obtainGet(ctx context.Context, piper PipeliningClient, rdb RedisClient, key, value string, ttl time.Duration, holder *NotObtainedError) (bool, error) {
+	pipe := piper.Pipeline()
+	set := pipe.Do(ctx, "set", key, value, "px", int64(ttl/time.Millisecond), "nx", "get")
+	pttl := pipe.PTTL(ctx, key)
+	_, _ = pipe.Exec(ctx)
+
+	current, err := set.Text()
+	if err == redis.Nil {
+		return true, nil
+	} else if err != nil {
+		if strings.HasPrefix(err.Error(), "ERR syntax error") {
+			atomic.StoreInt32(&c.noSetGet, 1)
+			return c.obtainSetNX(ctx, rdb, key, value, ttl, holder)
+		}
+		return false, err
+	}
+
+	_, md := splitValue(current)
+	holder.Metadata = c.decryptMetadata(md)
+	holder.TTL, holder.value = 0, current
+	if d := pttl.Val(); d > 0 {
+		holder.TTL = d
+	}
+	return false, nil
+}
+
+// obtainInspect is like SETNX, but records the current holder if the key is
+// already locked.
+func (c *Client) obtainInspect(ctx context.Context, rdb RedisClient, key, value string, ttl time.Duration, holder *NotObtainedError) (bool, error) {
+	res, err := luaObtainInspect.Run(ctx, rdb, []string{key}, value, msArg(ttl)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if res == int64(1) {
+		return true, nil
+	}
+
+	vals, _ := res.([]interface{})
+	if len(vals) != 2 {
+		return false, nil
+	}
+
+	current, _ := vals[0].(string)
+	pttl, _ := vals[1].(int64)
+
+	_, md := splitValue(current)
+	holder.Metadata = c.decryptMetadata(md)
+	holder.TTL, holder.value = 0, current
+	if pttl > 0 {
+		holder.TTL = time.Duration(pttl) * time.Millisecond
+	}
+	return false, nil
+}
+
+// reobtain re-acquires the lock if key is already held with value. It resets
+// the TTL as if the lock was obtained fresh.
+func (c *Client) reobtain(ctx context.Context, rdb RedisClient, key, value string, ttl, opTimeout time.Duration) (bool, error) {
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	status, err := luaRefresh.Run(opctx, rdb, []string{key}, value, msArg(ttl)).Result()
+	if err != nil {
+		return false, wrapOperationErr(ctx, opctx, err)
+	}
+	return status == int64(1), nil
+}
+
+// obtainOrReobtain obtains the lock if free, or re-acquires it if it is held
+// with value, in a single step.
+func (c *Client) obtainOrReobtain(ctx context.Context, rdb RedisClient, key, value string, ttl time.Duration) (bool, error) {
+	status, err := luaObtainOrReobtain.Run(ctx, rdb, []string{key}, value, msArg(ttl)).Result()
+	if err != nil {
+		return false, err
+	}
+	return status == int64(1), nil
+}
+
+// fence increments the fencing counter of key if the lock is still held with
+// value. It returns 0 if the lock was lost in the meantime.
+func (c *Client) fence(ctx context.Context, rdb RedisClient, key, value string, opTimeout time.Duration) (int64, error) {
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	fence, err := luaFence.Run(opctx, rdb, []string{key, c.fenceKey(key)}, value).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	} else if err != nil {
+		return 0, wrapOperationErr(ctx, opctx, err)
+	}
+	return fence, nil
+}
+
+func (c *Client) fenceKey(key string) string {
+	return c.companionKey(key, ":fence")
+}
+
+// obtainIf replaces the current value of key if acquireIf accepts the
+// current holder's metadata and the holder has not changed in the meantime.
+func (c *Client) obtainIf(ctx context.Context, rdb RedisClient, key, value string, ttl, opTimeout time.Duration, acquireIf func(string) bool) (bool, error) {
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	current, err := luaGet.Run(opctx, rdb, []string{key}).Text()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, wrapOperationErr(ctx, opctx, err)
+	}
+
+	if _, metadata := splitValue(current); !acquireIf(c.decryptMetadata(metadata)) {
+		return false, nil
+	}
+
+	ttlVal := strconv.FormatInt(int64(ttl/time.Millisecond), 10)
+	err = luaReplace.Run(opctx, rdb, []string{key}, current, value, ttlVal).Err()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, wrapOperationErr(ctx, opctx, err)
+	}
+	return true, nil
+}
+
+// clientFor returns the redis client for the DB selected by selectDB, or
+// the client selected by Defaults.Routes if selectDB is nil.
+func (c *Client) clientFor(key string, selectDB func(string) int) (RedisClient, error) {
+	if selectDB == nil {
+		return c.route(key)
+	}
+	return c.dbClient(selectDB(key))
+}
+
+// dbClient returns the redis client for db, a logical DB of the client passed
+// to New.
+func (c *Client) dbClient(db int) (RedisClient, error) {
+	base, ok := c.client.(*redis.Client)
+	if !ok {
+		return nil, errSelectDBUnsupported
+	}
+
+	if db == base.Options().DB {
+		return base, nil
+	}
+
+	c.dbsMu.Lock()
+	defer c.dbsMu.Unlock()
+
+	if client, ok := c.dbs[db]; ok {
+		return client, nil
+	}
+
+	opt := *base.Options()
+	opt.DB = db
+	client := redis.NewClient(&opt)
+
+	if c.dbs == nil {
+		c.dbs = make(map[int]*redis.Client)
+	}
+	c.dbs[db] = client
+	return client, nil
+}
+
+// newToken returns the idempotency token, if given, or a generated token.
+func (c *Client) newToken(opt *Options) (string, error) {
+	if token := opt.getIdempotencyToken(); token != "" {
+		return token, nil
+	}
+	if generate := opt.getTokenGenerator(); generate != nil {
+		return generate()
+	}
+	if c.defaults.Compat != CompatNone {
+		return compatToken(c.defaults.Compat)
+	}
+	return RandomToken()
+}
+
+// newValue generates the token of a lock obtained with opt and returns it
+// along with the lock value, i.e. the token followed by the owner tag and
+// the metadata, or the token only in compatibility mode. Random tokens are
+// generated right into the value, which then takes a single allocation.
+func (c *Client) newValue(opt *Options) (token, value string, err error) {
+	metadata, err := c.encryptMetadata(opt.getMetadata())
+	if err != nil {
+		return "", "", err
+	}
+	if c.defaults.Compat != CompatNone || opt.getIdempotencyToken() != "" || opt.getTokenGenerator() != nil {
+		if token, err = c.newToken(opt); err != nil {
+			return "", "", err
+		} else if c.defaults.Compat != CompatNone {
+			return token, token, nil
+		}
+		return token, token + c.valueTag() + metadata, nil
+	}
+
+	var buf [randomTokenLen]byte
+	if err := readRandomToken(&buf); err != nil {
+		return "", "", err
+	}
+
+	tag := c.valueTag()
+	var b strings.Builder
+	b.Grow(randomTokenLen + len(tag) + len(metadata))
+	b.Write(buf[:])
+	b.WriteString(tag)
+	b.WriteString(metadata)
+	value = b.String()
+	return value[:randomTokenLen], value, nil
+}
+
+// RandomToken returns a 22 character token of 128 random bits from
+// crypto/rand. It is the default Options.TokenGenerator.
+func RandomToken() (string, error) {
+	var buf [randomTokenLen]byte
+	if err := readRandomToken(&buf); err != nil {
+		return "", err
+	}
+	return string(buf[:]), nil
+}
+
+// randomBufs pools the buffers random bytes are read into, which would
+// otherwise escape to the heap via rand.Reader.
+var randomBufs = sync.Pool{New: func() interface{} { return new([16]byte) }}
+
+// readRandomToken generates a random token, see RandomToken, into dst.
+func readRandomToken(dst *[randomTokenLen]byte) error {
+	buf := randomBufs.Get().(*[16]byte)
+	defer randomBufs.Put(buf)
+
+	if _, err := io.ReadFull(rand.Reader, buf[:]); err != nil {
+		return err
+	}
+	base64.RawURLEncoding.Encode(dst[:], buf[:])
+	return nil
+}
+
+// MarshalMetadata encodes v as JSON for use as Options.Metadata or with
+// Resume. It can be decoded via Lock.UnmarshalMetadata and
+// LockInfo.UnmarshalMetadata.
+func MarshalMetadata(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// encodeMetadataMap encodes m as a JSON object with sorted keys.
+func encodeMetadataMap(m map[string]string) string {
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// decodeMetadataMap decodes metadata encoded by encodeMetadataMap, it returns
+// nil if metadata is not a JSON object.
+func decodeMetadataMap(metadata string) map[string]string {
+	if !strings.HasPrefix(metadata, "{") {
+		return nil
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal([]byte(metadata), &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// randomTokenLen is the length of tokens generated by RandomToken.
+const randomTokenLen = 22
+
+// splitValue splits a lock value into the token and metadata, assuming a
+// random token.
+func splitValue(value string) (token, metadata string) {
+	if len(value) > randomTokenLen {
+		_, metadata = splitOwner(value[randomTokenLen:])
+		return value[:randomTokenLen], metadata
+	}
+	return value, ""
+}
+
+// --------------------------------------------------------------------
+
+// lockScripts are the scripts used to manage an obtained lock. Each script
+// receives the lock's script keys and argument (value or token) as KEYS and
+// ARGV[1], refresh receives the TTL in milliseconds as ARGV[2]. Extend
+// receives the extension and the maximum TTL in milliseconds as ARGV[2] and
+// ARGV[3] and returns the new TTL.
+type lockScripts struct {
+	pttl, refresh, extend, release *redis.Script
+
+	// releaseTTL passes the lock TTL in milliseconds as ARGV[2] on release.
+	releaseTTL bool
+
+	// releaseStats is release, additionally recording the hold time in the
+	// statistics of the key, see Defaults.RecordStats.
+	releaseStats *redis.Script
+
+	// unlinkRelease and unlinkReleaseStats are release and releaseStats,
+	// deleting keys via UNLINK, see Defaults.Unlink.
+	unlinkRelease, unlinkReleaseStats *redis.Script
+}
+
+var (
+	exclusiveScripts = &lockScripts{pttl: luaPTTL, refresh: luaRefresh, extend: luaExtend, release: luaRelease, releaseStats: luaReleaseStats, unlinkRelease: luaUnlinkRelease, unlinkReleaseStats: luaUnlinkReleaseStats}
+	signalScripts    = &lockScripts{pttl: luaPTTL, refresh: luaRefresh, extend: luaExtend, release: luaReleaseSignal, releaseTTL: true, releaseStats: luaReleaseSignalStats, unlinkRelease: luaUnlinkReleaseSignal, unlinkReleaseStats: luaUnlinkReleaseSignalStats}
+	notifyScripts    = &lockScripts{pttl: luaPTTL, refresh: luaRefresh, extend: luaExtend, release: luaReleaseNotify, releaseStats: luaReleaseNotifyStats, unlinkRelease: luaUnlinkReleaseNotify, unlinkReleaseStats: luaUnlinkReleaseNotifyStats}
+	sharedScripts    = &lockScripts{pttl: luaSharedPTTL, refresh: luaSharedRefresh, extend: luaSharedExtend, release: luaSharedRelease}
+)
+
+// LockStats describes how a lock was obtained.
+type LockStats struct {
+	// Attempts is the number of attempts made, including the successful one.
+	Attempts int
+	// Wait is the total time spent obtaining the lock.
+	Wait time.Duration
+	// Obtained is the time the successful attempt was started.
+	Obtained time.Time
+}
+
+// Lock represents an obtained, distributed lock.
+type Lock struct {
+	client       *Client
+	rdb          RedisClient
+	key          string
+	token        string
+	value        string
+	ttl          time.Duration
+	effectiveTTL time.Duration
+	fence        int64
+	opTimeout    time.Duration
+	logger       Logger
+	scripts      *lockScripts
+	statsKey     string
+	audited      bool
+	intent       bool
+	minHold      time.Duration
+	prepared     bool
+	epochs       bool
+	conflicts    int
+
+	// scriptKeys and scriptArg are passed to the scripts. scriptArg is
+	// boxed once, so script calls do not allocate it, keyBuf backs the
+	// scriptKeys of single-key locks.
+	scriptKeys []string
+	scriptArg  interface{}
+	keyBuf     [1]string
+
+	stats     LockStats
+	watchdog  *watchdog
+	obtained  time.Time
+	expires   time.Time
+	expiry    *time.Timer
+	done      chan struct{}
+	doneErr   error
+	releasing bool
+	mu        sync.Mutex
+
+	// argMu guards value and scriptArg, which are replaced by SetMetadata,
+	// for the duration of each operation.
+	argMu sync.RWMutex
+
+	unlockLocal func()
+
+	// parent is the lock of a child lock, children are the child locks
+	// obtained via ObtainChild, guarded by mu. children is not nil once a
+	// child was obtained.
+	parent   *Lock
+	children map[*Lock]struct{}
+}
+
+// Obtain is a short-cut for New(...).Obtain(...).
+func Obtain(ctx context.Context, client RedisClient, key string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	return New(client).Obtain(ctx, key, waitTimeout, lockTTL, opt)
+}
+
+// Key returns the redis key used by the lock. For locks obtained via
+// ObtainMulti, this is the first key.
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Keys returns all redis keys locked by the lock.
+func (l *Lock) Keys() []string {
+	if l.scripts == multiScripts {
+		return append([]string(nil), l.scriptKeys...)
+	}
+	return []string{l.key}
+}
+
+// Token returns the token value set by the lock.
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Metadata returns the metadata of the lock.
+func (l *Lock) Metadata() string {
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	_, md := splitOwner(l.value[len(l.token):])
+	return l.client.decryptMetadata(md)
+}
+
+// MetadataMap returns the metadata of the lock decoded as set via
+// Options.MetadataMap. Returns nil if the metadata is not a JSON object.
+func (l *Lock) MetadataMap() map[string]string {
+	return decodeMetadataMap(l.Metadata())
+}
+
+// UnmarshalMetadata decodes the metadata of the lock, as encoded by
+// MarshalMetadata or set via Options.MetadataMap, into v.
+func (l *Lock) UnmarshalMetadata(v interface{}) error {
+	return json.Unmarshal([]byte(l.Metadata()), v)
+}
+
+// SetMetadata atomically replaces the metadata of the lock, e.g. to publish
+// the progress of a long-running job, without changing its TTL. It is not
+// supported by read locks, semaphores and child locks.
+// May return ErrLockExpired or ErrLockStolen, both of which match
+// ErrLockNotHeld, if the lock is no longer held.
+func (l *Lock) SetMetadata(ctx context.Context, md string) (err error) {
+	defer wrapErr("set metadata", l.key, &err)
+
+	l.argMu.Lock()
+	defer l.argMu.Unlock()
+
+	if l.scriptArg != l.value || l.parent != nil {
+		return errMetadataUnsupported
+	}
+
+	keys := l.scriptKeys[:1]
+	if l.scripts == multiScripts {
+		keys = l.scriptKeys
+	}
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	if md, err = l.client.encryptMetadata(md); err != nil {
+		return err
+	}
+	value := l.token + ownerTag(l.value[len(l.token):]) + md
+	status, err := luaSetMetadata.Run(opctx, l.rdb, keys, l.value, value).Result()
+	if err != nil {
+		return wrapOperationErr(ctx, opctx, err)
+	} else if status != int64(1) {
+		return l.lostBy(status, l.logger, ErrLockNotHeld)
+	}
+
+	l.value, l.scriptArg = value, value
+	return nil
+}
+
+// RequestedTTL returns the TTL requested when the lock was obtained.
+func (l *Lock) RequestedTTL() time.Duration {
+	return l.ttl
+}
+
+// EffectiveTTL returns the validity of the lock at the time it was obtained,
+// i.e. the requested TTL minus the time spent on the successful attempt.
+func (l *Lock) EffectiveTTL() time.Duration {
+	return l.effectiveTTL
+}
+
+// ValidUntil returns the local time until which the lock is known to be held,
+// measured from before the successful obtain or latest refresh was sent, so
+// round-trip time is accounted for. It does not contact redis. Returns the
+// zero time for resumed locks which have not been refreshed yet.
+func (l *Lock) ValidUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.expires
+}
+
+// RemainingLocal returns the time left until ValidUntil, or 0 if it has
+// passed. Unlike TTL, it does not contact redis, so it can be used to check
+// for a safety margin before committing side effects.
+func (l *Lock) RemainingLocal() time.Duration {
+	if d := time.Until(l.ValidUntil()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Stats returns statistics about how the lock was obtained. Attempts and Wait
+// are zero for resumed and converted locks.
+func (l *Lock) Stats() LockStats {
+	stats := l.stats
+	stats.Obtained = l.obtained
+	return stats
+}
+
+// FencingToken returns the fencing token assigned on Obtain, see
+// Options.Fencing. Tokens increase monotonically with each holder of the key,
+// so storage can reject writes carrying a token lower than one already seen.
+// Refreshes with RefreshOptions.BumpFence assign a new token. Returns 0 if fencing
+// was not enabled.
+func (l *Lock) FencingToken() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.fence
+}
+
+// String returns a human-readable representation of the lock, suitable for
+// logging. Only a short prefix of the token is included.
+func (l *Lock) String() string {
+	return "Lock(key=" + l.key + ", token=" + shortToken(l.token) + "..., ttl-hint=" + l.ttl.String() + ")"
+}
+
+// shortToken truncates token for use in logs and string representations.
+func shortToken(token string) string {
+	if len(token) > 6 {
+		return token[:6]
+	}
+	return token
+}
+
+// TTL returns the remaining time-to-live. Returns 0 if the lock has expired.
+func (l *Lock) TTL(ctx context.Context) (_ time.Duration, err error) {
+	defer wrapErr("ttl", l.key, &err)
+
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	res, err := l.activeScripts().pttl.Run(opctx, l.rdb, l.scriptKeys, l.scriptArg).Result()
+	if err == redis.Nil {
+		return 0, nil
+	} else if err != nil {
+		return 0, wrapOperationErr(ctx, opctx, err)
+	}
+
+	if num := res.(int64); num > 0 {
+		return time.Duration(num) * time.Millisecond, nil
+	}
+	return 0, nil
+}
+
+// IsHeld checks atomically whether the lock is still held, i.e. whether its
+// key still contains the lock token. Unlike Err, it contacts redis.
+func (l *Lock) IsHeld(ctx context.Context) (_ bool, err error) {
+	defer wrapErr("check", l.key, &err)
+
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	res, err := l.activeScripts().pttl.Run(opctx, l.rdb, l.scriptKeys, l.scriptArg).Result()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, wrapOperationErr(ctx, opctx, err)
+	}
+	return res.(int64) != -3, nil
+}
+
+// RefreshOptions configure a single Refresh.
+type RefreshOptions struct {
+	// BumpFence assigns the lock a new fencing token from the key:fence
+	// counter, atomically with the refresh, see Lock.FencingToken. Storage
+	// then rejects writes of the lock made before the refresh, e.g. by a
+	// process stalled past an earlier refresh. It is only supported by
+	// exclusive locks.
+	// Default: false
+	BumpFence bool
+}
+
+// Refresh extends the lock with a new TTL. Locks obtained with Options.Epoch
+// move on to the next epoch. If refresh options are given, the last one
+// applies.
+// May return ErrLockExpired, ErrLockStolen or ErrLeaseEpochMismatch, all of
+// which match ErrNotObtained, if refresh is unsuccessful.
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration, opt *Options, ropt ...RefreshOptions) (err error) {
+	defer wrapErr("refresh", l.key, &err)
+
+	if ctx == nil {
+		return ErrNilContext
+	} else if ttl < time.Millisecond {
+		return ErrInvalidTTL
+	}
+	var bumpFence bool
+	if len(ropt) != 0 {
+		bumpFence = ropt[len(ropt)-1].BumpFence
+	}
+	if bumpFence && l.scripts != exclusiveScripts && l.scripts != signalScripts && l.scripts != notifyScripts {
+		return errBumpFenceUnsupported
+	}
+
+	if l.epochs {
+		l.argMu.Lock()
+		defer l.argMu.Unlock()
+	} else {
+		l.argMu.RLock()
+		defer l.argMu.RUnlock()
+	}
+
+	if opt == nil {
+		opt = &defaultOptions
+	}
+	if metrics := l.client.defaults.Metrics; metrics != nil {
+		defer func() { metrics.RefreshDone(l.key, err) }()
+	}
+	if sctx, span := l.client.startSpan(ctx, "redislock.refresh", l.key); span != nil {
+		ctx = sctx
+		defer func() { endSpan(span, err, "lost") }()
+	}
+
+	opTimeout := l.opTimeout
+	if d := opt.getOperationTimeout(); d > 0 {
+		opTimeout = d
+	}
+	logger := l.logger
+	if lg := opt.getLogger(); lg != nil {
+		logger = lg
+	}
+
+	ttlVal := msArg(ttl)
+	refresh := func() (interface{}, error) {
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		if l.epochs {
+			next, prefix := l.epochRefreshArgs()
+			status, err := luaEpochRefresh.Run(opctx, l.rdb, l.scriptKeys, l.scriptArg, ttlVal, next, prefix).Result()
+			if status == int64(1) {
+				l.value, l.scriptArg = next, next
+			}
+			if status == int64(1) && bumpFence {
+				return l.bumpFence(opctx, status)
+			}
+			return status, wrapOperationErr(ctx, opctx, err)
+		}
+
+		if bumpFence {
+			res, err := luaRefreshFence.Run(opctx, l.rdb, []string{l.key, l.client.fenceKey(l.key)}, l.scriptArg, ttlVal).Result()
+			if vals, ok := res.([]interface{}); ok && len(vals) == 2 {
+				l.setFence(vals[1].(int64))
+				res = vals[0]
+			}
+			return res, wrapOperationErr(ctx, opctx, err)
+		}
+
+		status, err := l.activeScripts().refresh.Run(opctx, l.rdb, l.scriptKeys, l.scriptArg, ttlVal).Result()
+		return status, wrapOperationErr(ctx, opctx, err)
+	}
+
+	start := time.Now()
+	status, err := refresh()
+	if err != nil && opt.getRetryOnError() != nil {
+		retry := opt.getRetryStrategy()
+		for err != nil && ctx.Err() == nil && opt.getRetryOnError()(err) {
+			backoff := retry.NextBackoff()
+			if backoff < 1 {
+				break
+			}
+			if logger != nil {
+				logger.Log(LevelWarn, "refresh failed, retrying", "key", l.key, "token", shortToken(l.token), "backoff", backoff, "error", err)
+			}
+			if sleep(ctx, backoff) != nil {
+				break
+			}
+
+			start = time.Now()
+			status, err = refresh()
+		}
+	}
+	if err = l.refreshed(start, ttl, status, err, logger); err != nil {
+		return err
+	}
+	l.audit(ctx, AuditRefresh, ttl)
+	return nil
+}
+
+// bumpFence assigns the lock a new fencing token after a refresh of a lock
+// with epochs, which moved the lock value, and returns status unless the lock
+// was lost in between.
+func (l *Lock) bumpFence(ctx context.Context, status interface{}) (interface{}, error) {
+	fence, err := luaFence.Run(ctx, l.rdb, []string{l.key, l.client.fenceKey(l.key)}, l.value).Int64()
+	if err == redis.Nil {
+		return int64(-2), nil
+	} else if err != nil {
+		return nil, err
+	}
+	l.setFence(fence)
+	return status, nil
+}
+
+func (l *Lock) setFence(fence int64) {
+	l.mu.Lock()
+	l.fence = fence
+	l.mu.Unlock()
+}
+
+// refreshed handles the result of the refresh script started at start.
+func (l *Lock) refreshed(start time.Time, ttl time.Duration, status interface{}, err error, logger Logger) error {
+	if err != nil {
+		if logger != nil {
+			logger.Log(LevelError, "refresh failed", "key", l.key, "token", shortToken(l.token), "error", err)
+		}
+		return err
+	}
+
+	if status != int64(1) {
+		return l.lostBy(status, logger, ErrNotObtained)
+	}
+
+	l.extend(start, ttl)
+	if logger != nil {
+		logger.Log(LevelDebug, "lock refreshed", "key", l.key, "token", shortToken(l.token), "ttl", ttl)
+	}
+	return nil
+}
+
+// Extend atomically adds d to the remaining TTL of the lock, unlike Refresh,
+// which resets the TTL to a fixed value. If max is positive, the resulting TTL
+// is capped at max. Returns the new TTL.
+// May return ErrLockExpired or ErrLockStolen, both of which match
+// ErrNotObtained, if the extension is unsuccessful.
+func (l *Lock) Extend(ctx context.Context, d, max time.Duration) (ttl time.Duration, err error) {
+	defer wrapErr("extend", l.key, &err)
+
+	if ctx == nil {
+		return 0, ErrNilContext
+	} else if d < time.Millisecond {
+		return 0, ErrInvalidTTL
+	}
+
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	if metrics := l.client.defaults.Metrics; metrics != nil {
+		defer func() { metrics.RefreshDone(l.key, err) }()
+	}
+	if sctx, span := l.client.startSpan(ctx, "redislock.extend", l.key); span != nil {
+		ctx = sctx
+		defer func() { endSpan(span, err, "lost") }()
+	}
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	start := time.Now()
+	status, err := l.activeScripts().extend.Run(opctx, l.rdb, l.scriptKeys, l.scriptArg, msArg(d), msArg(max)).Result()
+	if err != nil {
+		err = wrapOperationErr(ctx, opctx, err)
+		if l.logger != nil {
+			l.logger.Log(LevelError, "extend failed", "key", l.key, "token", shortToken(l.token), "error", err)
+		}
+		return 0, err
+	}
+
+	num, _ := status.(int64)
+	if num <= 0 {
+		return 0, l.lostBy(status, l.logger, ErrNotObtained)
+	}
+
+	ttl = time.Duration(num) * time.Millisecond
+	l.extend(start, ttl)
+	l.audit(ctx, AuditRefresh, ttl)
+	if l.logger != nil {
+		l.logger.Log(LevelDebug, "lock extended", "key", l.key, "token", shortToken(l.token), "ttl", ttl)
+	}
+	return ttl, nil
+}
+
+// lostBy marks the lock as lost according to the status returned by a
+// script, -1 if it expired, -2 if it was stolen, -4 if its epoch diverged,
+// and returns the error. Any other status is reported as notHeld.
+func (l *Lock) lostBy(status interface{}, logger Logger, notHeld error) error {
+	err := notHeld
+	switch status {
+	case int64(-1):
+		err = ErrLockExpired
+	case int64(-2):
+		err = ErrLockStolen
+	case int64(-4):
+		err = ErrLeaseEpochMismatch
+	}
+	err = l.strictExpiry(err, notHeld)
+
+	if logger != nil {
+		expired, _ := err.(*ExpiredError)
+		switch {
+		case expired != nil:
+			logger.Log(LevelWarn, "lock lost", "key", l.key, "token", shortToken(l.token), "reason", "expired", "ago", expired.Ago)
+		case err == ErrLockExpired:
+			logger.Log(LevelWarn, "lock lost", "key", l.key, "token", shortToken(l.token), "reason", "expired")
+		case err == ErrLockStolen:
+			logger.Log(LevelWarn, "lock lost", "key", l.key, "token", shortToken(l.token), "reason", "stolen")
+		case err == ErrLeaseEpochMismatch:
+			logger.Log(LevelWarn, "lock lost", "key", l.key, "token", shortToken(l.token), "reason", "epoch mismatch")
+		default:
+			logger.Log(LevelWarn, "lock lost", "key", l.key, "token", shortToken(l.token))
+		}
+	}
+	l.lost(err)
+	return err
+}
+
+// Release manually releases the lock and stops its auto-refresh watchdog.
+// May return ErrLockExpired or ErrLockStolen, both of which match ErrLockNotHeld.
+func (l *Lock) Release(ctx context.Context) error {
+	_, err := l.release(ctx, false)
+	return err
+}
+
+// ReleaseInfo describes a release, see Lock.ReleaseWithInfo.
+type ReleaseInfo struct {
+	// Deleted reports whether the lock key was deleted, i.e. the lock was
+	// still held.
+	Deleted bool
+	// RemainingTTL is the TTL the lock had left when it was released.
+	RemainingTTL time.Duration
+	// Held is the time since the lock was obtained.
+	Held time.Duration
+}
+
+// ReleaseWithInfo is like Release, but also reports the remaining TTL of the
+// lock, which is read in the same round trip if the client implements
+// PipeliningClient, e.g. to log releases shortly before expiry, which
+// indicate lock TTLs that are too tight. It returns the info even if the lock
+// was not held anymore.
+func (l *Lock) ReleaseWithInfo(ctx context.Context) (*ReleaseInfo, error) {
+	return l.release(ctx, true)
+}
+
+func (l *Lock) release(ctx context.Context, withInfo bool) (info *ReleaseInfo, err error) {
+	defer wrapErr("release", l.key, &err)
+	if ctx == nil {
+		return nil, ErrNilContext
+	}
+	if err := l.beforeRelease(ctx); err != nil {
+		return nil, err
+	}
+	if err := l.waitMinHold(ctx); err != nil {
+		return nil, err
+	}
+	defer l.releaseLocal()
+	defer func() {
+		if err == nil || errors.Is(err, ErrLockNotHeld) {
+			l.forgetIntent()
+		}
+	}()
+
+	l.StopAutoRefresh()
+	l.setReleasing(true)
+	defer l.setReleasing(false)
+
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	if sctx, span := l.client.startSpan(ctx, "redislock.release", l.key); span != nil {
+		ctx = sctx
+		defer func() { endSpan(span, err, "lost") }()
+	}
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	script, keys, args := l.releaseCmd()
+	if !withInfo {
+		res, err := script.Run(opctx, l.rdb, keys, args...).Result()
+		if err = l.released(res, wrapOperationErr(ctx, opctx, err)); err != nil {
+			return nil, err
+		}
+		l.releaseChildren(ctx)
+		l.audit(ctx, AuditRelease, 0)
+		return nil, nil
+	}
+
+	cmds := runBatch(opctx, l.rdb, []batchCmd{
+		{script: l.activeScripts().pttl, keys: l.scriptKeys, args: []interface{}{l.scriptArg}},
+		{script: script, keys: keys, args: args},
+	})
+	info = &ReleaseInfo{Held: time.Since(l.obtained)}
+	if pttl, _ := cmds[0].Val().(int64); pttl > 0 {
+		info.RemainingTTL = time.Duration(pttl) * time.Millisecond
+	}
+	if err = l.released(cmds[1].Val(), wrapOperationErr(ctx, opctx, cmds[1].Err())); err != nil {
+		return info, err
+	}
+	info.Deleted = true
+	l.releaseChildren(ctx)
+	l.audit(ctx, AuditRelease, 0)
+	return info, nil
+}
+
+// releaseCmd returns the release script of the lock and its arguments.
+func (l *Lock) releaseCmd() (*redis.Script, []string, []interface{}) {
+	scripts := l.activeScripts()
+	unlink := l.client.defaults.Unlink && scripts.unlinkRelease != nil
+	script, keys, args := scripts.release, l.scriptKeys, []interface{}{l.scriptArg}
+	if unlink {
+		script = scripts.unlinkRelease
+	}
+	if scripts.releaseTTL {
+		args = append(args, msArg(l.ttl))
+	}
+	if l.statsKey != "" && scripts.releaseStats != nil {
+		script = scripts.releaseStats
+		if unlink {
+			script = scripts.unlinkReleaseStats
+		}
+		keys = append(keys[:len(keys):len(keys)], l.statsKey)
+		args = append(args, msArg(time.Since(l.obtained)))
+	}
+	return script, keys, args
+}
+
+// released handles the result of the release script.
+func (l *Lock) released(res interface{}, err error) error {
+	if err == redis.Nil {
+		if l.logger != nil {
+			l.logger.Log(LevelWarn, "lock lost", "key", l.key, "token", shortToken(l.token))
+		}
+		l.lost(ErrLockNotHeld)
+		return ErrLockNotHeld
+	} else if err != nil {
+		if l.logger != nil {
+			l.logger.Log(LevelError, "release failed", "key", l.key, "token", shortToken(l.token), "error", err)
+		}
+		return err
+	}
+
+	if res != int64(1) {
+		return l.lostBy(res, l.logger, ErrLockNotHeld)
+	}
+
+	if l.logger != nil {
+		l.logger.Log(LevelDebug, "lock released", "key", l.key, "token", shortToken(l.token))
+	}
+	if metrics := l.client.defaults.Metrics; metrics != nil {
+		metrics.Released(l.key, time.Since(l.obtained))
+	}
+	l.lost(ErrLockNotHeld)
+	if l.parent != nil {
+		l.parent.removeChild(l)
+	}
+	return nil
+}
+
+// --------------------------------------------------------------------
+
+// defaultOptions are used when nil options are passed.
+var defaultOptions Options
+
+// Options describe the options for the lock
+type Options struct {
+	// RetryStrategy allows to customise the lock retry strategy. Strategies
+	// implementing ReusableRetryStrategy, like all built-in ones, start
+	// afresh on every call, so the Options can be shared between concurrent
+	// calls.
+	// Default: do not retry
+	RetryStrategy RetryStrategy
+
+	// Metadata string is appended to the lock token.
+	Metadata string
+
+	// MetadataMap is appended to the lock token as a JSON object, taking
+	// precedence over Metadata. It can be retrieved via Lock.MetadataMap and
+	// LockInfo.MetadataMap.
+	MetadataMap map[string]string
+
+	// OperationTimeout limits the duration of each individual redis command,
+	// independently of the context passed by the caller.
+	// Default: Defaults.OperationTimeout, or no limit
+	OperationTimeout time.Duration
+
+	// AcquireIf is called with the metadata of the current holder when the
+	// lock is already taken. If it returns true, the lock is taken over,
+	// provided the holder has not changed in the meantime.
+	// Default: never take over
+	AcquireIf func(existingMetadata string) bool
+
+	// Logger receives structured log entries at each decision point.
+	// Default: no logging
+	Logger Logger
+
+	// ReleaseSignal makes Release push a signal to a companion list, which
+	// waiters block on (via BLPOP) instead of sleeping between retries.
+	// Waiters use the RetryStrategy backoff, rounded up to whole seconds, as
+	// the maximum wait. Both the holder and the waiters must enable it and the
+	// client must implement BlockingClient.
+	// Default: false
+	ReleaseSignal bool
+
+	// ReleaseNotify makes Release publish to the key:released channel, which
+	// waiters subscribe to in order to retry immediately instead of sleeping
+	// through the RetryStrategy backoff. Waiters also wake up on expiry if
+	// keyspace notifications are enabled on the server and the client is a
+	// *redis.Client. Both the holder and the waiters must enable it and the
+	// client must implement SubscribingClient. Takes precedence over
+	// ReleaseSignal.
+	// Default: false
+	ReleaseNotify bool
+
+	// SelectDB selects the logical redis DB for a key. Connections to each
+	// DB are pooled by the Client and can be closed via Client.Close. It is
+	// only supported if the Client wraps a *redis.Client.
+	// Default: use the DB of the wrapped client
+	SelectDB func(key string) int
+
+	// IdempotencyToken is used as the lock token instead of a random one. If
+	// the lock is already held with the same token (and metadata), Obtain
+	// re-acquires it instead of returning ErrNotObtained. Both are done in a
+	// single script, e.g. for retried message handlers re-entering with the
+	// same identity.
+	// Default: use a random token
+	IdempotencyToken string
+
+	// TokenGenerator generates lock tokens, e.g. to embed the hostname or
+	// PID of the holder. It is ignored if IdempotencyToken is set. Generated
+	// tokens must be unique. Inspect, AcquireIf and HolderDetails assume
+	// tokens of the length produced by RandomToken to split the metadata
+	// off a lock value.
+	// Default: RandomToken
+	TokenGenerator func() (string, error)
+
+	// Fencing assigns each obtained lock a fencing token from the key:fence
+	// counter, see Lock.FencingToken. The counter never expires.
+	// Default: false
+	Fencing bool
+
+	// HolderDetails makes Obtain return a *NotObtainedError describing the
+	// current holder instead of ErrNotObtained. On redis 7 and clients
+	// implementing PipeliningClient, the details are fetched in the same
+	// round trip as the attempt to obtain the lock via SET NX GET, otherwise
+	// a failed attempt is followed by a script.
+	// Default: false
+	HolderDetails bool
+
+	// CapBackoffAtTTL caps each retry backoff at the remaining TTL of the
+	// current holder, as seen by the failed attempt, so the next attempt is
+	// made once the lock expires instead of polling or sleeping past its
+	// expiry. The TTL is learned like with HolderDetails, which does not
+	// have to be set.
+	// Default: false
+	CapBackoffAtTTL bool
+
+	// Heartbeat records the server time of the last refresh of the lock in
+	// a companion key under the lock key + ":heartbeat", so a holder which
+	// stopped refreshing can be taken over via StealAfter before its TTL
+	// runs out. It applies to Obtain and ObtainWith and does not support
+	// Scripts, RecordStats, Quota, release signals or notifications.
+	// Default: false
+	Heartbeat bool
+
+	// RetryOnError classifies errors returned by redis. If it returns true,
+	// Obtain retries according to the RetryStrategy instead of failing, as
+	// does Refresh. IsTransientError is a suitable classifier for
+	// failovers and network errors.
+	// Default: fail on errors
+	RetryOnError func(error) bool
+
+	// OnRetry is called after each failed attempt with the number of the
+	// attempt and the backoff before the next one, e.g. for logging or to
+	// abort on external signals. Retrying stops with ErrNotObtained, or the
+	// error of the last attempt, if it returns false.
+	// Default: retry according to the RetryStrategy
+	OnRetry func(attempt int, backoff time.Duration) bool
+
+	// MaxElapsed bounds the time spent obtaining a lock: retrying stops with
+	// ErrNotObtained, or the error of the last attempt, rather than back off
+	// past it. The first attempt is always made immediately, backoffs only
+	// apply between attempts. Unlike the wait timeout, it does not cancel
+	// an attempt in progress. The number of attempts made is reported by
+	// Error.Attempts.
+	// Default: only limited by the context and the retry strategy
+	MaxElapsed time.Duration
+
+	// Group makes the lock shared by all holders obtaining it with the same
+	// group ID and metadata, e.g. the name of a deployment, so the old and
+	// the new instances of a service do not contend for it during a
+	// rollout. The lock value is the group ID followed by the metadata, and
+	// Lock.Token reports the group ID. Each holder has its own TTL and
+	// refreshes and releases its own share, tracked in a sorted set under
+	// key + ":group". The lock is released once the last holder releases
+	// it, or expires with the last holder. Group locks are only supported
+	// by Obtain and ObtainWith, ignore TokenGenerator, IdempotencyToken,
+	// AcquireIf, HolderDetails, Fencing, Scripts, release signals and
+	// notifications, and do not support SetMetadata or MarshalBinary.
+	// Default: exclusive locks
+	Group string
+
+	// DryRun makes Obtain behave as if the lock was obtained, without
+	// writing the lock key, see Defaults.DryRun.
+	// Default: Defaults.DryRun
+	DryRun bool
+
+	// Advisory makes Obtain obtain an advisory lock, which never waits for
+	// other holders, see Defaults.Advisory.
+	// Default: Defaults.Advisory
+	Advisory bool
+
+	// ReleaseOnCancel ties the lock to the context passed to Obtain: once
+	// it is done, the lock is marked as lost with the context error and
+	// released in the background, on a best effort basis.
+	// Default: false
+	ReleaseOnCancel bool
+
+	// Priority orders the waiters for a fair lock, see ObtainFair. Waiters
+	// with a higher priority are granted the lock first. Priorities are
+	// clamped to [-100, 100].
+	// Default: 0
+	Priority int
+
+	// PriorityAging is the time after which the priority of a waiter for a
+	// fair lock rises by one level, see ObtainFair. It bounds the time a
+	// waiter can be bypassed by waiters of a higher priority to the
+	// difference of their priorities times PriorityAging. All waiters for a
+	// lock should use the same value. A negative value disables aging.
+	// Default: 1s
+	PriorityAging time.Duration
+
+	// QueueTimeout is the time after which a waiter is removed from the
+	// queue of a fair lock if it does not retry. It must exceed the
+	// backoff of the RetryStrategy.
+	// Default: 5s
+	QueueTimeout time.Duration
+
+	// OnQueue is called after each attempt of a waiter for a fair lock
+	// which did not obtain it, see ObtainFair, with its current position
+	// in the queue, starting at 1, and the estimated time until it is
+	// granted the lock, e.g. to report progress to users. The estimate is
+	// based on the recent hold durations of the lock while contended, and
+	// is negative until one was recorded.
+	// Default: no progress reports
+	OnQueue func(position int, eta time.Duration)
+
+	// TTLFromContext sets the lock TTL to the time remaining until the
+	// deadline of the context passed to Obtain, bounded by MinTTL and
+	// MaxTTL, so the lock does not outlive the request that took it. The
+	// given TTL is used if the context has no deadline.
+	// Default: false
+	TTLFromContext bool
+
+	// MinTTL is the lower bound of a TTL derived via TTLFromContext.
+	// Default: no lower bound
+	MinTTL time.Duration
+
+	// MaxTTL is the upper bound of a TTL derived via TTLFromContext.
+	// Default: no upper bound
+	MaxTTL time.Duration
+
+	// Scripts replace the Lua scripts used by Obtain and the returned lock,
+	// see Scripts. They are ignored by ObtainFair, ObtainMulti, RWLock and
+	// Semaphore.
+	// Default: Defaults.Scripts, or the built-in scripts
+	Scripts *Scripts
+
+	// TTL is used by Obtain and ObtainWith when a lock TTL of zero is
+	// passed, e.g. to set the TTL of a profile.
+	// Default: Defaults.TTL
+	TTL time.Duration
+
+	// Profile names options registered via Client.RegisterProfile, which
+	// apply to Obtain, ObtainWith and the helpers built on them. Fields set
+	// in the Options take precedence over those of the profile, in
+	// particular, boolean fields of the profile cannot be reset.
+	// Default: no profile
+	Profile string
+
+	// MinHold is the minimum time the lock is held: Release and
+	// ReleaseWithInfo wait until it has passed since the lock was obtained,
+	// while the lock keeps being auto-refreshed, so a holder releasing and
+	// re-obtaining a lock in a tight loop does not starve other waiters.
+	// Releases of locks which expire or are lost earlier proceed at once.
+	// If the context passed to Release is done first, its error is returned
+	// and the lock remains held. It applies to Obtain, ObtainWith and the
+	// helpers built on them.
+	// Default: release immediately
+	MinHold time.Duration
+
+	// ReleasingBackoff caps each retry backoff while the holder prepares its
+	// release via Lock.PrepareRelease, or once the lock was released since
+	// the last attempt, so the lock is obtained shortly after the release is
+	// confirmed. Each failed attempt is then followed by a script checking
+	// for the releasing mark.
+	// Default: do not check for prepared releases
+	ReleasingBackoff time.Duration
+
+	// Sequence stamps the lock value with an acquisition sequence number,
+	// drawn from the key:seq counter by the script obtaining the lock, see
+	// Lock.Sequence and LockInfo.Sequence. Unlike fencing tokens, it takes no
+	// extra round trip and is meant for debugging and metrics, e.g. to count
+	// the acquisitions of a key. The counter never expires. It applies to
+	// Obtain and ObtainWith and does not support Compat, Scripts, Heartbeat,
+	// Quota, RecordStats, IdempotencyToken, AcquireIf and HolderDetails.
+	// Default: false
+	Sequence bool
+
+	// Epoch stores a lease epoch in the lock value, see Lock.Epoch, which
+	// Refresh verifies and increments, so it detects a lock key holding its
+	// token with an earlier epoch, e.g. after redis was restored from a
+	// stale snapshot or failed over to a lagging replica, and reports
+	// ErrLeaseEpochMismatch instead of extending a lease whose history
+	// diverged. Other operations report such locks as stolen. It applies to
+	// Obtain and ObtainWith and does not support Compat, Group, Scripts,
+	// Heartbeat and Quota, whose locks keep their initial epoch.
+	// Default: false
+	Epoch bool
+}
+
+func (o *Options) getMetadata() string {
+	if o != nil && o.MetadataMap != nil {
+		return encodeMetadataMap(o.MetadataMap)
+	} else if o != nil {
+		return o.Metadata
+	}
+	return ""
+}
+
+func (o *Options) getOperationTimeout() time.Duration {
+	if o != nil && o.OperationTimeout > 0 {
+		return o.OperationTimeout
+	}
+	return 0
+}
+
+func (o *Options) getAcquireIf() func(string) bool {
+	if o != nil {
+		return o.AcquireIf
+	}
+	return nil
+}
+
+func (o *Options) getLogger() Logger {
+	if o != nil {
+		return o.Logger
+	}
+	return nil
+}
+
+func (o *Options) getReleaseSignal() bool {
+	if o != nil {
+		return o.ReleaseSignal
+	}
+	return false
+}
+
+func (o *Options) getReleaseNotify() bool {
+	if o != nil {
+		return o.ReleaseNotify
+	}
+	return false
+}
+
+func (o *Options) getSelectDB() func(string) int {
+	if o != nil {
+		return o.SelectDB
+	}
+	return nil
+}
+
+func (o *Options) getIdempotencyToken() string {
+	if o != nil {
+		return o.IdempotencyToken
+	}
+	return ""
+}
+
+func (o *Options) getTokenGenerator() func() (string, error) {
+	if o != nil {
+		return o.TokenGenerator
+	}
+	return nil
+}
+
+func (o *Options) getFencing() bool {
+	if o != nil {
+		return o.Fencing
+	}
+	return false
+}
+
+func (o *Options) getHolderDetails() bool {
+	if o != nil {
+		return o.HolderDetails
+	}
+	return false
 }
 
-// New creates a new Client instance with a custom namespace.
-func New(client RedisClient) *Client {
-	return &Client{client: client}
+func (o *Options) getCapBackoffAtTTL() bool {
+	if o != nil {
+		return o.CapBackoffAtTTL
+	}
+	return false
 }
 
-// Obtain tries to obtain a new lock using a key with the given TTL.
-// May return ErrNotObtained if not successful.
-func (c *Client) Obtain(ctx context.Context, key string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
-	// Create a random token
-	token, err := c.randomToken()
-	if err != nil {
-		return nil, err
+func (o *Options) getHeartbeat() bool {
+	if o != nil {
+		return o.Heartbeat
 	}
+	return false
+}
 
-	value := token + opt.getMetadata()
-	retry := opt.getRetryStrategy()
+func (o *Options) getPriority() int {
+	if o == nil {
+		return 0
+	} else if o.Priority > maxPriority {
+		return maxPriority
+	} else if o.Priority < -maxPriority {
+		return -maxPriority
+	}
+	return o.Priority
+}
 
-	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
-	defer cancel()
+func (o *Options) getPriorityAging() time.Duration {
+	if o == nil || o.PriorityAging == 0 {
+		return defaultPriorityAging
+	} else if o.PriorityAging < 0 {
+		return 0
+	}
+	return o.PriorityAging
+}
 
-	var timer *time.Timer
-	for {
-		ok, err := c.obtain(deadlinectx, key, value, lockTTL)
-		if err != nil {
-			return nil, err
-		} else if ok {
-			return &Lock{client: c, key: key, value: value}, nil
-		}
+func (o *Options) getQueueTimeout() time.Duration {
+	if o != nil && o.QueueTimeout > 0 {
+		return o.QueueTimeout
+	}
+	return defaultQueueTimeout
+}
 
-		backoff := retry.NextBackoff()
-		if backoff < 1 {
-			return nil, ErrNotObtained
-		}
+func (o *Options) getReleaseOnCancel() bool {
+	if o != nil {
+		return o.ReleaseOnCancel
+	}
+	return false
+}
 
-		if timer == nil {
-			timer = time.NewTimer(backoff)
-			defer timer.Stop()
-		} else {
-			timer.Reset(backoff)
-		}
+func (o *Options) getRetryOnError() func(error) bool {
+	if o != nil {
+		return o.RetryOnError
+	}
+	return nil
+}
 
-		select {
-		case <-deadlinectx.Done():
-			return nil, ErrNotObtained
-		case <-timer.C:
-		}
+func (o *Options) getScripts() *Scripts {
+	if o != nil {
+		return o.Scripts
 	}
+	return nil
 }
 
-func (c *Client) obtain(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
-	return c.client.SetNX(ctx, key, value, ttl).Result()
+func (o *Options) getOnRetry() func(int, time.Duration) bool {
+	if o != nil {
+		return o.OnRetry
+	}
+	return nil
 }
 
-func (c *Client) randomToken() (string, error) {
-	c.tmpMu.Lock()
-	defer c.tmpMu.Unlock()
+func (o *Options) getOnQueue() func(int, time.Duration) {
+	if o != nil {
+		return o.OnQueue
+	}
+	return nil
+}
 
-	if len(c.tmp) == 0 {
-		c.tmp = make([]byte, 16)
+func (o *Options) getGroup() string {
+	if o != nil {
+		return o.Group
 	}
+	return ""
+}
 
-	if _, err := io.ReadFull(rand.Reader, c.tmp); err != nil {
-		return "", err
+func (o *Options) getDryRun() bool {
+	if o != nil {
+		return o.DryRun
 	}
-	return base64.RawURLEncoding.EncodeToString(c.tmp), nil
+	return false
 }
 
-// --------------------------------------------------------------------
+func (o *Options) getAdvisory() bool {
+	if o != nil {
+		return o.Advisory
+	}
+	return false
+}
 
-// Lock represents an obtained, distributed lock.
-type Lock struct {
-	client *Client
-	key    string
-	value  string
+func (o *Options) getMinHold() time.Duration {
+	if o != nil {
+		return o.MinHold
+	}
+	return 0
 }
 
-// Obtain is a short-cut for New(...).Obtain(...).
-func Obtain(ctx context.Context, client RedisClient, key string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
-	return New(client).Obtain(ctx, key, waitTimeout, lockTTL, opt)
+func (o *Options) getReleasingBackoff() time.Duration {
+	if o != nil {
+		return o.ReleasingBackoff
+	}
+	return 0
 }
 
-// Key returns the redis key used by the lock.
-func (l *Lock) Key() string {
-	return l.key
+func (o *Options) getSequence() bool {
+	if o != nil {
+		return o.Sequence
+	}
+	return false
 }
 
-// Token returns the token value set by the lock.
-func (l *Lock) Token() string {
-	return l.value[:22]
+func (o *Options) getEpoch() bool {
+	if o != nil {
+		return o.Epoch
+	}
+	return false
 }
 
-// Metadata returns the metadata of the lock.
-func (l *Lock) Metadata() string {
-	return l.value[22:]
+func (o *Options) getMaxElapsed() time.Duration {
+	if o != nil {
+		return o.MaxElapsed
+	}
+	return 0
 }
 
-// TTL returns the remaining time-to-live. Returns 0 if the lock has expired.
-func (l *Lock) TTL(ctx context.Context) (time.Duration, error) {
-	res, err := luaPTTL.Run(ctx, l.client.client, []string{l.key}, l.value).Result()
-	if err == redis.Nil {
-		return 0, nil
-	} else if err != nil {
-		return 0, err
+// ttlFromContext returns the lock TTL derived from the deadline of ctx if
+// TTLFromContext is set, or ttl otherwise.
+func (o *Options) ttlFromContext(ctx context.Context, ttl time.Duration) time.Duration {
+	if o == nil || !o.TTLFromContext || ctx == nil {
+		return ttl
 	}
 
-	if num := res.(int64); num > 0 {
-		return time.Duration(num) * time.Millisecond, nil
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ttl
 	}
-	return 0, nil
-}
 
-// Refresh extends the lock with a new TTL.
-// May return ErrNotObtained if refresh is unsuccessful.
-func (l *Lock) Refresh(ctx context.Context, ttl time.Duration, opt *Options) error {
-	ttlVal := strconv.FormatInt(int64(ttl/time.Millisecond), 10)
-	status, err := luaRefresh.Run(ctx, l.client.client, []string{l.key}, l.value, ttlVal).Result()
-	if err != nil {
-		return err
-	} else if status == int64(1) {
-		return nil
+	ttl = time.Until(deadline)
+	if o.MaxTTL > 0 && ttl > o.MaxTTL {
+		ttl = o.MaxTTL
+	}
+	if ttl < o.MinTTL {
+		ttl = o.MinTTL
+	}
+	if ttl < time.Millisecond {
+		// The deadline has passed, but the lock must never be persisted.
+		ttl = time.Millisecond
 	}
-	return ErrNotObtained
+	return ttl
 }
 
-// Release manually releases the lock.
-// May return ErrLockNotHeld.
-func (l *Lock) Release(ctx context.Context) error {
-	res, err := luaRelease.Run(ctx, l.client.client, []string{l.key}, l.value).Result()
-	if err == redis.Nil {
-		return ErrLockNotHeld
-	} else if err != nil {
-		return err
+func (o *Options) getRetryStrategy() RetryStrategy {
+	if o != nil && o.RetryStrategy != nil {
+		return iterate(o.RetryStrategy)
 	}
+	return NoRetry()
+}
 
-	if i, ok := res.(int64); !ok || i != 1 {
-		return ErrLockNotHeld
+// --------------------------------------------------------------------
+
+// LogLevel indicates the severity of a log entry.
+type LogLevel int
+
+// Supported log levels.
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the name of the level.
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
 	}
-	return nil
+	return "LogLevel(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Logger is a minimal structured logger interface.
+type Logger interface {
+	// Log emits a log entry with alternating key/value pairs.
+	Log(level LogLevel, msg string, keyvals ...interface{})
 }
 
 // --------------------------------------------------------------------
 
-// Options describe the options for the lock
-type Options struct {
-	// RetryStrategy allows to customise the lock retry strategy.
-	// Default: do not retry
-	RetryStrategy RetryStrategy
+// msArg converts d to milliseconds for use as a script argument. go-redis
+// encodes integers straight into its write buffer, unlike strings, which
+// would have to be formatted first.
+func msArg(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
 
-	// Metadata string is appended to the lock token.
-	Metadata string
+func withOperationTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
 }
 
-func (o *Options) getMetadata() string {
-	if o != nil {
-		return o.Metadata
+// cleanupTimeout limits cleanups after failed operations, such as leaving a
+// queue, unless an OperationTimeout is set.
+const cleanupTimeout = 5 * time.Second
+
+// cleanupContext returns a context for a cleanup, which runs even if the
+// context of the failed operation is done, limited by timeout or else
+// cleanupTimeout.
+func cleanupContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = cleanupTimeout
 	}
-	return ""
+	return context.WithTimeout(context.Background(), timeout)
 }
 
-func (o *Options) getRetryStrategy() RetryStrategy {
-	if o != nil && o.RetryStrategy != nil {
-		return o.RetryStrategy
+// wrapOperationErr wraps err with context.DeadlineExceeded if the operation
+// context timed out while the parent context is still active.
+func wrapOperationErr(parent, opctx context.Context, err error) error {
+	if err != nil && parent.Err() == nil && opctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("redislock: operation timed out: %w", context.DeadlineExceeded)
+	}
+	return err
+}
+
+// IsTransientError reports whether err is likely to go away on retry, such as
+// network errors and errors returned by redis during failovers (LOADING,
+// READONLY, MASTERDOWN, TRYAGAIN, CLUSTERDOWN). See Options.RetryOnError.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var rerr redis.Error
+	if errors.As(err, &rerr) && err != redis.Nil {
+		msg := rerr.Error()
+		for _, prefix := range []string{"LOADING ", "READONLY ", "MASTERDOWN ", "TRYAGAIN ", "CLUSTERDOWN "} {
+			if strings.HasPrefix(msg, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var nerr net.Error
+	return errors.As(err, &nerr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	return NoRetry()
 }
 
 // --------------------------------------------------------------------
@@ -217,6 +2709,25 @@ type RetryStrategy interface {
 	NextBackoff() time.Duration
 }
 
+// ReusableRetryStrategy is a RetryStrategy which acts as an immutable
+// template. Its NextBackoff is never called directly, instead each Obtain or
+// Refresh call iterates over a fresh copy returned by Iterator. Iterator must
+// be safe for concurrent use.
+type ReusableRetryStrategy interface {
+	RetryStrategy
+
+	// Iterator returns a copy of the strategy in its initial state.
+	Iterator() RetryStrategy
+}
+
+// iterate returns a fresh iterator of s if it is reusable, or s itself.
+func iterate(s RetryStrategy) RetryStrategy {
+	if r, ok := s.(ReusableRetryStrategy); ok {
+		return r.Iterator()
+	}
+	return s
+}
+
 type linearBackoff time.Duration
 
 // LinearBackoff allows retries regularly with customized intervals
@@ -233,6 +2744,8 @@ func (r linearBackoff) NextBackoff() time.Duration {
 	return time.Duration(r)
 }
 
+func (r linearBackoff) Iterator() RetryStrategy { return r }
+
 type limitedRetry struct {
 	s RetryStrategy
 
@@ -252,6 +2765,150 @@ func (r *limitedRetry) NextBackoff() time.Duration {
 	return r.s.NextBackoff()
 }
 
+func (r *limitedRetry) stopReason() StopReason {
+	if r.cnt >= r.max {
+		return StopAttempts
+	}
+	return stopReason(r.s)
+}
+
+func (r *limitedRetry) Iterator() RetryStrategy {
+	return &limitedRetry{s: iterate(r.s), max: r.max}
+}
+
+type switchRetry struct {
+	first, then RetryStrategy
+
+	cnt, n int
+}
+
+// SwitchAfter delegates to the first strategy for the first n attempts, then
+// switches to the second strategy.
+func SwitchAfter(first RetryStrategy, n int, then RetryStrategy) RetryStrategy {
+	return &switchRetry{first: first, n: n, then: then}
+}
+
+func (r *switchRetry) NextBackoff() time.Duration {
+	if r.cnt >= r.n {
+		return r.then.NextBackoff()
+	}
+	r.cnt++
+	return r.first.NextBackoff()
+}
+
+func (r *switchRetry) Iterator() RetryStrategy {
+	return &switchRetry{first: iterate(r.first), n: r.n, then: iterate(r.then)}
+}
+
+type untilRetry struct {
+	s RetryStrategy
+
+	start      time.Time
+	maxElapsed time.Duration
+	expired    bool
+}
+
+// RetryUntil stops retrying once the next backoff would end more than
+// maxElapsed after the first retry.
+func RetryUntil(s RetryStrategy, maxElapsed time.Duration) RetryStrategy {
+	return &untilRetry{s: s, maxElapsed: maxElapsed}
+}
+
+func (r *untilRetry) NextBackoff() time.Duration {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+
+	backoff := r.s.NextBackoff()
+	if r.expired = backoff > 0 && time.Since(r.start)+backoff > r.maxElapsed; r.expired {
+		return 0
+	}
+	return backoff
+}
+
+func (r *untilRetry) Iterator() RetryStrategy {
+	return &untilRetry{s: iterate(r.s), maxElapsed: r.maxElapsed}
+}
+
+func (r *untilRetry) stopReason() StopReason {
+	if r.expired {
+		return StopElapsed
+	}
+	return stopReason(r.s)
+}
+
+type limitRetry struct {
+	s RetryStrategy
+
+	maxAttempts int
+	maxElapsed  time.Duration
+	attempts    int
+	start       time.Time
+	stopped     StopReason
+}
+
+// Limit limits retrying to maxAttempts attempts in total, including the first
+// one, and stops once the next backoff would end more than maxElapsed after
+// the first attempt, whichever comes first. Zero disables either limit. The
+// limit which stopped retrying is reported by Error.Stopped.
+func Limit(s RetryStrategy, maxAttempts int, maxElapsed time.Duration) RetryStrategy {
+	return &limitRetry{s: s, maxAttempts: maxAttempts, maxElapsed: maxElapsed}
+}
+
+func (r *limitRetry) NextBackoff() time.Duration {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+
+	r.attempts++
+	if r.maxAttempts > 0 && r.attempts >= r.maxAttempts {
+		r.stopped = StopAttempts
+		return 0
+	}
+	backoff := r.s.NextBackoff()
+	if backoff < 1 {
+		r.stopped = stopReason(r.s)
+		return 0
+	}
+	if r.maxElapsed > 0 && time.Since(r.start)+backoff > r.maxElapsed {
+		r.stopped = StopElapsed
+		return 0
+	}
+	return backoff
+}
+
+func (r *limitRetry) Iterator() RetryStrategy {
+	return &limitRetry{s: iterate(r.s), maxAttempts: r.maxAttempts, maxElapsed: r.maxElapsed, start: time.Now()}
+}
+
+func (r *limitRetry) stopReason() StopReason { return r.stopped }
+
+type funcRetry struct {
+	fn func(attempt int, elapsed time.Duration) time.Duration
+
+	attempt int
+	start   time.Time
+}
+
+// RetryFunc adapts fn to a RetryStrategy. It is called with the number of
+// failed attempts so far, starting at 1, and the time elapsed since the first
+// of them, and returns the next backoff, or zero to give up.
+func RetryFunc(fn func(attempt int, elapsed time.Duration) time.Duration) RetryStrategy {
+	return &funcRetry{fn: fn}
+}
+
+func (r *funcRetry) NextBackoff() time.Duration {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	r.attempt++
+	return r.fn(r.attempt, time.Since(r.start))
+}
+
+func (r *funcRetry) Iterator() RetryStrategy {
+	return &funcRetry{fn: r.fn}
+}
+
 type exponentialBackoff struct {
 	cnt uint
 
@@ -280,3 +2937,106 @@ func (r *exponentialBackoff) NextBackoff() time.Duration {
 		return d
 	}
 }
+
+func (r *exponentialBackoff) Iterator() RetryStrategy {
+	return &exponentialBackoff{min: r.min, max: r.max}
+}
+
+// cappedBackoff returns base * 2**n, limited to max unless max is zero.
+func cappedBackoff(base, max time.Duration, n uint) time.Duration {
+	d := base
+	for i := uint(0); i < n && (max == 0 || d < max); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if max != 0 && d > max {
+		return max
+	}
+	return d
+}
+
+// jitter returns a random duration in [1ns, d].
+func jitter(d time.Duration) time.Duration {
+	if d < 1 {
+		return 1
+	}
+	return time.Duration(mathrand.Int63n(int64(d))) + 1
+}
+
+type fullJitterBackoff struct {
+	cnt uint
+
+	base, max time.Duration
+}
+
+// FullJitterBackoff strategy picks a random backoff between zero and an
+// exponentially growing ceiling of base * 2**n, capped at max.
+func FullJitterBackoff(base, max time.Duration) RetryStrategy {
+	return &fullJitterBackoff{base: base, max: max}
+}
+
+func (r *fullJitterBackoff) NextBackoff() time.Duration {
+	d := cappedBackoff(r.base, r.max, r.cnt)
+	r.cnt++
+	return jitter(d)
+}
+
+func (r *fullJitterBackoff) Iterator() RetryStrategy {
+	return &fullJitterBackoff{base: r.base, max: r.max}
+}
+
+type equalJitterBackoff struct {
+	cnt uint
+
+	base, max time.Duration
+}
+
+// EqualJitterBackoff strategy waits for half of an exponentially growing
+// ceiling of base * 2**n, capped at max, plus a random share of the other half.
+func EqualJitterBackoff(base, max time.Duration) RetryStrategy {
+	return &equalJitterBackoff{base: base, max: max}
+}
+
+func (r *equalJitterBackoff) NextBackoff() time.Duration {
+	d := cappedBackoff(r.base, r.max, r.cnt)
+	r.cnt++
+	return d - d/2 + jitter(d/2) - 1
+}
+
+func (r *equalJitterBackoff) Iterator() RetryStrategy {
+	return &equalJitterBackoff{base: r.base, max: r.max}
+}
+
+type decorrelatedJitterBackoff struct {
+	prev, base, max time.Duration
+}
+
+// DecorrelatedJitterBackoff strategy picks a random backoff between base and
+// three times the previous backoff, capped at max.
+func DecorrelatedJitterBackoff(base, max time.Duration) RetryStrategy {
+	return &decorrelatedJitterBackoff{prev: base, base: base, max: max}
+}
+
+func (r *decorrelatedJitterBackoff) NextBackoff() time.Duration {
+	upper := r.prev * 3
+	if upper < r.prev {
+		upper = math.MaxInt64
+	}
+
+	d := r.base + jitter(upper-r.base) - 1
+	if d < 1 {
+		d = 1
+	}
+	if r.max != 0 && d > r.max {
+		d = r.max
+	}
+	r.prev = d
+	return d
+}
+
+func (r *decorrelatedJitterBackoff) Iterator() RetryStrategy {
+	return &decorrelatedJitterBackoff{prev: r.base, base: r.base, max: r.max}
+}
@@ -2,7 +2,13 @@ package redislock_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"math/rand"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -10,6 +16,7 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redislocktest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -25,7 +32,7 @@ var _ = Describe("Client", func() {
 	})
 
 	AfterEach(func() {
-		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":signal", lockKey+":fence").Err()).To(Succeed())
 	})
 
 	It("should obtain once with TTL", func() {
@@ -36,7 +43,7 @@ var _ = Describe("Client", func() {
 		defer lock1.Release(ctx)
 
 		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
-		Expect(err).To(Equal(redislock.ErrNotObtained))
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
 		Expect(lock1.Release(ctx)).To(Succeed())
 
 		lock2, err := subject.Obtain(ctx, lockKey, time.Minute, time.Minute, nil)
@@ -58,6 +65,348 @@ var _ = Describe("Client", func() {
 		Expect(lock.Release(ctx)).To(Succeed())
 	})
 
+	It("should obtain asynchronously", func() {
+		var res redislock.ObtainResult
+		select {
+		case res = <-subject.ObtainAsync(ctx, lockKey, time.Hour, time.Hour, nil):
+		case <-time.After(time.Second):
+			Fail("timed out")
+		}
+		Expect(res.Err).NotTo(HaveOccurred())
+		Expect(res.Lock.Token()).To(HaveLen(22))
+		Expect(res.Lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should deliver context errors asynchronously", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+
+		cctx, cancel := context.WithCancel(ctx)
+		ch := subject.ObtainAsync(cctx, lockKey, time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+		})
+		cancel()
+
+		res, ok := <-ch
+		Expect(ok).To(BeTrue())
+		Expect(res.Err).To(MatchError(context.Canceled))
+		Expect(res.Lock).To(BeNil())
+		Eventually(ch).Should(BeClosed())
+	})
+
+	It("should re-obtain with the same idempotency token", func() {
+		opt := &redislock.Options{IdempotencyToken: "job-42", Metadata: "meta"}
+
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock1.Token()).To(Equal("job-42"))
+		Expect(lock1.Metadata()).To(Equal("meta"))
+
+		lock2, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.Token()).To(Equal(lock1.Token()))
+		Expect(lock2.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{IdempotencyToken: "job-43"})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock2.Release(ctx)).To(Succeed())
+	})
+
+	It("should re-obtain in a single round trip", func() {
+		counting := &scriptCountingClient{RedisClient: redisClient}
+		subject = redislock.New(counting)
+		opt := &redislock.Options{IdempotencyToken: "job-42"}
+
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(counting.scripts).To(Equal(int32(2)))
+		Expect(counting.setNXs).To(BeZero())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should store structured metadata", func() {
+		md := map[string]string{"host": "a", "pid": "42"}
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{MetadataMap: md, Metadata: "ignored"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.Metadata()).To(Equal(`{"host":"a","pid":"42"}`))
+		Expect(lock.MetadataMap()).To(Equal(md))
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.MetadataMap()).To(Equal(md))
+
+		lock2, err := subject.Obtain(ctx, lockKey+".2", time.Hour, time.Minute, &redislock.Options{Metadata: "plain"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock2.Release(ctx)
+		Expect(lock2.MetadataMap()).To(BeNil())
+	})
+
+	It("should encode typed metadata", func() {
+		type owner struct {
+			Host string `json:"host"`
+			PID  int    `json:"pid"`
+		}
+
+		md, err := redislock.MarshalMetadata(owner{Host: "a", PID: 42})
+		Expect(err).NotTo(HaveOccurred())
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: md})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		var o owner
+		Expect(lock.UnmarshalMetadata(&o)).To(Succeed())
+		Expect(o).To(Equal(owner{Host: "a", PID: 42}))
+
+		info, err := subject.Inspect(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		o = owner{}
+		Expect(info.UnmarshalMetadata(&o)).To(Succeed())
+		Expect(o).To(Equal(owner{Host: "a", PID: 42}))
+		Expect(info.MetadataMap()).To(BeNil())
+	})
+
+	It("should update metadata", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "step 1/7", ReleaseSignal: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.PExpire(ctx, lockKey, 30*time.Second).Err()).To(Succeed())
+
+		Expect(lock.SetMetadata(ctx, "step 3/7")).To(Succeed())
+		Expect(lock.Metadata()).To(Equal("step 3/7"))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal(lock.Token() + "step 3/7"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", 30*time.Second, time.Second))
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(Succeed())
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		Expect(lock.SetMetadata(ctx, "step 4/7")).To(MatchError(redislock.ErrLockStolen))
+		Expect(lock.Metadata()).To(Equal("step 3/7"))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal("ABCD"))
+	})
+
+	It("should retry on transient errors", func() {
+		flaky := &flakyClient{RedisClient: redisClient, failures: 2}
+		opt := &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+			RetryOnError:  redislock.IsTransientError,
+		}
+
+		lock, err := redislock.New(flaky).Obtain(ctx, lockKey, time.Hour, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		atomic.StoreInt32(&flaky.failures, 2)
+		Expect(lock.Refresh(ctx, time.Hour, opt)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+
+		atomic.StoreInt32(&flaky.failures, 1)
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(MatchError(errLoading))
+
+		atomic.StoreInt32(&flaky.failures, 3)
+		Expect(lock.Refresh(ctx, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(10*time.Millisecond), 2),
+			RetryOnError:  redislock.IsTransientError,
+		})).To(MatchError(errLoading))
+	})
+
+	It("should bound retries by elapsed time", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		start := time.Now()
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(20 * time.Millisecond),
+			MaxElapsed:    70 * time.Millisecond,
+		})
+		Expect(time.Since(start)).To(BeNumerically("<", 70*time.Millisecond))
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		var e *redislock.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Attempts).To(Equal(4))
+		Expect(e.Stopped).To(Equal(redislock.StopElapsed))
+	})
+
+	It("should report why retrying stopped", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		stopped := func(waitTimeout time.Duration, opt *redislock.Options) redislock.StopReason {
+			_, err := subject.Obtain(ctx, lockKey, waitTimeout, time.Minute, opt)
+			Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+			var e *redislock.Error
+			Expect(errors.As(err, &e)).To(BeTrue())
+			return e.Stopped
+		}
+		Expect(stopped(time.Hour, nil)).To(Equal(redislock.StopStrategy))
+		Expect(stopped(time.Hour, &redislock.Options{
+			RetryStrategy: redislock.Limit(redislock.LinearBackoff(time.Millisecond), 3, time.Hour),
+		})).To(Equal(redislock.StopAttempts))
+		Expect(stopped(time.Hour, &redislock.Options{
+			RetryStrategy: redislock.Limit(redislock.LinearBackoff(20*time.Millisecond), 100, 50*time.Millisecond),
+		})).To(Equal(redislock.StopElapsed))
+		Expect(stopped(30*time.Millisecond, &redislock.Options{
+			RetryStrategy: redislock.Limit(redislock.LinearBackoff(20*time.Millisecond), 100, time.Hour),
+		})).To(Equal(redislock.StopContext))
+		Expect(stopped(time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(time.Millisecond),
+			OnRetry:       func(int, time.Duration) bool { return false },
+		})).To(Equal(redislock.StopAborted))
+		Expect(redislock.StopAborted.String()).To(Equal("aborted"))
+	})
+
+	It("should classify transient errors", func() {
+		Expect(redislock.IsTransientError(errLoading)).To(BeTrue())
+		Expect(redislock.IsTransientError(redisError("READONLY You can't write against a read only replica."))).To(BeTrue())
+		Expect(redislock.IsTransientError(redisError("ERR unknown command"))).To(BeFalse())
+		Expect(redislock.IsTransientError(io.EOF)).To(BeTrue())
+		Expect(redislock.IsTransientError(redis.Nil)).To(BeFalse())
+		Expect(redislock.IsTransientError(context.Canceled)).To(BeFalse())
+		Expect(redislock.IsTransientError(nil)).To(BeFalse())
+	})
+
+	It("should generate tokens", func() {
+		var n int
+		generate := func() (string, error) {
+			n++
+			return "host-a/" + strconv.Itoa(n), nil
+		}
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{TokenGenerator: generate, Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Token()).To(Equal("host-a/1"))
+		Expect(lock.Metadata()).To(Equal("meta"))
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		errGenerate := errors.New("generate")
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{TokenGenerator: func() (string, error) { return "", errGenerate }})
+		Expect(err).To(Equal(errGenerate))
+
+		token, err := redislock.RandomToken()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(HaveLen(22))
+	})
+
+	It("should take over if the holder's metadata matches", func() {
+		sameEpoch := func(meta string) bool { return meta == "epoch:1" }
+
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "epoch:2"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "epoch:1", AcquireIf: sameEpoch})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock1.Release(ctx)).To(Succeed())
+
+		lock2, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "epoch:1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		lock3, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "epoch:1", AcquireIf: sameEpoch})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock3.Metadata()).To(Equal("epoch:1"))
+		Expect(lock3.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock2.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(lock3.Release(ctx)).To(Succeed())
+	})
+
+	It("should report holder details", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "worker-1"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{HolderDetails: true})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		var details *redislock.NotObtainedError
+		Expect(errors.As(err, &details)).To(BeTrue())
+		Expect(details.Key).To(Equal(lockKey))
+		Expect(details.Metadata).To(Equal("worker-1"))
+		Expect(details.TTL).To(BeNumerically("~", time.Hour, time.Second))
+
+		lock2, err := subject.Obtain(ctx, lockKey+"2", time.Hour, time.Hour, &redislock.Options{HolderDetails: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.Release(ctx)).To(Succeed())
+	})
+
+	It("should report holder details without scripts", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "worker-1"})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		noScripts := &noScriptClient{Client: redisClient}
+		_, err = redislock.New(noScripts).Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{HolderDetails: true})
+		var details *redislock.NotObtainedError
+		Expect(errors.As(err, &details)).To(BeTrue())
+		Expect(details.Metadata).To(Equal("worker-1"))
+		Expect(details.TTL).To(BeNumerically("~", time.Minute, time.Second))
+
+		lock2, err := redislock.New(noScripts).Obtain(ctx, lockKey+"2", time.Hour, time.Hour, &redislock.Options{HolderDetails: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.Token()).To(HaveLen(22))
+		Expect(redisClient.PTTL(ctx, lockKey+"2").Val()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(redisClient.Del(ctx, lockKey+"2").Err()).To(Succeed())
+	})
+
+	It("should cap backoffs at the TTL of the holder", func() {
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, 100*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		start := time.Now()
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{
+			RetryStrategy:   redislock.LinearBackoff(time.Minute),
+			CapBackoffAtTTL: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(lock.Stats().Attempts).To(Equal(2))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should obtain uncontended locks without scripts", func() {
+		counting := &scriptCountingClient{RedisClient: redisClient}
+		lock, err := redislock.New(counting).Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{HolderDetails: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&counting.scripts)).To(BeZero())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should record requested and effective TTLs", func() {
+		slow := redislock.New(&slowClient{RedisClient: redisClient, delay: 50 * time.Millisecond})
+
+		lock, err := slow.Obtain(ctx, lockKey, time.Hour, time.Second, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.RequestedTTL()).To(Equal(time.Second))
+		Expect(lock.EffectiveTTL()).To(BeNumerically("<=", 950*time.Millisecond))
+		Expect(lock.EffectiveTTL()).To(BeNumerically(">", 500*time.Millisecond))
+	})
+
+	It("should have a string representation", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.Key()).To(Equal(lockKey))
+		Expect(lock.String()).To(Equal("Lock(key=" + lockKey + ", token=" + lock.Token()[:6] + "..., ttl-hint=1m0s)"))
+		Expect(lock.String()).NotTo(ContainSubstring(lock.Token()))
+	})
+
+	It("should verify ownership", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.IsHeld(ctx)).To(BeTrue())
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		Expect(lock.IsHeld(ctx)).To(BeFalse())
+
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+		Expect(lock.IsHeld(ctx)).To(BeFalse())
+	})
+
 	It("should refresh", func() {
 		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Minute, time.Minute, nil)
 		Expect(err).NotTo(HaveOccurred())
@@ -67,11 +416,117 @@ var _ = Describe("Client", func() {
 		Expect(lock.Release(ctx)).To(Succeed())
 	})
 
+	It("should track validity locally", func() {
+		before := time.Now()
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Minute, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.ValidUntil()).To(BeTemporally(">=", before.Add(time.Minute)))
+		Expect(lock.ValidUntil()).To(BeTemporally("<=", time.Now().Add(time.Minute)))
+		Expect(lock.RemainingLocal()).To(BeNumerically("~", time.Minute, time.Second))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.RemainingLocal()).To(BeNumerically("~", time.Hour, time.Second))
+	})
+
+	It("should extend", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Minute, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		ttl, err := lock.Extend(ctx, time.Minute, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ttl).To(BeNumerically("~", 2*time.Minute, time.Second))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", 2*time.Minute, time.Second))
+
+		ttl, err = lock.Extend(ctx, time.Hour, 3*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ttl).To(Equal(3 * time.Minute))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", 3*time.Minute, time.Second))
+	})
+
+	It("should fail to extend if obtained by someone else", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Minute, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+
+		_, err = lock.Extend(ctx, time.Minute, 0)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(err).To(MatchError(redislock.ErrLockStolen))
+	})
+
+	It("should report release details", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(10 * time.Millisecond)
+
+		info, err := lock.ReleaseWithInfo(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Deleted).To(BeTrue())
+		Expect(info.RemainingTTL).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(info.Held).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+
+		info, err = lock.ReleaseWithInfo(ctx)
+		Expect(err).To(MatchError(redislock.ErrLockExpired))
+		Expect(info.Deleted).To(BeFalse())
+		Expect(info.RemainingTTL).To(BeZero())
+	})
+
 	It("should fail to release if expired", func() {
 		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Millisecond, time.Millisecond, nil)
 		Expect(err).NotTo(HaveOccurred())
 		time.Sleep(5 * time.Millisecond)
-		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		err = lock.Release(ctx)
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(err).To(MatchError(redislock.ErrLockExpired))
+	})
+
+	It("should describe failed operations", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(5 * time.Millisecond)
+
+		err = lock.Release(ctx)
+		Expect(err).To(MatchError(`redislock: release "` + lockKey + `": lock expired`))
+
+		var lockErr *redislock.Error
+		Expect(errors.As(err, &lockErr)).To(BeTrue())
+		Expect(lockErr.Op).To(Equal("release"))
+		Expect(lockErr.Key).To(Equal(lockKey))
+		Expect(lockErr.Err).To(Equal(redislock.ErrLockExpired))
+
+		flaky := &flakyClient{RedisClient: redisClient, failures: 1}
+		_, err = redislock.Obtain(ctx, flaky, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(errLoading))
+		Expect(errors.As(err, &lockErr)).To(BeTrue())
+		Expect(lockErr.Op).To(Equal("obtain"))
+	})
+
+	It("should validate arguments", func() {
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, 0, nil)
+		Expect(err).To(MatchError(`redislock: obtain "` + lockKey + `": invalid TTL`))
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, 500*time.Microsecond, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		_, err = subject.Obtain(ctx, "", time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidKey))
+		_, err = subject.Obtain(nil, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNilContext))
+		_, err = subject.ObtainMulti(ctx, []string{lockKey, ""}, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidKey))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(ctx, 0, nil)).To(MatchError(`redislock: refresh "` + lockKey + `": invalid TTL`))
+		_, err = lock.Extend(ctx, time.Microsecond, 0)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		Expect(lock.Release(nil)).To(MatchError(redislock.ErrNilContext))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
 	})
 
 	It("should fail to release if ontained by someone else", func() {
@@ -79,14 +534,32 @@ var _ = Describe("Client", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
-		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		err = lock.Release(ctx)
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(err).To(MatchError(redislock.ErrLockStolen))
 	})
 
 	It("should fail to refresh if expired", func() {
 		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Millisecond, time.Millisecond, nil)
 		Expect(err).NotTo(HaveOccurred())
 		time.Sleep(5 * time.Millisecond)
-		Expect(lock.Refresh(ctx, time.Hour, nil)).To(MatchError(redislock.ErrNotObtained))
+
+		err = lock.Refresh(ctx, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(err).To(MatchError(redislock.ErrLockExpired))
+	})
+
+	It("should fail to refresh if obtained by someone else", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Minute, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+
+		err = lock.Refresh(ctx, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(err).To(MatchError(redislock.ErrLockStolen))
+		Expect(err).NotTo(MatchError(redislock.ErrLockExpired))
 	})
 
 	It("should retry if enabled", func() {
@@ -117,6 +590,269 @@ var _ = Describe("Client", func() {
 		Expect(err).To(MatchError(redislock.ErrNotObtained))
 	})
 
+	It("should record acquisition statistics", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Stats().Attempts).To(Equal(1))
+		Expect(lock.Stats().Obtained).To(BeTemporally("~", time.Now(), time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+		Expect(redisClient.PExpire(ctx, lockKey, 50*time.Millisecond).Err()).NotTo(HaveOccurred())
+
+		lock, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(20 * time.Millisecond),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		stats := lock.Stats()
+		Expect(stats.Attempts).To(BeNumerically(">", 1))
+		Expect(stats.Wait).To(BeNumerically(">=", 40*time.Millisecond))
+	})
+
+	It("should report and abort retries", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		var attempts []int
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(time.Millisecond),
+			OnRetry: func(attempt int, backoff time.Duration) bool {
+				Expect(backoff).To(Equal(time.Millisecond))
+				attempts = append(attempts, attempt)
+				return attempt < 3
+			},
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(attempts).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("should not sleep past the deadline", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		start := time.Now()
+		_, err = subject.Obtain(ctx, lockKey, 50*time.Millisecond, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(time.Second),
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(time.Since(start)).To(BeNumerically("<", 40*time.Millisecond))
+	})
+
+	It("should time out slow operations", func() {
+		slow := redislock.New(&slowClient{RedisClient: redisClient, delay: time.Second})
+
+		start := time.Now()
+		_, err := slow.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{
+			OperationTimeout: 20 * time.Millisecond,
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(err).NotTo(MatchError(redislock.ErrNotObtained))
+		Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+	})
+
+	It("should time out slow operations by default", func() {
+		slow := redislock.New(&slowClient{RedisClient: redisClient, delay: time.Second}, redislock.Defaults{
+			OperationTimeout: 20 * time.Millisecond,
+		})
+
+		start := time.Now()
+		_, err := slow.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+	})
+
+	It("should hand over via release signals", func() {
+		opt := &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(3*time.Second), 2),
+			ReleaseSignal: true,
+		}
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+
+		released := make(chan time.Time, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			released <- time.Now()
+			_ = lock1.Release(ctx)
+		}()
+
+		lock2, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(<-released)).To(BeNumerically("<", 500*time.Millisecond))
+		Expect(lock2.Release(ctx)).To(Succeed())
+		Expect(redisClient.LLen(ctx, lockKey+":signal").Val()).To(Equal(int64(1)))
+	})
+
+	It("should apply client defaults", func() {
+		subject = redislock.New(redisClient, redislock.Defaults{
+			TTL: time.Hour,
+			RetryStrategy: func() redislock.RetryStrategy {
+				return redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond), 2)
+			},
+			Metadata: "default",
+		})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, 0, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("default"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		lock, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "custom"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("custom"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+
+		logger := new(capturingLogger)
+		for i := 0; i < 2; i++ {
+			_, err = subject.Obtain(ctx, lockKey, time.Hour, 0, &redislock.Options{Logger: logger})
+			Expect(err).To(MatchError(redislock.ErrNotObtained))
+		}
+		var attempts int
+		for _, msg := range logger.messages() {
+			if strings.HasSuffix(msg, ": obtain attempt") {
+				attempts++
+			}
+		}
+		Expect(attempts).To(Equal(2 * 3))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should prefix keys", func() {
+		subject = redislock.New(redisClient, redislock.Defaults{KeyPrefix: "__bsm_"})
+
+		lock, err := subject.Obtain(ctx, "redislock_unit_test__", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Key()).To(Equal(lockKey))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should assign increasing fencing tokens", func() {
+		opt := &redislock.Options{Fencing: true}
+
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock1.FencingToken()).To(Equal(int64(1)))
+		Expect(lock1.Release(ctx)).To(Succeed())
+
+		lock2, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.FencingToken()).To(Equal(int64(2)))
+		Expect(lock2.Release(ctx)).To(Succeed())
+
+		lock3, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock3.FencingToken()).To(BeZero())
+		Expect(lock3.Release(ctx)).To(Succeed())
+	})
+
+	It("should bump fencing tokens on refresh when requested", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Fencing: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+		Expect(lock.FencingToken()).To(Equal(int64(1)))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.FencingToken()).To(Equal(int64(1)))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil, redislock.RefreshOptions{BumpFence: true})).To(Succeed())
+		Expect(lock.FencingToken()).To(Equal(int64(2)))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.FencingToken()).To(Equal(int64(2)))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil, redislock.RefreshOptions{BumpFence: true})).To(Succeed())
+		Expect(lock.FencingToken()).To(Equal(int64(3)))
+		Expect(lock.TTL(ctx)).To(BeNumerically(">", 59*time.Minute))
+
+		Expect(redisClient.Set(ctx, lockKey, "other", 0).Err()).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Hour, nil, redislock.RefreshOptions{BumpFence: true})).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.FencingToken()).To(Equal(int64(3)))
+	})
+
+	It("should wake waiters on release notifications", func() {
+		opt := &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(time.Minute),
+			ReleaseNotify: true,
+		}
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+
+		released := make(chan time.Time, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			released <- time.Now()
+			_ = lock1.Release(ctx)
+		}()
+
+		lock2, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(<-released)).To(BeNumerically("<", 500*time.Millisecond))
+		Expect(lock2.Release(ctx)).To(Succeed())
+	})
+
+	It("should select DBs per key", func() {
+		defer subject.Close()
+
+		tenantA := &redislock.Options{SelectDB: func(string) int { return 9 }}
+		tenantB := &redislock.Options{SelectDB: func(string) int { return 10 }}
+
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, tenantA)
+		Expect(err).NotTo(HaveOccurred())
+		lock2, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, tenantB)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, tenantB)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock2.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock2.Release(ctx)).To(Succeed())
+		Expect(lock1.Release(ctx)).To(Succeed())
+	})
+
+	It("should log decision points", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+		Expect(redisClient.PExpire(ctx, lockKey, 20*time.Millisecond).Err()).NotTo(HaveOccurred())
+
+		logger := new(capturingLogger)
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 3),
+			Logger:        logger,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(logger.messages()).To(Equal([]string{
+			"debug: obtain attempt",
+			"debug: obtain backoff",
+			"debug: obtain attempt",
+			"info: lock obtained",
+			"debug: lock released",
+			"warn: lock lost",
+		}))
+	})
+
+	It("should log via the client logger with truncated tokens", func() {
+		logger := new(capturingLogger)
+		subject = redislock.New(redisClient, redislock.Defaults{Logger: logger})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(logger.messages()).To(Equal([]string{
+			"debug: obtain attempt",
+			"info: lock obtained",
+			"debug: lock released",
+		}))
+		Expect(logger.tokens).To(ConsistOf(lock.Token()[:6], lock.Token()[:6], lock.Token()[:6]))
+	})
+
 	It("should prevent multiple locks (fuzzing)", func() {
 		numLocks := int32(0)
 		wg := new(sync.WaitGroup)
@@ -131,7 +867,7 @@ var _ = Describe("Client", func() {
 				time.Sleep(time.Duration(wait))
 
 				_, err := subject.Obtain(ctx, lockKey, time.Minute, time.Minute, nil)
-				if err == redislock.ErrNotObtained {
+				if errors.Is(err, redislock.ErrNotObtained) {
 					return
 				}
 				Expect(err).NotTo(HaveOccurred())
@@ -163,6 +899,88 @@ var _ = Describe("RetryStrategy", func() {
 		Expect(subject.NextBackoff()).To(Equal(time.Duration(0)))
 	})
 
+	It("should iterate reusable strategies afresh", func() {
+		subject := redislock.LimitRetry(redislock.ExponentialBackoff(16*time.Millisecond, 0), 2).(redislock.ReusableRetryStrategy)
+		for i := 0; i < 2; i++ {
+			iter := subject.Iterator()
+			Expect(iter.NextBackoff()).To(Equal(16 * time.Millisecond))
+			Expect(iter.NextBackoff()).To(Equal(16 * time.Millisecond))
+			Expect(iter.NextBackoff()).To(Equal(time.Duration(0)))
+		}
+
+		ctx := context.Background()
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		defer redisClient.Del(ctx, lockKey)
+
+		logger := new(capturingLogger)
+		opt := &redislock.Options{RetryStrategy: subject, Logger: logger}
+		for i := 0; i < 2; i++ {
+			_, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Minute, opt)
+			Expect(err).To(MatchError(redislock.ErrNotObtained))
+		}
+		var attempts int
+		for _, msg := range logger.messages() {
+			if strings.HasSuffix(msg, ": obtain attempt") {
+				attempts++
+			}
+		}
+		Expect(attempts).To(Equal(2 * 3))
+	})
+
+	It("should support switching strategies", func() {
+		subject := redislock.SwitchAfter(redislock.LinearBackoff(time.Millisecond), 2, redislock.LimitRetry(redislock.LinearBackoff(time.Second), 1))
+		Expect(subject.NextBackoff()).To(Equal(time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(time.Second))
+		Expect(subject.NextBackoff()).To(Equal(time.Duration(0)))
+	})
+
+	It("should support elapsed time limits", func() {
+		subject := redislock.RetryUntil(redislock.LinearBackoff(20*time.Millisecond), 50*time.Millisecond)
+		Expect(subject.NextBackoff()).To(Equal(20 * time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+		Expect(subject.NextBackoff()).To(Equal(20 * time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+		Expect(subject.NextBackoff()).To(Equal(time.Duration(0)))
+	})
+
+	It("should limit attempts and elapsed time", func() {
+		subject := redislock.Limit(redislock.LinearBackoff(time.Millisecond), 3, time.Hour).(redislock.ReusableRetryStrategy)
+		Expect(subject.NextBackoff()).To(Equal(time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(time.Duration(0)))
+		Expect(subject.Iterator().NextBackoff()).To(Equal(time.Millisecond))
+
+		subject = redislock.Limit(redislock.LinearBackoff(20*time.Millisecond), 0, 50*time.Millisecond).(redislock.ReusableRetryStrategy)
+		it := subject.Iterator()
+		Expect(it.NextBackoff()).To(Equal(20 * time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+		Expect(it.NextBackoff()).To(Equal(20 * time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+		Expect(it.NextBackoff()).To(Equal(time.Duration(0)))
+	})
+
+	It("should support functions", func() {
+		subject := redislock.RetryFunc(func(attempt int, elapsed time.Duration) time.Duration {
+			switch {
+			case elapsed >= 20*time.Millisecond:
+				return 0
+			case attempt <= 2:
+				return time.Millisecond
+			default:
+				return time.Duration(attempt) * time.Second
+			}
+		})
+		Expect(subject.NextBackoff()).To(Equal(time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(3 * time.Second))
+		time.Sleep(20 * time.Millisecond)
+		Expect(subject.NextBackoff()).To(Equal(time.Duration(0)))
+
+		iter := subject.(redislock.ReusableRetryStrategy).Iterator()
+		Expect(iter.NextBackoff()).To(Equal(time.Millisecond))
+	})
+
 	It("should support exponential backoff", func() {
 		subject := redislock.ExponentialBackoff(10*time.Millisecond, 300*time.Millisecond)
 		Expect(subject.NextBackoff()).To(Equal(10 * time.Millisecond))
@@ -176,21 +994,318 @@ var _ = Describe("RetryStrategy", func() {
 		Expect(subject.NextBackoff()).To(Equal(300 * time.Millisecond))
 		Expect(subject.NextBackoff()).To(Equal(300 * time.Millisecond))
 	})
+
+	It("should support full jitter backoff", func() {
+		subject := redislock.FullJitterBackoff(10*time.Millisecond, 50*time.Millisecond)
+		for _, max := range []time.Duration{10, 20, 40, 50, 50} {
+			Expect(subject.NextBackoff()).To(And(
+				BeNumerically(">", 0),
+				BeNumerically("<=", max*time.Millisecond),
+			))
+		}
+	})
+
+	It("should support equal jitter backoff", func() {
+		subject := redislock.EqualJitterBackoff(10*time.Millisecond, 50*time.Millisecond)
+		for _, max := range []time.Duration{10, 20, 40, 50, 50} {
+			Expect(subject.NextBackoff()).To(And(
+				BeNumerically(">=", max*time.Millisecond/2),
+				BeNumerically("<=", max*time.Millisecond),
+			))
+		}
+	})
+
+	It("should support decorrelated jitter backoff", func() {
+		subject := redislock.DecorrelatedJitterBackoff(10*time.Millisecond, 50*time.Millisecond)
+		prev := 10 * time.Millisecond
+		for i := 0; i < 10; i++ {
+			d := subject.NextBackoff()
+			Expect(d).To(BeNumerically(">=", 10*time.Millisecond))
+			Expect(d).To(BeNumerically("<=", 50*time.Millisecond))
+			Expect(d).To(BeNumerically("<=", 3*prev))
+			prev = d
+		}
+	})
 })
 
 // --------------------------------------------------------------------
 
+func BenchmarkClient_Obtain(b *testing.B) {
+	ctx := context.Background()
+	subject := redislock.New(new(stubClient))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Second, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := lock.Release(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_Obtain_metadata(b *testing.B) {
+	ctx := context.Background()
+	subject := redislock.New(new(stubClient), redislock.Defaults{Metadata: "worker-1"})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Second, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := lock.Release(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_Obtain_parallel(b *testing.B) {
+	ctx := context.Background()
+	subject := redislock.New(new(stubClient))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Second, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := lock.Release(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLock_Refresh(b *testing.B) {
+	ctx := context.Background()
+	lock, err := redislock.New(new(stubClient)).Obtain(ctx, lockKey, time.Second, time.Second, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := lock.Refresh(ctx, time.Minute, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRandomToken(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := redislock.RandomToken(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestClient_Obtain_nilOptionsAllocs(t *testing.T) {
+	ctx := context.Background()
+	subject := redislock.New(new(stubClient))
+	cycle := func(opt *redislock.Options) func() {
+		return func() {
+			lock, _ := subject.Obtain(ctx, lockKey, time.Second, time.Second, opt)
+			_ = lock.Release(ctx)
+		}
+	}
+
+	explicit := testing.AllocsPerRun(100, cycle(&redislock.Options{}))
+	implicit := testing.AllocsPerRun(100, cycle(nil))
+	if implicit > explicit {
+		t.Fatalf("expected nil options to allocate no more than explicit options, got %v > %v", implicit, explicit)
+	}
+
+	// Neither options nor log arguments allocate. What remains is inherent to
+	// the cycle:
+	//   - 3 for the context of the wait timeout and 1 for its timer,
+	//   - 1 for the lock value, token and metadata, built in one go,
+	//   - 1 for boxing the value passed to SetNX,
+	//   - 1 for the Lock itself,
+	//   - 1 for boxing the value kept as the script argument of the lock,
+	//   - 1 for the argument slice of the release script.
+	const want = 9
+	if implicit > want {
+		t.Fatalf("expected an obtain/release cycle to allocate at most %d times, got %v", want, implicit)
+	}
+}
+
+// stubClient answers every command successfully without a redis round-trip.
+type stubClient struct{}
+
+var (
+	stubBoolCmd = redis.NewBoolResult(true, nil)
+	stubCmd     = redis.NewCmdResult(int64(1), nil)
+)
+
+func (*stubClient) SetNX(_ context.Context, _ string, _ interface{}, _ time.Duration) *redis.BoolCmd {
+	return stubBoolCmd
+}
+
+func (*stubClient) Eval(_ context.Context, _ string, _ []string, _ ...interface{}) *redis.Cmd {
+	return stubCmd
+}
+
+func (*stubClient) EvalSha(_ context.Context, _ string, _ []string, _ ...interface{}) *redis.Cmd {
+	return stubCmd
+}
+
+func (*stubClient) ScriptExists(_ context.Context, _ ...string) *redis.BoolSliceCmd {
+	return redis.NewBoolSliceResult([]bool{true}, nil)
+}
+
+func (*stubClient) ScriptLoad(_ context.Context, _ string) *redis.StringCmd {
+	return redis.NewStringResult("", nil)
+}
+
+type slowClient struct {
+	redislock.RedisClient
+	delay time.Duration
+}
+
+func (c *slowClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	select {
+	case <-ctx.Done():
+		return redis.NewBoolResult(false, ctx.Err())
+	case <-time.After(c.delay):
+	}
+	return c.RedisClient.SetNX(ctx, key, value, expiration)
+}
+
+// flakyClient fails the first failures commands with a LOADING error.
+type flakyClient struct {
+	redislock.RedisClient
+	failures int32
+}
+
+func (c *flakyClient) fail() bool {
+	return atomic.AddInt32(&c.failures, -1) >= 0
+}
+
+func (c *flakyClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if c.fail() {
+		return redis.NewBoolResult(false, errLoading)
+	}
+	return c.RedisClient.SetNX(ctx, key, value, expiration)
+}
+
+func (c *flakyClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	if c.fail() {
+		return redis.NewCmdResult(nil, errLoading)
+	}
+	return c.RedisClient.EvalSha(ctx, sha1, keys, args...)
+}
+
+// scriptCountingClient counts the scripts run.
+type scriptCountingClient struct {
+	redislock.RedisClient
+	scripts int32
+	setNXs  int32
+}
+
+func (c *scriptCountingClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	atomic.AddInt32(&c.setNXs, 1)
+	return c.RedisClient.SetNX(ctx, key, value, expiration)
+}
+
+func (c *scriptCountingClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	atomic.AddInt32(&c.scripts, 1)
+	return c.RedisClient.Eval(ctx, script, keys, args...)
+}
+
+func (c *scriptCountingClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	atomic.AddInt32(&c.scripts, 1)
+	return c.RedisClient.EvalSha(ctx, sha1, keys, args...)
+}
+
+// noScriptClient fails all scripts.
+type noScriptClient struct {
+	*redis.Client
+}
+
+func (*noScriptClient) Eval(_ context.Context, _ string, _ []string, _ ...interface{}) *redis.Cmd {
+	return redis.NewCmdResult(nil, errors.New("scripts disabled"))
+}
+
+func (*noScriptClient) EvalSha(_ context.Context, _ string, _ []string, _ ...interface{}) *redis.Cmd {
+	return redis.NewCmdResult(nil, errors.New("scripts disabled"))
+}
+
+var errLoading error = redisError("LOADING Redis is loading the dataset in memory")
+
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (redisError) RedisError() {}
+
+type capturingLogger struct {
+	entries []string
+	tokens  []interface{}
+	mu      sync.Mutex
+}
+
+func (l *capturingLogger) Log(level redislock.LogLevel, msg string, keyvals ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, level.String()+": "+msg)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == "token" {
+			l.tokens = append(l.tokens, keyvals[i+1])
+		}
+	}
+}
+
+func (l *capturingLogger) messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]string(nil), l.entries...)
+}
+
+// --------------------------------------------------------------------
+
 func TestSuite(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "redislock")
 }
 
+// redisAddr is the address of the redis server the suite and examples run
+// against, a redislocktest.Server if REDISLOCK_TEST_EMBEDDED is set.
+var redisAddr = "127.0.0.1:6379"
+
+func TestMain(m *testing.M) {
+	if os.Getenv("REDISLOCK_TEST_EMBEDDED") == "" {
+		os.Exit(m.Run())
+	}
+
+	srv, err := redislocktest.NewServer()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	redisAddr = srv.Addr()
+
+	code := m.Run()
+	_ = srv.Close()
+	os.Exit(code)
+}
+
 var redisClient *redis.Client
 
 var _ = BeforeSuite(func() {
 	redisClient = redis.NewClient(&redis.Options{
 		Network: "tcp",
-		Addr:    "127.0.0.1:6379", DB: 9,
+		Addr:    redisAddr, DB: 9,
 	})
 	Expect(redisClient.Ping(context.Background()).Err()).To(Succeed())
 })
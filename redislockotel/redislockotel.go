@@ -0,0 +1,71 @@
+// Package redislockotel traces redislock operations with OpenTelemetry.
+package redislockotel
+
+import (
+	"context"
+
+	"github.com/muroq/redislock"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+)
+
+const tracerName = "github.com/muroq/redislock"
+
+var _ redislock.Tracer = (*Tracer)(nil)
+
+// Options configure the Tracer.
+type Options struct {
+	// TracerProvider creates the tracer which records the spans.
+	// Default: the global provider, see global.SetTraceProvider
+	TracerProvider trace.Provider
+}
+
+// Tracer is a redislock.Tracer, which records lock operations as
+// OpenTelemetry spans. Pass it as redislock.Defaults.Tracer. Like go-redis,
+// it only creates spans if the context of an operation already carries a
+// recording span.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New creates a new Tracer.
+func New(opt *Options) *Tracer {
+	t := new(Tracer)
+	if opt != nil && opt.TracerProvider != nil {
+		t.tracer = opt.TracerProvider.Tracer(tracerName)
+	}
+	return t
+}
+
+// StartSpan implements redislock.Tracer.
+func (t *Tracer) StartSpan(ctx context.Context, name, key string) (context.Context, redislock.Span) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx, nil
+	}
+
+	tracer := t.tracer
+	if tracer == nil {
+		tracer = global.Tracer(tracerName)
+	}
+	ctx, s := tracer.Start(ctx, name, trace.WithAttributes(label.String("redislock.key", key)))
+	return ctx, &span{ctx: ctx, span: s}
+}
+
+type span struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+func (s *span) SetAttempts(n int) {
+	s.span.SetAttributes(label.Int("redislock.attempts", n))
+}
+
+func (s *span) End(result string, err error) {
+	s.span.SetAttributes(label.String("redislock.result", result))
+	if result == "error" {
+		s.span.RecordError(s.ctx, err, trace.WithErrorStatus(codes.Internal))
+	}
+	s.span.End()
+}
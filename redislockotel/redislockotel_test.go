@@ -0,0 +1,95 @@
+package redislockotel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redislockotel"
+	"github.com/muroq/redislock/redislocktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/api/trace/tracetest"
+	"go.opentelemetry.io/otel/label"
+)
+
+var _ = Describe("Tracer", func() {
+	var provider *tracetest.Provider
+	var recorder *tracetest.StandardSpanRecorder
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		recorder = new(tracetest.StandardSpanRecorder)
+		provider = tracetest.NewProvider(tracetest.WithSpanRecorder(recorder))
+		subject = redislock.New(redislocktest.New(), redislock.Defaults{
+			Tracer: redislockotel.New(&redislockotel.Options{TracerProvider: provider}),
+		})
+	})
+
+	completed := func() map[string]*tracetest.Span {
+		spans := make(map[string]*tracetest.Span)
+		for _, span := range recorder.Completed() {
+			spans[span.Name()] = span
+		}
+		return spans
+	}
+
+	It("should not trace without a parent span", func() {
+		lock, err := subject.Obtain(ctx, "key", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(completed()).NotTo(HaveKey("redislock.obtain"))
+	})
+
+	It("should trace obtain, refresh and release", func() {
+		tctx, parent := provider.Tracer("test").Start(ctx, "parent")
+		defer parent.End()
+
+		held, err := subject.Obtain(tctx, "key", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(tctx, "key", time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond), 2),
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		obtain := completed()["redislock.obtain"]
+		Expect(obtain).NotTo(BeNil())
+		Expect(obtain.ParentSpanID()).To(Equal(parent.SpanContext().SpanID))
+		Expect(obtain.Attributes()).To(HaveKeyWithValue(label.Key("redislock.key"), label.StringValue("key")))
+		Expect(obtain.Attributes()).To(HaveKeyWithValue(label.Key("redislock.attempts"), label.IntValue(3)))
+		Expect(obtain.Attributes()).To(HaveKeyWithValue(label.Key("redislock.result"), label.StringValue("not_obtained")))
+
+		Expect(held.Refresh(tctx, time.Hour, nil)).To(Succeed())
+		Expect(completed()["redislock.refresh"].Attributes()).To(HaveKeyWithValue(label.Key("redislock.result"), label.StringValue("ok")))
+
+		Expect(held.Release(tctx)).To(Succeed())
+		Expect(held.Release(tctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(completed()["redislock.release"].Attributes()).To(HaveKeyWithValue(label.Key("redislock.result"), label.StringValue("lost")))
+	})
+
+	It("should default to the global provider", func() {
+		global.SetTraceProvider(provider)
+		defer global.SetTraceProvider(trace.NoopProvider{})
+		subject = redislock.New(redislocktest.New(), redislock.Defaults{Tracer: redislockotel.New(nil)})
+
+		tctx, parent := provider.Tracer("test").Start(ctx, "parent")
+		defer parent.End()
+
+		lock, err := subject.Obtain(tctx, "key", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(tctx)).To(Succeed())
+		Expect(completed()).To(HaveKey("redislock.obtain"))
+		Expect(completed()).To(HaveKey("redislock.release"))
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock/redislockotel")
+}
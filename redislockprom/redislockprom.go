@@ -0,0 +1,180 @@
+// Package redislockprom exports redislock metrics to Prometheus.
+package redislockprom
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muroq/redislock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	_ redislock.MetricsCollector     = (*Collector)(nil)
+	_ redislock.HeldMetricsCollector = (*Collector)(nil)
+)
+
+// Options configure the Collector.
+type Options struct {
+	// Namespace is the metric namespace.
+	// Default: redislock
+	Namespace string
+
+	// KeyLabel maps lock keys to the value of the key label. Lock keys are
+	// often unbounded, so the default drops them to keep the cardinality low.
+	// Default: all keys share the empty label
+	KeyLabel func(key string) string
+
+	// Buckets are the histogram buckets in seconds.
+	// Default: prometheus.DefBuckets
+	Buckets []float64
+}
+
+func (o *Options) getNamespace() string {
+	if o != nil && o.Namespace != "" {
+		return o.Namespace
+	}
+	return "redislock"
+}
+
+func (o *Options) getKeyLabel() func(string) string {
+	if o != nil && o.KeyLabel != nil {
+		return o.KeyLabel
+	}
+	return func(string) string { return "" }
+}
+
+func (o *Options) getBuckets() []float64 {
+	if o != nil && len(o.Buckets) != 0 {
+		return o.Buckets
+	}
+	return prometheus.DefBuckets
+}
+
+// Collector is a redislock.MetricsCollector, which exports the metrics as a
+// prometheus.Collector. Pass it as redislock.Defaults.Metrics and register it
+// with a prometheus.Registerer.
+type Collector struct {
+	keyLabel func(string) string
+
+	attempts  *prometheus.CounterVec
+	retries   *prometheus.CounterVec
+	obtains   *prometheus.CounterVec
+	wait      *prometheus.HistogramVec
+	refreshes *prometheus.CounterVec
+	held      *prometheus.HistogramVec
+	locks     *prometheus.GaugeVec
+}
+
+// New creates a new Collector.
+func New(opt *Options) *Collector {
+	ns := opt.getNamespace()
+	buckets := opt.getBuckets()
+
+	return &Collector{
+		keyLabel: opt.getKeyLabel(),
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "obtain_attempts_total",
+			Help:      "Number of attempts to obtain a lock.",
+		}, []string{"key"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "obtain_retries_total",
+			Help:      "Number of retried attempts to obtain a lock.",
+		}, []string{"key"}),
+		obtains: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "obtain_total",
+			Help:      "Number of calls to obtain a lock by result (obtained, not_obtained, error).",
+		}, []string{"key", "result"}),
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Name:      "obtain_wait_seconds",
+			Help:      "Time spent waiting to obtain a lock.",
+			Buckets:   buckets,
+		}, []string{"key"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "refresh_total",
+			Help:      "Number of lock refreshes by result (ok, lost, error).",
+		}, []string{"key", "result"}),
+		held: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Name:      "hold_seconds",
+			Help:      "Time locks were held for until released.",
+			Buckets:   buckets,
+		}, []string{"key"}),
+		locks: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns,
+			Name:      "locks_held",
+			Help:      "Number of locks currently held, see redislock.Defaults.Register.",
+		}, []string{"key"}),
+	}
+}
+
+// ObtainAttempt implements redislock.MetricsCollector.
+func (c *Collector) ObtainAttempt(key string, retry bool) {
+	key = c.keyLabel(key)
+	c.attempts.WithLabelValues(key).Inc()
+	if retry {
+		c.retries.WithLabelValues(key).Inc()
+	}
+}
+
+// ObtainDone implements redislock.MetricsCollector.
+func (c *Collector) ObtainDone(key string, wait time.Duration, err error) {
+	key = c.keyLabel(key)
+
+	result := "obtained"
+	if errors.Is(err, redislock.ErrNotObtained) {
+		result = "not_obtained"
+	} else if err != nil {
+		result = "error"
+	}
+	c.obtains.WithLabelValues(key, result).Inc()
+	c.wait.WithLabelValues(key).Observe(wait.Seconds())
+}
+
+// RefreshDone implements redislock.MetricsCollector.
+func (c *Collector) RefreshDone(key string, err error) {
+	result := "ok"
+	if errors.Is(err, redislock.ErrNotObtained) {
+		result = "lost"
+	} else if err != nil {
+		result = "error"
+	}
+	c.refreshes.WithLabelValues(c.keyLabel(key), result).Inc()
+}
+
+// Released implements redislock.MetricsCollector.
+func (c *Collector) Released(key string, held time.Duration) {
+	c.held.WithLabelValues(c.keyLabel(key)).Observe(held.Seconds())
+}
+
+// HeldChanged implements redislock.HeldMetricsCollector.
+func (c *Collector) HeldChanged(key string, delta int) {
+	c.locks.WithLabelValues(c.keyLabel(key)).Add(float64(delta))
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.attempts.Describe(ch)
+	c.retries.Describe(ch)
+	c.obtains.Describe(ch)
+	c.wait.Describe(ch)
+	c.refreshes.Describe(ch)
+	c.held.Describe(ch)
+	c.locks.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.attempts.Collect(ch)
+	c.retries.Collect(ch)
+	c.obtains.Collect(ch)
+	c.wait.Collect(ch)
+	c.refreshes.Collect(ch)
+	c.held.Collect(ch)
+	c.locks.Collect(ch)
+}
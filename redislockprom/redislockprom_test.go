@@ -0,0 +1,105 @@
+package redislockprom_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redislockprom"
+	"github.com/muroq/redislock/redislocktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Collector", func() {
+	var backend *redislocktest.Client
+	var collector *redislockprom.Collector
+	var registry *prometheus.Registry
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		backend = redislocktest.New()
+		collector = redislockprom.New(&redislockprom.Options{
+			KeyLabel: func(key string) string { return strings.SplitN(key, ":", 2)[0] },
+		})
+		registry = prometheus.NewRegistry()
+		registry.MustRegister(collector)
+		subject = redislock.New(backend, redislock.Defaults{Metrics: collector})
+	})
+
+	It("should record obtains and retries", func() {
+		lock, err := subject.Obtain(ctx, "jobs:1", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(ctx, "jobs:1", time.Hour, time.Minute, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond), 2),
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP redislock_obtain_attempts_total Number of attempts to obtain a lock.
+# TYPE redislock_obtain_attempts_total counter
+redislock_obtain_attempts_total{key="jobs"} 4
+# HELP redislock_obtain_retries_total Number of retried attempts to obtain a lock.
+# TYPE redislock_obtain_retries_total counter
+redislock_obtain_retries_total{key="jobs"} 2
+# HELP redislock_obtain_total Number of calls to obtain a lock by result (obtained, not_obtained, error).
+# TYPE redislock_obtain_total counter
+redislock_obtain_total{key="jobs",result="not_obtained"} 1
+redislock_obtain_total{key="jobs",result="obtained"} 1
+`), "redislock_obtain_attempts_total", "redislock_obtain_retries_total", "redislock_obtain_total")).To(Succeed())
+		Expect(testutil.CollectAndCount(collector, "redislock_obtain_wait_seconds")).To(Equal(1))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should record refreshes and hold durations", func() {
+		lock, err := subject.Obtain(ctx, "jobs:1", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockExpired))
+
+		Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP redislock_refresh_total Number of lock refreshes by result (ok, lost, error).
+# TYPE redislock_refresh_total counter
+redislock_refresh_total{key="jobs",result="lost"} 1
+redislock_refresh_total{key="jobs",result="ok"} 1
+`), "redislock_refresh_total")).To(Succeed())
+		Expect(testutil.CollectAndCount(collector, "redislock_hold_seconds")).To(Equal(1))
+	})
+
+	It("should track held locks", func() {
+		subject = redislock.New(backend, redislock.Defaults{Metrics: collector, Register: true})
+		lock1, err := subject.Obtain(ctx, "jobs:1", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		lock2, err := subject.Obtain(ctx, "jobs:2", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP redislock_locks_held Number of locks currently held, see redislock.Defaults.Register.
+# TYPE redislock_locks_held gauge
+redislock_locks_held{key="jobs"} 2
+`), "redislock_locks_held")).To(Succeed())
+
+		Expect(lock1.Release(ctx)).To(Succeed())
+		Eventually(func() error {
+			return testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP redislock_locks_held Number of locks currently held, see redislock.Defaults.Register.
+# TYPE redislock_locks_held gauge
+redislock_locks_held{key="jobs"} 1
+`), "redislock_locks_held")
+		}).Should(Succeed())
+		Expect(lock2.Release(ctx)).To(Succeed())
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock/redislockprom")
+}
@@ -0,0 +1,281 @@
+package redislocktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+)
+
+var _ redislock.RedisClient = (*ChaosClient)(nil)
+
+var (
+	// ErrInjected is returned by a ChaosClient for commands which failed
+	// before they were executed.
+	ErrInjected = errors.New("redislocktest: injected error")
+
+	// ErrDropped is returned by a ChaosClient for commands which were
+	// executed, but whose response was lost.
+	ErrDropped = errors.New("redislocktest: response dropped")
+
+	// ErrFailover is returned by a ChaosClient for all commands during a
+	// simulated failover. It is classified as transient by
+	// redislock.IsTransientError.
+	ErrFailover error = failoverError{}
+)
+
+type failoverError struct{}
+
+func (failoverError) Error() string { return "LOADING redislocktest: failover in progress" }
+
+func (failoverError) RedisError() {}
+
+// Faults describe the faults injected by a ChaosClient.
+type Faults struct {
+	// Latency is the maximum random delay before each command is sent.
+	Latency time.Duration
+
+	// ErrorRate is the probability of a command failing with ErrInjected
+	// without being executed.
+	ErrorRate float64
+
+	// DropRate is the probability of a command being executed, but failing
+	// with ErrDropped, e.g. because the connection broke before the
+	// response arrived.
+	DropRate float64
+}
+
+// ChaosClient wraps a redislock.RedisClient, e.g. a Client or a connection to
+// a real deployment, and injects faults into the commands used by redislock.
+// It is safe for concurrent use.
+type ChaosClient struct {
+	client redislock.RedisClient
+
+	faults   Faults
+	failover time.Time
+	rnd      *rand.Rand
+	mu       sync.Mutex
+}
+
+// NewChaos wraps client, injecting faults. The seed makes the sequence of
+// faults reproducible.
+func NewChaos(client redislock.RedisClient, faults Faults, seed int64) *ChaosClient {
+	return &ChaosClient{client: client, faults: faults, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// SetFaults replaces the injected faults.
+func (c *ChaosClient) SetFaults(faults Faults) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.faults = faults
+}
+
+// Failover simulates a failover, during which all commands fail with
+// ErrFailover for d.
+func (c *ChaosClient) Failover(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failover = time.Now().Add(d)
+}
+
+// SetNX implements redislock.RedisClient.
+func (c *ChaosClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	drop, err := c.inject(ctx)
+	if err != nil {
+		return redis.NewBoolResult(false, err)
+	}
+
+	cmd := c.client.SetNX(ctx, key, value, expiration)
+	if drop {
+		return redis.NewBoolResult(false, ErrDropped)
+	}
+	return cmd
+}
+
+// Eval implements redislock.RedisClient.
+func (c *ChaosClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	drop, err := c.inject(ctx)
+	if err != nil {
+		return redis.NewCmdResult(nil, err)
+	}
+
+	cmd := c.client.Eval(ctx, script, keys, args...)
+	if drop {
+		return redis.NewCmdResult(nil, ErrDropped)
+	}
+	return cmd
+}
+
+// EvalSha implements redislock.RedisClient.
+func (c *ChaosClient) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	drop, err := c.inject(ctx)
+	if err != nil {
+		return redis.NewCmdResult(nil, err)
+	}
+
+	cmd := c.client.EvalSha(ctx, sha1, keys, args...)
+	if drop {
+		return redis.NewCmdResult(nil, ErrDropped)
+	}
+	return cmd
+}
+
+// ScriptExists implements redislock.RedisClient. No faults are injected.
+func (c *ChaosClient) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	return c.client.ScriptExists(ctx, hashes...)
+}
+
+// ScriptLoad implements redislock.RedisClient. No faults are injected.
+func (c *ChaosClient) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	return c.client.ScriptLoad(ctx, script)
+}
+
+// inject delays the command and decides whether it fails or its response is
+// dropped.
+func (c *ChaosClient) inject(ctx context.Context) (drop bool, err error) {
+	c.mu.Lock()
+	faults := c.faults
+	failover := time.Now().Before(c.failover)
+	var delay time.Duration
+	if faults.Latency > 0 {
+		delay = time.Duration(c.rnd.Int63n(int64(faults.Latency)))
+	}
+	fail := c.rnd.Float64() < faults.ErrorRate
+	drop = c.rnd.Float64() < faults.DropRate
+	c.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if failover {
+		return false, ErrFailover
+	} else if fail {
+		return false, ErrInjected
+	}
+	return drop, nil
+}
+
+// --------------------------------------------------------------------
+
+// ErrExclusionViolated is returned by CheckMutualExclusion if two workers
+// held the lock at the same time.
+var ErrExclusionViolated = errors.New("redislocktest: mutual exclusion violated")
+
+// ExclusionTest configures CheckMutualExclusion.
+type ExclusionTest struct {
+	// Workers is the number of concurrent workers.
+	// Default: 10
+	Workers int
+
+	// Duration is the duration of the test.
+	// Default: 1s
+	Duration time.Duration
+
+	// TTL is the lock TTL.
+	// Default: 100ms
+	TTL time.Duration
+
+	// HoldTime is the time a worker holds an obtained lock. Only the part
+	// within the lock's validity counts as held.
+	// Default: 10ms
+	HoldTime time.Duration
+
+	// Options are passed to Obtain by all workers, e.g. to configure
+	// retries. The RetryStrategy must be safe for concurrent use, or
+	// implement redislock.ReusableRetryStrategy like the built-in ones.
+	// Default: retry every half HoldTime
+	Options *redislock.Options
+}
+
+// CheckMutualExclusion lets concurrent workers obtain and release a lock on
+// key via client for the duration of the test, e.g. while faults are injected
+// via a ChaosClient. A worker is considered to hold a lock from the time
+// Obtain returns until it starts to release it, cut short at Lock.ValidUntil.
+// It returns the number of acquisitions, or ErrExclusionViolated if two
+// workers held the lock at the same time. Run it against a redis server, as
+// the fake clock of Client does not advance on its own.
+func CheckMutualExclusion(ctx context.Context, client *redislock.Client, key string, t ExclusionTest) (int, error) {
+	if t.Workers <= 0 {
+		t.Workers = 10
+	}
+	if t.Duration <= 0 {
+		t.Duration = time.Second
+	}
+	if t.TTL <= 0 {
+		t.TTL = 100 * time.Millisecond
+	}
+	if t.HoldTime <= 0 {
+		t.HoldTime = 10 * time.Millisecond
+	}
+
+	if t.Options == nil {
+		t.Options = &redislock.Options{RetryStrategy: redislock.LinearBackoff(t.HoldTime / 2)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.Duration)
+	defer cancel()
+
+	var holds []hold
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < t.Workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				lock, err := client.Obtain(ctx, key, t.TTL, t.TTL, t.Options)
+				if err != nil {
+					continue
+				}
+
+				start := time.Now()
+				time.Sleep(t.HoldTime)
+				end := time.Now()
+				if validUntil := lock.ValidUntil(); end.After(validUntil) {
+					end = validUntil
+				}
+				_ = lock.Release(context.Background())
+
+				if end.After(start) {
+					mu.Lock()
+					holds = append(holds, hold{worker: worker, start: start, end: end})
+					mu.Unlock()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Slice(holds, func(i, j int) bool { return holds[i].start.Before(holds[j].start) })
+	for i, last := 1, 0; i < len(holds); i++ {
+		if holds[i].start.Before(holds[last].end) {
+			return len(holds), fmt.Errorf("%w: workers %d and %d held %q at the same time", ErrExclusionViolated, holds[last].worker, holds[i].worker, key)
+		} else if holds[i].end.After(holds[last].end) {
+			last = i
+		}
+	}
+	return len(holds), nil
+}
+
+// hold is the time a worker held a valid lock.
+type hold struct {
+	worker     int
+	start, end time.Time
+}
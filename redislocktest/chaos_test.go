@@ -0,0 +1,64 @@
+package redislocktest_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redislocktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChaosClient", func() {
+	var backend *redislocktest.Client
+	var chaos *redislocktest.ChaosClient
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		backend = redislocktest.New()
+		chaos = redislocktest.NewChaos(backend, redislocktest.Faults{}, 1)
+		subject = redislock.New(chaos)
+	})
+
+	It("should inject faults", func() {
+		chaos.SetFaults(redislocktest.Faults{ErrorRate: 1})
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislocktest.ErrInjected))
+		Expect(backend.SetNX(ctx, lockKey, "x", time.Hour).Val()).To(BeTrue())
+		backend.FlushAll()
+
+		chaos.SetFaults(redislocktest.Faults{DropRate: 1})
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislocktest.ErrDropped))
+		Expect(backend.SetNX(ctx, lockKey, "x", time.Hour).Val()).To(BeFalse())
+		backend.FlushAll()
+
+		chaos.SetFaults(redislocktest.Faults{Latency: 20 * time.Millisecond})
+		chaos.Failover(time.Hour)
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislocktest.ErrFailover))
+		Expect(redislock.IsTransientError(err)).To(BeTrue())
+	})
+
+	It("should check mutual exclusion", func() {
+		chaos.SetFaults(redislocktest.Faults{Latency: time.Millisecond})
+		n, err := redislocktest.CheckMutualExclusion(ctx, subject, lockKey, redislocktest.ExclusionTest{
+			Workers:  5,
+			Duration: 200 * time.Millisecond,
+			HoldTime: 2 * time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(BeNumerically(">", 5))
+	})
+
+	It("should detect violations", func() {
+		_, err := redislocktest.CheckMutualExclusion(ctx, subject, lockKey, redislocktest.ExclusionTest{
+			Workers:  5,
+			Duration: 100 * time.Millisecond,
+			Options:  &redislock.Options{IdempotencyToken: "shared"},
+		})
+		Expect(err).To(MatchError(redislocktest.ErrExclusionViolated))
+	})
+})
@@ -0,0 +1,878 @@
+package redislocktest
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errSyntax      = errors.New("ERR syntax error")
+	errWrongType   = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInteger  = errors.New("ERR value is not an integer or out of range")
+	errNotFloat    = errors.New("ERR value is not a valid float")
+	errMinMaxFloat = errors.New("ERR min or max is not a float")
+)
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindList
+	kindZSet
+	kindHash
+)
+
+// status is a status reply, such as OK.
+type status string
+
+type entry struct {
+	kind     kind
+	str      string
+	list     []string
+	zset     map[string]float64
+	hash     map[string]string
+	expireAt time.Time
+}
+
+type member struct {
+	name  string
+	score float64
+}
+
+// lookup returns the entry stored at key, removing it if expired. The caller
+// must hold c.mu.
+func (c *Client) lookup(key string) *entry {
+	e, ok := c.data[key]
+	if !ok {
+		return nil
+	}
+	if !e.expireAt.IsZero() && !c.now.Before(e.expireAt) {
+		delete(c.data, key)
+		return nil
+	}
+	return e
+}
+
+func (c *Client) lookupKind(key string, k kind) (*entry, error) {
+	e := c.lookup(key)
+	if e != nil && e.kind != k {
+		return nil, errWrongType
+	}
+	return e, nil
+}
+
+// exec executes a single command. The caller must hold c.mu.
+func (c *Client) exec(cmd string, args []string) (interface{}, error) {
+	fn, ok := commands[cmd]
+	if !ok {
+		return nil, errors.New("ERR unknown command '" + cmd + "'")
+	}
+	if len(args) < fn.minArgs {
+		return nil, errors.New("ERR wrong number of arguments for '" + cmd + "' command")
+	}
+	return fn.exec(c, args)
+}
+
+var commands = map[string]struct {
+	minArgs int
+	exec    func(*Client, []string) (interface{}, error)
+}{
+	"time":             {0, (*Client).cmdTime},
+	"get":              {1, (*Client).cmdGet},
+	"set":              {2, (*Client).cmdSet},
+	"del":              {1, (*Client).cmdDel},
+	"unlink":           {1, (*Client).cmdDel},
+	"exists":           {1, (*Client).cmdExists},
+	"type":             {1, (*Client).cmdType},
+	"incr":             {1, (*Client).cmdIncr},
+	"incrby":           {2, (*Client).cmdIncrBy},
+	"decrby":           {2, (*Client).cmdDecrBy},
+	"pexpire":          {2, (*Client).cmdPExpire},
+	"pexpireat":        {2, (*Client).cmdPExpireAt},
+	"pttl":             {1, (*Client).cmdPTTL},
+	"publish":          {2, (*Client).cmdPublish},
+	"rpush":            {2, (*Client).cmdRPush},
+	"lpush":            {2, (*Client).cmdLPush},
+	"lpop":             {1, (*Client).cmdLPop},
+	"llen":             {1, (*Client).cmdLLen},
+	"lrange":           {3, (*Client).cmdLRange},
+	"ltrim":            {3, (*Client).cmdLTrim},
+	"zadd":             {3, (*Client).cmdZAdd},
+	"zrem":             {2, (*Client).cmdZRem},
+	"zscore":           {2, (*Client).cmdZScore},
+	"zrank":            {2, (*Client).cmdZRank},
+	"zcard":            {1, (*Client).cmdZCard},
+	"zcount":           {3, (*Client).cmdZCount},
+	"zrange":           {3, (*Client).cmdZRange},
+	"zrangebyscore":    {3, (*Client).cmdZRangeByScore},
+	"zremrangebyscore": {3, (*Client).cmdZRemRangeByScore},
+	"hget":             {2, (*Client).cmdHGet},
+	"hmget":            {2, (*Client).cmdHMGet},
+	"hgetall":          {1, (*Client).cmdHGetAll},
+	"hset":             {3, (*Client).cmdHSet},
+	"hsetnx":           {3, (*Client).cmdHSetNX},
+	"hdel":             {2, (*Client).cmdHDel},
+	"hexists":          {2, (*Client).cmdHExists},
+	"hincrby":          {3, (*Client).cmdHIncrBy},
+	"hlen":             {1, (*Client).cmdHLen},
+}
+
+func (c *Client) cmdTime(_ []string) (interface{}, error) {
+	usec := c.now.UnixNano() / int64(time.Microsecond)
+	return []string{
+		strconv.FormatInt(usec/1e6, 10),
+		strconv.FormatInt(usec%1e6, 10),
+	}, nil
+}
+
+func (c *Client) cmdGet(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindString)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	return e.str, nil
+}
+
+func (c *Client) cmdSet(args []string) (interface{}, error) {
+	var nx, xx, get, keepTTL bool
+	var ttl time.Duration
+	for i := 2; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "get":
+			get = true
+		case "keepttl":
+			keepTTL = true
+		case "px", "ex":
+			if i+1 >= len(args) {
+				return nil, errSyntax
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return nil, errNotInteger
+			}
+			ttl = time.Duration(n) * time.Millisecond
+			if strings.ToLower(args[i]) == "ex" {
+				ttl *= 1000
+			}
+			i++
+		default:
+			return nil, errSyntax
+		}
+	}
+
+	prev := c.lookup(args[0])
+	var reply interface{} = status("OK")
+	if get {
+		if prev != nil && prev.kind != kindString {
+			return nil, errWrongType
+		}
+		reply = nil
+		if prev != nil {
+			reply = prev.str
+		}
+	}
+	if (nx && prev != nil) || (xx && prev == nil) {
+		if get {
+			return reply, nil
+		}
+		return nil, nil
+	}
+
+	e := &entry{kind: kindString, str: args[1]}
+	if ttl > 0 {
+		e.expireAt = c.now.Add(ttl)
+	} else if keepTTL && prev != nil {
+		e.expireAt = prev.expireAt
+	}
+	c.data[args[0]] = e
+	return reply, nil
+}
+
+func (c *Client) cmdDel(args []string) (interface{}, error) {
+	var n int64
+	for _, key := range args {
+		if c.lookup(key) != nil {
+			delete(c.data, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *Client) cmdExists(args []string) (interface{}, error) {
+	var n int64
+	for _, key := range args {
+		if c.lookup(key) != nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *Client) cmdType(args []string) (interface{}, error) {
+	e := c.lookup(args[0])
+	if e == nil {
+		return status("none"), nil
+	}
+	switch e.kind {
+	case kindList:
+		return status("list"), nil
+	case kindZSet:
+		return status("zset"), nil
+	case kindHash:
+		return status("hash"), nil
+	}
+	return status("string"), nil
+}
+
+func (c *Client) cmdIncr(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindString)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		e = &entry{kind: kindString, str: "0"}
+		c.data[args[0]] = e
+	}
+
+	n, err := strconv.ParseInt(e.str, 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	n++
+	e.str = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (c *Client) cmdIncrBy(args []string) (interface{}, error) {
+	n, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	return c.incrBy(args[0], n)
+}
+
+func (c *Client) cmdDecrBy(args []string) (interface{}, error) {
+	n, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	return c.incrBy(args[0], -n)
+}
+
+func (c *Client) incrBy(key string, by int64) (interface{}, error) {
+	e, err := c.lookupKind(key, kindString)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		e = &entry{kind: kindString, str: "0"}
+		c.data[key] = e
+	}
+
+	n, err := strconv.ParseInt(e.str, 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	n += by
+	e.str = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (c *Client) cmdPExpire(args []string) (interface{}, error) {
+	ms, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	return c.expireAt(args[0], c.now.Add(time.Duration(ms)*time.Millisecond)), nil
+}
+
+func (c *Client) cmdPExpireAt(args []string) (interface{}, error) {
+	ms, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	return c.expireAt(args[0], time.Unix(0, ms*int64(time.Millisecond))), nil
+}
+
+func (c *Client) expireAt(key string, at time.Time) int64 {
+	e := c.lookup(key)
+	if e == nil {
+		return 0
+	}
+	if !c.now.Before(at) {
+		delete(c.data, key)
+		return 1
+	}
+	e.expireAt = at
+	return 1
+}
+
+func (c *Client) cmdPTTL(args []string) (interface{}, error) {
+	e := c.lookup(args[0])
+	if e == nil {
+		return int64(-2), nil
+	} else if e.expireAt.IsZero() {
+		return int64(-1), nil
+	}
+	return int64(e.expireAt.Sub(c.now) / time.Millisecond), nil
+}
+
+func (c *Client) cmdPublish(args []string) (interface{}, error) {
+	return int64(0), nil
+}
+
+func (c *Client) cmdRPush(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindList)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		e = &entry{kind: kindList}
+		c.data[args[0]] = e
+	}
+	e.list = append(e.list, args[1:]...)
+	return int64(len(e.list)), nil
+}
+
+func (c *Client) cmdLPush(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindList)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		e = &entry{kind: kindList}
+		c.data[args[0]] = e
+	}
+	for _, v := range args[1:] {
+		e.list = append([]string{v}, e.list...)
+	}
+	return int64(len(e.list)), nil
+}
+
+func (c *Client) cmdLPop(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindList)
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	v := e.list[0]
+	if e.list = e.list[1:]; len(e.list) == 0 {
+		delete(c.data, args[0])
+	}
+	return v, nil
+}
+
+func (c *Client) cmdLLen(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindList)
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+	return int64(len(e.list)), nil
+}
+
+func (c *Client) cmdLRange(args []string) (interface{}, error) {
+	start, err1 := strconv.Atoi(args[1])
+	stop, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		return nil, errNotInteger
+	}
+
+	e, err := c.lookupKind(args[0], kindList)
+	if err != nil || e == nil {
+		return []string{}, err
+	}
+
+	start, stop = normalizeRange(start, stop, len(e.list))
+	if start > stop {
+		return []string{}, nil
+	}
+	return append([]string(nil), e.list[start:stop+1]...), nil
+}
+
+func (c *Client) cmdLTrim(args []string) (interface{}, error) {
+	start, err1 := strconv.Atoi(args[1])
+	stop, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		return nil, errNotInteger
+	}
+
+	e, err := c.lookupKind(args[0], kindList)
+	if err != nil || e == nil {
+		return status("OK"), err
+	}
+
+	start, stop = normalizeRange(start, stop, len(e.list))
+	if start > stop {
+		delete(c.data, args[0])
+	} else {
+		e.list = append([]string(nil), e.list[start:stop+1]...)
+	}
+	return status("OK"), nil
+}
+
+func (c *Client) cmdZAdd(args []string) (interface{}, error) {
+	if len(args)%2 != 1 {
+		return nil, errSyntax
+	}
+
+	scores := make([]float64, 0, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		score, err := parseScore(args[i])
+		if err != nil {
+			return nil, errNotFloat
+		}
+		scores = append(scores, score)
+	}
+
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		e = &entry{kind: kindZSet, zset: make(map[string]float64)}
+		c.data[args[0]] = e
+	}
+
+	var added int64
+	for i, score := range scores {
+		name := args[2+2*i]
+		if _, ok := e.zset[name]; !ok {
+			added++
+		}
+		e.zset[name] = score
+	}
+	return added, nil
+}
+
+func (c *Client) cmdZRem(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+
+	var n int64
+	for _, name := range args[1:] {
+		if _, ok := e.zset[name]; ok {
+			delete(e.zset, name)
+			n++
+		}
+	}
+	if len(e.zset) == 0 {
+		delete(c.data, args[0])
+	}
+	return n, nil
+}
+
+func (c *Client) cmdZScore(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	score, ok := e.zset[args[1]]
+	if !ok {
+		return nil, nil
+	}
+	return formatScore(score), nil
+}
+
+func (c *Client) cmdZRank(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	for i, m := range sortedMembers(e.zset) {
+		if m.name == args[1] {
+			return int64(i), nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *Client) cmdZCard(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+	return int64(len(e.zset)), nil
+}
+
+func (c *Client) cmdZCount(args []string) (interface{}, error) {
+	inRange, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+
+	var n int64
+	for _, score := range e.zset {
+		if inRange(score) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *Client) cmdZRange(args []string) (interface{}, error) {
+	start, err1 := strconv.Atoi(args[1])
+	stop, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil {
+		return nil, errNotInteger
+	}
+	withScores, err := parseWithScores(args[3:])
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil || e == nil {
+		return []string{}, err
+	}
+
+	members := sortedMembers(e.zset)
+	start, stop = normalizeRange(start, stop, len(members))
+	if start > stop {
+		return []string{}, nil
+	}
+	return formatMembers(members[start:stop+1], withScores), nil
+}
+
+func (c *Client) cmdZRangeByScore(args []string) (interface{}, error) {
+	inRange, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	offset, count := 0, -1
+	opts := args[3:]
+	for i := 0; i < len(opts); i++ {
+		if strings.ToLower(opts[i]) != "limit" || i+2 >= len(opts) {
+			continue
+		}
+		o, err1 := strconv.Atoi(opts[i+1])
+		n, err2 := strconv.Atoi(opts[i+2])
+		if err1 != nil || err2 != nil {
+			return nil, errNotInteger
+		}
+		offset, count = o, n
+		opts = append(opts[:i:i], opts[i+3:]...)
+		break
+	}
+	withScores, err := parseWithScores(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil || e == nil {
+		return []string{}, err
+	}
+
+	var members []member
+	for _, m := range sortedMembers(e.zset) {
+		if !inRange(m.score) {
+			continue
+		} else if offset > 0 {
+			offset--
+			continue
+		} else if count >= 0 && len(members) == count {
+			break
+		}
+		members = append(members, m)
+	}
+	return formatMembers(members, withScores), nil
+}
+
+func (c *Client) cmdZRemRangeByScore(args []string) (interface{}, error) {
+	inRange, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := c.lookupKind(args[0], kindZSet)
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+
+	var n int64
+	for name, score := range e.zset {
+		if inRange(score) {
+			delete(e.zset, name)
+			n++
+		}
+	}
+	if len(e.zset) == 0 {
+		delete(c.data, args[0])
+	}
+	return n, nil
+}
+
+func (c *Client) cmdHGet(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindHash)
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	v, ok := e.hash[args[1]]
+	if !ok {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (c *Client) cmdHMGet(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindHash)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]interface{}, len(args)-1)
+	for i, field := range args[1:] {
+		if v, ok := e.hashValue(field); ok {
+			res[i] = v
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) cmdHGetAll(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindHash)
+	if err != nil || e == nil {
+		return []string{}, err
+	}
+
+	fields := make([]string, 0, len(e.hash))
+	for field := range e.hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	res := make([]string, 0, 2*len(fields))
+	for _, field := range fields {
+		res = append(res, field, e.hash[field])
+	}
+	return res, nil
+}
+
+func (c *Client) cmdHSet(args []string) (interface{}, error) {
+	if len(args)%2 != 1 {
+		return nil, errors.New("ERR wrong number of arguments for 'hset' command")
+	}
+
+	e, err := c.hashEntry(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var added int64
+	for i := 1; i < len(args); i += 2 {
+		if _, ok := e.hash[args[i]]; !ok {
+			added++
+		}
+		e.hash[args[i]] = args[i+1]
+	}
+	return added, nil
+}
+
+func (c *Client) cmdHSetNX(args []string) (interface{}, error) {
+	e, err := c.hashEntry(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := e.hash[args[1]]; ok {
+		return int64(0), nil
+	}
+	e.hash[args[1]] = args[2]
+	return int64(1), nil
+}
+
+func (c *Client) cmdHDel(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindHash)
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+
+	var n int64
+	for _, field := range args[1:] {
+		if _, ok := e.hash[field]; ok {
+			delete(e.hash, field)
+			n++
+		}
+	}
+	if len(e.hash) == 0 {
+		delete(c.data, args[0])
+	}
+	return n, nil
+}
+
+func (c *Client) cmdHExists(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindHash)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := e.hashValue(args[1]); ok {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func (c *Client) cmdHIncrBy(args []string) (interface{}, error) {
+	by, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+
+	e, err := c.hashEntry(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	if v, ok := e.hash[args[1]]; ok {
+		if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, errors.New("ERR hash value is not an integer")
+		}
+	}
+	n += by
+	e.hash[args[1]] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (c *Client) cmdHLen(args []string) (interface{}, error) {
+	e, err := c.lookupKind(args[0], kindHash)
+	if err != nil || e == nil {
+		return int64(0), err
+	}
+	return int64(len(e.hash)), nil
+}
+
+// hashEntry returns the hash at key, creating it if needed.
+func (c *Client) hashEntry(key string) (*entry, error) {
+	e, err := c.lookupKind(key, kindHash)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		e = &entry{kind: kindHash, hash: make(map[string]string)}
+		c.data[key] = e
+	}
+	return e, nil
+}
+
+func (e *entry) hashValue(field string) (string, bool) {
+	if e == nil {
+		return "", false
+	}
+	v, ok := e.hash[field]
+	return v, ok
+}
+
+// normalizeRange resolves negative indices and clamps them to [0, n).
+func normalizeRange(start, stop, n int) (int, int) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	return start, stop
+}
+
+func sortedMembers(zset map[string]float64) []member {
+	members := make([]member, 0, len(zset))
+	for name, score := range zset {
+		members = append(members, member{name: name, score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].score != members[j].score {
+			return members[i].score < members[j].score
+		}
+		return members[i].name < members[j].name
+	})
+	return members
+}
+
+func formatMembers(members []member, withScores bool) []string {
+	res := make([]string, 0, len(members))
+	for _, m := range members {
+		res = append(res, m.name)
+		if withScores {
+			res = append(res, formatScore(m.score))
+		}
+	}
+	return res
+}
+
+func parseWithScores(args []string) (bool, error) {
+	switch {
+	case len(args) == 0:
+		return false, nil
+	case len(args) == 1 && strings.ToLower(args[0]) == "withscores":
+		return true, nil
+	}
+	return false, errSyntax
+}
+
+func parseScoreRange(min, max string) (func(float64) bool, error) {
+	lo, loEx, err1 := parseScoreBound(min)
+	hi, hiEx, err2 := parseScoreBound(max)
+	if err1 != nil || err2 != nil {
+		return nil, errMinMaxFloat
+	}
+	return func(score float64) bool {
+		if score < lo || (loEx && score == lo) {
+			return false
+		}
+		return score < hi || (!hiEx && score == hi)
+	}, nil
+}
+
+func parseScoreBound(s string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	f, err := parseScore(s)
+	return f, exclusive, err
+}
+
+func parseScore(s string) (float64, error) {
+	switch strings.ToLower(s) {
+	case "-inf":
+		return math.Inf(-1), nil
+	case "+inf", "inf":
+		return math.Inf(1), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func formatScore(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return formatNumber(f)
+}
@@ -0,0 +1,394 @@
+// Package redislocktest provides an in-memory redislock.RedisClient for unit
+// tests, so locking code can be tested without a redis server.
+//
+// Keys expire on a fake clock, which only moves forward via Advance. Passed
+// as Defaults.Clock, the client also drives retry backoffs and auto-refresh
+// of the locks, so tests do not need to sleep through them. Lua
+// scripts are evaluated by an embedded interpreter supporting the subset of
+// redis commands used by redislock. Blocking and pub/sub commands are not
+// supported, so release signals and notifications fall back to the retry
+// backoff.
+//
+// Server is an embedded redis server backed by miniredis, so integration
+// tests written against a real redis client can run without a redis server.
+// The suite of redislock itself runs against it if REDISLOCK_TEST_EMBEDDED is
+// set.
+//
+// ChaosClient injects latency, errors, dropped responses and failovers around
+// any redislock.RedisClient, and CheckMutualExclusion verifies that locks
+// remain exclusive under such faults.
+package redislocktest
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	lua "github.com/yuin/gopher-lua"
+)
+
+var (
+	_ redislock.RedisClient = (*Client)(nil)
+	_ redislock.Clock       = (*Client)(nil)
+)
+
+// Client is an in-memory redis client. It is safe for concurrent use.
+//
+// It also implements redislock.Clock, so passing it as Defaults.Clock makes
+// retry backoffs and auto-refresh follow the fake clock, too.
+type Client struct {
+	mu      sync.Mutex
+	now     time.Time
+	data    map[string]*entry
+	scripts map[string]string
+	timers  map[*timer]struct{}
+}
+
+// New creates a new, empty Client with its clock set to the current time.
+func New() *Client {
+	return &Client{
+		now:     time.Now(),
+		data:    make(map[string]*entry),
+		scripts: make(map[string]string),
+		timers:  make(map[*timer]struct{}),
+	}
+}
+
+// Now returns the current time of the fake clock.
+func (c *Client) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the fake clock forward, expiring keys and firing timers on
+// the way.
+func (c *Client) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for t := range c.timers {
+		if !t.when.After(c.now) {
+			c.fire(t)
+		}
+	}
+}
+
+// NewTimer implements redislock.Clock. The timer fires once Advance has moved
+// the fake clock past d.
+func (c *Client) NewTimer(d time.Duration) redislock.Timer {
+	t := &timer{client: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// fire delivers the current time to t and deactivates it. It requires c.mu to
+// be held.
+func (c *Client) fire(t *timer) {
+	delete(c.timers, t)
+	select {
+	case t.ch <- c.now:
+	default:
+	}
+}
+
+type timer struct {
+	client *Client
+	ch     chan time.Time
+	when   time.Time
+}
+
+func (t *timer) C() <-chan time.Time { return t.ch }
+
+func (t *timer) Stop() bool {
+	t.client.mu.Lock()
+	defer t.client.mu.Unlock()
+
+	_, active := t.client.timers[t]
+	delete(t.client.timers, t)
+	return active
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	c := t.client
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, active := c.timers[t]
+	t.when = c.now.Add(d)
+	c.timers[t] = struct{}{}
+	if d <= 0 {
+		c.fire(t)
+	}
+	return active
+}
+
+// FlushAll removes all keys.
+func (c *Client) FlushAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data = make(map[string]*entry)
+}
+
+// SetNX implements redislock.RedisClient.
+func (c *Client) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if err := ctx.Err(); err != nil {
+		return redis.NewBoolResult(false, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lookup(key) != nil {
+		return redis.NewBoolResult(false, nil)
+	}
+
+	e := &entry{str: stringify(value)}
+	if expiration > 0 {
+		e.expireAt = c.now.Add(expiration)
+	}
+	c.data[key] = e
+	return redis.NewBoolResult(true, nil)
+}
+
+// Eval implements redislock.RedisClient.
+func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	if err := ctx.Err(); err != nil {
+		return redis.NewCmdResult(nil, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scripts[scriptSHA(script)] = script
+	return c.eval(script, keys, args)
+}
+
+// EvalSha implements redislock.RedisClient.
+func (c *Client) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	if err := ctx.Err(); err != nil {
+		return redis.NewCmdResult(nil, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	script, ok := c.scripts[sha1]
+	if !ok {
+		return redis.NewCmdResult(nil, redisError("NOSCRIPT No matching script. Please use EVAL."))
+	}
+	return c.eval(script, keys, args)
+}
+
+// ScriptExists implements redislock.RedisClient.
+func (c *Client) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	if err := ctx.Err(); err != nil {
+		return redis.NewBoolSliceResult(nil, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := make([]bool, len(hashes))
+	for i, sha := range hashes {
+		_, res[i] = c.scripts[sha]
+	}
+	return redis.NewBoolSliceResult(res, nil)
+}
+
+// ScriptLoad implements redislock.RedisClient.
+func (c *Client) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	if err := ctx.Err(); err != nil {
+		return redis.NewStringResult("", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sha := scriptSHA(script)
+	c.scripts[sha] = script
+	return redis.NewStringResult(sha, nil)
+}
+
+// eval runs script atomically. The caller must hold c.mu.
+func (c *Client) eval(script string, keys []string, args []interface{}) *redis.Cmd {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	keysTbl := L.NewTable()
+	for _, k := range keys {
+		keysTbl.Append(lua.LString(k))
+	}
+	L.SetGlobal("KEYS", keysTbl)
+
+	argvTbl := L.NewTable()
+	for _, a := range args {
+		argvTbl.Append(lua.LString(stringify(a)))
+	}
+	L.SetGlobal("ARGV", argvTbl)
+
+	redisTbl := L.NewTable()
+	L.SetField(redisTbl, "call", L.NewFunction(c.luaCall))
+	L.SetGlobal("redis", redisTbl)
+
+	fn, err := L.LoadString(script)
+	if err != nil {
+		return redis.NewCmdResult(nil, redisError("ERR Error compiling script: "+err.Error()))
+	}
+
+	L.Push(fn)
+	if err := L.PCall(0, 1, nil); err != nil {
+		return redis.NewCmdResult(nil, redisError("ERR Error running script: "+err.Error()))
+	}
+	return fromLua(L.Get(-1))
+}
+
+// luaCall implements redis.call.
+func (c *Client) luaCall(L *lua.LState) int {
+	n := L.GetTop()
+	if n == 0 {
+		L.RaiseError("wrong number of arguments for redis.call")
+	}
+
+	args := make([]string, n)
+	for i := 1; i <= n; i++ {
+		switch v := L.Get(i).(type) {
+		case lua.LString:
+			args[i-1] = string(v)
+		case lua.LNumber:
+			args[i-1] = formatNumber(float64(v))
+		default:
+			L.RaiseError("lua redis() command arguments must be strings or integers")
+		}
+	}
+
+	res, err := c.exec(strings.ToLower(args[0]), args[1:])
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+	L.Push(toLua(L, res))
+	return 1
+}
+
+// fromLua converts a script result into a command result, following the
+// conversion rules of redis.
+func fromLua(v lua.LValue) *redis.Cmd {
+	switch v := v.(type) {
+	case lua.LNumber:
+		return redis.NewCmdResult(int64(v), nil)
+	case lua.LString:
+		return redis.NewCmdResult(string(v), nil)
+	case lua.LBool:
+		if v {
+			return redis.NewCmdResult(int64(1), nil)
+		}
+	case *lua.LTable:
+		if ok := v.RawGetString("ok"); ok.Type() == lua.LTString {
+			return redis.NewCmdResult(ok.String(), nil)
+		}
+		if msg := v.RawGetString("err"); msg.Type() == lua.LTString {
+			return redis.NewCmdResult(nil, redisError(msg.String()))
+		}
+
+		var vals []interface{}
+		for i := 1; ; i++ {
+			el := v.RawGetInt(i)
+			if el == lua.LNil {
+				break
+			}
+			val, _ := fromLua(el).Result()
+			vals = append(vals, val)
+		}
+		return redis.NewCmdResult(vals, nil)
+	}
+	return redis.NewCmdResult(nil, redis.Nil)
+}
+
+// toLua converts a command reply into a Lua value, following the conversion
+// rules of redis.
+func toLua(L *lua.LState, v interface{}) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LFalse
+	case int64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case status:
+		tbl := L.NewTable()
+		L.SetField(tbl, "ok", lua.LString(v))
+		return tbl
+	case []string:
+		tbl := L.NewTable()
+		for _, s := range v {
+			tbl.Append(lua.LString(s))
+		}
+		return tbl
+	case []interface{}:
+		tbl := L.NewTable()
+		for i, el := range v {
+			tbl.RawSetInt(i+1, toLua(L, el))
+		}
+		return tbl
+	}
+	panic(fmt.Sprintf("redislocktest: unexpected reply type %T", v))
+}
+
+// redisError is an error reply, it implements redis.Error.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (redisError) RedisError() {}
+
+func scriptSHA(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func stringify(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatNumber(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatNumber(f float64) string {
+	if f == float64(int64(f)) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
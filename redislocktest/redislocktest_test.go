@@ -0,0 +1,192 @@
+package redislocktest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redislocktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const lockKey = "__bsm_redislock_redislocktest_unit_test__"
+
+var _ = Describe("Client", func() {
+	var backend *redislocktest.Client
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		backend = redislocktest.New()
+		subject = redislock.New(backend)
+	})
+
+	It("should obtain, refresh and release", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "fake"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("fake"))
+		Expect(lock.TTL(ctx)).To(Equal(time.Hour))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(Equal(time.Minute))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should expire locks on the fake clock", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.Advance(59 * time.Second)
+		Expect(lock.TTL(ctx)).To(Equal(time.Second))
+
+		backend.Advance(time.Second)
+		Expect(lock.TTL(ctx)).To(BeZero())
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockExpired))
+
+		lock, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should detect stolen locks", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.Advance(time.Minute)
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockStolen))
+	})
+
+	It("should assign fencing tokens", func() {
+		opt := &redislock.Options{Fencing: true}
+		for i := int64(1); i <= 3; i++ {
+			lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, opt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.FencingToken()).To(Equal(i))
+			Expect(lock.Release(ctx)).To(Succeed())
+		}
+	})
+
+	It("should support read-write locks", func() {
+		rw := redislock.NewRWLock(backend, lockKey)
+
+		r1, err := rw.RLock(ctx, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		r2, err := rw.RLock(ctx, time.Hour, 2*time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = rw.Lock(ctx, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(r1.Release(ctx)).To(Succeed())
+		backend.Advance(2 * time.Minute)
+		Expect(r2.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		w, err := rw.Lock(ctx, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Release(ctx)).To(Succeed())
+	})
+
+	It("should support semaphores", func() {
+		sem := redislock.NewSemaphore(backend, lockKey, 2)
+
+		s1, err := sem.Acquire(ctx, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = sem.Acquire(ctx, time.Hour, 2*time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = sem.Acquire(ctx, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(sem.Count(ctx)).To(Equal(2))
+
+		backend.Advance(time.Minute)
+		Expect(sem.Count(ctx)).To(Equal(1))
+		Expect(s1.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should extend locks", func() {
+		lock, err := subject.ObtainMulti(ctx, []string{lockKey + ".a", lockKey + ".b"}, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Extend(ctx, time.Minute, 0)).To(Equal(2 * time.Minute))
+
+		sem := redislock.NewSemaphore(backend, lockKey, 1)
+		s1, err := sem.Acquire(ctx, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.Advance(30 * time.Second)
+		Expect(s1.Extend(ctx, time.Hour, 2*time.Minute)).To(Equal(2 * time.Minute))
+		Expect(s1.TTL(ctx)).To(Equal(2 * time.Minute))
+
+		backend.Advance(2 * time.Minute)
+		_, err = lock.Extend(ctx, time.Minute, 0)
+		Expect(err).To(MatchError(redislock.ErrLockExpired))
+		_, err = s1.Extend(ctx, time.Minute, 0)
+		Expect(err).To(MatchError(redislock.ErrLockExpired))
+	})
+
+	It("should fail on cancelled contexts", func() {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := subject.Obtain(cctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should retry on the fake clock", func() {
+		subject = redislock.New(backend, redislock.Defaults{Clock: backend})
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		done := make(chan *redislock.Lock, 1)
+		go func() {
+			defer GinkgoRecover()
+
+			lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{
+				RetryStrategy: redislock.LinearBackoff(10 * time.Second),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			done <- lock
+		}()
+
+		Consistently(done).ShouldNot(Receive())
+		Eventually(func() chan *redislock.Lock {
+			backend.Advance(10 * time.Second)
+			return done
+		}).Should(Receive(&lock))
+		Expect(lock.Stats().Wait).To(BeNumerically(">=", time.Minute))
+	})
+
+	It("should auto-refresh on the fake clock", func() {
+		subject = redislock.New(backend, redislock.Defaults{Clock: backend})
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		lock.StartAutoRefresh(ctx, 20*time.Second, time.Minute, nil)
+		defer lock.Release(ctx)
+
+		backend.Advance(30 * time.Second)
+		Eventually(func() (time.Duration, error) { return lock.TTL(ctx) }).Should(Equal(time.Minute))
+	})
+
+	It("should flush all keys", func() {
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.FlushAll()
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock/redislocktest")
+}
@@ -0,0 +1,70 @@
+package redislocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+)
+
+// Server is an embedded redis server on a random local port, so code using a
+// real redis client, such as integration tests, can run without a redis
+// server:
+//
+//	srv, err := redislocktest.NewServer()
+//	...
+//	defer srv.Close()
+//	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+//
+// It is backed by miniredis, which supports the commands and scripts used by
+// redislock, including blocking commands, pub/sub, streams and the cluster
+// commands of a single node. Unlike miniredis, keys expire in real time.
+type Server struct {
+	mr *miniredis.Miniredis
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewServer starts a Server on a random local port.
+func NewServer() (*Server, error) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{mr: mr, last: time.Now()}
+	mr.Server().SetPreHook(s.expire)
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.mr.Addr()
+}
+
+// Close stops the server and closes all connections.
+func (s *Server) Close() error {
+	s.mr.Close()
+	return nil
+}
+
+// expire moves the clock of miniredis, which only expires keys when told to,
+// to the current time before each command, like redis expires keys when they
+// are accessed. Commands called by scripts run while miniredis is locked, and
+// are skipped; the clock was moved before the script.
+func (s *Server) expire(*server.Peer, string, ...string) bool {
+	if !s.mr.TryLock() {
+		return false
+	}
+	s.mr.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.mr.FastForward(now.Sub(s.last))
+	s.last = now
+	return false
+}
@@ -0,0 +1,89 @@
+package redislocktest_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redislocktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var server *redislocktest.Server
+	var rdb *redis.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		var err error
+		server, err = redislocktest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+		rdb = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	})
+
+	AfterEach(func() {
+		Expect(rdb.Close()).To(Succeed())
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should serve locks", func() {
+		subject := redislock.New(rdb)
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "served"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rdb.Get(ctx, lockKey).Val()).To(HaveSuffix("served"))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should expire keys in real time", func() {
+		Expect(rdb.Set(ctx, lockKey, "x", 20*time.Millisecond).Err()).To(Succeed())
+		Eventually(func() int64 { return rdb.Exists(ctx, lockKey).Val() }).Should(BeZero())
+	})
+
+	It("should separate databases", func() {
+		other := redis.NewClient(&redis.Options{Addr: server.Addr(), DB: 3})
+		defer other.Close()
+
+		Expect(other.Set(ctx, lockKey, "x", 0).Err()).To(Succeed())
+		Expect(rdb.Exists(ctx, lockKey).Val()).To(BeZero())
+		Expect(other.SetNX(ctx, lockKey, "y", 0).Val()).To(BeFalse())
+	})
+
+	It("should support transactions and pipelines", func() {
+		cmds, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, lockKey, "x", 0)
+			pipe.Get(ctx, lockKey)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cmds).To(HaveLen(2))
+		Expect(cmds[1].(*redis.StringCmd).Val()).To(Equal("x"))
+	})
+
+	It("should deliver published messages", func() {
+		sub := rdb.Subscribe(ctx, lockKey)
+		defer sub.Close()
+		_, err := sub.Receive(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rdb.Publish(ctx, lockKey, "hello").Val()).To(Equal(int64(1)))
+		var msg *redis.Message
+		Eventually(sub.Channel()).Should(Receive(&msg))
+		Expect(msg.Payload).To(Equal("hello"))
+	})
+
+	It("should block on lists", func() {
+		go func() {
+			defer GinkgoRecover()
+
+			time.Sleep(20 * time.Millisecond)
+			Expect(rdb.Eval(ctx, `return redis.call("lpush", KEYS[1], "x")`, []string{lockKey}).Err()).To(Succeed())
+		}()
+		Expect(rdb.BLPop(ctx, time.Second, lockKey).Val()).To(Equal([]string{lockKey, "x"}))
+		Expect(rdb.BLPop(ctx, 10*time.Millisecond, lockKey).Err()).To(Equal(redis.Nil))
+	})
+})
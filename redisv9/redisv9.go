@@ -0,0 +1,82 @@
+// Package redisv9 adapts github.com/redis/go-redis/v9 clients for use with
+// redislock.
+package redisv9
+
+import (
+	"context"
+	"time"
+
+	v8 "github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	"github.com/redis/go-redis/v9"
+)
+
+// Cmdable is the subset of go-redis/v9 commands used by the adapter. It is
+// implemented by *redis.Client, *redis.ClusterClient and *redis.Ring.
+type Cmdable interface {
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd
+	ScriptLoad(ctx context.Context, script string) *redis.StringCmd
+	BLPop(ctx context.Context, timeout time.Duration, keys ...string) *redis.StringSliceCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+}
+
+// Client is the interface implemented by wrapped clients.
+type Client interface {
+	redislock.RedisClient
+	redislock.BlockingClient
+	redislock.ScanningClient
+}
+
+// Wrap wraps a go-redis/v9 client, so it can be passed to redislock.New.
+func Wrap(client Cmdable) Client {
+	return &adapter{client: client}
+}
+
+type adapter struct {
+	client Cmdable
+}
+
+func (a *adapter) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *v8.BoolCmd {
+	val, err := a.client.SetNX(ctx, key, value, expiration).Result()
+	return v8.NewBoolResult(val, convertErr(err))
+}
+
+func (a *adapter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *v8.Cmd {
+	val, err := a.client.Eval(ctx, script, keys, args...).Result()
+	return v8.NewCmdResult(val, convertErr(err))
+}
+
+func (a *adapter) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *v8.Cmd {
+	val, err := a.client.EvalSha(ctx, sha1, keys, args...).Result()
+	return v8.NewCmdResult(val, convertErr(err))
+}
+
+func (a *adapter) ScriptExists(ctx context.Context, hashes ...string) *v8.BoolSliceCmd {
+	val, err := a.client.ScriptExists(ctx, hashes...).Result()
+	return v8.NewBoolSliceResult(val, convertErr(err))
+}
+
+func (a *adapter) ScriptLoad(ctx context.Context, script string) *v8.StringCmd {
+	val, err := a.client.ScriptLoad(ctx, script).Result()
+	return v8.NewStringResult(val, convertErr(err))
+}
+
+func (a *adapter) BLPop(ctx context.Context, timeout time.Duration, keys ...string) *v8.StringSliceCmd {
+	val, err := a.client.BLPop(ctx, timeout, keys...).Result()
+	return v8.NewStringSliceResult(val, convertErr(err))
+}
+
+func (a *adapter) Scan(ctx context.Context, cursor uint64, match string, count int64) *v8.ScanCmd {
+	keys, cursor, err := a.client.Scan(ctx, cursor, match, count).Result()
+	return v8.NewScanCmdResult(keys, cursor, convertErr(err))
+}
+
+func convertErr(err error) error {
+	if err == redis.Nil {
+		return v8.Nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+//go:build redisv9
+
+package redisv9_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redisv9"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+)
+
+const lockKey = "__bsm_redislock_redisv9_unit_test__"
+
+var _ = Describe("Wrap", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisv9.Wrap(redisClient))
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should obtain and release", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "v9"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("v9"))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+
+		Expect(lock.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockExpired))
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock/redisv9")
+}
+
+var redisClient *redis.Client
+
+var _ = BeforeSuite(func() {
+	redisClient = redis.NewClient(&redis.Options{
+		Network: "tcp",
+		Addr:    "127.0.0.1:6379", DB: 9,
+	})
+	Expect(redisClient.Ping(context.Background()).Err()).To(Succeed())
+})
+
+var _ = AfterSuite(func() {
+	Expect(redisClient.Close()).To(Succeed())
+})
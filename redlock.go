@@ -0,0 +1,357 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockDriftFactor is the fraction of the TTL added to the drift allowance,
+// as suggested by the Redlock algorithm.
+const clockDriftFactor = 0.01
+
+// ErrInvalidQuorum is returned by NewMultiWith when MultiOptions.Quorum is out
+// of range for the number of instances.
+var ErrInvalidQuorum = errors.New("redislock: invalid quorum")
+
+var errNoClients = errors.New("redislock: no clients given")
+
+// NodeErrors holds the errors of the instances of a MultiClient, in the order
+// of the clients passed to NewMulti, with nil for instances which did not
+// fail. It is returned, wrapped in an Error, when failed instances kept a lock
+// from reaching its quorum.
+type NodeErrors []error
+
+func (e NodeErrors) Error() string {
+	var msgs []string
+	for i, err := range e {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("instance %d: %v", i, err))
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether the error of any instance matches target.
+func (e NodeErrors) Is(target error) bool {
+	for _, err := range e {
+		if err != nil && errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// MultiClient obtains locks across multiple independent redis instances,
+// following the Redlock algorithm. A lock is considered obtained if a quorum
+// of the instances has granted it within the lock's validity time.
+type MultiClient struct {
+	client *Client
+	nodes  []*Client
+	quorum int
+	opt    MultiOptions
+}
+
+// MultiOptions configure a MultiClient.
+type MultiOptions struct {
+	// Quorum is the number of instances which must grant a lock. It must
+	// not exceed the number of instances, nor be less than a majority of
+	// them unless AllowMinority is set.
+	// Default: a majority of the instances
+	Quorum int
+
+	// AllowMinority permits a Quorum of less than a majority of the
+	// instances. Such locks are not mutually exclusive: disjoint sets of
+	// instances may grant the same lock to different holders at once.
+	// Default: false
+	AllowMinority bool
+
+	// NodeTimeout limits the time each instance may take to grant a lock,
+	// so slow instances do not eat up the validity time.
+	// Default: no limit
+	NodeTimeout time.Duration
+
+	// RetrySlow retries instances which exceeded the NodeTimeout in the
+	// background once a quorum has been reached, so the lock ends up on as
+	// many instances as possible. The retries are abandoned on release.
+	// Default: false
+	RetrySlow bool
+
+	// Metrics is notified of attempts to obtain locks, see
+	// Defaults.Metrics.
+	// Default: nil
+	Metrics MetricsCollector
+
+	// Clock is used for retry backoffs, see Defaults.Clock.
+	// Default: the system clock
+	Clock Clock
+}
+
+// NewMulti creates a new MultiClient across the given, independent clients.
+// It returns an error if no clients are given.
+func NewMulti(clients ...RedisClient) (*MultiClient, error) {
+	return NewMultiWith(MultiOptions{}, clients...)
+}
+
+// NewMultiWith creates a new MultiClient across the given, independent
+// clients, configured by opt. It returns an error if no clients are given,
+// or ErrInvalidQuorum if opt.Quorum is out of range.
+func NewMultiWith(opt MultiOptions, clients ...RedisClient) (*MultiClient, error) {
+	majority := len(clients)/2 + 1
+	quorum := opt.Quorum
+	if quorum == 0 {
+		quorum = majority
+	}
+	switch {
+	case len(clients) == 0:
+		return nil, errNoClients
+	case quorum < 0 || quorum > len(clients):
+		return nil, fmt.Errorf("%w: %d out of range for %d instances", ErrInvalidQuorum, quorum, len(clients))
+	case quorum < majority && !opt.AllowMinority:
+		return nil, fmt.Errorf("%w: %d is less than a majority of %d instances, see MultiOptions.AllowMinority", ErrInvalidQuorum, quorum, len(clients))
+	}
+
+	nodes := make([]*Client, 0, len(clients))
+	for _, client := range clients {
+		nodes = append(nodes, New(client))
+	}
+	client := New(clients[0], Defaults{Metrics: opt.Metrics, Clock: opt.Clock})
+	return &MultiClient{client: client, nodes: nodes, quorum: quorum, opt: opt}, nil
+}
+
+// Obtain tries to obtain a new lock on all instances using a key with the
+// given TTL. Attempts are retried like those of Client.Obtain.
+// May return ErrNotObtained if a quorum could not be reached, or NodeErrors if
+// failed instances kept it from being reached.
+func (m *MultiClient) Obtain(ctx context.Context, key string, waitTimeout, lockTTL time.Duration, opt *Options) (*MultiLock, error) {
+	c := m.client
+	if err := c.validate(ctx, key, lockTTL); err != nil {
+		return nil, err
+	}
+	if opt == nil {
+		opt = &defaultOptions
+	}
+
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	value := token + opt.getMetadata()
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	var lock *MultiLock
+	if _, err := c.retry(deadlinectx, c.client, key, token, opt, false, func(ctx context.Context) (bool, error) {
+		var err error
+		lock, err = m.obtain(ctx, key, token, value, lockTTL, opt)
+		return lock != nil, err
+	}); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+func (m *MultiClient) obtain(ctx context.Context, key, token, value string, ttl time.Duration, opt *Options) (*MultiLock, error) {
+	lock := &MultiLock{
+		quorum: m.quorum,
+		locks:  make([]*Lock, len(m.nodes)),
+	}
+	for i, node := range m.nodes {
+		lock.locks[i] = &Lock{
+			client:     node,
+			rdb:        node.client,
+			key:        key,
+			token:      token,
+			value:      value,
+			ttl:        ttl,
+			opTimeout:  opt.getOperationTimeout(),
+			scripts:    exclusiveScripts,
+			scriptKeys: []string{key},
+			scriptArg:  value,
+		}
+	}
+
+	start := time.Now()
+	var acquired int
+	var mu sync.Mutex
+	errs := lock.each(func(l *Lock) error {
+		nodectx, cancel := m.nodeContext(ctx)
+		defer cancel()
+
+		ok, err := l.client.obtain(nodectx, l.rdb, key, value, ttl, opt, nil)
+		if ok {
+			mu.Lock()
+			acquired++
+			mu.Unlock()
+		} else if ctx.Err() == nil && nodectx.Err() != nil {
+			err = context.DeadlineExceeded
+		}
+		return err
+	})
+
+	drift := time.Duration(float64(ttl)*clockDriftFactor) + 2*time.Millisecond
+	validity := ttl - time.Since(start) - drift
+	if acquired >= m.quorum && validity > 0 {
+		lock.validUntil = start.Add(ttl - drift)
+		if m.opt.RetrySlow {
+			lock.retrySlow(errs, key, value, ttl, opt)
+		}
+		return lock, nil
+	}
+
+	// Release partially obtained locks on all instances.
+	lock.each(func(l *Lock) error {
+		nodectx, cancel := m.nodeContext(context.Background())
+		defer cancel()
+		return l.Release(nodectx)
+	})
+
+	var failed int
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if len(m.nodes)-failed < m.quorum {
+		return nil, NodeErrors(errs)
+	}
+	return nil, ctx.Err()
+}
+
+// nodeContext returns a context limited by the NodeTimeout, if any.
+func (m *MultiClient) nodeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.opt.NodeTimeout > 0 {
+		return context.WithTimeout(ctx, m.opt.NodeTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// --------------------------------------------------------------------
+
+// MultiLock represents a lock obtained across multiple redis instances.
+type MultiLock struct {
+	locks      []*Lock
+	quorum     int
+	validUntil time.Time
+	mu         sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// retrySlow retries to obtain the lock in the background on the instances
+// which failed with a timeout, until the lock is released or its validity
+// ends.
+func (l *MultiLock) retrySlow(errs []error, key, value string, ttl time.Duration, opt *Options) {
+	ctx, cancel := context.WithDeadline(context.Background(), l.validUntil)
+	l.cancel = cancel
+
+	for i, err := range errs {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			continue
+		}
+
+		l.wg.Add(1)
+		go func(node *Lock) {
+			defer l.wg.Done()
+			_, _ = node.client.obtain(ctx, node.rdb, key, value, ttl, opt, nil)
+		}(l.locks[i])
+	}
+}
+
+// Key returns the redis key used by the lock.
+func (l *MultiLock) Key() string {
+	return l.locks[0].Key()
+}
+
+// Token returns the token value set by the lock.
+func (l *MultiLock) Token() string {
+	return l.locks[0].Token()
+}
+
+// Metadata returns the metadata of the lock.
+func (l *MultiLock) Metadata() string {
+	return l.locks[0].Metadata()
+}
+
+// ValidUntil returns the time until which the lock is considered valid,
+// accounting for the time taken to obtain it and for clock drift.
+func (l *MultiLock) ValidUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.validUntil
+}
+
+// Refresh extends the lock with a new TTL on all instances.
+// May return ErrNotObtained if refresh is unsuccessful on a quorum.
+func (l *MultiLock) Refresh(ctx context.Context, ttl time.Duration, opt *Options) error {
+	start := time.Now()
+	if err := l.quorumOf(func(lock *Lock) error { return lock.Refresh(ctx, ttl, opt) }, ErrNotObtained); err != nil {
+		return err
+	}
+
+	drift := time.Duration(float64(ttl)*clockDriftFactor) + 2*time.Millisecond
+	if time.Since(start) >= ttl-drift {
+		return ErrNotObtained
+	}
+	l.mu.Lock()
+	l.validUntil = start.Add(ttl - drift)
+	l.mu.Unlock()
+	return nil
+}
+
+// Release manually releases the lock on all instances.
+// May return ErrLockNotHeld if the lock was not held on a quorum.
+func (l *MultiLock) Release(ctx context.Context) error {
+	if l.cancel != nil {
+		l.cancel()
+		l.wg.Wait()
+	}
+	return l.quorumOf(func(lock *Lock) error { return lock.Release(ctx) }, ErrLockNotHeld)
+}
+
+// quorumOf runs fn on all instances and returns nil if it succeeded on a
+// quorum of them. Otherwise, it returns the first error not matching
+// notHeld, or notHeld.
+func (l *MultiLock) quorumOf(fn func(*Lock) error, notHeld error) error {
+	errs := l.each(fn)
+
+	var succeeded int
+	var firstErr error
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else if firstErr == nil && !errors.Is(err, notHeld) {
+			firstErr = err
+		}
+	}
+
+	if succeeded >= l.quorum {
+		return nil
+	} else if firstErr != nil {
+		return firstErr
+	}
+	return notHeld
+}
+
+// each runs fn on all instances concurrently and returns the errors.
+func (l *MultiLock) each(fn func(*Lock) error) []error {
+	errs := make([]error, len(l.locks))
+
+	var wg sync.WaitGroup
+	for i, lock := range l.locks {
+		wg.Add(1)
+		go func(i int, lock *Lock) {
+			defer wg.Done()
+			errs[i] = fn(lock)
+		}(i, lock)
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,176 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MultiClient", func() {
+	var subject *redislock.MultiClient
+	var nodes []*redis.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		// Separate DBs stand in for independent instances.
+		nodes = nil
+		for db := 9; db < 12; db++ {
+			nodes = append(nodes, redis.NewClient(&redis.Options{Network: "tcp", Addr: redisClient.Options().Addr, DB: db}))
+		}
+		var err error
+		subject, err = redislock.NewMulti(nodes[0], nodes[1], nodes[2])
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		for _, node := range nodes {
+			Expect(node.Del(ctx, lockKey).Err()).To(Succeed())
+			Expect(node.Close()).To(Succeed())
+		}
+	})
+
+	It("should obtain on all instances", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Key()).To(Equal(lockKey))
+		Expect(lock.Token()).To(HaveLen(22))
+		Expect(lock.Metadata()).To(Equal("meta"))
+		Expect(lock.ValidUntil()).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+
+		for _, node := range nodes {
+			Expect(node.Get(ctx, lockKey).Val()).To(Equal(lock.Token() + "meta"))
+		}
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(nodes[1].PTTL(ctx, lockKey).Val()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should obtain with a quorum", func() {
+		Expect(nodes[0].Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes[0].Get(ctx, lockKey).Val()).To(Equal("ABCD"))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(nodes[0].Get(ctx, lockKey).Val()).To(Equal("ABCD"))
+	})
+
+	It("should not obtain without a quorum", func() {
+		Expect(nodes[0].Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		Expect(nodes[2].Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(nodes[1].Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+
+	It("should fail to refresh without a quorum", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(nodes[0].Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+
+		Expect(nodes[1].Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should support custom quorums", func() {
+		subject, err := redislock.NewMultiWith(redislock.MultiOptions{Quorum: 3}, nodes[0], nodes[1], nodes[2])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes[0].Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(nodes[1].Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+
+	It("should validate quorums", func() {
+		_, err := redislock.NewMulti()
+		Expect(err).To(HaveOccurred())
+		_, err = redislock.NewMultiWith(redislock.MultiOptions{Quorum: -1}, nodes[0])
+		Expect(err).To(MatchError(redislock.ErrInvalidQuorum))
+		_, err = redislock.NewMultiWith(redislock.MultiOptions{Quorum: 4}, nodes[0], nodes[1], nodes[2])
+		Expect(err).To(MatchError(redislock.ErrInvalidQuorum))
+		_, err = redislock.NewMultiWith(redislock.MultiOptions{Quorum: 1}, nodes[0], nodes[1], nodes[2])
+		Expect(err).To(MatchError(redislock.ErrInvalidQuorum))
+
+		subject, err := redislock.NewMultiWith(redislock.MultiOptions{Quorum: 1, AllowMinority: true}, nodes[0], nodes[1], nodes[2])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes[0].Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		Expect(nodes[1].Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should retry like Client.Obtain", func() {
+		_, err := subject.Obtain(ctx, "", time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidKey))
+
+		for _, node := range nodes[:2] {
+			Expect(node.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		}
+		var retries []int
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(time.Millisecond),
+			OnRetry: func(attempt int, _ time.Duration) bool {
+				retries = append(retries, attempt)
+				return attempt < 3
+			},
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(retries).To(Equal([]int{1, 2, 3}))
+
+		var e *redislock.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Attempts).To(Equal(3))
+		Expect(e.Stopped).To(Equal(redislock.StopAborted))
+	})
+
+	It("should report failed instances", func() {
+		subject, err := redislock.NewMulti(nodes[0], &flakyClient{RedisClient: nodes[1], failures: 1}, &flakyClient{RedisClient: nodes[2], failures: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{RetryStrategy: redislock.LinearBackoff(time.Millisecond)})
+		Expect(err).To(MatchError(errLoading))
+		Expect(nodes[0].Exists(ctx, lockKey).Val()).To(BeZero())
+
+		var nerrs redislock.NodeErrors
+		Expect(errors.As(err, &nerrs)).To(BeTrue())
+		Expect(nerrs[0]).NotTo(HaveOccurred())
+		Expect(nerrs[1]).To(MatchError(errLoading))
+		Expect(nerrs[2]).To(MatchError(errLoading))
+
+		var e *redislock.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Attempts).To(Equal(1))
+	})
+
+	It("should retry slow instances in the background", func() {
+		slow := &slowClient{RedisClient: nodes[2], delay: 50 * time.Millisecond}
+		subject, err := redislock.NewMultiWith(redislock.MultiOptions{
+			NodeTimeout: 20 * time.Millisecond,
+			RetrySlow:   true,
+		}, nodes[0], nodes[1], slow)
+		Expect(err).NotTo(HaveOccurred())
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes[2].Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+		Eventually(func() string { return nodes[2].Get(ctx, lockKey).Val() }).Should(Equal(lock.Token()))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(nodes[2].Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+})
@@ -0,0 +1,212 @@
+package redislock
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// lockDataVersion is the version of the format written by Lock.MarshalBinary.
+const lockDataVersion = 1
+
+var (
+	errMarshalUnsupported = errors.New("redislock: only exclusive locks can be marshaled")
+	errInvalidLockData    = errors.New("redislock: invalid lock data")
+)
+
+// Resume reconstructs a lock on key, which was obtained elsewhere with token
+// and metadata, so it can be refreshed or released by this process. The key
+// is subject to the client's key prefix. Resume does not contact redis, the
+// returned lock may therefore no longer be held.
+//
+// Resumed locks release without signals or notifications, use MarshalBinary
+// and ResumeBinary to hand over locks obtained with Options.ReleaseSignal or
+// Options.ReleaseNotify.
+func (c *Client) Resume(key, token, metadata string) *Lock {
+	return c.track(c.resume(c.defaults.KeyPrefix+key, token, metadata))
+}
+
+// Resume is a short-cut for New(client).Resume(...).
+func Resume(client RedisClient, key, token, metadata string) *Lock {
+	return New(client).Resume(key, token, metadata)
+}
+
+func (c *Client) resume(key, token, metadata string) *Lock {
+	value := token + metadata
+
+	scripts := exclusiveScripts
+	if c.defaults.Compat == CompatRedisson {
+		scripts = redissonScripts
+	}
+	rdb, err := c.route(key)
+	if err != nil {
+		rdb = c.client
+	}
+	return &Lock{
+		client:     c,
+		rdb:        rdb,
+		key:        key,
+		token:      token,
+		value:      value,
+		obtained:   time.Now(),
+		logger:     c.defaults.Logger,
+		scripts:    scripts,
+		scriptKeys: []string{key},
+		scriptArg:  value,
+		audited:    c.defaults.Audit != nil,
+		epochs:     scripts == exclusiveScripts && hasEpoch(metadata),
+	}
+}
+
+// ResumeBinary reconstructs a lock from data produced by Lock.MarshalBinary.
+// Unlike Resume, the key stored in data is used as-is. ResumeBinary does not
+// contact redis, the returned lock may therefore no longer be held.
+func (c *Client) ResumeBinary(data []byte) (*Lock, error) {
+	lock, err := c.resumeBinary(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.track(lock), nil
+}
+
+func (c *Client) resumeBinary(data []byte) (*Lock, error) {
+	d := lockDecoder{buf: data}
+	if d.byte() != lockDataVersion {
+		return nil, errInvalidLockData
+	}
+
+	scripts := d.byte()
+	ttl := time.Duration(d.varint())
+	fence := d.varint()
+	obtained := d.time()
+	expires := d.time()
+	key := d.string()
+	token := d.string()
+	metadata := d.string()
+	if d.err != nil || len(d.buf) != 0 {
+		return nil, errInvalidLockData
+	}
+
+	lock := c.resume(key, token, metadata)
+	lock.ttl = ttl
+	lock.fence = fence
+	lock.obtained = obtained
+	lock.expires = expires
+
+	switch scripts {
+	case 0:
+		lock.scripts = exclusiveScripts
+	case 1:
+		lock.scripts = signalScripts
+		lock.scriptKeys = []string{key, c.signalKey(key)}
+	case 2:
+		lock.scripts = notifyScripts
+	case 3:
+		lock.scripts = redissonScripts
+	default:
+		return nil, errInvalidLockData
+	}
+	return lock, nil
+}
+
+// MarshalBinary encodes the lock, so it can be handed to another process and
+// resumed there via Client.ResumeBinary. Only exclusive locks, as returned by
+// Obtain, ObtainFair or Resume, can be marshaled. It implements
+// encoding.BinaryMarshaler.
+func (l *Lock) MarshalBinary() ([]byte, error) {
+	var scripts byte
+	switch l.scripts {
+	case exclusiveScripts:
+	case signalScripts:
+		scripts = 1
+	case notifyScripts:
+		scripts = 2
+	case redissonScripts:
+		scripts = 3
+	default:
+		return nil, errMarshalUnsupported
+	}
+
+	l.mu.Lock()
+	expires, fence := l.expires, l.fence
+	l.mu.Unlock()
+
+	l.argMu.RLock()
+	md := l.value[len(l.token):]
+	l.argMu.RUnlock()
+
+	buf := make([]byte, 0, 64+len(l.key)+len(l.token)+len(md))
+	buf = append(buf, lockDataVersion, scripts)
+	buf = appendVarint(buf, int64(l.ttl))
+	buf = appendVarint(buf, fence)
+	buf = appendTime(buf, l.obtained)
+	buf = appendTime(buf, expires)
+	buf = appendString(buf, l.key)
+	buf = appendString(buf, l.token)
+	buf = appendString(buf, md)
+	return buf, nil
+}
+
+func appendTime(buf []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return appendVarint(buf, 0)
+	}
+	return appendVarint(buf, t.UnixNano())
+}
+
+func appendString(buf []byte, s string) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(len(s)))
+	return append(append(buf, tmp[:n]...), s...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+// lockDecoder decodes data written by Lock.MarshalBinary, recording the first
+// error.
+type lockDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *lockDecoder) byte() byte {
+	if len(d.buf) == 0 {
+		d.err = errInvalidLockData
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *lockDecoder) varint() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errInvalidLockData
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *lockDecoder) time() time.Time {
+	if ns := d.varint(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+func (d *lockDecoder) string() string {
+	n, m := binary.Uvarint(d.buf)
+	if m <= 0 || uint64(len(d.buf)-m) < n {
+		d.err = errInvalidLockData
+		return ""
+	}
+	s := string(d.buf[m : m+int(n)])
+	d.buf = d.buf[m+int(n):]
+	return s
+}
@@ -0,0 +1,87 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.Resume", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":fence").Err()).To(Succeed())
+	})
+
+	It("should resume locks by token", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+
+		resumed := redislock.Resume(redisClient, lockKey, lock.Token(), lock.Metadata())
+		Expect(resumed.Key()).To(Equal(lockKey))
+		Expect(resumed.Metadata()).To(Equal("meta"))
+		Expect(resumed.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(resumed.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should not resume with the wrong token", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		resumed := subject.Resume(lockKey, "wrong", "")
+		Expect(resumed.Refresh(ctx, time.Minute, nil)).To(MatchError(redislock.ErrLockStolen))
+	})
+
+	It("should marshal and resume locks", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{Metadata: "meta", Fencing: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := lock.MarshalBinary()
+		Expect(err).NotTo(HaveOccurred())
+
+		resumed, err := redislock.New(redisClient, redislock.Defaults{KeyPrefix: "ignored:"}).ResumeBinary(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed.Key()).To(Equal(lockKey))
+		Expect(resumed.Token()).To(Equal(lock.Token()))
+		Expect(resumed.Metadata()).To(Equal("meta"))
+		Expect(resumed.RequestedTTL()).To(Equal(time.Hour))
+		Expect(resumed.FencingToken()).To(Equal(lock.FencingToken()))
+		Expect(resumed.Done()).NotTo(BeClosed())
+		Expect(resumed.Release(ctx)).To(Succeed())
+	})
+
+	It("should reject invalid data", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		data, err := lock.MarshalBinary()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.ResumeBinary(data[:len(data)-1])
+		Expect(err).To(HaveOccurred())
+		_, err = subject.ResumeBinary(nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not marshal shared locks", func() {
+		sem := redislock.NewSemaphore(redisClient, lockKey, 1)
+		lock, err := sem.Acquire(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		_, err = lock.MarshalBinary()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,59 @@
+package redislock
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Route directs the locks on keys starting with Prefix to another redis DB
+// or endpoint, see Defaults.Routes.
+type Route struct {
+	// Prefix is matched against lock keys, without Defaults.KeyPrefix. The
+	// route with the longest matching prefix applies.
+	Prefix string
+
+	// Client serves the matching locks, e.g. a dedicated instance for noisy
+	// high-churn locks. It is not closed by Client.Close.
+	// Default: the client passed to New, with DB applied
+	Client RedisClient
+
+	// DB selects the logical DB of the client passed to New for the matching
+	// locks, unless Client is set. As with Options.SelectDB, connections are
+	// pooled by the Client, which must wrap a *redis.Client.
+	DB int
+}
+
+// newRoutes returns a copy of routes, ordered by descending prefix length,
+// with cluster clients wrapped like by New.
+func newRoutes(routes []Route) []Route {
+	if len(routes) == 0 {
+		return nil
+	}
+
+	sorted := make([]Route, len(routes))
+	copy(sorted, routes)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].Prefix) > len(sorted[j].Prefix) })
+	for i, r := range sorted {
+		if cc, ok := r.Client.(*redis.ClusterClient); ok {
+			sorted[i].Client = clusterClient{cc}
+		}
+	}
+	return sorted
+}
+
+// route returns the client serving the lock on key, which includes the key
+// prefix, according to Defaults.Routes.
+func (c *Client) route(key string) (RedisClient, error) {
+	key = strings.TrimPrefix(key, c.defaults.KeyPrefix)
+	for _, r := range c.routes {
+		if !strings.HasPrefix(key, r.Prefix) {
+			continue
+		} else if r.Client != nil {
+			return r.Client, nil
+		}
+		return c.dbClient(r.DB)
+	}
+	return c.client, nil
+}
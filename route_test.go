@@ -0,0 +1,76 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Routes", func() {
+	var subject *redislock.Client
+	var other *redis.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		opt := *redisClient.Options()
+		opt.DB = 10
+		other = redis.NewClient(&opt)
+
+		subject = redislock.New(redisClient, redislock.Defaults{Routes: []redislock.Route{
+			{Prefix: "__bsm", DB: 10},
+			{Prefix: "__bsm_redislock_unit_test_", Client: redisClient},
+		}})
+	})
+
+	AfterEach(func() {
+		Expect(other.Del(ctx, "__bsm_other").Err()).To(Succeed())
+		Expect(other.Close()).To(Succeed())
+		Expect(subject.Close()).To(Succeed())
+		Expect(redisClient.Del(ctx, lockKey, "__bsm_other").Err()).To(Succeed())
+	})
+
+	It("should route keys by their longest prefix", func() {
+		lock1, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock1.Release(ctx)
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+
+		lock2, err := subject.Obtain(ctx, "__bsm_other", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Exists(ctx, "__bsm_other").Val()).To(BeZero())
+		Expect(other.Get(ctx, "__bsm_other").Val()).To(Equal(lock2.Token()))
+
+		info, err := subject.Inspect(ctx, "__bsm_other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Token).To(Equal(lock2.Token()))
+
+		Expect(lock2.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock2.Release(ctx)).To(Succeed())
+		Expect(other.Exists(ctx, "__bsm_other").Val()).To(BeZero())
+	})
+
+	It("should let SelectDB take precedence", func() {
+		lock, err := subject.Obtain(ctx, "__bsm_other", time.Hour, time.Hour, &redislock.Options{
+			SelectDB: func(string) int { return 9 },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(redisClient.Exists(ctx, "__bsm_other").Val()).To(Equal(int64(1)))
+		Expect(other.Exists(ctx, "__bsm_other").Val()).To(BeZero())
+	})
+
+	It("should resume routed locks", func() {
+		lock, err := subject.Obtain(ctx, "__bsm_other", time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		resumed := subject.Resume("__bsm_other", lock.Token(), "")
+		Expect(resumed.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(resumed.Release(ctx)).To(Succeed())
+		Expect(other.Exists(ctx, "__bsm_other").Val()).To(BeZero())
+	})
+})
@@ -0,0 +1,240 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// luaNow sets the local variable now to the current server time in
+// milliseconds.
+const luaNow = `local t = redis.call("time") local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000) `
+
+var (
+	luaWLock = redis.NewScript(luaNow + `
+if redis.call("exists", KEYS[1]) == 1 then return 0 end
+redis.call("zremrangebyscore", KEYS[2], "-inf", now)
+if redis.call("zcard", KEYS[2]) > 0 then return 0 end
+redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])
+return 1`)
+	luaRLock = redis.NewScript(luaNow + `
+if redis.call("exists", KEYS[1]) == 1 then return 0 end
+redis.call("zremrangebyscore", KEYS[2], "-inf", now)
+redis.call("zadd", KEYS[2], now + tonumber(ARGV[2]), ARGV[1])
+local last = redis.call("zrange", KEYS[2], -1, -1, "withscores")
+redis.call("pexpireat", KEYS[2], last[2])
+return 1`)
+	luaUpgrade = redis.NewScript(luaNow + `
+redis.call("zremrangebyscore", KEYS[2], "-inf", now)
+if not redis.call("zscore", KEYS[2], ARGV[1]) then return -1 end
+if redis.call("zcard", KEYS[2]) > 1 or redis.call("exists", KEYS[1]) == 1 then return 0 end
+redis.call("zrem", KEYS[2], ARGV[1])
+redis.call("set", KEYS[1], ARGV[2], "px", ARGV[3])
+return 1`)
+	luaDowngrade = redis.NewScript(luaNow + `
+local v = redis.call("get", KEYS[1])
+if v ~= ARGV[2] then
+	if v then return -2 else return -1 end
+end
+redis.call("del", KEYS[1])
+redis.call("zadd", KEYS[2], now + tonumber(ARGV[3]), ARGV[1])
+local last = redis.call("zrange", KEYS[2], -1, -1, "withscores")
+redis.call("pexpireat", KEYS[2], last[2])
+return 1`)
+	luaSharedRefresh = redis.NewScript(luaNow + `
+local s = redis.call("zscore", KEYS[1], ARGV[1])
+if not s or tonumber(s) <= now then
+	redis.call("zrem", KEYS[1], ARGV[1])
+	return -1
+end
+redis.call("zadd", KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+local last = redis.call("zrange", KEYS[1], -1, -1, "withscores")
+redis.call("pexpireat", KEYS[1], last[2])
+return 1`)
+	luaSharedExtend = redis.NewScript(luaNow + `
+local s = redis.call("zscore", KEYS[1], ARGV[1])
+if not s or tonumber(s) <= now then
+	redis.call("zrem", KEYS[1], ARGV[1])
+	return -1
+end
+local t = tonumber(s) - now + tonumber(ARGV[2])
+if tonumber(ARGV[3]) > 0 then t = math.min(t, tonumber(ARGV[3])) end
+redis.call("zadd", KEYS[1], now + t, ARGV[1])
+local last = redis.call("zrange", KEYS[1], -1, -1, "withscores")
+redis.call("pexpireat", KEYS[1], last[2])
+return t`)
+	luaSharedRelease = redis.NewScript(luaNow + `
+local s = redis.call("zscore", KEYS[1], ARGV[1])
+if not s then return -1 end
+redis.call("zrem", KEYS[1], ARGV[1])
+if tonumber(s) <= now then return -1 end
+return 1`)
+	luaSharedPTTL = redis.NewScript(luaNow + `
+local s = redis.call("zscore", KEYS[1], ARGV[1])
+if not s then return -3 end
+local d = tonumber(s) - now
+if d <= 0 then return -3 end
+return d`)
+)
+
+var (
+	errNotReadLock  = errors.New("redislock: not a read lock of this RWLock")
+	errNotWriteLock = errors.New("redislock: not a write lock of this RWLock")
+)
+
+// RWLock is a distributed reader/writer lock. Any number of readers may hold
+// the lock concurrently, while a writer requires exclusive access.
+//
+// The writer lock is stored under the key itself, readers are tracked in a
+// sorted set under key + ":readers", with individual expiry times.
+type RWLock struct {
+	client *Client
+	key    string
+}
+
+// NewRWLock creates a new RWLock for key. Optional defaults configure the
+// underlying client like New, except that the key is used as is.
+func NewRWLock(client RedisClient, key string, defaults ...Defaults) *RWLock {
+	return &RWLock{client: New(client, defaults...), key: key}
+}
+
+// RLock obtains a shared read lock with the given TTL.
+// May return ErrNotObtained if not successful.
+func (rw *RWLock) RLock(ctx context.Context, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	return rw.obtain(ctx, luaRLock, true, waitTimeout, lockTTL, opt)
+}
+
+// Lock obtains an exclusive write lock with the given TTL.
+// May return ErrNotObtained if not successful.
+func (rw *RWLock) Lock(ctx context.Context, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	return rw.obtain(ctx, luaWLock, false, waitTimeout, lockTTL, opt)
+}
+
+func (rw *RWLock) readersKey() string {
+	return rw.client.companionKey(rw.key, ":readers")
+}
+
+func (rw *RWLock) obtain(ctx context.Context, script *redis.Script, read bool, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	c := rw.client
+	if err := c.validate(ctx, rw.key, lockTTL); err != nil {
+		return nil, err
+	}
+	opt = c.options(opt)
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, &Error{Op: "obtain", Key: rw.key, Err: err}
+	}
+
+	value := token + opt.getMetadata()
+	member := value
+	if read {
+		member = token
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	keys := []string{rw.key, rw.readersKey()}
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	var start time.Time
+	stats, err := c.retry(deadlinectx, c.client, rw.key, token, opt, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		status, err := script.Run(opctx, c.client, keys, member, ttlVal).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		return status == int64(1), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lock := rw.newLock(token, value, read, start, lockTTL, opTimeout, opt.getLogger())
+	lock.stats = stats
+	return lock.bind(ctx, opt), nil
+}
+
+// Upgrade atomically converts a read lock obtained via RLock into a write lock
+// with the given TTL, provided there are no other readers. The read lock is no
+// longer held afterwards.
+// May return ErrNotObtained if there are other readers, or ErrLockExpired if
+// the read lock has expired.
+func (rw *RWLock) Upgrade(ctx context.Context, read *Lock, lockTTL time.Duration) (_ *Lock, err error) {
+	defer wrapErr("upgrade", rw.key, &err)
+
+	if read.scripts != sharedScripts || read.key != rw.key {
+		return nil, errNotReadLock
+	}
+	return rw.convert(ctx, read, luaUpgrade, false, lockTTL)
+}
+
+// Downgrade atomically converts a write lock obtained via Lock into a read
+// lock with the given TTL, so other readers may join without the lock being
+// free in between. The write lock is no longer held afterwards.
+// May return ErrLockExpired or ErrLockStolen if the write lock was lost.
+func (rw *RWLock) Downgrade(ctx context.Context, write *Lock, lockTTL time.Duration) (_ *Lock, err error) {
+	defer wrapErr("downgrade", rw.key, &err)
+
+	if write.scripts != exclusiveScripts || write.key != rw.key {
+		return nil, errNotWriteLock
+	}
+	return rw.convert(ctx, write, luaDowngrade, true, lockTTL)
+}
+
+func (rw *RWLock) convert(ctx context.Context, from *Lock, script *redis.Script, read bool, lockTTL time.Duration) (*Lock, error) {
+	from.StopAutoRefresh()
+	from.setReleasing(true)
+	defer from.setReleasing(false)
+
+	from.argMu.RLock()
+	defer from.argMu.RUnlock()
+
+	opctx, cancel := withOperationTimeout(ctx, from.opTimeout)
+	defer cancel()
+
+	start := time.Now()
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+	status, err := script.Run(opctx, from.rdb, []string{rw.key, rw.readersKey()}, from.token, from.value, ttlVal).Result()
+	if err != nil {
+		return nil, wrapOperationErr(ctx, opctx, err)
+	} else if status == int64(0) {
+		return nil, ErrNotObtained
+	} else if status != int64(1) {
+		return nil, from.lostBy(status, from.logger, ErrLockNotHeld)
+	}
+
+	from.lost(ErrLockNotHeld)
+	return rw.newLock(from.token, from.value, read, start, lockTTL, from.opTimeout, from.logger), nil
+}
+
+func (rw *RWLock) newLock(token, value string, read bool, start time.Time, lockTTL, opTimeout time.Duration, logger Logger) *Lock {
+	lock := &Lock{
+		client:     rw.client,
+		rdb:        rw.client.client,
+		key:        rw.key,
+		token:      token,
+		value:      value,
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opTimeout,
+		logger:     logger,
+		scripts:    exclusiveScripts,
+		scriptKeys: []string{rw.key},
+		scriptArg:  value,
+	}
+	if read {
+		lock.scripts = sharedScripts
+		lock.scriptKeys = []string{rw.readersKey()}
+		lock.scriptArg = token
+	}
+	return lock
+}
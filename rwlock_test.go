@@ -0,0 +1,146 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RWLock", func() {
+	var subject *redislock.RWLock
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.NewRWLock(redisClient, lockKey)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":readers").Err()).To(Succeed())
+	})
+
+	It("should validate arguments", func() {
+		_, err := subject.RLock(ctx, time.Second, 0, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		_, err = subject.RLock(ctx, time.Second, time.Microsecond, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		_, err = subject.Lock(ctx, time.Second, 0, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		_, err = subject.Lock(ctx, time.Second, time.Microsecond, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		_, err = redislock.NewRWLock(redisClient, "").Lock(ctx, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidKey))
+	})
+
+	It("should allow concurrent readers", func() {
+		r1, err := subject.RLock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		r2, err := subject.RLock(ctx, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r1.Token()).NotTo(Equal(r2.Token()))
+
+		Expect(r1.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(r2.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(r2.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(r2.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+
+		Expect(r1.Release(ctx)).To(Succeed())
+		Expect(r2.Release(ctx)).To(Succeed())
+		Expect(r2.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should exclude writers while read-locked", func() {
+		r, err := subject.RLock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Lock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(r.Release(ctx)).To(Succeed())
+
+		w, err := subject.Lock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Release(ctx)).To(Succeed())
+	})
+
+	It("should exclude readers and writers while write-locked", func() {
+		w, err := subject.Lock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.RLock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Lock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(w.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(w.Release(ctx)).To(Succeed())
+
+		r, err := subject.RLock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Release(ctx)).To(Succeed())
+	})
+
+	It("should expire crashed readers", func() {
+		r, err := subject.RLock(ctx, time.Hour, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		w, err := subject.Lock(ctx, time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(10*time.Millisecond), 10),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(r.TTL(ctx)).To(BeZero())
+		Expect(r.Refresh(ctx, time.Hour, nil)).To(MatchError(redislock.ErrLockExpired))
+		Expect(r.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(w.Release(ctx)).To(Succeed())
+	})
+
+	It("should upgrade sole readers", func() {
+		r1, err := subject.RLock(ctx, time.Hour, time.Hour, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		r2, err := subject.RLock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Upgrade(ctx, r1, time.Minute)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(r2.Release(ctx)).To(Succeed())
+
+		w, err := subject.Upgrade(ctx, r1, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Token()).To(Equal(r1.Token()))
+		Expect(w.Metadata()).To(Equal("meta"))
+		Expect(w.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(r1.Done()).To(BeClosed())
+		Expect(redisClient.ZCard(ctx, lockKey+":readers").Val()).To(BeZero())
+
+		_, err = subject.RLock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Upgrade(ctx, w, time.Minute)
+		Expect(err).To(HaveOccurred())
+		var e *redislock.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Op).To(Equal("upgrade"))
+		Expect(w.Release(ctx)).To(Succeed())
+	})
+
+	It("should downgrade writers", func() {
+		w, err := subject.Lock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		r1, err := subject.Downgrade(ctx, w, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r1.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(w.Done()).To(BeClosed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+
+		r2, err := subject.RLock(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r2.Release(ctx)).To(Succeed())
+		Expect(r1.Release(ctx)).To(Succeed())
+
+		_, err = subject.Downgrade(ctx, w, time.Minute)
+		Expect(err).To(MatchError(redislock.ErrLockExpired))
+	})
+})
@@ -0,0 +1,148 @@
+package redislock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// scheduleBackoff is the backoff of ObtainAt unless Options.RetryStrategy is
+// set.
+const scheduleBackoff = 10 * time.Millisecond
+
+var luaScheduleObtain = redis.NewScript(luaNow + `
+local stale = redis.call("zrangebyscore", KEYS[3], "-inf", now)
+for i = 1, #stale do redis.call("zrem", KEYS[2], stale[i]) end
+redis.call("zremrangebyscore", KEYS[3], "-inf", now)
+
+local at = tonumber(ARGV[4])
+if not redis.call("zscore", KEYS[2], ARGV[3]) then redis.call("zadd", KEYS[2], at, ARGV[3]) end
+local timeout = math.max(at, now) + tonumber(ARGV[5])
+redis.call("zadd", KEYS[3], timeout, ARGV[3])
+
+if at <= now and redis.call("zrange", KEYS[2], 0, 0)[1] == ARGV[3] and redis.call("exists", KEYS[1]) == 0 then
+	redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])
+	redis.call("zrem", KEYS[2], ARGV[3])
+	redis.call("zrem", KEYS[3], ARGV[3])
+	return 1
+end
+
+for i = 2, 3 do
+	if redis.call("pttl", KEYS[i]) < timeout - now then redis.call("pexpire", KEYS[i], timeout - now) end
+end
+return 0`)
+
+func (c *Client) scheduleKey(key string) string { return c.companionKey(key, ":schedule") }
+func (c *Client) scheduleTimeoutKey(key string) string {
+	return c.companionKey(key, ":schedule-timeouts")
+}
+
+// ObtainAt obtains a lock like Obtain, but not before at, e.g. to coordinate
+// time-based exclusive tasks across a fleet. It registers the intent under
+// the lock key + ":schedule" right away and waits until at, then retries
+// according to Options.RetryStrategy, every 10ms by default, until the lock
+// is obtained or ctx is done. Once due, intents are granted the lock in the
+// order of their target times, so an intent never takes the lock while an
+// earlier one is still waiting for it. The target time is compared to the
+// clock of the server. Intents which stop retrying for longer than
+// Options.QueueTimeout past their target time are removed. Scheduling only
+// applies among callers of ObtainAt, plain Obtain calls may still take the
+// lock while it is free.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainAt(ctx context.Context, key string, lockTTL time.Duration, at time.Time, opt *Options) (*Lock, error) {
+	if err := c.validate(ctx, key, lockTTL); err != nil {
+		return nil, err
+	}
+	opt = c.options(opt)
+	if opt.RetryStrategy == nil {
+		o := *opt
+		o.RetryStrategy = LinearBackoff(scheduleBackoff)
+		opt = &o
+	}
+	lockTTL = c.lockTTL(lockTTL)
+	key = c.defaults.KeyPrefix + key
+
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := c.lockValue(token, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	keys := []string{key, c.scheduleKey(key), c.scheduleTimeoutKey(key)}
+	atVal := at.UnixNano() / int64(time.Millisecond)
+	queueTimeoutVal := msArg(opt.getQueueTimeout())
+
+	var start time.Time
+	try := func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		status, err := luaScheduleObtain.Run(opctx, c.client, keys, value, msArg(lockTTL), token, atVal, queueTimeoutVal).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		return status == int64(1), nil
+	}
+
+	var stats LockStats
+	ok, err := try(ctx)
+	if err != nil {
+		err = &Error{Op: "obtain", Key: key, Err: err, Attempts: 1, Stopped: StopError}
+	} else if ok {
+		stats = LockStats{Attempts: 1}
+	} else if err = c.sleepUntil(ctx, at); err != nil {
+		err = &Error{Op: "obtain", Key: key, Err: ErrNotObtained, Attempts: 1, Stopped: StopContext}
+	} else {
+		stats, err = c.retry(ctx, c.client, key, token, opt, false, try)
+	}
+	if err != nil {
+		leavectx, cancel := cleanupContext(opTimeout)
+		defer cancel()
+		_ = luaFairLeave.Run(leavectx, c.client, keys[1:], token).Err()
+		return nil, err
+	}
+
+	return c.track(&Lock{
+		client:     c,
+		rdb:        c.client,
+		key:        key,
+		token:      token,
+		value:      value,
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opTimeout,
+		logger:     opt.getLogger(),
+		scripts:    exclusiveScripts,
+		scriptKeys: []string{key},
+		scriptArg:  value,
+		stats:      stats,
+	}).bind(ctx, opt), nil
+}
+
+// sleepUntil waits until t according to the clock of the client, or until
+// ctx is done.
+func (c *Client) sleepUntil(ctx context.Context, t time.Time) error {
+	clock := c.clock()
+	d := t.Sub(clock.Now())
+	if d <= 0 {
+		return nil
+	}
+
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
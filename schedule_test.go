@@ -0,0 +1,94 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.ObtainAt", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":schedule", lockKey+":schedule-timeouts").Err()).To(Succeed())
+	})
+
+	It("should obtain at the target time", func() {
+		start := time.Now()
+		lock, err := subject.ObtainAt(ctx, lockKey, time.Minute, start.Add(50*time.Millisecond), nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(time.Since(start)).To(BeNumerically("~", 50*time.Millisecond, 30*time.Millisecond))
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(redisClient.Exists(ctx, lockKey+":schedule", lockKey+":schedule-timeouts").Val()).To(BeZero())
+	})
+
+	It("should obtain immediately if due", func() {
+		lock, err := subject.ObtainAt(ctx, lockKey, time.Minute, time.Now().Add(-time.Second), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Stats().Attempts).To(Equal(1))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should grant earlier intents first", func() {
+		holder, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		now := time.Now()
+		type result struct {
+			lock *redislock.Lock
+			at   time.Time
+		}
+		later := make(chan result, 1)
+		earlier := make(chan result, 1)
+		for _, intent := range []struct {
+			at  time.Time
+			out chan result
+		}{{now.Add(20 * time.Millisecond), later}, {now.Add(10 * time.Millisecond), earlier}} {
+			intent := intent
+			go func() {
+				defer GinkgoRecover()
+
+				cctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+				defer cancel()
+				lock, err := subject.ObtainAt(cctx, lockKey, time.Minute, intent.at, nil)
+				Expect(err).NotTo(HaveOccurred())
+				intent.out <- result{lock: lock, at: time.Now()}
+			}()
+		}
+
+		time.Sleep(60 * time.Millisecond)
+		Expect(holder.Release(ctx)).To(Succeed())
+
+		var first result
+		Eventually(earlier).Should(Receive(&first))
+		Consistently(later, 50*time.Millisecond).ShouldNot(Receive())
+		Expect(first.lock.Release(ctx)).To(Succeed())
+
+		var second result
+		Eventually(later).Should(Receive(&second))
+		Expect(second.at).To(BeTemporally(">", first.at))
+		Expect(second.lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should withdraw intents which are not granted", func() {
+		holder, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer holder.Release(ctx)
+
+		cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = subject.ObtainAt(cctx, lockKey, time.Minute, time.Now().Add(10*time.Millisecond), nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(redisClient.Exists(ctx, lockKey+":schedule", lockKey+":schedule-timeouts").Val()).To(BeZero())
+	})
+})
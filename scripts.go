@@ -0,0 +1,65 @@
+package redislock
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Scripts replace the Lua scripts of exclusive locks, e.g. to also maintain
+// an audit counter, while Obtain continues to handle tokens, retries and
+// errors. Each script receives the lock key as KEYS[1], followed by Keys, and
+// the lock value, i.e. the token and metadata, as ARGV[1]. Scripts which are
+// not set default to the built-in ones, see package scripts, whose Verify
+// checks replacements against the contract.
+//
+// Locks obtained with Scripts do not send release signals or notifications,
+// see Options.ReleaseSignal and Options.ReleaseNotify, and cannot be
+// marshaled.
+type Scripts struct {
+	// Obtain sets the lock key to ARGV[1] with a TTL of ARGV[2]
+	// milliseconds, unless it is set already. It returns 1 if the lock was
+	// obtained.
+	// Default: SET NX
+	Obtain *redis.Script
+
+	// Refresh resets the TTL of the lock key to ARGV[2] milliseconds if it
+	// still holds ARGV[1]. It returns 1 on success, -1 if the key has
+	// expired and -2 if it holds another value.
+	Refresh *redis.Script
+
+	// Release deletes the lock key if it still holds ARGV[1]. It returns the
+	// same codes as Refresh.
+	Release *redis.Script
+
+	// Keys are additional keys passed to all scripts, as-is.
+	Keys []string
+}
+
+// lockScripts returns the script set of locks obtained with s.
+func (s *Scripts) lockScripts() *lockScripts {
+	scripts := *exclusiveScripts
+	if s.Refresh != nil {
+		scripts.refresh = s.Refresh
+	}
+	if s.Release != nil {
+		scripts.release = s.Release
+		scripts.releaseStats = nil
+		scripts.unlinkRelease, scripts.unlinkReleaseStats = nil, nil
+	}
+	return &scripts
+}
+
+// obtainScript makes a single attempt to obtain the lock via s.Obtain.
+func (s *Scripts) obtainScript(ctx context.Context, rdb RedisClient, key, value string, ttl time.Duration) (bool, error) {
+	ttlVal := strconv.FormatInt(int64(ttl/time.Millisecond), 10)
+	status, err := s.Obtain.Run(ctx, rdb, append([]string{key}, s.Keys...), value, ttlVal).Result()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return status == int64(1), nil
+}
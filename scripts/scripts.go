@@ -0,0 +1,61 @@
+// Package scripts contains the Lua scripts redislock uses for exclusive
+// locks, and documents their contract, so other implementations, e.g. in
+// other languages or via redislock.Scripts, can share lock keys with
+// redislock and verify their compatibility with Verify.
+//
+// A lock is a string key holding the lock value, i.e. the token of the holder
+// followed by its metadata, with a TTL in milliseconds. Each script receives
+// the lock key as KEYS[1] and the lock value as ARGV[1]. Scripts which check
+// the holder return StatusExpired if the key does not exist and StatusStolen
+// if it holds another value, except for PTTL, which returns StatusNotHeld in
+// both cases.
+package scripts
+
+import "github.com/go-redis/redis/v8"
+
+// Status codes returned by the scripts if the lock is not held.
+const (
+	// StatusExpired is returned if the lock key does not exist.
+	StatusExpired = -1
+	// StatusStolen is returned if the lock key holds another value.
+	StatusStolen = -2
+	// StatusNotHeld is returned by PTTL if the lock is not held.
+	StatusNotHeld = -3
+)
+
+// Sources of the scripts.
+const (
+	// ObtainSrc sets KEYS[1] to ARGV[1] with a TTL of ARGV[2] milliseconds,
+	// unless it exists. It returns 1 if the lock was obtained and 0
+	// otherwise, even if the key already holds ARGV[1]. redislock sends the
+	// equivalent SET NX PX command instead of the script.
+	ObtainSrc = `if redis.call("set", KEYS[1], ARGV[1], "nx", "px", ARGV[2]) then return 1 end return 0`
+
+	// RefreshSrc resets the TTL of KEYS[1] to ARGV[2] milliseconds if it
+	// holds ARGV[1]. It returns 1 on success, StatusExpired or StatusStolen.
+	RefreshSrc = `local v = redis.call("get", KEYS[1]) if v == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) elseif v then return -2 else return -1 end`
+
+	// ReleaseSrc deletes KEYS[1] if it holds ARGV[1]. It returns 1 on
+	// success, StatusExpired or StatusStolen.
+	ReleaseSrc = `local v = redis.call("get", KEYS[1]) if v == ARGV[1] then return redis.call("del", KEYS[1]) elseif v then return -2 else return -1 end`
+
+	// PTTLSrc returns the remaining TTL of KEYS[1] in milliseconds, or -1 if
+	// it has none, if it holds ARGV[1], and StatusNotHeld otherwise.
+	PTTLSrc = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pttl", KEYS[1]) else return -3 end`
+)
+
+// The scripts, see the sources for their contracts.
+var (
+	Obtain  = redis.NewScript(ObtainSrc)
+	Refresh = redis.NewScript(RefreshSrc)
+	Release = redis.NewScript(ReleaseSrc)
+	PTTL    = redis.NewScript(PTTLSrc)
+)
+
+// Set is a set of scripts implementing the contract, see Verify.
+type Set struct {
+	Obtain, Refresh, Release, PTTL *redis.Script
+}
+
+// Default is the set of built-in scripts.
+var Default = Set{Obtain: Obtain, Refresh: Refresh, Release: Release, PTTL: PTTL}
@@ -0,0 +1,89 @@
+package scripts_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	"github.com/muroq/redislock/redislocktest"
+	"github.com/muroq/redislock/scripts"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const lockKey = "__redislock_scripts_unit_test__"
+
+var _ = Describe("Verify", func() {
+	var ctx = context.Background()
+
+	It("should accept the built-in scripts", func() {
+		Expect(scripts.Verify(ctx, redisClient, lockKey, scripts.Default)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should accept the built-in scripts in memory", func() {
+		Expect(scripts.Verify(ctx, redislocktest.New(), lockKey, scripts.Default)).To(Succeed())
+	})
+
+	It("should report violations", func() {
+		set := scripts.Default
+		set.Release = redis.NewScript(`return redis.call("del", KEYS[1])`)
+		Expect(scripts.Verify(ctx, redisClient, lockKey, set)).To(MatchError("scripts: release stolen: release returned 1, want -2"))
+
+		set = scripts.Default
+		set.Refresh = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return 1 end return -1`)
+		Expect(scripts.Verify(ctx, redisClient, lockKey, set)).To(MatchError(MatchRegexp(`^scripts: refresh held: key has a TTL of \d+ms, want 20000ms$`)))
+	})
+
+	It("should skip missing scripts", func() {
+		Expect(scripts.Verify(ctx, redisClient, lockKey, scripts.Set{PTTL: scripts.PTTL})).To(Succeed())
+	})
+
+	It("should share keys with redislock", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "meta"})
+		Expect(err).NotTo(HaveOccurred())
+		value := lock.Token() + lock.Metadata()
+
+		Expect(scripts.Obtain.Run(ctx, redisClient, []string{lockKey}, value, 1000).Int64()).To(Equal(int64(0)))
+		Expect(scripts.PTTL.Run(ctx, redisClient, []string{lockKey}, value).Int64()).To(BeNumerically("~", 60000, 1000))
+		Expect(scripts.Release.Run(ctx, redisClient, []string{lockKey}, value).Int64()).To(Equal(int64(1)))
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock/scripts")
+}
+
+var redisClient *redis.Client
+
+var embedded *redislocktest.Server
+
+var _ = BeforeSuite(func() {
+	addr := "127.0.0.1:6379"
+	if os.Getenv("REDISLOCK_TEST_EMBEDDED") != "" {
+		var err error
+		embedded, err = redislocktest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+		addr = embedded.Addr()
+	}
+
+	redisClient = redis.NewClient(&redis.Options{
+		Network: "tcp",
+		Addr:    addr, DB: 9,
+	})
+	Expect(redisClient.Ping(context.Background()).Err()).To(Succeed())
+})
+
+var _ = AfterSuite(func() {
+	Expect(redisClient.Close()).To(Succeed())
+	if embedded != nil {
+		Expect(embedded.Close()).To(Succeed())
+	}
+})
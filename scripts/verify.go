@@ -0,0 +1,143 @@
+package scripts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Scripter is the client interface required by Verify. It is implemented by
+// go-redis/v8 clients and redislock.RedisClient.
+type Scripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd
+	ScriptLoad(ctx context.Context, script string) *redis.StringCmd
+}
+
+var (
+	luaSet  = redis.NewScript(`return redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])`)
+	luaGet  = redis.NewScript(`return redis.call("get", KEYS[1]) or ""`)
+	luaPTTL = redis.NewScript(`return redis.call("pttl", KEYS[1])`)
+	luaDel  = redis.NewScript(`return redis.call("del", KEYS[1])`)
+)
+
+// step is an operation of a verification case.
+type step struct {
+	script string
+	args   []interface{}
+	want   int64
+}
+
+// verifyCase is a scenario of the contract. The key is set to value with a
+// TTL of 10s first, unless value is empty. Afterwards, the key must hold
+// wantValue, or not exist if it is empty, with a TTL of at most wantTTL
+// milliseconds and more than half of it.
+type verifyCase struct {
+	name      string
+	value     string
+	step      step
+	wantValue string
+	wantTTL   int64
+}
+
+var verifyCases = []verifyCase{
+	{"obtain free", "", step{"obtain", []interface{}{"a", 20000}, 1}, "a", 20000},
+	{"obtain taken", "b", step{"obtain", []interface{}{"a", 20000}, 0}, "b", 10000},
+	{"obtain held", "a", step{"obtain", []interface{}{"a", 20000}, 0}, "a", 10000},
+	{"refresh held", "a", step{"refresh", []interface{}{"a", 20000}, 1}, "a", 20000},
+	{"refresh stolen", "b", step{"refresh", []interface{}{"a", 20000}, StatusStolen}, "b", 10000},
+	{"refresh expired", "", step{"refresh", []interface{}{"a", 20000}, StatusExpired}, "", 0},
+	{"release held", "a", step{"release", []interface{}{"a"}, 1}, "", 0},
+	{"release stolen", "b", step{"release", []interface{}{"a"}, StatusStolen}, "b", 10000},
+	{"release expired", "", step{"release", []interface{}{"a"}, StatusExpired}, "", 0},
+	{"pttl stolen", "b", step{"pttl", []interface{}{"a"}, StatusNotHeld}, "b", 10000},
+	{"pttl expired", "", step{"pttl", []interface{}{"a"}, StatusNotHeld}, "", 0},
+}
+
+// Verify runs set against rdb and checks that it implements the contract of
+// the built-in scripts, using key, which is deleted before and after. Scripts
+// of set which are nil are skipped. It returns the first violation found.
+func Verify(ctx context.Context, rdb Scripter, key string, set Set) error {
+	defer luaDel.Run(ctx, rdb, []string{key})
+
+	for _, c := range verifyCases {
+		script := set.script(c.step.script)
+		if script == nil {
+			continue
+		}
+		if err := c.run(ctx, rdb, key, script); err != nil {
+			return fmt.Errorf("scripts: %s: %w", c.name, err)
+		}
+	}
+
+	if set.PTTL != nil {
+		if err := verifyPTTL(ctx, rdb, key, set.PTTL); err != nil {
+			return fmt.Errorf("scripts: pttl held: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Set) script(name string) *redis.Script {
+	switch name {
+	case "obtain":
+		return s.Obtain
+	case "refresh":
+		return s.Refresh
+	case "release":
+		return s.Release
+	}
+	return s.PTTL
+}
+
+func (c *verifyCase) run(ctx context.Context, rdb Scripter, key string, script *redis.Script) error {
+	if err := luaDel.Run(ctx, rdb, []string{key}).Err(); err != nil {
+		return err
+	}
+	if c.value != "" {
+		if err := luaSet.Run(ctx, rdb, []string{key}, c.value, 10000).Err(); err != nil {
+			return err
+		}
+	}
+
+	got, err := script.Run(ctx, rdb, []string{key}, c.step.args...).Int64()
+	if err != nil {
+		return err
+	} else if got != c.step.want {
+		return fmt.Errorf("%s returned %d, want %d", c.step.script, got, c.step.want)
+	}
+
+	value, err := luaGet.Run(ctx, rdb, []string{key}).Text()
+	if err != nil {
+		return err
+	} else if value != c.wantValue {
+		return fmt.Errorf("key holds %q, want %q", value, c.wantValue)
+	}
+	if c.wantValue == "" {
+		return nil
+	}
+
+	pttl, err := luaPTTL.Run(ctx, rdb, []string{key}).Int64()
+	if err != nil {
+		return err
+	} else if pttl > c.wantTTL || pttl <= c.wantTTL/2 {
+		return fmt.Errorf("key has a TTL of %dms, want %dms", pttl, c.wantTTL)
+	}
+	return nil
+}
+
+func verifyPTTL(ctx context.Context, rdb Scripter, key string, script *redis.Script) error {
+	if err := luaSet.Run(ctx, rdb, []string{key}, "a", 10000).Err(); err != nil {
+		return err
+	}
+
+	got, err := script.Run(ctx, rdb, []string{key}, "a").Int64()
+	if err != nil {
+		return err
+	} else if got > 10000 || got <= 5000 {
+		return fmt.Errorf("pttl returned %d, want 10000", got)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scripts", func() {
+	var ctx = context.Background()
+	var auditKey = lockKey + ".audit"
+
+	scripts := &redislock.Scripts{
+		Obtain:  redis.NewScript(`if redis.call("set", KEYS[1], ARGV[1], "nx", "px", ARGV[2]) then redis.call("hincrby", KEYS[2], "obtain", 1) return 1 end return 0`),
+		Release: redis.NewScript(`local v = redis.call("get", KEYS[1]) if v == ARGV[1] then redis.call("hincrby", KEYS[2], "release", 1) return redis.call("del", KEYS[1]) elseif v then return -2 else return -1 end`),
+		Keys:    []string{auditKey},
+	}
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, auditKey).Err()).To(Succeed())
+	})
+
+	It("should run custom scripts", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{Scripts: scripts})
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockExpired))
+
+		Expect(redisClient.HGetAll(ctx, auditKey).Val()).To(Equal(map[string]string{"obtain": "1", "release": "1"}))
+	})
+
+	It("should apply per-call scripts", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Minute, &redislock.Options{Scripts: scripts})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		Expect(redisClient.HGetAll(ctx, auditKey).Val()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,140 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// luaSemUsedSrc sets the local variable used to the total weight of the live
+// holders of the semaphore KEYS[1]. Members are formatted as
+// "<weight>|<token>", members without a weight count as one.
+const luaSemUsedSrc = luaNow + `
+redis.call("zremrangebyscore", KEYS[1], "-inf", now)
+local used = 0
+for _, m in ipairs(redis.call("zrange", KEYS[1], 0, -1)) do
+	used = used + (tonumber(string.match(m, "^(%d+)|")) or 1)
+end
+`
+
+var (
+	luaSemAcquire = redis.NewScript(luaSemUsedSrc + `
+if used + tonumber(ARGV[4]) > tonumber(ARGV[3]) then return 0 end
+redis.call("zadd", KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+local last = redis.call("zrange", KEYS[1], -1, -1, "withscores")
+redis.call("pexpireat", KEYS[1], last[2])
+return 1`)
+	luaSemCount = redis.NewScript(luaNow + `
+redis.call("zremrangebyscore", KEYS[1], "-inf", now)
+return redis.call("zcard", KEYS[1])`)
+	luaSemUsed = redis.NewScript(luaSemUsedSrc + `return used`)
+)
+
+var errInvalidWeight = errors.New("redislock: weight must be between 1 and the capacity")
+
+// Semaphore is a distributed counting semaphore, which allows up to a
+// capacity of holders at the same time. Holders may also acquire a weight,
+// e.g. units of memory, in which case the capacity caps their total weight.
+// Each holder has an individual TTL, so slots of crashed holders are freed
+// automatically.
+type Semaphore struct {
+	client   *Client
+	key      string
+	capacity int
+}
+
+// NewSemaphore creates a new Semaphore for key with the given capacity.
+// Optional defaults configure the underlying client like New, except that
+// the key is used as is.
+func NewSemaphore(client RedisClient, key string, capacity int, defaults ...Defaults) *Semaphore {
+	return &Semaphore{client: New(client, defaults...), key: key, capacity: capacity}
+}
+
+// Acquire obtains a slot with the given TTL. The slot is returned by
+// releasing the returned lock.
+// May return ErrNotObtained if not successful.
+func (s *Semaphore) Acquire(ctx context.Context, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	return s.AcquireWeighted(ctx, 1, waitTimeout, lockTTL, opt)
+}
+
+// AcquireWeighted is like Acquire, but obtains weight slots at once, which
+// are held and returned together.
+// May return ErrNotObtained if not successful.
+func (s *Semaphore) AcquireWeighted(ctx context.Context, weight int, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	if err := s.client.validate(ctx, s.key, lockTTL); err != nil {
+		return nil, err
+	}
+	if weight < 1 || weight > s.capacity {
+		return nil, &Error{Op: "obtain", Key: s.key, Err: errInvalidWeight}
+	}
+	opt = s.client.options(opt)
+
+	c := s.client
+	token, err := c.newToken(opt)
+	if err != nil {
+		return nil, &Error{Op: "obtain", Key: s.key, Err: err}
+	}
+
+	opTimeout := opt.getOperationTimeout()
+	ttlVal := strconv.FormatInt(int64(lockTTL/time.Millisecond), 10)
+	capVal := strconv.Itoa(s.capacity)
+	member := strconv.Itoa(weight) + "|" + token
+
+	deadlinectx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	var start time.Time
+	stats, err := c.retry(deadlinectx, c.client, s.key, token, opt, false, func(ctx context.Context) (bool, error) {
+		start = time.Now()
+		opctx, cancel := withOperationTimeout(ctx, opTimeout)
+		defer cancel()
+
+		status, err := luaSemAcquire.Run(opctx, c.client, []string{s.key}, member, ttlVal, capVal, weight).Result()
+		if err != nil {
+			return false, wrapOperationErr(ctx, opctx, err)
+		}
+		return status == int64(1), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{
+		client:     c,
+		rdb:        c.client,
+		key:        s.key,
+		token:      token,
+		value:      token + opt.getMetadata(),
+		ttl:        lockTTL,
+		obtained:   start,
+		expires:    start.Add(lockTTL),
+		opTimeout:  opTimeout,
+		logger:     opt.getLogger(),
+		scripts:    sharedScripts,
+		scriptKeys: []string{s.key},
+		scriptArg:  member,
+		stats:      stats,
+	}
+	return lock.bind(ctx, opt), nil
+}
+
+// Count returns the number of currently held slots.
+func (s *Semaphore) Count(ctx context.Context) (int, error) {
+	n, err := luaSemCount.Run(ctx, s.client.client, []string{s.key}).Int()
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Used returns the total weight of the currently held slots.
+func (s *Semaphore) Used(ctx context.Context) (int, error) {
+	n, err := luaSemUsed.Run(ctx, s.client.client, []string{s.key}).Int()
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
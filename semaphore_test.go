@@ -0,0 +1,108 @@
+package redislock_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Semaphore", func() {
+	var subject *redislock.Semaphore
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.NewSemaphore(redisClient, lockKey, 2)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should validate arguments", func() {
+		_, err := subject.Acquire(ctx, time.Second, 0, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		_, err = subject.Acquire(ctx, time.Second, time.Microsecond, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidTTL))
+		_, err = redislock.NewSemaphore(redisClient, "", 2).Acquire(ctx, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrInvalidKey))
+		Expect(subject.Count(ctx)).To(BeZero())
+	})
+
+	It("should limit holders to capacity", func() {
+		s1, err := subject.Acquire(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		s2, err := subject.Acquire(ctx, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.Count(ctx)).To(Equal(2))
+
+		_, err = subject.Acquire(ctx, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(s2.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(s2.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(s2.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+
+		Expect(s1.Release(ctx)).To(Succeed())
+		Expect(subject.Count(ctx)).To(Equal(1))
+
+		s3, err := subject.Acquire(ctx, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s3.Release(ctx)).To(Succeed())
+		Expect(s2.Release(ctx)).To(Succeed())
+		Expect(s2.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should free slots of expired holders", func() {
+		_, err := subject.Acquire(ctx, time.Hour, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = subject.Acquire(ctx, time.Hour, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		s, err := subject.Acquire(ctx, time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(10*time.Millisecond), 10),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.Count(ctx)).To(Equal(1))
+		Expect(s.Release(ctx)).To(Succeed())
+	})
+
+	It("should limit the total weight of holders", func() {
+		subject = redislock.NewSemaphore(redisClient, lockKey, 10)
+
+		s1, err := subject.AcquireWeighted(ctx, 6, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		s2, err := subject.Acquire(ctx, time.Hour, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.Count(ctx)).To(Equal(2))
+		Expect(subject.Used(ctx)).To(Equal(7))
+
+		_, err = subject.AcquireWeighted(ctx, 4, time.Hour, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		s3, err := subject.AcquireWeighted(ctx, 4, time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(10*time.Millisecond), 10),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.Used(ctx)).To(Equal(10))
+		Expect(s2.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		Expect(s3.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(s3.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(s1.Release(ctx)).To(Succeed())
+		Expect(subject.Used(ctx)).To(Equal(4))
+		Expect(s3.Release(ctx)).To(Succeed())
+		Expect(subject.Used(ctx)).To(Equal(0))
+
+		_, err = subject.AcquireWeighted(ctx, 11, time.Hour, time.Hour, nil)
+		Expect(err).To(HaveOccurred())
+		var e *redislock.Error
+		Expect(errors.As(err, &e)).To(BeTrue())
+		Expect(e.Op).To(Equal("obtain"))
+		_, err = subject.AcquireWeighted(ctx, 0, time.Hour, time.Hour, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
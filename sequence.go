@@ -0,0 +1,71 @@
+package redislock
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// seqTagPrefix starts the sequence tag, which is stored between the token and
+// the owner tag of a lock value, see Options.Sequence.
+const seqTagPrefix = "seq.v1?"
+
+var luaObtainSequence = redis.NewScript(`
+if redis.call("exists", KEYS[1]) == 1 then return 0 end
+local n = redis.call("incr", KEYS[2])
+redis.call("set", KEYS[1], ARGV[1] .. "` + seqTagPrefix + `" .. n .. "|" .. ARGV[3], "px", ARGV[2])
+return n`)
+
+func (c *Client) sequenceKey(key string) string { return c.companionKey(key, ":seq") }
+
+// obtainSequence is like SETNX, but stamps the lock value with the next
+// acquisition sequence number of key, which it returns, or 0 if the lock is
+// taken. value is the lock value without the tag.
+func (c *Client) obtainSequence(ctx context.Context, rdb RedisClient, key, token, value string, ttl, opTimeout time.Duration) (int64, error) {
+	opctx, cancel := withOperationTimeout(ctx, opTimeout)
+	defer cancel()
+
+	keys := []string{key, c.sequenceKey(key)}
+	seq, err := luaObtainSequence.Run(opctx, rdb, keys, token, msArg(ttl), value[len(token):]).Int64()
+	if err != nil {
+		return 0, wrapOperationErr(ctx, opctx, err)
+	}
+	return seq, nil
+}
+
+// withSequence returns value with the sequence tag of seq inserted after
+// token.
+func withSequence(token, value string, seq int64) string {
+	return token + seqTagPrefix + strconv.FormatInt(seq, 10) + "|" + value[len(token):]
+}
+
+// splitSequence splits the sequence tag off the metadata of a lock value, if
+// there is one. It returns 0 otherwise.
+func splitSequence(metadata string) (int64, string) {
+	if !strings.HasPrefix(metadata, seqTagPrefix) {
+		return 0, metadata
+	}
+	end := strings.IndexByte(metadata, '|')
+	if end < 0 {
+		return 0, metadata
+	}
+
+	seq, err := strconv.ParseInt(metadata[len(seqTagPrefix):end], 10, 64)
+	if err != nil {
+		return 0, metadata
+	}
+	return seq, metadata[end+1:]
+}
+
+// Sequence returns the acquisition sequence number of the lock, see
+// Options.Sequence. Returns 0 if it was obtained without.
+func (l *Lock) Sequence() int64 {
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	seq, _ := splitSequence(l.value[len(l.token):])
+	return seq
+}
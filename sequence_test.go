@@ -0,0 +1,72 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Options.Sequence", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient, redislock.Defaults{Owner: &redislock.Owner{Host: "web-1", PID: 42}})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":seq", lockKey+":fence").Err()).To(Succeed())
+	})
+
+	It("should stamp acquisitions", func() {
+		opt := &redislock.Options{Sequence: true, Metadata: "meta"}
+		for i := int64(1); i <= 3; i++ {
+			lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, opt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Sequence()).To(Equal(i))
+			Expect(lock.Metadata()).To(Equal("meta"))
+
+			info, err := subject.Inspect(ctx, lockKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Sequence).To(Equal(i))
+			Expect(info.Metadata).To(Equal("meta"))
+			Expect(info.Owner).To(Equal(&redislock.Owner{Host: "web-1", PID: 42}))
+
+			Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(lock.Refresh(ctx, time.Minute, nil)).To(Succeed())
+			Expect(lock.Release(ctx)).To(Succeed())
+		}
+	})
+
+	It("should not count failed attempts", func() {
+		opt := &redislock.Options{Sequence: true}
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, opt)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(redisClient.Get(ctx, lockKey+":seq").Val()).To(Equal("1"))
+	})
+
+	It("should keep the sequence across metadata updates", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Sequence: true, Fencing: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+		Expect(lock.FencingToken()).To(Equal(int64(1)))
+
+		Expect(lock.SetMetadata(ctx, "progress")).To(Succeed())
+		Expect(lock.Sequence()).To(Equal(int64(1)))
+		Expect(lock.Metadata()).To(Equal("progress"))
+	})
+
+	It("should report zero without", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+		Expect(lock.Sequence()).To(BeZero())
+	})
+})
@@ -0,0 +1,220 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardPoints is the number of points of each shard on the hash ring.
+const shardPoints = 128
+
+// ErrShardUnavailable is returned by a ShardedClient for keys routed to a
+// shard which failed its last health check.
+var ErrShardUnavailable = errors.New("redislock: shard unavailable")
+
+// ShardedClient routes lock keys across independent redis instances via
+// consistent hashing, so lock traffic scales horizontally without a redis
+// cluster.
+//
+// Keys are routed by the names of the shards, not by their health, so all
+// processes configured with the same shard names agree on the instance
+// responsible for a key, even when it is unavailable or its address changes.
+// Keys routed to an unavailable shard fail instead of moving to another one,
+// which would break mutual exclusion. Adding or removing a shard moves only
+// the keys routed to it, but locks on moved keys are not exclusive until all
+// processes use the new shards and the locks obtained before have expired.
+// It is safe for concurrent use.
+type ShardedClient struct {
+	shards map[string]*shard
+	ring   []ringPoint
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// ShardedOptions configure a ShardedClient.
+type ShardedOptions struct {
+	// Defaults apply to the clients of all shards.
+	Defaults Defaults
+
+	// HealthCheckInterval is the time between health checks of each shard.
+	// Keys routed to a shard which failed its last health check fail with
+	// ErrShardUnavailable.
+	// Default: no health checks
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout limits the duration of each health check.
+	// Default: 1s
+	HealthCheckTimeout time.Duration
+}
+
+func (o *ShardedOptions) getHealthCheckTimeout() time.Duration {
+	if o.HealthCheckTimeout > 0 {
+		return o.HealthCheckTimeout
+	}
+	return time.Second
+}
+
+type shard struct {
+	name   string
+	client *Client
+	down   int32
+}
+
+type ringPoint struct {
+	hash  uint64
+	shard *shard
+}
+
+// NewSharded creates a new ShardedClient across the given, independent
+// clients, keyed by the names of the shards. Call Close to stop the health
+// checks.
+func NewSharded(clients map[string]RedisClient, opt *ShardedOptions) *ShardedClient {
+	if opt == nil {
+		opt = new(ShardedOptions)
+	}
+
+	s := &ShardedClient{shards: make(map[string]*shard, len(clients))}
+	for name, client := range clients {
+		sh := &shard{name: name, client: New(client, opt.Defaults)}
+		s.shards[name] = sh
+		for i := 0; i < shardPoints; i++ {
+			s.ring = append(s.ring, ringPoint{hash: hashKey(name + "#" + strconv.Itoa(i)), shard: sh})
+		}
+	}
+	sort.Slice(s.ring, func(i, j int) bool {
+		if s.ring[i].hash != s.ring[j].hash {
+			return s.ring[i].hash < s.ring[j].hash
+		}
+		return s.ring[i].shard.name < s.ring[j].shard.name
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	if opt.HealthCheckInterval > 0 {
+		for _, sh := range s.shards {
+			s.wg.Add(1)
+			go s.check(ctx, sh, opt)
+		}
+	}
+	return s
+}
+
+// Shard returns the name of the shard responsible for key.
+func (s *ShardedClient) Shard(key string) string {
+	if sh := s.lookup(key); sh != nil {
+		return sh.name
+	}
+	return ""
+}
+
+// Client returns the client of the shard responsible for key.
+// May return ErrShardUnavailable if the shard failed its last health check.
+func (s *ShardedClient) Client(key string) (*Client, error) {
+	sh := s.lookup(key)
+	if sh == nil || atomic.LoadInt32(&sh.down) != 0 {
+		return nil, ErrShardUnavailable
+	}
+	return sh.client, nil
+}
+
+// Healthy reports whether the named shard passed its last health check.
+func (s *ShardedClient) Healthy(name string) bool {
+	sh, ok := s.shards[name]
+	return ok && atomic.LoadInt32(&sh.down) == 0
+}
+
+// Obtain tries to obtain a new lock using a key with the given TTL on the
+// shard responsible for key, see Client.Obtain.
+// May return ErrNotObtained if not successful, or ErrShardUnavailable.
+func (s *ShardedClient) Obtain(ctx context.Context, key string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, error) {
+	client, err := s.Client(key)
+	if err != nil {
+		return nil, &Error{Op: "obtain", Key: key, Err: err}
+	}
+	return client.Obtain(ctx, key, waitTimeout, lockTTL, opt)
+}
+
+// Close stops the health checks and closes the clients of all shards, see
+// Client.Close.
+func (s *ShardedClient) Close() error {
+	s.cancel()
+	s.wg.Wait()
+
+	var err error
+	for _, sh := range s.shards {
+		if e := sh.client.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *ShardedClient) lookup(key string) *shard {
+	if len(s.ring) == 0 {
+		return nil
+	}
+
+	h := hashKey(key)
+	i := sort.Search(len(s.ring), func(i int) bool { return s.ring[i].hash >= h })
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.ring[i].shard
+}
+
+// check runs the health checks of sh until ctx is cancelled.
+func (s *ShardedClient) check(ctx context.Context, sh *shard, opt *ShardedOptions) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(opt.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkctx, cancel := context.WithTimeout(ctx, opt.getHealthCheckTimeout())
+		err := sh.client.Ping(checkctx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		var down int32
+		if err != nil {
+			down = 1
+		}
+		if atomic.SwapInt32(&sh.down, down) != down {
+			if logger := opt.Defaults.Logger; logger != nil && err != nil {
+				logger.Log(LevelWarn, "shard unavailable", "shard", sh.name, "error", err)
+			} else if logger != nil {
+				logger.Log(LevelInfo, "shard available", "shard", sh.name)
+			}
+		}
+	}
+}
+
+// hashKey hashes key via FNV-1a, mixed by the murmur3 finalizer, which spreads
+// similar keys such as the points of a shard evenly across the ring.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,98 @@
+package redislock_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ShardedClient", func() {
+	var subject *redislock.ShardedClient
+	var nodes map[string]*redis.Client
+	var ctx = context.Background()
+
+	clients := func(names ...string) map[string]redislock.RedisClient {
+		clients := make(map[string]redislock.RedisClient)
+		for _, name := range names {
+			clients[name] = nodes[name]
+		}
+		return clients
+	}
+
+	BeforeEach(func() {
+		nodes = make(map[string]*redis.Client)
+		for i, name := range []string{"a", "b", "c"} {
+			nodes[name] = redis.NewClient(&redis.Options{Network: "tcp", Addr: redisClient.Options().Addr, DB: 12 + i})
+		}
+		subject = redislock.NewSharded(clients("a", "b", "c"), nil)
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+		for _, node := range nodes {
+			Expect(node.Del(ctx, lockKey).Err()).To(Succeed())
+			Expect(node.Close()).To(Succeed())
+		}
+	})
+
+	It("should route keys consistently", func() {
+		other := redislock.NewSharded(clients("c", "a", "b"), nil)
+		shrunk := redislock.NewSharded(clients("a", "b"), nil)
+
+		counts := make(map[string]int)
+		for i := 0; i < 300; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			shard := subject.Shard(key)
+			counts[shard]++
+
+			Expect(other.Shard(key)).To(Equal(shard))
+			if shard != "c" {
+				Expect(shrunk.Shard(key)).To(Equal(shard))
+			}
+		}
+		Expect(counts).To(HaveLen(3))
+		for _, n := range counts {
+			Expect(n).To(BeNumerically(">", 50))
+		}
+	})
+
+	It("should obtain on the responsible shard", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		for name, node := range nodes {
+			held := name == subject.Shard(lockKey)
+			Expect(node.Exists(ctx, lockKey).Val()).To(Equal(map[bool]int64{true: 1}[held]))
+		}
+
+		_, err = subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should fail fast on unavailable shards", func() {
+		name := redislock.NewSharded(clients("a", "b", "c"), nil).Shard(lockKey)
+		shards := clients("a", "b", "c")
+		shards[name] = &flakyClient{RedisClient: nodes[name], failures: 1 << 30}
+
+		Expect(subject.Close()).To(Succeed())
+		subject = redislock.NewSharded(shards, &redislock.ShardedOptions{HealthCheckInterval: 10 * time.Millisecond})
+		Eventually(func() bool { return subject.Healthy(name) }).Should(BeFalse())
+
+		_, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrShardUnavailable))
+
+		atomic.StoreInt32(&shards[name].(*flakyClient).failures, 0)
+		Eventually(func() bool { return subject.Healthy(name) }).Should(BeTrue())
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+})
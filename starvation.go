@@ -0,0 +1,140 @@
+package redislock
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Starvation detects keys which callers keep failing to obtain, see
+// Defaults.Starvation. A key is starved once Failures consecutive calls
+// failed to obtain it, or a single call waited for it for at least Wait,
+// whether it obtained it or not. OnStarvation is then called once per
+// streak, which ends as soon as a call obtains the key. Calls which fail with
+// errors other than ErrNotObtained, e.g. network errors, neither count as
+// failures nor end the streak. Only calls made within the process are
+// tracked. A Starvation may be shared by multiple clients and must not be
+// copied after first use.
+type Starvation struct {
+	// Failures is the number of consecutive failed calls after which a key
+	// is starved.
+	// Default: 5
+	Failures int
+
+	// Wait is the time a single call may wait for a key before it is
+	// starved.
+	// Default: disabled
+	Wait time.Duration
+
+	// OnStarvation is called when a key becomes starved. It must not block.
+	OnStarvation func(key string, stats StarvationStats)
+
+	mu    sync.Mutex
+	keys  map[string]*StarvationStats
+	alert map[string]bool
+}
+
+// StarvationStats describes the contention of a starved key.
+type StarvationStats struct {
+	// Failures is the number of consecutive calls which failed to obtain
+	// the key.
+	Failures int
+	// Wait is the total time the failed calls waited, plus the wait of the
+	// call which obtained the key, if any.
+	Wait time.Duration
+	// MaxWait is the longest wait of a single call.
+	MaxWait time.Duration
+	// Since is the time the first of the failed calls finished.
+	Since time.Time
+}
+
+// StarvationMetricsCollector is an optional extension of MetricsCollector,
+// which is notified of starved keys, see Defaults.Starvation.
+type StarvationMetricsCollector interface {
+	// Starved is called when a key becomes starved.
+	Starved(key string, stats StarvationStats)
+}
+
+func (s *Starvation) getFailures() int {
+	if s.Failures > 0 {
+		return s.Failures
+	}
+	return 5
+}
+
+// Starving returns the stats of the keys which are starved at the moment.
+func (s *Starvation) Starving() map[string]StarvationStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	starving := make(map[string]StarvationStats, len(s.alert))
+	for key := range s.alert {
+		starving[key] = *s.keys[key]
+	}
+	return starving
+}
+
+// done records the outcome of a call to obtain key, which finished at now
+// after waiting for wait. It reports the stats of the key if it just became
+// starved.
+func (s *Starvation) done(key string, now time.Time, wait time.Duration, err error) (StarvationStats, bool) {
+	failed := errors.Is(err, ErrNotObtained)
+	if err != nil && !failed {
+		return StarvationStats{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.keys[key]
+	if stats == nil {
+		if !failed && (s.Wait <= 0 || wait < s.Wait) {
+			return StarvationStats{}, false
+		}
+		if s.keys == nil {
+			s.keys, s.alert = make(map[string]*StarvationStats), make(map[string]bool)
+		}
+		stats = &StarvationStats{Since: now}
+		s.keys[key] = stats
+	}
+
+	if failed {
+		stats.Failures++
+	}
+	stats.Wait += wait
+	if wait > stats.MaxWait {
+		stats.MaxWait = wait
+	}
+
+	starved := stats.Failures >= s.getFailures() || s.Wait > 0 && wait >= s.Wait
+	report := starved && !s.alert[key]
+	if report {
+		s.alert[key] = true
+	}
+	res := *stats
+	if !failed {
+		delete(s.keys, key)
+		delete(s.alert, key)
+	}
+	return res, report
+}
+
+// starvationDone records the outcome of a call to obtain key and reports the
+// key if it just became starved.
+func (c *Client) starvationDone(key string, now time.Time, wait time.Duration, err error) {
+	s := c.defaults.Starvation
+	stats, starved := s.done(key, now, wait, err)
+	if !starved {
+		return
+	}
+
+	if s.OnStarvation != nil {
+		s.OnStarvation(key, stats)
+	}
+	if metrics, ok := c.defaults.Metrics.(StarvationMetricsCollector); ok {
+		metrics.Starved(key, stats)
+	}
+	if logger := c.defaults.Logger; logger != nil {
+		logger.Log(LevelWarn, "lock starved", "key", key, "failures", stats.Failures, "wait", stats.Wait)
+	}
+}
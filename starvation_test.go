@@ -0,0 +1,126 @@
+package redislock_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Starvation", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should report keys after consecutive failures", func() {
+		var mu sync.Mutex
+		var reports []redislock.StarvationStats
+		starvation := &redislock.Starvation{Failures: 3, OnStarvation: func(key string, stats redislock.StarvationStats) {
+			Expect(key).To(Equal(lockKey))
+			mu.Lock()
+			reports = append(reports, stats)
+			mu.Unlock()
+		}}
+		subject := redislock.New(redisClient, redislock.Defaults{Starvation: starvation})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < 4; i++ {
+			_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+			Expect(err).To(MatchError(redislock.ErrNotObtained))
+		}
+		Expect(reports).To(HaveLen(1))
+		Expect(reports[0].Failures).To(Equal(3))
+		Expect(reports[0].Since).NotTo(BeZero())
+		Expect(starvation.Starving()).To(HaveKey(lockKey))
+		Expect(starvation.Starving()[lockKey].Failures).To(Equal(4))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		lock, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(starvation.Starving()).To(BeEmpty())
+
+		for i := 0; i < 3; i++ {
+			_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+			Expect(err).To(MatchError(redislock.ErrNotObtained))
+		}
+		Expect(reports).To(HaveLen(2))
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should apply to semaphores", func() {
+		var keys []string
+		starvation := &redislock.Starvation{Failures: 2, OnStarvation: func(key string, _ redislock.StarvationStats) {
+			keys = append(keys, key)
+		}}
+		subject := redislock.NewSemaphore(redisClient, lockKey, 1, redislock.Defaults{Starvation: starvation})
+
+		lock, err := subject.Acquire(ctx, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < 2; i++ {
+			_, err = subject.Acquire(ctx, time.Hour, time.Minute, nil)
+			Expect(err).To(MatchError(redislock.ErrNotObtained))
+		}
+		Expect(keys).To(Equal([]string{lockKey}))
+		Expect(starvation.Starving()).To(HaveKey(lockKey))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		lock, err = subject.Acquire(ctx, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(starvation.Starving()).To(BeEmpty())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should report long waits", func() {
+		metrics := new(starvationMetrics)
+		subject := redislock.New(redisClient, redislock.Defaults{
+			Metrics:    metrics,
+			Starvation: &redislock.Starvation{Wait: 20 * time.Millisecond},
+		})
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Minute, 30*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		lock2, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.Release(ctx)).To(Succeed())
+
+		Expect(metrics.starved).To(HaveLen(1))
+		Expect(metrics.starved[0].Failures).To(BeZero())
+		Expect(metrics.starved[0].MaxWait).To(BeNumerically(">=", 20*time.Millisecond))
+	})
+
+	It("should ignore errors", func() {
+		starvation := &redislock.Starvation{Failures: 1}
+		subject := redislock.New(&flakyClient{RedisClient: redisClient, failures: 100}, redislock.Defaults{Starvation: starvation})
+
+		_, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(errLoading))
+		Expect(starvation.Starving()).To(BeEmpty())
+	})
+})
+
+type starvationMetrics struct {
+	mu      sync.Mutex
+	starved []redislock.StarvationStats
+}
+
+func (*starvationMetrics) ObtainAttempt(string, bool)              {}
+func (*starvationMetrics) ObtainDone(string, time.Duration, error) {}
+func (*starvationMetrics) RefreshDone(string, error)               {}
+func (*starvationMetrics) Released(string, time.Duration)          {}
+
+func (m *starvationMetrics) Starved(_ string, stats redislock.StarvationStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.starved = append(m.starved, stats)
+}
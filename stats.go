@@ -0,0 +1,66 @@
+package redislock
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	luaObtainStats        = redis.NewScript(`if redis.call("set", KEYS[1], ARGV[1], "nx", "px", ARGV[2]) then redis.call("hincrby", KEYS[2], "acquired", 1) return 1 end redis.call("hincrby", KEYS[2], "failed", 1) return 0`)
+	luaReleaseStats       = withReleaseStats(luaReleaseSrc)
+	luaReleaseSignalStats = withReleaseStats(luaReleaseSignalSrc)
+	luaReleaseNotifyStats = withReleaseStats(luaReleaseNotifySrc)
+	luaStats              = redis.NewScript(`return redis.call("hmget", KEYS[1], "acquired", "failed", "held_ms")`)
+)
+
+// withReleaseStats wraps the source of a release script, so that it also adds
+// the hold time in milliseconds, passed as the last ARGV, to the statistics
+// key, passed as the last KEYS, on success.
+func withReleaseStats(src string) *redis.Script {
+	return redis.NewScript(`local function release() ` + src + ` end local res = release() if res == 1 then redis.call("hincrby", KEYS[#KEYS], "held_ms", ARGV[#ARGV]) end return res`)
+}
+
+// KeyStats are the statistics recorded for a key, see Defaults.RecordStats.
+type KeyStats struct {
+	// Acquired is the number of times the lock was obtained.
+	Acquired int64
+	// Failed is the number of failed attempts to obtain the lock.
+	Failed int64
+	// HoldTime is the total time the lock was held, counted on release.
+	HoldTime time.Duration
+}
+
+// Stats returns the statistics recorded for key, see Defaults.RecordStats.
+func (c *Client) Stats(ctx context.Context, key string) (*KeyStats, error) {
+	res, err := luaStats.Run(ctx, c.client, []string{c.statsKey(c.defaults.KeyPrefix + key)}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var nums [3]int64
+	values, _ := res.([]interface{})
+	for i := range nums {
+		if i < len(values) {
+			if s, ok := values[i].(string); ok {
+				nums[i], _ = strconv.ParseInt(s, 10, 64)
+			}
+		}
+	}
+	return &KeyStats{Acquired: nums[0], Failed: nums[1], HoldTime: time.Duration(nums[2]) * time.Millisecond}, nil
+}
+
+// obtainStats is like SETNX, but records the attempt in the statistics of key.
+func (c *Client) obtainStats(ctx context.Context, rdb RedisClient, key, value string, ttl time.Duration) (bool, error) {
+	status, err := luaObtainStats.Run(ctx, rdb, []string{key, c.statsKey(key)}, value, msArg(ttl)).Result()
+	if err != nil {
+		return false, err
+	}
+	return status == int64(1), nil
+}
+
+func (c *Client) statsKey(key string) string {
+	return c.companionKey(key, ":stats")
+}
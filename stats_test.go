@@ -0,0 +1,52 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stats", func() {
+	var ctx = context.Background()
+	var statsKey = lockKey + ":stats"
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":signal", statsKey).Err()).To(Succeed())
+	})
+
+	It("should record contention statistics", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{RecordStats: true})
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		time.Sleep(20 * time.Millisecond)
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		lock, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{ReleaseSignal: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		stats, err := subject.Stats(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.Acquired).To(Equal(int64(2)))
+		Expect(stats.Failed).To(Equal(int64(1)))
+		Expect(stats.HoldTime).To(BeNumerically(">=", 20*time.Millisecond))
+		Expect(stats.HoldTime).To(BeNumerically("<", time.Second))
+	})
+
+	It("should not record by default", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		Expect(redisClient.Exists(ctx, statsKey).Val()).To(Equal(int64(0)))
+		Expect(redislock.New(redisClient).Stats(ctx, lockKey)).To(Equal(&redislock.KeyStats{}))
+	})
+})
@@ -0,0 +1,50 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+)
+
+// Tracer traces lock operations, see Defaults.Tracer. The redislockotel
+// package implements it with OpenTelemetry. It must be safe for concurrent
+// use.
+type Tracer interface {
+	// StartSpan starts a span for the operation name on key, one of
+	// "redislock.obtain", "redislock.refresh", "redislock.extend" and
+	// "redislock.release", and returns a context carrying it. It may return
+	// a nil Span to skip tracing the operation.
+	StartSpan(ctx context.Context, name, key string) (context.Context, Span)
+}
+
+// Span traces a single lock operation, see Tracer.
+type Span interface {
+	// SetAttempts records the number of attempts made to obtain a lock.
+	SetAttempts(n int)
+
+	// End ends the span with the result of the operation: "ok", "error" or,
+	// for errors matching ErrNotObtained or ErrLockNotHeld, "not_obtained"
+	// for obtains and "lost" otherwise. err is the error of the operation,
+	// if any.
+	End(result string, err error)
+}
+
+// startSpan starts a span for an operation on key, if a Tracer is set.
+func (c *Client) startSpan(ctx context.Context, name, key string) (context.Context, Span) {
+	if c.defaults.Tracer == nil {
+		return ctx, nil
+	}
+	return c.defaults.Tracer.StartSpan(ctx, name, key)
+}
+
+// endSpan records the result of an operation and ends span. lost is the
+// result recorded for errors matching ErrNotObtained or ErrLockNotHeld.
+func endSpan(span Span, err error, lost string) {
+	switch {
+	case err == nil:
+		span.End("ok", nil)
+	case errors.Is(err, ErrNotObtained), errors.Is(err, ErrLockNotHeld):
+		span.End(lost, err)
+	default:
+		span.End("error", err)
+	}
+}
@@ -0,0 +1,78 @@
+package redislock_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+	var subject *redislock.Client
+	var tracer *recordingTracer
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		tracer = new(recordingTracer)
+		subject = redislock.New(redisClient, redislock.Defaults{Tracer: tracer})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should trace obtain, refresh and release", func() {
+		held, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond), 2),
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(held.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(held.Release(ctx)).To(Succeed())
+		Expect(held.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		Expect(tracer.spans()).To(Equal([]string{
+			"redislock.obtain " + lockKey + " 1 ok",
+			"redislock.obtain " + lockKey + " 3 not_obtained",
+			"redislock.refresh " + lockKey + " 0 ok",
+			"redislock.release " + lockKey + " 0 ok",
+			"redislock.release " + lockKey + " 0 lost",
+		}))
+	})
+})
+
+// recordingTracer records the name, key, attempts and result of each span.
+type recordingTracer struct {
+	mu    sync.Mutex
+	ended []string
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name, key string) (context.Context, redislock.Span) {
+	return ctx, &recordingSpan{tracer: t, name: name + " " + key}
+}
+
+func (t *recordingTracer) spans() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.ended...)
+}
+
+type recordingSpan struct {
+	tracer   *recordingTracer
+	name     string
+	attempts int
+}
+
+func (s *recordingSpan) SetAttempts(n int) { s.attempts = n }
+
+func (s *recordingSpan) End(result string, _ error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.ended = append(s.tracer.ended, fmt.Sprintf("%s %d %s", s.name, s.attempts, result))
+}
@@ -0,0 +1,59 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	errTransferUnsupported = errors.New("redislock: TransferTo is only supported by exclusive locks")
+	errInvalidToken        = errors.New("redislock: invalid token")
+)
+
+// TransferTo atomically hands the lock over to a successor by replacing its
+// token with token, keeping its TTL, owner tag and metadata, so that no other
+// process can obtain the lock in between. The successor takes over via
+// Client.Resume with the key, token and metadata of the lock, including its
+// owner tag if Defaults.Owner is set. Afterwards, the lock is no longer held
+// by l: its watchdog is stopped and Err reports ErrLockNotHeld. Only
+// exclusive locks, as returned by Obtain, ObtainFair or Resume, can be
+// transferred.
+// May return ErrLockExpired or ErrLockStolen, both of which match
+// ErrLockNotHeld, if the lock is no longer held.
+func (l *Lock) TransferTo(ctx context.Context, token string) (err error) {
+	defer wrapErr("transfer", l.key, &err)
+	if ctx == nil {
+		return ErrNilContext
+	} else if token == "" {
+		return errInvalidToken
+	} else if l.scripts != exclusiveScripts && l.scripts != signalScripts && l.scripts != notifyScripts {
+		return errTransferUnsupported
+	}
+
+	l.StopAutoRefresh()
+	l.setReleasing(true)
+	defer l.setReleasing(false)
+
+	l.argMu.RLock()
+	defer l.argMu.RUnlock()
+
+	opctx, cancel := withOperationTimeout(ctx, l.opTimeout)
+	defer cancel()
+
+	value := token + l.value[len(l.token):]
+	status, err := luaSetMetadata.Run(opctx, l.rdb, l.scriptKeys[:1], l.value, value).Result()
+	if err != nil {
+		return wrapOperationErr(ctx, opctx, err)
+	} else if status != int64(1) {
+		return l.lostBy(status, l.logger, ErrLockNotHeld)
+	}
+
+	if l.logger != nil {
+		l.logger.Log(LevelDebug, "lock transferred", "key", l.key, "token", shortToken(l.token), "successor", shortToken(token))
+	}
+	l.audit(ctx, AuditTransfer, 0)
+	l.forgetIntent()
+	l.releaseLocal()
+	l.lost(ErrLockNotHeld)
+	return nil
+}
@@ -0,0 +1,57 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock.TransferTo", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should hand the lock over to a successor", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "job-1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(lock.TransferTo(ctx, "successor")).To(Succeed())
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal("successorjob-1"))
+		Expect(redisClient.PTTL(ctx, lockKey).Val()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock.Done()).To(BeClosed())
+		Expect(lock.Err()).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockStolen))
+
+		successor := subject.Resume(lockKey, "successor", "job-1")
+		Expect(successor.Refresh(ctx, time.Minute, nil)).To(Succeed())
+		Expect(successor.Release(ctx)).To(Succeed())
+	})
+
+	It("should fail if the lock is no longer held", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		Expect(lock.TransferTo(ctx, "successor")).To(MatchError(redislock.ErrLockStolen))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal("ABCD"))
+	})
+
+	It("should reject empty tokens", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		Expect(lock.TransferTo(ctx, "")).To(HaveOccurred())
+		Expect(lock.IsHeld(ctx)).To(BeTrue())
+	})
+})
@@ -0,0 +1,148 @@
+package redislock_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UniversalClient", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+":signal", lockKey+":fence", "{"+lockKey+"}:signal", "{"+lockKey+"}:fence").Err()).To(Succeed())
+	})
+
+	exercise := func(client redis.UniversalClient) {
+		lock, err := redislock.Obtain(ctx, client, lockKey, time.Hour, time.Minute, &redislock.Options{Fencing: true, ReleaseSignal: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.FencingToken()).To(BeNumerically(">", 0))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal(lock.Token()))
+
+		_, err = redislock.Obtain(ctx, client, lockKey, time.Hour, time.Minute, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(10*time.Millisecond), 2),
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(lock.Refresh(ctx, time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL(ctx)).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	}
+
+	It("should support Sentinel-managed failover clients", func() {
+		sentinel, err := startFakeSentinel("mymaster", redisClient.Options().Addr)
+		Expect(err).NotTo(HaveOccurred())
+		defer sentinel.Close()
+
+		client := redis.NewUniversalClient(&redis.UniversalOptions{
+			MasterName: "mymaster",
+			Addrs:      []string{sentinel.Addr().String()},
+			DB:         redisClient.Options().DB,
+		})
+		defer client.Close()
+
+		exercise(client)
+	})
+
+	It("should support ring clients", func() {
+		client := redis.NewRing(&redis.RingOptions{
+			Addrs: map[string]string{"shard": redisClient.Options().Addr},
+			DB:    redisClient.Options().DB,
+		})
+		defer client.Close()
+
+		exercise(client)
+	})
+})
+
+// startFakeSentinel starts a minimal Sentinel, which reports addr as the
+// master of name.
+func startFakeSentinel(name, addr string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeSentinel(conn, name, host, port)
+		}
+	}()
+	return ln, nil
+}
+
+func serveFakeSentinel(conn net.Conn, name, host, port string) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch cmd := strings.ToLower(strings.Join(args, " ")); {
+		case cmd == "ping":
+			reply = "+PONG\r\n"
+		case cmd == "sentinel get-master-addr-by-name "+strings.ToLower(name):
+			reply = fmt.Sprintf("*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(host), host, len(port), port)
+		case strings.HasPrefix(cmd, "sentinel sentinels "):
+			reply = "*0\r\n"
+		case strings.HasPrefix(cmd, "subscribe "):
+			for i, ch := range args[1:] {
+				reply += fmt.Sprintf("*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:%d\r\n", len(ch), ch, i+1)
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as a RESP array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
@@ -0,0 +1,62 @@
+package redislock
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	luaUnlinkRelease            = redis.NewScript(unlinkSrc(luaReleaseSrc))
+	luaUnlinkReleaseSignal      = redis.NewScript(unlinkSrc(luaReleaseSignalSrc))
+	luaUnlinkReleaseNotify      = redis.NewScript(unlinkSrc(luaReleaseNotifySrc))
+	luaUnlinkReleaseStats       = withReleaseStats(unlinkSrc(luaReleaseSrc))
+	luaUnlinkReleaseSignalStats = withReleaseStats(unlinkSrc(luaReleaseSignalSrc))
+	luaUnlinkReleaseNotifyStats = withReleaseStats(unlinkSrc(luaReleaseNotifySrc))
+	luaUnlinkMultiRelease       = redis.NewScript(unlinkSrc(luaMultiReleaseSrc))
+	luaUnlinkForceRelease       = redis.NewScript(unlinkSrc(luaForceReleaseSrc))
+
+	// luaPurge deletes the companion keys, passed as KEYS[2:], via the
+	// command ARGV[1] unless the lock, KEYS[1], is held.
+	luaPurge = redis.NewScript(`if redis.call("exists", KEYS[1]) == 1 then return -1 end
+local n = 0
+for i = 2, #KEYS do n = n + redis.call(ARGV[1], KEYS[i]) end
+return n`)
+)
+
+// unlinkSrc rewrites the source of a script to delete keys via UNLINK, see
+// Defaults.Unlink.
+func unlinkSrc(src string) string {
+	return strings.Replace(src, `redis.call("del",`, `redis.call("unlink",`, -1)
+}
+
+// Purge deletes the companion keys of the exclusive lock on key, i.e. its
+// fencing counter, sequence counter, statistics, release signal list, child
+// locks and advisory conflicts, via UNLINK if Defaults.Unlink is set, and
+// returns the number of keys deleted.
+// It is meant for keys which are no longer used, as fencing tokens restart
+// from one afterwards.
+// May return ErrNotObtained if the lock is held, in which case nothing is
+// deleted.
+func (c *Client) Purge(ctx context.Context, key string) (int64, error) {
+	key = c.defaults.KeyPrefix + key
+	cmd := "del"
+	if c.defaults.Unlink {
+		cmd = "unlink"
+	}
+
+	keys := []string{key, c.fenceKey(key), c.sequenceKey(key), c.statsKey(key), c.signalKey(key), c.childrenKey(key), c.conflictsKey(key)}
+	rdb, err := c.route(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := luaPurge.Run(ctx, rdb, keys, cmd).Int64()
+	if err != nil {
+		return 0, err
+	} else if n < 0 {
+		return 0, ErrNotObtained
+	}
+	return n, nil
+}
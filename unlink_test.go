@@ -0,0 +1,59 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults.Unlink", func() {
+	var ctx = context.Background()
+	var subject *redislock.Client
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient, redislock.Defaults{Unlink: true, RecordStats: true})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey, lockKey+"2", lockKey+":fence", lockKey+":stats", lockKey+":signal").Err()).To(Succeed())
+	})
+
+	It("should release locks", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{ReleaseSignal: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+		Expect(lock.Release(ctx)).To(MatchError(redislock.ErrLockNotHeld))
+
+		multi, err := subject.ObtainMulti(ctx, []string{lockKey, lockKey + "2"}, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(multi.Release(ctx)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey, lockKey+"2").Val()).To(Equal(int64(0)))
+
+		_, err = subject.Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.ForceRelease(ctx, lockKey)).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+
+		stats, err := subject.Stats(ctx, lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.Acquired).To(Equal(int64(2)))
+	})
+
+	It("should purge companion keys", func() {
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Fencing: true, ReleaseSignal: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = subject.Purge(ctx, lockKey)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(redisClient.Exists(ctx, lockKey+":fence").Val()).To(Equal(int64(1)))
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Expect(subject.Purge(ctx, lockKey)).To(Equal(int64(3)))
+		Expect(redisClient.Exists(ctx, lockKey+":fence", lockKey+":stats", lockKey+":signal").Val()).To(Equal(int64(0)))
+		Expect(subject.Purge(ctx, lockKey)).To(Equal(int64(0)))
+	})
+})
@@ -0,0 +1,66 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// LockView is a read-only view of the holder of a lock, see ObtainOrInspect.
+// It does not include the full token of the holder, so it cannot be used to
+// release or refresh the lock on its behalf.
+type LockView struct {
+	// Key is the key of the lock, without the client's key prefix.
+	Key string
+	// TokenPrefix is the first characters of the token of the holder, as
+	// logged by the library.
+	TokenPrefix string
+	// Metadata is the metadata of the holder.
+	Metadata string
+	// Owner identifies the holder, if it set Defaults.Owner.
+	Owner *Owner
+	// TTL is the remaining TTL of the holder, or zero if the lock does not
+	// expire.
+	TTL time.Duration
+}
+
+// HeldBy reports whether the holder is lock, as far as the token prefix
+// tells, e.g. to tell a lock held by the process itself from one held by
+// another replica.
+func (v *LockView) HeldBy(lock *Lock) bool {
+	return lock != nil && v.TokenPrefix != "" && strings.HasPrefix(lock.token, v.TokenPrefix)
+}
+
+// ObtainOrInspect is like Obtain, but returns a view of the current holder
+// along with ErrNotObtained if the lock is not obtained. The holder is
+// learned in the same round trip as the last attempt, see
+// Options.HolderDetails. The view is nil if the holder is unknown, e.g. the
+// lock was released just after the attempt, or with CompatRedisson.
+func (c *Client) ObtainOrInspect(ctx context.Context, key string, waitTimeout, lockTTL time.Duration, opt *Options) (*Lock, *LockView, error) {
+	var o Options
+	if opt != nil {
+		o = *opt
+	}
+	o.HolderDetails = true
+
+	lock, err := c.Obtain(ctx, key, waitTimeout, lockTTL, &o)
+	if err == nil {
+		return lock, nil, nil
+	}
+
+	var holder *NotObtainedError
+	if !errors.As(err, &holder) || holder.value == "" {
+		return nil, nil, err
+	}
+
+	token, metadata := splitValue(holder.value)
+	owner, _ := splitOwner(strings.TrimPrefix(holder.value, token))
+	return nil, &LockView{
+		Key:         strings.TrimPrefix(holder.Key, c.defaults.KeyPrefix),
+		TokenPrefix: shortToken(token),
+		Metadata:    c.decryptMetadata(metadata),
+		Owner:       owner,
+		TTL:         holder.TTL,
+	}, err
+}
@@ -0,0 +1,49 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.ObtainOrInspect", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should obtain free locks", func() {
+		subject := redislock.New(redisClient)
+		lock, view, err := subject.ObtainOrInspect(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(view).To(BeNil())
+		Expect(lock.Release(ctx)).To(Succeed())
+	})
+
+	It("should return a view of the holder", func() {
+		owner := &redislock.Owner{Host: "web-1", PID: 42}
+		holder, err := redislock.New(redisClient, redislock.Defaults{Owner: owner}).Obtain(ctx, lockKey, time.Hour, time.Minute, &redislock.Options{Metadata: "replica-1"})
+		Expect(err).NotTo(HaveOccurred())
+		defer holder.Release(ctx)
+
+		subject := redislock.New(redisClient)
+		lock, view, err := subject.ObtainOrInspect(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock).To(BeNil())
+		Expect(view.Key).To(Equal(lockKey))
+		Expect(view.TokenPrefix).To(Equal(holder.Token()[:6]))
+		Expect(view.Metadata).To(Equal("replica-1"))
+		Expect(view.Owner).To(Equal(owner))
+		Expect(view.TTL).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(view.HeldBy(holder)).To(BeTrue())
+
+		other, err := subject.Obtain(ctx, lockKey+".other", time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer other.Release(ctx)
+		Expect(view.HeldBy(other)).To(BeFalse())
+	})
+})
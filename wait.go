@@ -0,0 +1,84 @@
+package redislock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// waitPollInterval is the maximum time between checks of WaitForRelease,
+// unless it is woken by release notifications.
+const waitPollInterval = 100 * time.Millisecond
+
+var luaKeyPTTL = redis.NewScript(`return redis.call("pttl", KEYS[1])`)
+
+// WaitForRelease blocks until the lock on key is free, without attempting to
+// obtain it. It polls the key, sleeping no longer than its remaining TTL, and
+// is woken early if the holder releases with Options.ReleaseNotify and the
+// client implements SubscribingClient. Returns the context error wrapped in
+// an Error, if ctx is done first.
+func (c *Client) WaitForRelease(ctx context.Context, key string) (err error) {
+	key = c.defaults.KeyPrefix + key
+	defer wrapErr("wait", key, &err)
+
+	rdb, err := c.route(key)
+	if err != nil {
+		return err
+	}
+
+	var released <-chan *redis.Message
+	interval := waitPollInterval
+	if subscriber, ok := rdb.(SubscribingClient); ok {
+		sub, err := c.subscribe(ctx, subscriber, rdb, key)
+		if err != nil {
+			return err
+		}
+		defer sub.Close()
+
+		released = sub.Channel()
+		interval = time.Second
+	}
+
+	clock := c.clock()
+	var timer Timer
+	for {
+		opctx, cancel := withOperationTimeout(ctx, c.defaults.OperationTimeout)
+		pttl, err := luaKeyPTTL.Run(opctx, rdb, []string{key}).Int64()
+		cancel()
+		if err != nil {
+			return wrapOperationErr(ctx, opctx, err)
+		} else if pttl == -2 {
+			return nil
+		}
+
+		d := interval
+		if ttl := time.Duration(pttl) * time.Millisecond; pttl >= 0 && ttl < d {
+			d = ttl + time.Millisecond
+		}
+
+		if timer == nil {
+			timer = clock.NewTimer(d)
+			defer timer.Stop()
+		} else {
+			timer.Reset(d)
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C():
+				break wait
+			case msg := <-released:
+				if isReleaseMessage(msg) {
+					if !timer.Stop() {
+						<-timer.C()
+					}
+					break wait
+				}
+			}
+		}
+	}
+}
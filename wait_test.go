@@ -0,0 +1,55 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.WaitForRelease", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should return once the lock is free", func() {
+		Expect(subject.WaitForRelease(ctx, lockKey)).To(Succeed())
+
+		lock, err := subject.Obtain(ctx, lockKey, time.Second, time.Minute, &redislock.Options{ReleaseNotify: true})
+		Expect(err).NotTo(HaveOccurred())
+
+		done := make(chan error, 1)
+		go func() { done <- subject.WaitForRelease(ctx, lockKey) }()
+		Consistently(done).ShouldNot(Receive())
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+	})
+
+	It("should poll without notifications", func() {
+		subject = redislock.New(&scriptCountingClient{RedisClient: redisClient})
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 50*time.Millisecond).Err()).To(Succeed())
+
+		start := time.Now()
+		Expect(subject.WaitForRelease(ctx, lockKey)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("~", 50*time.Millisecond, 30*time.Millisecond))
+	})
+
+	It("should stop once the context is done", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+
+		waitctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		Expect(subject.WaitForRelease(waitctx, lockKey)).To(MatchError(context.DeadlineExceeded))
+	})
+})
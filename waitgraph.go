@@ -0,0 +1,282 @@
+package redislock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// luaWaitGraph returns the value of the lock on KEYS[1], or false, followed by
+// the tokens of the waiters in its fair queue which have not timed out, in
+// queue order.
+var luaWaitGraph = redis.NewScript(luaNow + `
+local res = {false}
+if redis.call("type", KEYS[1]).ok == "string" then res[1] = redis.call("get", KEYS[1]) end
+for _, m in ipairs(redis.call("zrange", KEYS[2], 0, -1)) do
+	local t = redis.call("zscore", KEYS[3], m)
+	if t and tonumber(t) > now then res[#res + 1] = m end
+end
+return res`)
+
+// Kinds of WaitNode and WaitEdge.
+const (
+	WaitNodeLock  = "lock"
+	WaitNodeOwner = "owner"
+	WaitNodeToken = "token"
+
+	WaitEdgeWaits = "waits"
+	WaitEdgeHeld  = "held"
+)
+
+// WaitGraph is a snapshot of the wait-for graph of locks, see
+// Client.WaitGraph. Its nodes are locks and the parties holding or waiting
+// for them, its edges lead from waiters to locks and from locks to their
+// holders, so each cycle is a potential deadlock. Parties are identified by
+// their Owner, if known, or by their token otherwise. Encode it as JSON via
+// encoding/json or as Graphviz DOT via DOT.
+type WaitGraph struct {
+	Nodes []WaitNode `json:"nodes"`
+	Edges []WaitEdge `json:"edges"`
+}
+
+// WaitNode is a node of a WaitGraph.
+type WaitNode struct {
+	// ID identifies the node within the graph.
+	ID string `json:"id"`
+	// Kind is WaitNodeLock, WaitNodeOwner or WaitNodeToken.
+	Kind string `json:"kind"`
+	// Key is the key of a lock node, without the client's key prefix.
+	Key string `json:"key,omitempty"`
+	// Owner identifies the process of an owner node.
+	Owner *Owner `json:"owner,omitempty"`
+	// Token is the token of a token node.
+	Token string `json:"token,omitempty"`
+}
+
+// WaitEdge is an edge of a WaitGraph.
+type WaitEdge struct {
+	// From and To are the IDs of the nodes.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Kind is WaitEdgeWaits, from a waiter to a lock, or WaitEdgeHeld,
+	// from a lock to its holder.
+	Kind string `json:"kind"`
+	// Since is the time the process started to wait for or obtained the
+	// lock, if it is known locally.
+	Since *time.Time `json:"since,omitempty"`
+}
+
+// localWait is a call of the process waiting for a lock.
+type localWait struct {
+	client     *Client
+	key, token string
+	since      time.Time
+}
+
+// trackWait records that the process waits for key with token in the
+// client's manager or the process-wide registry, and returns a func which
+// ends the wait.
+func (c *Client) trackWait(key, token string) func() {
+	w := &localWait{client: c, key: key, token: token, since: time.Now()}
+	m := c.defaults.Manager
+	if m == nil {
+		m = registry
+	}
+
+	m.mu.Lock()
+	if m.waits == nil {
+		m.waits = make(map[*localWait]struct{})
+	}
+	m.waits[w] = struct{}{}
+	m.mu.Unlock()
+
+	return func() {
+		m.mu.Lock()
+		delete(m.waits, w)
+		m.mu.Unlock()
+	}
+}
+
+// WaitGraph exports the current wait-for graph of the locks on keys and of
+// the locks the client holds or waits for, e.g. for deadlock and bottleneck
+// analysis across services. Locks held or waited for by the process are
+// taken from Defaults.Manager or, without one, the process-wide registry,
+// which requires Defaults.Manager or Register to be set; they are attributed
+// to the owner of the process, see Defaults.Owner. The holders and the fair
+// queues of all those keys are read from redis, see ObtainFair, one round
+// trip per key. As the process is a single node, cycles within the process,
+// e.g. of independent goroutines, may be false positives.
+func (c *Client) WaitGraph(ctx context.Context, keys ...string) (*WaitGraph, error) {
+	m := c.defaults.Manager
+	if m == nil {
+		m = registry
+	}
+
+	self := c.processOwner()
+	selfID := ownerNodeID(self)
+	b := &waitGraphBuilder{nodes: make(map[string]WaitNode), edges: make(map[[2]string]WaitEdge)}
+	local := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		seen[key] = true
+	}
+
+	m.mu.Lock()
+	var held []*Lock
+	for lock := range m.locks {
+		if lock.client == c {
+			held = append(held, lock)
+		}
+	}
+	var waits []*localWait
+	for w := range m.waits {
+		if w.client == c {
+			waits = append(waits, w)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(held) != 0 || len(waits) != 0 {
+		b.node(WaitNode{ID: selfID, Kind: WaitNodeOwner, Owner: self})
+	}
+	for _, lock := range held {
+		key := strings.TrimPrefix(lock.key, c.defaults.KeyPrefix)
+		since := lock.obtained
+		b.edge(WaitEdge{From: b.lock(key), To: selfID, Kind: WaitEdgeHeld, Since: &since})
+		local[lock.token] = true
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, w := range waits {
+		key := strings.TrimPrefix(w.key, c.defaults.KeyPrefix)
+		since := w.since
+		b.edge(WaitEdge{From: selfID, To: b.lock(key), Kind: WaitEdgeWaits, Since: &since})
+		local[w.token] = true
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		prefixed := c.defaults.KeyPrefix + key
+		rdb, err := c.route(prefixed)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := luaWaitGraph.Run(ctx, rdb, []string{prefixed, c.queueKey(prefixed), c.queueTimeoutKey(prefixed)}).Result()
+		if err != nil {
+			return nil, err
+		}
+		vals, _ := res.([]interface{})
+		if len(vals) == 0 {
+			continue
+		}
+
+		lockID := b.lock(key)
+		if value, ok := vals[0].(string); ok {
+			token, _ := splitValue(value)
+			owner, _ := splitOwner(strings.TrimPrefix(value, token))
+			if !local[token] {
+				b.edge(WaitEdge{From: lockID, To: b.party(token, owner), Kind: WaitEdgeHeld})
+			}
+		}
+		for _, v := range vals[1:] {
+			if token, _ := v.(string); token != "" && !local[token] {
+				b.edge(WaitEdge{From: b.party(token, nil), To: lockID, Kind: WaitEdgeWaits})
+			}
+		}
+	}
+	return b.graph(), nil
+}
+
+// processOwner returns the owner of the process, as set by Defaults.Owner,
+// with its defaults resolved.
+func (c *Client) processOwner() *Owner {
+	owner, _ := splitOwner(encodeOwnerTag(c.defaults.Owner))
+	if owner == nil {
+		owner, _ = splitOwner(encodeOwnerTag(new(Owner)))
+	}
+	return owner
+}
+
+func ownerNodeID(o *Owner) string {
+	id := WaitNodeOwner + ":" + o.Host + "/" + strconv.Itoa(o.PID)
+	if o.Label != "" {
+		id += "/" + o.Label
+	}
+	return id
+}
+
+type waitGraphBuilder struct {
+	nodes map[string]WaitNode
+	edges map[[2]string]WaitEdge
+}
+
+func (b *waitGraphBuilder) node(n WaitNode) string {
+	if _, ok := b.nodes[n.ID]; !ok {
+		b.nodes[n.ID] = n
+	}
+	return n.ID
+}
+
+func (b *waitGraphBuilder) lock(key string) string {
+	return b.node(WaitNode{ID: WaitNodeLock + ":" + key, Kind: WaitNodeLock, Key: key})
+}
+
+func (b *waitGraphBuilder) party(token string, owner *Owner) string {
+	if owner != nil {
+		return b.node(WaitNode{ID: ownerNodeID(owner), Kind: WaitNodeOwner, Owner: owner})
+	}
+	return b.node(WaitNode{ID: WaitNodeToken + ":" + token, Kind: WaitNodeToken, Token: token})
+}
+
+func (b *waitGraphBuilder) edge(e WaitEdge) {
+	if _, ok := b.edges[[2]string{e.From, e.To}]; !ok {
+		b.edges[[2]string{e.From, e.To}] = e
+	}
+}
+
+func (b *waitGraphBuilder) graph() *WaitGraph {
+	g := &WaitGraph{Nodes: make([]WaitNode, 0, len(b.nodes)), Edges: make([]WaitEdge, 0, len(b.edges))}
+	for _, n := range b.nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	for _, e := range b.edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+// DOT renders the graph in the Graphviz DOT language, with locks as boxes.
+func (g *WaitGraph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph redislock {\n")
+	for _, n := range g.Nodes {
+		label, shape := strings.TrimPrefix(n.ID, n.Kind+":"), "ellipse"
+		if n.Kind == WaitNodeLock {
+			shape = "box"
+		}
+		fmt.Fprintf(&sb, "\t%s [label=%s, shape=%s];\n", strconv.Quote(n.ID), strconv.Quote(label), shape)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "\t%s -> %s [label=%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strconv.Quote(e.Kind))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,83 @@
+package redislock_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client.WaitGraph", func() {
+	var subject *redislock.Client
+	var ctx = context.Background()
+	var keyA, keyB = lockKey + "a", lockKey + "b"
+
+	BeforeEach(func() {
+		subject = redislock.New(redisClient, redislock.Defaults{
+			Manager: redislock.NewManager(),
+			Owner:   &redislock.Owner{Host: "web-1", PID: 1},
+		})
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, keyA, keyB, keyA+":queue", keyA+":queue-timeouts").Err()).To(Succeed())
+	})
+
+	It("should export holders and waiters", func() {
+		lockA, err := subject.Obtain(ctx, keyA, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lockA.Release(ctx)
+
+		other := redislock.New(redisClient, redislock.Defaults{Owner: &redislock.Owner{Host: "web-2", PID: 2}})
+		lockB, err := other.Obtain(ctx, keyB, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lockB.Release(ctx)
+
+		waiting, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go subject.Obtain(waiting, keyB, time.Hour, time.Minute, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+		})
+		go redislock.New(redisClient).ObtainFair(waiting, keyA, time.Hour, time.Minute, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+		})
+
+		var graph *redislock.WaitGraph
+		Eventually(func() int {
+			graph, err = subject.WaitGraph(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			return len(graph.Edges)
+		}).Should(Equal(4))
+
+		lockAID, lockBID := "lock:"+keyA, "lock:"+keyB
+		Expect(graph.Nodes).To(HaveLen(5))
+		Expect(graph.Edges[0].From).To(Equal(lockAID))
+		Expect(graph.Edges[0].To).To(Equal("owner:web-1/1"))
+		Expect(graph.Edges[0].Kind).To(Equal(redislock.WaitEdgeHeld))
+		Expect(graph.Edges[0].Since).NotTo(BeNil())
+		Expect(graph.Edges[1].From).To(Equal(lockBID))
+		Expect(graph.Edges[1].To).To(Equal("owner:web-2/2"))
+		Expect(graph.Edges[2].From).To(Equal("owner:web-1/1"))
+		Expect(graph.Edges[2].To).To(Equal(lockBID))
+		Expect(graph.Edges[2].Kind).To(Equal(redislock.WaitEdgeWaits))
+		Expect(graph.Edges[3].From).To(HavePrefix("token:"))
+		Expect(graph.Edges[3].To).To(Equal(lockAID))
+
+		Expect(graph.DOT()).To(ContainSubstring(`"owner:web-1/1" -> "lock:` + keyB + `" [label="waits"];`))
+		Expect(graph.DOT()).To(ContainSubstring(`"lock:` + keyA + `" [label="` + keyA + `", shape=box];`))
+
+		data, err := json.Marshal(graph)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`{"id":"owner:web-2/2","kind":"owner","owner":{"Host":"web-2","PID":2,"Label":""}}`))
+	})
+
+	It("should include requested keys", func() {
+		graph, err := subject.WaitGraph(ctx, keyA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(graph.Nodes).To(Equal([]redislock.WaitNode{{ID: "lock:" + keyA, Kind: redislock.WaitNodeLock, Key: keyA}}))
+		Expect(graph.Edges).To(BeEmpty())
+	})
+})
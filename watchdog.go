@@ -0,0 +1,213 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	mathrand "math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// AutoRefreshOptions configures the watchdog started by
+// StartAutoRefreshWith.
+type AutoRefreshOptions struct {
+	// Interval is the time between refreshes.
+	// Default: a third of the TTL
+	Interval time.Duration
+
+	// TTL is the lock TTL set by each refresh.
+	// Default: the requested TTL of the lock
+	TTL time.Duration
+
+	// Jitter shortens each interval by a random fraction of up to Jitter,
+	// e.g. 0.2 refreshes after 80% to 100% of Interval. This spreads out the
+	// refreshes of many locks obtained at once.
+	// Default: 0, must be in [0, 1]
+	Jitter float64
+
+	// MaxFailures is the number of consecutive failed refreshes after which
+	// the lock is declared lost, so that Lock.Done is closed and Lock.Err
+	// returns the last refresh error.
+	// Default: 0, the lock is only lost once its TTL runs out or a refresh
+	// finds it expired or stolen
+	MaxFailures int
+
+	// SafetyMargin is the minimum time left until the lock is expected to
+	// expire, see Lock.ValidUntil, when a refresh is sent. Refreshes are
+	// sent earlier than Interval where needed to keep the margin, on top of
+	// the time previous refreshes took, from the scheduled time until the
+	// response, to compensate for slow networks and pauses of the process.
+	// Default: 0, refreshes are only brought forward by their observed lag
+	SafetyMargin time.Duration
+
+	// OnRefreshError is called with every refresh error, if non-nil. It may
+	// release the lock or stop the watchdog.
+	OnRefreshError func(error)
+}
+
+func (o *AutoRefreshOptions) getTTL(l *Lock) time.Duration {
+	if o.TTL > 0 {
+		return o.TTL
+	}
+	return l.ttl
+}
+
+func (o *AutoRefreshOptions) nextInterval() time.Duration {
+	if o.Jitter <= 0 {
+		return o.Interval
+	}
+
+	f := o.Jitter
+	if f > 1 {
+		f = 1
+	}
+	return o.Interval - time.Duration(f*mathrand.Float64()*float64(o.Interval))
+}
+
+// nextDelay returns the time until the next refresh of l, if refreshes lag
+// behind by lag.
+func (o *AutoRefreshOptions) nextDelay(l *Lock, lag time.Duration) time.Duration {
+	d := o.nextInterval()
+	until := l.ValidUntil()
+	if until.IsZero() {
+		return d
+	}
+	if early := time.Until(until) - o.SafetyMargin - lag; early < d {
+		d = early
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+type watchdog struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// notifying is set while OnRefreshError runs. stop does not wait for the
+	// watchdog then, as the callback may stop it itself, e.g. via Release.
+	notifying int32
+}
+
+func (w *watchdog) stop() {
+	w.cancel()
+	if atomic.LoadInt32(&w.notifying) == 0 {
+		<-w.done
+	}
+}
+
+// StartAutoRefresh starts a background watchdog which refreshes the lock with
+// ttl every interval, until the lock is released or ctx is cancelled. A
+// non-positive interval refreshes after a third of the ttl.
+// Refresh errors are reported to onError, if non-nil. The watchdog stops once
+// the lock is lost, i.e. after an error matching ErrNotObtained.
+// Calling StartAutoRefresh again replaces the running watchdog.
+func (l *Lock) StartAutoRefresh(ctx context.Context, interval, ttl time.Duration, onError func(error)) {
+	l.StartAutoRefreshWith(ctx, &AutoRefreshOptions{
+		Interval:       interval,
+		TTL:            ttl,
+		OnRefreshError: onError,
+	})
+}
+
+// StartAutoRefreshWith is a variant of StartAutoRefresh configured by opt.
+// The watchdog stops once the lock is lost, i.e. after an error matching
+// ErrNotObtained or after opt.MaxFailures consecutive failures.
+func (l *Lock) StartAutoRefreshWith(ctx context.Context, opt *AutoRefreshOptions) {
+	o := *opt
+	if o.Interval <= 0 {
+		o.Interval = o.getTTL(l) / 3
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watchdog{cancel: cancel, done: make(chan struct{})}
+
+	l.mu.Lock()
+	prev := l.watchdog
+	l.watchdog = w
+	l.mu.Unlock()
+
+	if prev != nil {
+		prev.stop()
+	}
+
+	// The timer starts right away, so a fake Clock may advance immediately.
+	clock := l.client.clock()
+	delay := o.nextDelay(l, 0)
+	scheduled := clock.Now().Add(delay)
+	timer := clock.NewTimer(delay)
+	go func() {
+		defer close(w.done)
+		defer timer.Stop()
+
+		// lag is the time from the scheduled time of the last refresh
+		// until its response, or half the previous lag if that is longer.
+		var lag time.Duration
+		var failures int
+		reset := func() {
+			// Refreshes which are overdue right after a refresh are sent
+			// after Interval, so a SafetyMargin beyond the TTL does not
+			// refresh continuously.
+			d := o.nextDelay(l, lag)
+			if d <= 0 {
+				d = o.nextInterval()
+			}
+			scheduled = clock.Now().Add(d)
+			timer.Reset(d)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C():
+			}
+
+			err := l.Refresh(ctx, o.getTTL(l), nil)
+			if d := clock.Now().Sub(scheduled); d > lag/2 {
+				lag = d
+			} else {
+				lag /= 2
+			}
+			if err == nil {
+				failures = 0
+				reset()
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if o.OnRefreshError != nil {
+				atomic.StoreInt32(&w.notifying, 1)
+				o.OnRefreshError(err)
+				atomic.StoreInt32(&w.notifying, 0)
+				if ctx.Err() != nil {
+					return
+				}
+			}
+			if errors.Is(err, ErrNotObtained) {
+				return
+			}
+			if failures++; o.MaxFailures > 0 && failures >= o.MaxFailures {
+				if l.logger != nil {
+					l.logger.Log(LevelError, "lock lost after failed refreshes", "key", l.key, "token", shortToken(l.token), "failures", failures, "error", err)
+				}
+				l.lost(err)
+				return
+			}
+			reset()
+		}
+	}()
+}
+
+// StopAutoRefresh stops the watchdog started by StartAutoRefresh or
+// StartAutoRefreshWith, if any.
+func (l *Lock) StopAutoRefresh() {
+	l.mu.Lock()
+	w := l.watchdog
+	l.watchdog = nil
+	l.mu.Unlock()
+
+	if w != nil {
+		w.stop()
+	}
+}
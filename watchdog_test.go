@@ -0,0 +1,165 @@
+package redislock_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AutoRefresh", func() {
+	var ctx = context.Background()
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should keep the lock alive until released", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, 100*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var errs []error
+		lock.StartAutoRefresh(ctx, 20*time.Millisecond, 100*time.Millisecond, func(err error) {
+			errs = append(errs, err)
+		})
+
+		time.Sleep(300 * time.Millisecond)
+		Expect(lock.TTL(ctx)).To(BeNumerically(">", 0))
+		Expect(lock.Release(ctx)).To(Succeed())
+
+		time.Sleep(50 * time.Millisecond)
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("should default the interval to a third of the TTL", func() {
+		counting := &scriptCountingClient{RedisClient: redisClient}
+		lock, err := redislock.New(counting).Obtain(ctx, lockKey, time.Hour, 150*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		lock.StartAutoRefreshWith(ctx, &redislock.AutoRefreshOptions{})
+
+		Consistently(lock.Done(), 300*time.Millisecond).ShouldNot(BeClosed())
+		lock.StopAutoRefresh()
+		Expect(lock.TTL(ctx)).To(BeNumerically(">", 0))
+		Expect(atomic.LoadInt32(&counting.scripts)).To(BeNumerically("<=", 10))
+	})
+
+	It("should stop when the context is cancelled", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, 100*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		cctx, cancel := context.WithCancel(ctx)
+		lock.StartAutoRefresh(cctx, 20*time.Millisecond, 100*time.Millisecond, nil)
+		cancel()
+
+		Eventually(func() (time.Duration, error) { return lock.TTL(ctx) }, time.Second).Should(BeZero())
+	})
+
+	It("should refresh early to keep the safety margin", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, 100*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		lock.StartAutoRefreshWith(ctx, &redislock.AutoRefreshOptions{
+			Interval:     time.Hour,
+			SafetyMargin: 50 * time.Millisecond,
+		})
+		defer lock.StopAutoRefresh()
+
+		Consistently(lock.Done(), 300*time.Millisecond).ShouldNot(BeClosed())
+		Expect(lock.TTL(ctx)).To(BeNumerically(">", 0))
+	})
+
+	It("should report lost locks", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var mu sync.Mutex
+		var errs []error
+		lock.StartAutoRefresh(ctx, 10*time.Millisecond, time.Minute, func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		})
+		defer lock.StopAutoRefresh()
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		Eventually(func() []error {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]error(nil), errs...)
+		}).Should(ConsistOf(MatchError(redislock.ErrLockStolen)))
+	})
+	It("should declare locks lost after consecutive failures", func() {
+		flaky := &flakyClient{RedisClient: redisClient}
+		lock, err := redislock.New(flaky).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		var mu sync.Mutex
+		var errs []error
+		atomic.StoreInt32(&flaky.failures, 3)
+		lock.StartAutoRefreshWith(ctx, &redislock.AutoRefreshOptions{
+			Interval:    10 * time.Millisecond,
+			Jitter:      0.5,
+			MaxFailures: 3,
+			OnRefreshError: func(err error) {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			},
+		})
+		defer lock.StopAutoRefresh()
+
+		Eventually(lock.Done(), time.Second).Should(BeClosed())
+		Expect(lock.Err()).To(MatchError(errLoading))
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(errs).To(HaveLen(3))
+	})
+
+	It("should allow releasing from the error callback", func() {
+		flaky := &flakyClient{RedisClient: redisClient}
+		lock, err := redislock.New(flaky).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		released := make(chan error, 1)
+		atomic.StoreInt32(&flaky.failures, 1)
+		lock.StartAutoRefreshWith(ctx, &redislock.AutoRefreshOptions{
+			Interval: 10 * time.Millisecond,
+			OnRefreshError: func(error) {
+				released <- lock.Release(ctx)
+			},
+		})
+
+		var rerr error
+		Eventually(released, time.Second).Should(Receive(&rerr))
+		Expect(rerr).NotTo(HaveOccurred())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+		lock.StopAutoRefresh()
+	})
+
+	It("should reset the failure count after a successful refresh", func() {
+		flaky := &flakyClient{RedisClient: redisClient}
+		lock, err := redislock.New(flaky).Obtain(ctx, lockKey, time.Hour, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release(ctx)
+
+		atomic.StoreInt32(&flaky.failures, 1)
+		lock.StartAutoRefreshWith(ctx, &redislock.AutoRefreshOptions{
+			Interval:    10 * time.Millisecond,
+			MaxFailures: 2,
+		})
+		defer lock.StopAutoRefresh()
+
+		Eventually(func() int32 { return atomic.LoadInt32(&flaky.failures) }).Should(BeNumerically("<", -2))
+		atomic.StoreInt32(&flaky.failures, 1)
+		Consistently(lock.Done(), 100*time.Millisecond).ShouldNot(BeClosed())
+	})
+})
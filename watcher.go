@@ -0,0 +1,153 @@
+package redislock
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// keyEventPatterns are the keyspace notification channels a Watcher
+// subscribes to. The message payload is the affected key.
+var keyEventPatterns = []string{"__keyevent@*__:del", "__keyevent@*__:expired", "__keyevent@*__:set"}
+
+// Watcher detects the loss of locks via keyspace notifications, so Lock.Done
+// is closed within milliseconds of a lock key being deleted, expiring or
+// being overwritten by someone else, instead of on the next failed refresh.
+//
+// Keyspace notifications must be enabled on the server, e.g. via
+// "CONFIG SET notify-keyspace-events Egx$". They are only delivered by the
+// node holding the key, so on a cluster a Watcher covers a single node.
+// It is safe for concurrent use.
+type Watcher struct {
+	sub   *redis.PubSub
+	locks map[string]map[*Lock]struct{}
+	mu    sync.Mutex
+
+	closed chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewWatcher subscribes to keyspace notifications via client. Pass it via
+// Defaults.Watcher to watch all locks obtained by a client, or use Watch.
+// Call Close to unsubscribe.
+func NewWatcher(ctx context.Context, client RedisClient) (*Watcher, error) {
+	subscriber, ok := client.(PatternSubscribingClient)
+	if !ok {
+		return nil, errWatchUnsupported
+	}
+
+	sub := subscriber.PSubscribe(ctx, keyEventPatterns...)
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		sub:    sub,
+		locks:  make(map[string]map[*Lock]struct{}),
+		closed: make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.loop(sub.Channel())
+	return w, nil
+}
+
+// Watch watches lock until it is known to be lost and returns it. Locks
+// obtained via ObtainMulti are watched on all keys.
+func (w *Watcher) Watch(lock *Lock) *Lock {
+	keys := lock.scriptKeys[:1]
+	if lock.scripts == multiScripts {
+		keys = lock.scriptKeys
+	}
+
+	w.mu.Lock()
+	for _, key := range keys {
+		locks, ok := w.locks[key]
+		if !ok {
+			locks = make(map[*Lock]struct{})
+			w.locks[key] = locks
+		}
+		locks[lock] = struct{}{}
+	}
+	w.mu.Unlock()
+
+	go func() {
+		select {
+		case <-lock.Done():
+		case <-w.closed:
+		}
+
+		w.mu.Lock()
+		for _, key := range keys {
+			delete(w.locks[key], lock)
+			if len(w.locks[key]) == 0 {
+				delete(w.locks, key)
+			}
+		}
+		w.mu.Unlock()
+	}()
+	return lock
+}
+
+// Close unsubscribes from keyspace notifications and stops watching.
+func (w *Watcher) Close() error {
+	err := w.sub.Close()
+	w.wg.Wait()
+	return err
+}
+
+func (w *Watcher) loop(ch <-chan *redis.Message) {
+	defer w.wg.Done()
+	defer close(w.closed)
+
+	for msg := range ch {
+		event := msg.Channel[strings.LastIndexByte(msg.Channel, ':')+1:]
+		for _, lock := range w.watched(msg.Payload) {
+			w.check(lock, event)
+		}
+	}
+}
+
+func (w *Watcher) watched(key string) []*Lock {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	locks := make([]*Lock, 0, len(w.locks[key]))
+	for lock := range w.locks[key] {
+		locks = append(locks, lock)
+	}
+	return locks
+}
+
+// check marks lock as lost after event unless it is still held.
+func (w *Watcher) check(lock *Lock, event string) {
+	if lock.Err() != nil {
+		return
+	}
+	if held, err := lock.IsHeld(context.Background()); err != nil || held {
+		return
+	}
+
+	err, reason := ErrLockExpired, "expired"
+	if event == "set" {
+		err, reason = ErrLockStolen, "stolen"
+	}
+	if lock.lostRemotely(err) && lock.logger != nil {
+		lock.logger.Log(LevelWarn, "lock lost", "key", lock.key, "token", shortToken(lock.token), "reason", reason, "event", event)
+	}
+}
+
+// lostRemotely marks the lock as lost unless it is being released by this
+// process. It reports whether the lock was marked.
+func (l *Lock) lostRemotely(err error) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.releasing || l.doneErr != nil {
+		return false
+	}
+	l.setLost(err)
+	return true
+}
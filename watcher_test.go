@@ -0,0 +1,66 @@
+package redislock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/muroq/redislock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watcher", func() {
+	var watcher *redislock.Watcher
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		var err error
+		watcher, err = redislock.NewWatcher(ctx, redisClient)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(watcher.Close()).To(Succeed())
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	// publish simulates the keyspace notifications sent by redis, if enabled.
+	publish := func(event string) {
+		Expect(redisClient.Publish(ctx, "__keyevent@0__:"+event, lockKey).Err()).To(Succeed())
+	}
+
+	It("should detect deleted locks", func() {
+		subject := redislock.New(redisClient, redislock.Defaults{Watcher: watcher})
+		lock, err := subject.Obtain(ctx, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		publish("del")
+		Consistently(lock.Done(), 50*time.Millisecond).ShouldNot(BeClosed())
+
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+		publish("del")
+		Eventually(lock.Done()).Should(BeClosed())
+		Expect(lock.Err()).To(Equal(redislock.ErrLockExpired))
+	})
+
+	It("should detect overwritten locks", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		watcher.Watch(lock)
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		publish("set")
+		Eventually(lock.Done()).Should(BeClosed())
+		Expect(lock.Err()).To(Equal(redislock.ErrLockStolen))
+	})
+
+	It("should not mistake releases for losses", func() {
+		lock, err := redislock.Obtain(ctx, redisClient, lockKey, time.Hour, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		watcher.Watch(lock)
+
+		Expect(lock.Release(ctx)).To(Succeed())
+		publish("del")
+		Consistently(lock.Err, 50*time.Millisecond).Should(Equal(redislock.ErrLockNotHeld))
+	})
+})